@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxAuditBody 记录到审计日志中的请求正文最大长度，超出时不记录正文
+	maxAuditBody = 64 * 1024
+	// maxAuditResponse 解析结果时读取的响应正文最大长度
+	maxAuditResponse = 4096
+	// maxAuditDetail 审计日志中请求参数的最大长度
+	maxAuditDetail = 4096
+)
+
+// auditWriter 记录响应正文开头部分的 ResponseWriter，用于解析操作结果
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(data []byte) (int, error) {
+	if remain := maxAuditResponse - w.body.Len(); remain > 0 {
+		if len(data) < remain {
+			remain = len(data)
+		}
+		w.body.Write(data[:remain])
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Audit 记录请求到审计日志，action 为空时使用请求方法和路由作为操作名称
+func Audit(category, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		detail := readAuditBody(c)
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		entry := &model.AuditLog{
+			UserID:    auditActor(c),
+			Category:  category,
+			Action:    action,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Target:    c.Request.URL.Path,
+			Detail:    detail,
+			Success:   writer.Status() < 400,
+		}
+		if entry.Action == "" {
+			entry.Action = c.Request.Method + " " + c.FullPath()
+		}
+
+		// 业务错误以 JSON 响应中的状态码表示
+		var res struct {
+			Code  int    `json:"code"`
+			Msg   string `json:"msg"`
+			Error string `json:"error"`
+		}
+		if strings.Contains(writer.Header().Get("Content-Type"), "json") &&
+			json.Unmarshal(writer.body.Bytes(), &res) == nil {
+			entry.Code = res.Code
+			entry.Success = entry.Success && res.Code == 0
+			entry.Error = res.Msg
+			if res.Error != "" {
+				entry.Error += ": " + res.Error
+			}
+		}
+
+		if err := entry.Create(); err != nil {
+			util.Log().Warning("Failed to write audit log: %s", err)
+		}
+	}
+}
+
+// auditActor 返回操作者，登录请求返回登录成功或待二步验证的用户
+func auditActor(c *gin.Context) uint {
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok {
+			return u.ID
+		}
+	}
+
+	for _, key := range []string{"user_id", "2fa_user_id"} {
+		if uid, ok := util.GetSession(c, key).(uint); ok {
+			return uid
+		}
+	}
+
+	return 0
+}
+
+// readAuditBody 读取并还原 JSON 请求正文，返回脱敏后的内容
+func readAuditBody(c *gin.Context) string {
+	if c.Request.Body == nil || !strings.Contains(c.ContentType(), "json") {
+		return ""
+	}
+
+	buf, _ := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditBody+1))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), c.Request.Body), c.Request.Body}
+	if len(buf) > maxAuditBody {
+		return ""
+	}
+
+	var body interface{}
+	if json.Unmarshal(buf, &body) != nil {
+		return ""
+	}
+
+	res, _ := json.Marshal(redact(body))
+	if len(res) > maxAuditDetail {
+		res = res[:maxAuditDetail]
+	}
+
+	return string(res)
+}
+
+// redact 隐去请求参数中的密码、密钥等敏感内容
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// 设置项以 {"key": 名称, "value": 值} 的形式提交
+		if name, ok := v["key"].(string); ok && isSensitive(name) {
+			if _, ok := v["value"]; ok {
+				v["value"] = "***"
+			}
+		}
+
+		for k, item := range v {
+			if isSensitive(k) {
+				v[k] = "***"
+			} else {
+				v[k] = redact(item)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+
+	return value
+}
+
+// isSensitive 返回参数名是否为敏感内容
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	if name != "key" && strings.HasSuffix(name, "key") {
+		return true
+	}
+
+	for _, word := range []string{"pass", "pwd", "secret", "token", "private", "credential", "salt"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
 	"net/http"
 
 	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/auth"
 	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/s3gateway"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -117,6 +119,36 @@ func WebDAVAuth() gin.HandlerFunc {
 	}
 }
 
+// S3Auth 验证 S3 接口请求的签名
+func S3Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, err := s3gateway.Authenticate(c.Request)
+		if err != nil {
+			s3gateway.WriteError(c.Writer, c.Request, err)
+			c.Abort()
+			return
+		}
+
+		expectedUser, err := model.GetActiveUserByID(key.UserID)
+		if err != nil {
+			s3gateway.WriteError(c.Writer, c.Request, s3gateway.ErrInvalidAccessKeyID)
+			c.Abort()
+			return
+		}
+
+		// 用户组已启用S3接口？
+		if !expectedUser.Group.OptionsSerialized.S3Gateway {
+			s3gateway.WriteError(c.Writer, c.Request, s3gateway.ErrAccessDenied)
+			c.Abort()
+			return
+		}
+
+		c.Set("user", &expectedUser)
+		c.Set("s3key", key)
+		c.Next()
+	}
+}
+
 // UseUploadSession 对上传会话进行验证
 func UseUploadSession(policyType string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -180,6 +212,20 @@ func RemoteCallbackAuth() gin.HandlerFunc {
 	}
 }
 
+// UploadCallbackKeyAuth 验证回调地址中的 callback key
+func UploadCallbackKeyAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := c.MustGet(filesystem.UploadSessionCtx).(*serializer.UploadSession)
+		if subtle.ConstantTimeCompare([]byte(c.Param("key")), []byte(session.CallbackSecret)) != 1 {
+			c.JSON(CallbackFailedStatusCode, serializer.Err(serializer.CodeCredentialInvalid, "Invalid callback key", nil))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // IsAdmin 必须为管理员用户组
 func IsAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
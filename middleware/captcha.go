@@ -54,36 +54,39 @@ func CaptchaRequired(configName string) gin.HandlerFunc {
 			}
 
 			c.Request.Body = ioutil.NopCloser(bytes.NewReader(bodyData))
-			switch options["captcha_type"] {
-			case "normal":
-				captchaID := util.GetSession(c, "captchaID")
-				util.DeleteSession(c, "captchaID")
-				if captchaID == nil || !base64Captcha.VerifyCaptcha(captchaID.(string), service.CaptchaCode) {
-					c.JSON(200, serializer.Err(serializer.CodeCaptchaError, captchaNotMatch, err))
-					c.Abort()
-					return
-				}
-
-				break
-			case "recaptcha":
-				reCAPTCHA, err := recaptcha.NewReCAPTCHA(options["captcha_ReCaptchaSecret"], recaptcha.V2, 10*time.Second)
-				if err != nil {
-					util.Log().Warning("reCAPTCHA verification failed, %s", err)
-					c.Abort()
-					break
-				}
-
-				err = reCAPTCHA.Verify(service.CaptchaCode)
-				if err != nil {
-					util.Log().Warning("reCAPTCHA verification failed, %s", err)
-					c.JSON(200, serializer.Err(serializer.CodeCaptchaRefreshNeeded, captchaRefresh, nil))
-					c.Abort()
-					return
-				}
-
-				break
+			if !verifyCaptcha(c, options, service.CaptchaCode) {
+				c.Abort()
+				return
 			}
 		}
 		c.Next()
 	}
 }
+
+// verifyCaptcha 校验验证码，未通过时写入错误响应并返回 false
+func verifyCaptcha(c *gin.Context, options map[string]string, code string) bool {
+	switch options["captcha_type"] {
+	case "normal":
+		captchaID := util.GetSession(c, "captchaID")
+		util.DeleteSession(c, "captchaID")
+		if captchaID == nil || !base64Captcha.VerifyCaptcha(captchaID.(string), code) {
+			c.JSON(200, serializer.Err(serializer.CodeCaptchaError, captchaNotMatch, nil))
+			return false
+		}
+	case "recaptcha":
+		reCAPTCHA, err := recaptcha.NewReCAPTCHA(options["captcha_ReCaptchaSecret"], recaptcha.V2, 10*time.Second)
+		if err != nil {
+			util.Log().Warning("reCAPTCHA verification failed, %s", err)
+			return false
+		}
+
+		err = reCAPTCHA.Verify(code)
+		if err != nil {
+			util.Log().Warning("reCAPTCHA verification failed, %s", err)
+			c.JSON(200, serializer.Err(serializer.CodeCaptchaRefreshNeeded, captchaRefresh, nil))
+			return false
+		}
+	}
+
+	return true
+}
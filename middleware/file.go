@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"net/http"
+
 	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/tus"
 	"github.com/gin-gonic/gin"
 )
 
@@ -28,3 +31,17 @@ func ValidateSourceLink() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// TusResumable 校验 tus 协议版本，OPTIONS 请求用于获取服务端支持的版本，无需校验
+func TusResumable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(tus.ResumableHeader, tus.Version)
+		if c.Request.Method != http.MethodOptions && c.GetHeader(tus.ResumableHeader) != tus.Version {
+			c.Header(tus.VersionHeader, tus.Version)
+			c.AbortWithStatus(http.StatusPreconditionFailed)
+			return
+		}
+
+		c.Next()
+	}
+}
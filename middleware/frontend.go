@@ -42,6 +42,7 @@ func FrontendFileHandler() gin.HandlerFunc {
 		if strings.HasPrefix(path, "/api") ||
 			strings.HasPrefix(path, "/custom") ||
 			strings.HasPrefix(path, "/dav") ||
+			strings.HasPrefix(path, "/s3") ||
 			strings.HasPrefix(path, "/f") ||
 			path == "/manifest.json" {
 			c.Next()
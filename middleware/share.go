@@ -45,7 +45,7 @@ func ShareAvailable() gin.HandlerFunc {
 
 		share := model.GetShareByHashID(c.Param("id"))
 
-		if share == nil || !share.IsAvailable() {
+		if share == nil || !share.IsAvailable() || !share.HasViewQuota(user, c) {
 			c.JSON(200, serializer.Err(serializer.CodeShareLinkNotFound, "", nil))
 			c.Abort()
 			return
@@ -57,6 +57,36 @@ func ShareAvailable() gin.HandlerFunc {
 	}
 }
 
+// ShareUnlockLimit 限制分享密码的尝试次数，失败次数过多时锁定，超过一定次数后要求验证码
+func ShareUnlockLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		share := c.MustGet("share").(*model.Share)
+		if share.Password == "" || c.Query("password") == "" ||
+			util.GetSession(c, fmt.Sprintf("share_unlock_%d", share.ID)) != nil {
+			c.Next()
+			return
+		}
+
+		if share.UnlockLocked(c.ClientIP()) {
+			c.JSON(200, serializer.Err(serializer.CodeShareUnlockLocked, "", nil))
+			c.Abort()
+			return
+		}
+
+		byIP, _ := share.UnlockFailures(c.ClientIP())
+		threshold := model.GetIntSetting("share_unlock_captcha_attempts", 3)
+		if threshold > 0 && byIP >= threshold {
+			options := model.GetSettingByNames("captcha_type", "captcha_ReCaptchaSecret")
+			if !verifyCaptcha(c, options, c.Query("captchaCode")) {
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
 // ShareCanPreview 检查分享是否可被预览
 func ShareCanPreview() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -79,6 +109,14 @@ func CheckShareUnlocked() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if shareCtx, ok := c.Get("share"); ok {
 			share := shareCtx.(*model.Share)
+			// 受限分享是否可被访问
+			user := c.MustGet("user").(*model.User)
+			if !share.CanBeAccessedBy(user, c) {
+				c.JSON(200, serializer.Err(serializer.CodeShareRestricted, "", nil))
+				c.Abort()
+				return
+			}
+
 			// 分享是否已解锁
 			if share.Password != "" {
 				sessionKey := fmt.Sprintf("share_unlock_%d", share.ID)
@@ -91,6 +129,13 @@ func CheckShareUnlocked() gin.HandlerFunc {
 				}
 			}
 
+			// 限制访问次数的分享须先通过获取分享信息扣减访问配额
+			if !share.ViewCounted(user, c) {
+				c.JSON(200, serializer.Err(serializer.CodeNoPermissionErr, "", nil))
+				c.Abort()
+				return
+			}
+
 			c.Next()
 			return
 		}
@@ -131,3 +176,18 @@ func BeforeShareDownload() gin.HandlerFunc {
 		c.Abort()
 	}
 }
+
+// FileRequestAvailable 检查文件收集链接是否可用
+func FileRequestAvailable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request := model.GetFileRequestByHashID(c.Param("id"))
+		if request == nil || !request.IsAvailable() {
+			c.JSON(200, serializer.Err(serializer.CodeFileRequestNotFound, "", nil))
+			c.Abort()
+			return
+		}
+
+		c.Set("fileRequest", request)
+		c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+)
+
+// 审计日志分类
+const (
+	AuditFile   = "file"
+	AuditShare  = "share"
+	AuditLogin  = "login"
+	AuditWebDAV = "webdav"
+	AuditS3     = "s3"
+	AuditAdmin  = "admin"
+)
+
+// AuditLog 用户及管理员操作的审计日志
+type AuditLog struct {
+	ID        uint      `gorm:"primary_key"`
+	CreatedAt time.Time `gorm:"index:audit_created_at"`
+	// UserID 操作者，未登录时为 0
+	UserID    uint   `gorm:"index:audit_user_id"`
+	Category  string `gorm:"index:audit_category"`
+	Action    string
+	IP        string
+	UserAgent string `gorm:"type:text"`
+	// Target 操作对象，一般为请求路径
+	Target string `gorm:"type:text"`
+	// Detail 脱敏后的请求参数
+	Detail  string `gorm:"type:text"`
+	Success bool   `gorm:"type:bool"`
+	// Code 响应中的业务状态码
+	Code  int
+	Error string `gorm:"type:text"`
+}
+
+// Create 写入审计日志
+func (log *AuditLog) Create() error {
+	return DB.Create(log).Error
+}
+
+// DeleteAuditLogsBefore 删除 before 之前的审计日志，返回删除的数量
+func DeleteAuditLogsBefore(before time.Time) (int64, error) {
+	res := DB.Where("created_at < ?", before).Delete(&AuditLog{})
+	return res.RowsAffected, res.Error
+}
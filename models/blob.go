@@ -0,0 +1,109 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Blob 去重存储中以内容哈希寻址的物理文件，多个文件记录共享同一个 Blob
+type Blob struct {
+	gorm.Model
+	PolicyID   uint   `gorm:"unique_index:idx_blob_hash"`
+	Hash       string `gorm:"size:64;unique_index:idx_blob_hash"`
+	Size       uint64
+	SourceName string `gorm:"type:text"`
+	// RefCount 引用此 Blob 的文件记录数
+	RefCount int
+}
+
+// BlobStatistics 单个存储策略的去重统计
+type BlobStatistics struct {
+	PolicyID uint   `json:"policy_id"`
+	Blobs    int    `json:"blobs"`
+	Refs     int    `json:"refs"`
+	Physical uint64 `json:"physical"`
+	Logical  uint64 `json:"logical"`
+}
+
+// GetBlobByHash 根据内容哈希查找 Blob
+func GetBlobByHash(policyID uint, hash string, size uint64) (*Blob, error) {
+	blob := &Blob{}
+	result := DB.Where("policy_id = ? and hash = ? and size = ?", policyID, hash, size).First(blob)
+	return blob, result.Error
+}
+
+// GetBlobSources 返回 sources 中属于去重存储 Blob 的物理路径
+func GetBlobSources(policyID uint, sources []string) map[string]bool {
+	res := make(map[string]bool)
+	if len(sources) == 0 {
+		return res
+	}
+
+	var blobs []Blob
+	DB.Select("source_name").Where("policy_id = ? and source_name in (?)", policyID, sources).Find(&blobs)
+	for _, blob := range blobs {
+		res[blob.SourceName] = true
+	}
+
+	return res
+}
+
+// IsBlobSource 返回给定物理路径是否属于去重存储 Blob
+func IsBlobSource(policyID uint, source string) bool {
+	return GetBlobSources(policyID, []string{source})[source]
+}
+
+// Create 创建引用计数为 1 的 Blob
+func (blob *Blob) Create() error {
+	blob.RefCount = 1
+	return DB.Create(blob).Error
+}
+
+// Retain 增加一次引用，Blob 已被删除时返回 false
+func (blob *Blob) Retain() bool {
+	result := DB.Model(&Blob{}).Where("id = ?", blob.ID).
+		UpdateColumn("ref_count", gorm.Expr("ref_count + ?", 1))
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+// RetainBlobBySource 文件记录被复制时，增加其对应 Blob 的引用
+func RetainBlobBySource(policyID uint, source string) error {
+	return DB.Model(&Blob{}).Where("policy_id = ? and source_name = ?", policyID, source).
+		UpdateColumn("ref_count", gorm.Expr("ref_count + ?", 1)).Error
+}
+
+// ReleaseBlobBySource 释放一次物理路径对应 Blob 的引用，返回剩余的引用数。
+// 最后一个引用释放后删除 Blob 记录；物理路径不属于 Blob 时 ok 为 false
+func ReleaseBlobBySource(policyID uint, source string) (remain int, ok bool, err error) {
+	tx := DB.Begin()
+	result := tx.Model(&Blob{}).Where("policy_id = ? and source_name = ?", policyID, source).
+		UpdateColumn("ref_count", gorm.Expr("ref_count - ?", 1))
+	if result.Error != nil || result.RowsAffected == 0 {
+		tx.Rollback()
+		return 0, false, result.Error
+	}
+
+	blob := &Blob{}
+	if err := tx.Where("policy_id = ? and source_name = ?", policyID, source).First(blob).Error; err != nil {
+		tx.Rollback()
+		return 0, true, err
+	}
+
+	if blob.RefCount <= 0 {
+		if err := tx.Unscoped().Delete(blob).Error; err != nil {
+			tx.Rollback()
+			return 0, true, err
+		}
+	}
+
+	return blob.RefCount, true, tx.Commit().Error
+}
+
+// GetBlobStatistics 按存储策略统计去重存储节省的空间
+func GetBlobStatistics() ([]BlobStatistics, error) {
+	var res []BlobStatistics
+	err := DB.Model(&Blob{}).
+		Select("policy_id, count(*) as blobs, sum(ref_count) as refs, sum(size) as physical, sum(size * ref_count) as logical").
+		Group("policy_id").
+		Scan(&res).Error
+	return res, err
+}
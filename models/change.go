@@ -0,0 +1,81 @@
+package model
+
+import (
+	"time"
+)
+
+// 变更类型
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeRename = "rename"
+	ChangeMove   = "move"
+	ChangeDelete = "delete"
+)
+
+// Change 文件和目录的变更日志，ID 递增，作为变更流的游标。
+// 目录的创建和删除只记录目录本身，不记录其中的子对象
+type Change struct {
+	ID        uint      `gorm:"primary_key"`
+	CreatedAt time.Time `gorm:"index:change_created_at"`
+	UserID    uint      `gorm:"index:change_user_id"`
+	Type      string
+	IsFolder  bool
+	ObjectID  uint
+	Name      string
+	// Path 变更后所在目录的路径，删除时为删除前所在目录的路径
+	Path string `gorm:"type:text"`
+	// OldName 重命名、移动前的名称
+	OldName string
+	// OldPath 移动前所在目录的路径
+	OldPath string `gorm:"type:text"`
+	Size    uint64
+}
+
+// RecordChanges 写入变更日志
+func RecordChanges(changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	tx := DB.Begin()
+	for i := range changes {
+		if err := tx.Create(&changes[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// GetChanges 列出用户 ID 大于 after 的变更，root 不为 / 时只列出变更前后位于 root 下的变更
+func GetChanges(uid, after uint, root string, limit int) ([]Change, error) {
+	var changes []Change
+	result := DB.Where("user_id = ? and id > ?", uid, after)
+	if root != "/" {
+		pattern := escapeLike(root) + "/%"
+		result = result.Where("path = ? or path like ? escape '!' or old_path = ? or old_path like ? escape '!'",
+			root, pattern, root, pattern)
+	}
+
+	result = result.Order("id asc").Limit(limit).Find(&changes)
+	return changes, result.Error
+}
+
+// GetLatestChangeID 获取用户最新的变更 ID
+func GetLatestChangeID(uid uint) (uint, error) {
+	var change Change
+	result := DB.Where("user_id = ?", uid).Order("id desc").Limit(1).Find(&change)
+	if result.RecordNotFound() {
+		return 0, nil
+	}
+
+	return change.ID, result.Error
+}
+
+// DeleteChangesBefore 压缩变更日志，删除 before 之前的变更
+func DeleteChangesBefore(before time.Time) (int64, error) {
+	result := DB.Where("created_at < ?", before).Delete(&Change{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,233 @@
+package model
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+// ContentTerm 文件内容倒排索引，数据库不支持 SQLite FTS5 时使用
+type ContentTerm struct {
+	ID     uint   `gorm:"primary_key"`
+	UserID uint   `gorm:"index:content_user_term"`
+	Term   string `gorm:"size:64;index:content_user_term"`
+	FileID uint   `gorm:"index:content_file_id"`
+}
+
+const (
+	// maxContentTerms 单个文件最多索引的词数
+	maxContentTerms = 20000
+	// maxTermLength 词的最大长度
+	maxTermLength = 64
+	// contentTermBatch 批量写入倒排索引的行数
+	contentTermBatch = 300
+	// maxQueryTerms 搜索词的最大数量
+	maxQueryTerms = 32
+)
+
+var (
+	contentFTS     bool
+	contentFTSOnce sync.Once
+)
+
+// useContentFTS 返回是否使用 SQLite FTS5 存储文件内容索引，首次调用时尝试创建 FTS5 表
+func useContentFTS() bool {
+	contentFTSOnce.Do(func() {
+		err := DB.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS file_contents USING fts5(content, file_id UNINDEXED, user_id UNINDEXED)").Error
+		contentFTS = err == nil
+		if contentFTS {
+			util.Log().Info("Using SQLite FTS5 for content index.")
+		}
+	})
+
+	return contentFTS
+}
+
+// IndexFileContent 更新文件的内容索引
+func IndexFileContent(file *File, text string) error {
+	if err := DeleteFileContents([]uint{file.ID}); err != nil {
+		return err
+	}
+
+	terms := contentTerms(text, maxContentTerms)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	if useContentFTS() {
+		return DB.Exec("INSERT INTO file_contents (content, file_id, user_id) VALUES (?, ?, ?)",
+			strings.Join(terms, " "), file.ID, file.UserID).Error
+	}
+
+	tx := DB.Begin()
+	for start := 0; start < len(terms); start += contentTermBatch {
+		end := start + contentTermBatch
+		if end > len(terms) {
+			end = len(terms)
+		}
+
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*3)
+		for _, term := range terms[start:end] {
+			values = append(values, "(?, ?, ?)")
+			args = append(args, file.UserID, term, file.ID)
+		}
+
+		if err := tx.Exec("INSERT INTO content_terms (user_id, term, file_id) VALUES "+
+			strings.Join(values, ", "), args...).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteFileContents 删除文件的内容索引
+func DeleteFileContents(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if useContentFTS() {
+		return DB.Exec("DELETE FROM file_contents WHERE file_id IN (?)", ids).Error
+	}
+
+	return DB.Where("file_id in (?)", ids).Delete(&ContentTerm{}).Error
+}
+
+// SearchFileContent 搜索内容包含 query 中所有词的文件，按文件ID排序并以 opts.Limit 分页，
+// opts.Folders 不为空时限定父目录；返回的游标为空时表示没有更多结果
+func SearchFileContent(opts *SearchOptions, query string) ([]File, *SearchCursor, error) {
+	terms := contentTerms(query, maxQueryTerms)
+	if len(terms) == 0 {
+		return []File{}, nil, nil
+	}
+
+	parents := make(map[uint]bool, len(opts.Folders))
+	for _, id := range opts.Folders {
+		parents[id] = true
+	}
+
+	var after uint
+	if opts.Cursor != nil {
+		after = opts.Cursor.ID
+	}
+
+	// 分批取出匹配的文件ID，父目录在取出文件后过滤，避免绑定过多参数
+	files := make([]File, 0, opts.Limit+1)
+	for len(files) <= opts.Limit {
+		ids, err := matchContent(opts.UserID, terms, after, searchBatchSize)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(ids) == 0 {
+			break
+		}
+		after = ids[len(ids)-1]
+
+		var batch []File
+		if err := DB.Where("user_id = ? and id in (?)", opts.UserID, ids).Order("id").Find(&batch).Error; err != nil {
+			return nil, nil, err
+		}
+
+		for _, file := range batch {
+			if len(parents) == 0 || parents[file.FolderID] {
+				files = append(files, file)
+			}
+		}
+
+		if len(ids) < searchBatchSize {
+			break
+		}
+	}
+
+	if len(files) <= opts.Limit {
+		return files, nil, nil
+	}
+
+	files = files[:opts.Limit]
+	return files, &SearchCursor{ID: files[opts.Limit-1].ID}, nil
+}
+
+// matchContent 按文件ID顺序取出 after 之后最多 limit 个内容包含所有词的文件ID
+func matchContent(uid uint, terms []string, after uint, limit int) ([]uint, error) {
+	var ids []uint
+	if useContentFTS() {
+		quoted := make([]string, 0, len(terms))
+		for _, term := range terms {
+			quoted = append(quoted, `"`+term+`"`)
+		}
+
+		err := DB.Raw("SELECT file_id FROM file_contents WHERE file_contents MATCH ? AND user_id = ? AND file_id > ? ORDER BY file_id LIMIT ?",
+			strings.Join(quoted, " AND "), uid, after, limit).Pluck("file_id", &ids).Error
+		return ids, err
+	}
+
+	err := DB.Model(&ContentTerm{}).Where("user_id = ? and term in (?) and file_id > ?", uid, terms, after).
+		Group("file_id").Having("count(distinct term) = ?", len(terms)).
+		Order("file_id").Limit(limit).Pluck("file_id", &ids).Error
+	return ids, err
+}
+
+// contentTerms 将文本拆分为去重后的小写词，中日韩文字按相邻两字拆分
+func contentTerms(text string, limit int) []string {
+	terms := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if len(terms) >= limit || term == "" || len(term) > maxTermLength || seen[term] {
+			return
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+
+	var (
+		word []rune
+		cjk  []rune
+	)
+
+	flushWord := func() {
+		add(strings.ToLower(string(word)))
+		word = word[:0]
+	}
+
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			add(string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			add(string(cjk[i : i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+
+		if len(terms) >= limit {
+			return terms
+		}
+	}
+
+	flushWord()
+	flushCJK()
+	return terms
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -27,6 +27,8 @@ type File struct {
 	PolicyID        uint
 	UploadSessionID *string `gorm:"index:session_id;unique_index:session_only_one"`
 	Metadata        string  `gorm:"type:text"`
+	// Extension 小写的扩展名，不含 .，按类型排序时使用
+	Extension string
 
 	// 关联模型
 	Policy Policy `gorm:"PRELOAD:false,association_autoupdate:false"`
@@ -70,6 +72,11 @@ func (file *File) Create() error {
 		return err
 	}
 
+	if err := changeFolderSize(tx, file.UserID, file.FolderID, "+", file.Size); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit().Error
 }
 
@@ -87,6 +94,7 @@ func (file *File) AfterFind() (err error) {
 
 // BeforeSave Save策略前的钩子
 func (file *File) BeforeSave() (err error) {
+	file.Extension = FileExtension(file.Name)
 	if len(file.MetadataSerialized) > 0 {
 		metaValue, err := json.Marshal(&file.MetadataSerialized)
 		file.Metadata = string(metaValue)
@@ -96,6 +104,11 @@ func (file *File) BeforeSave() (err error) {
 	return nil
 }
 
+// FileExtension 返回文件名小写的扩展名，不含 .
+func FileExtension(name string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
 // GetChildFile 查找目录下名为name的子文件
 func (folder *Folder) GetChildFile(name string) (*File, error) {
 	var file File
@@ -202,7 +215,8 @@ func (file *File) GetPolicy() *Policy {
 	return &file.Policy
 }
 
-// RemoveFilesWithSoftLinks 去除给定的文件列表中有软链接的文件
+// RemoveFilesWithSoftLinks 去除给定的文件列表中有软链接的文件，
+// 去重存储的文件和被历史版本引用的文件总是视为有软链接
 func RemoveFilesWithSoftLinks(files []File) ([]File, error) {
 	// 结果值
 	filteredFiles := make([]File, 0)
@@ -214,8 +228,14 @@ func RemoveFilesWithSoftLinks(files []File) ([]File, error) {
 	// 查询软链接的文件
 	filesWithSoftLinks := make([]File, 0)
 	for _, file := range files {
+		if IsBlobSource(file.PolicyID, file.SourceName) || IsVersionSource(file.PolicyID, file.SourceName) {
+			filesWithSoftLinks = append(filesWithSoftLinks, file)
+			continue
+		}
+
+		// 回收站中的文件同样引用物理文件
 		var softLinkFile File
-		res := DB.
+		res := DB.Unscoped().
 			Where("source_name = ? and policy_id = ? and id != ?", file.SourceName, file.PolicyID, file.ID).
 			First(&softLinkFile)
 		if res.Error == nil {
@@ -270,6 +290,14 @@ func DeleteFiles(files []*File, uid uint) error {
 			return errors.New("file size is dirty")
 		}
 
+		// 回收站中的文件在移入时已扣除目录大小
+		if file.DeletedAt == nil {
+			if err := changeFolderSize(tx, file.UserID, file.FolderID, "-", file.Size); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+
 		size += file.Size
 	}
 
@@ -280,7 +308,17 @@ func DeleteFiles(files []*File, uid uint) error {
 		}
 	}
 
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// 删除内容索引
+	ids := make([]uint, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.ID)
+	}
+
+	return DeleteFileContents(ids)
 }
 
 // GetFilesByParentIDs 根据父目录ID查找文件
@@ -309,8 +347,9 @@ func (file *File) Rename(new string) error {
 	}
 
 	return DB.Model(&file).Set("gorm:association_autoupdate", false).Updates(map[string]interface{}{
-		"name":     new,
-		"metadata": file.Metadata,
+		"name":      new,
+		"extension": FileExtension(new),
+		"metadata":  file.Metadata,
 	}).Error
 }
 
@@ -372,6 +411,11 @@ func (file *File) UpdateSize(value uint64) error {
 		return err
 	}
 
+	if err := changeFolderSize(tx, file.UserID, file.FolderID, operator, sizeDelta); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	file.Size = value
 	return tx.Commit().Error
 }
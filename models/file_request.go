@@ -0,0 +1,224 @@
+package model
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// FileRequestMetadataKey 通过文件收集链接上传的文件所属的链接
+	FileRequestMetadataKey = "file_request"
+	// UploaderNameMetadataKey 通过文件收集链接上传时上传者填写的姓名
+	UploaderNameMetadataKey = "uploader_name"
+)
+
+// FileRequest 文件收集链接，访客可通过链接向所有者的目录上传文件，但无法查看目录内容
+type FileRequest struct {
+	gorm.Model
+	UserID      uint       `gorm:"index:file_request_user_id"` // 创建用户ID
+	FolderID    uint       // 接收文件的目录
+	Name        string     // 标题
+	Description string     `gorm:"type:text"`
+	Password    string     // 上传密码的哈希，空值为不需要密码
+	Expires     *time.Time // 过期时间，空值表示无过期时间
+	MaxFiles    int        // 最多可上传的文件数，0 为不限制
+	MaxSize     uint64     // 单个文件的最大尺寸，0 为不限制
+	Extensions  string     `gorm:"type:text"` // 允许的扩展名，以逗号分隔，空值为不限制
+	RequireName bool       // 是否要求上传者填写姓名
+	Uploaded    int        // 已创建的上传数
+
+	// 数据库忽略字段
+	User   User   `gorm:"PRELOAD:false,association_autoupdate:false"`
+	Folder Folder `gorm:"PRELOAD:false,association_autoupdate:false"`
+}
+
+// Create 创建文件收集链接
+func (request *FileRequest) Create() (uint, error) {
+	if err := DB.Create(request).Error; err != nil {
+		util.Log().Warning("Failed to insert file request record: %s", err)
+		return 0, err
+	}
+	return request.ID, nil
+}
+
+// GetFileRequestByHashID 根据HashID查找文件收集链接
+func GetFileRequestByHashID(hashID string) *FileRequest {
+	id, err := hashid.DecodeHashID(hashID, hashid.FileRequestID)
+	if err != nil {
+		return nil
+	}
+
+	var request FileRequest
+	if result := DB.First(&request, id); result.Error != nil {
+		return nil
+	}
+
+	return &request
+}
+
+// ListFileRequests 列出用户的所有文件收集链接
+func ListFileRequests(uid uint) []FileRequest {
+	var requests []FileRequest
+	DB.Where("user_id = ?", uid).Order("created_at desc").Find(&requests)
+	return requests
+}
+
+// DeleteFileRequestByID 根据ID和UID删除文件收集链接
+func DeleteFileRequestByID(id, uid uint) error {
+	result := DB.Where("user_id = ? and id = ?", uid, id).Delete(&FileRequest{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// IsAvailable 返回此链接是否可用（是否过期、创建者及目录是否有效）
+func (request *FileRequest) IsAvailable() bool {
+	if request.Expires != nil && time.Now().After(*request.Expires) {
+		return false
+	}
+
+	// 检查创建者状态
+	if request.Creator().Status != Active {
+		return false
+	}
+
+	// 检查目标目录是否存在
+	return request.TargetFolder().ID != 0
+}
+
+// IsFull 返回是否已达到文件数上限
+func (request *FileRequest) IsFull() bool {
+	return request.MaxFiles > 0 && request.Uploaded >= request.MaxFiles
+}
+
+// Creator 获取链接的创建者
+func (request *FileRequest) Creator() *User {
+	if request.User.ID == 0 {
+		request.User, _ = GetUserByID(request.UserID)
+	}
+	return &request.User
+}
+
+// TargetFolder 获取接收文件的目录
+func (request *FileRequest) TargetFolder() *Folder {
+	if request.Folder.ID == 0 {
+		folders, _ := GetFoldersByIDs([]uint{request.FolderID}, request.UserID)
+		if len(folders) > 0 {
+			request.Folder = folders[0]
+		}
+	}
+	return &request.Folder
+}
+
+// TargetPath 返回接收文件的目录的完整路径
+func (request *FileRequest) TargetPath() (string, error) {
+	folder := request.TargetFolder()
+	if err := folder.TraceRoot(); err != nil {
+		return "", err
+	}
+
+	return path.Join(folder.Position, folder.Name), nil
+}
+
+// AllowExtension 返回文件的扩展名是否被允许
+func (request *FileRequest) AllowExtension(name string) bool {
+	if request.Extensions == "" {
+		return true
+	}
+
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	for _, allowed := range strings.Split(request.Extensions, ",") {
+		if strings.ToLower(strings.TrimSpace(allowed)) == ext {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reserve 占用一个上传名额，文件数已达上限时返回 false
+func (request *FileRequest) Reserve() bool {
+	result := DB.Model(&FileRequest{}).
+		Where("id = ? and (max_files = 0 or uploaded < max_files)", request.ID).
+		UpdateColumn("uploaded", gorm.Expr("uploaded + ?", 1))
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+
+	request.Uploaded++
+	return true
+}
+
+// SetPassword 根据给定明文设定哈希后的上传密码，空值为取消密码
+func (request *FileRequest) SetPassword(password string) error {
+	if password == "" {
+		request.Password = ""
+		return nil
+	}
+
+	hashed, err := HashSharePassword(password)
+	if err != nil {
+		return err
+	}
+
+	request.Password = hashed
+	return nil
+}
+
+// CheckPassword 根据明文校验上传密码，校验通过时将明文存储的密码升级为哈希
+func (request *FileRequest) CheckPassword(password string) bool {
+	ok, upgrade := CheckSharePassword(request.Password, password)
+	if ok && upgrade && request.SetPassword(password) == nil {
+		if err := DB.Model(request).UpdateColumn("password", request.Password).Error; err != nil {
+			util.Log().Warning("Failed to upgrade password of file request %d: %s", request.ID, err)
+		}
+	}
+
+	return ok
+}
+
+// UnlockLocked 返回给定 IP 是否因失败次数过多而被禁止尝试上传密码
+func (request *FileRequest) UnlockLocked(ip string) bool {
+	return unlockLocked("file_request", request.ID, ip)
+}
+
+// UnlockFailed 记录一次上传密码尝试失败
+func (request *FileRequest) UnlockFailed(ip string) {
+	unlockFailed("file_request", request.ID, ip)
+}
+
+// UnlockSucceeded 密码正确后清除给定 IP 的失败次数
+func (request *FileRequest) UnlockSucceeded(ip string) {
+	unlockSucceeded("file_request", ip)
+}
+
+// Release 归还占用的上传名额
+func (request *FileRequest) Release() {
+	DB.Model(&FileRequest{}).
+		Where("id = ? and uploaded > 0", request.ID).
+		UpdateColumn("uploaded", gorm.Expr("uploaded - ?", 1))
+	request.Uploaded--
+}
+
+// ReleaseFileRequestSlot 归还未完成上传的占位文件占用的文件收集链接名额
+func ReleaseFileRequestSlot(file *File) {
+	key, ok := file.MetadataSerialized[FileRequestMetadataKey]
+	if !ok || file.UploadSessionID == nil {
+		return
+	}
+
+	id, err := hashid.DecodeHashID(key, hashid.FileRequestID)
+	if err != nil {
+		return
+	}
+
+	request := &FileRequest{}
+	request.ID = id
+	request.Release()
+}
@@ -16,6 +16,10 @@ type Folder struct {
 	Name     string `gorm:"unique_index:idx_only_one_name"`
 	ParentID *uint  `gorm:"index:parent_id;unique_index:idx_only_one_name"`
 	OwnerID  uint   `gorm:"index:owner_id"`
+	// Quota 目录配额，0 为不限制
+	Quota uint64
+	// Size 开启配额时，目录下所有文件的总大小
+	Size uint64
 
 	// 数据库忽略字段
 	Position      string `gorm:"-"`
@@ -179,16 +183,44 @@ func (folder *Folder) MoveOrCopyFileTo(files []uint, dstFolder *Folder, isCopy b
 				return copiedSize, err
 			}
 
+			// 复制的文件引用同一个 Blob
+			if err := RetainBlobBySource(oldFile.PolicyID, oldFile.SourceName); err != nil {
+				return copiedSize, err
+			}
+
 			copiedSize += oldFile.Size
 		}
 
+		if err := ChangeFolderSize(dstFolder.OwnerID, dstFolder.ID, "+", copiedSize); err != nil {
+			return copiedSize, err
+		}
+
 	} else {
+		// 已移动文件的总大小
+		var movedSize uint64
+		if HasFolderQuota(folder.OwnerID) {
+			var movedFiles []File
+			if err := DB.Where(
+				"id in (?) and user_id = ? and folder_id = ?",
+				files,
+				folder.OwnerID,
+				folder.ID,
+			).Find(&movedFiles).Error; err != nil {
+				return 0, err
+			}
+
+			for _, file := range movedFiles {
+				movedSize += file.Size
+			}
+		}
+
 		var updates = map[string]interface{}{
 			"folder_id": dstFolder.ID,
 		}
 		// webdav目标名重置
 		if dstFolder.WebdavDstName != "" {
 			updates["name"] = dstFolder.WebdavDstName
+			updates["extension"] = FileExtension(dstFolder.WebdavDstName)
 		}
 
 		// 更改顶级要移动文件的父目录指向
@@ -204,6 +236,10 @@ func (folder *Folder) MoveOrCopyFileTo(files []uint, dstFolder *Folder, isCopy b
 			return 0, err
 		}
 
+		if err := folder.moveFolderSize(dstFolder, movedSize); err != nil {
+			return 0, err
+		}
+
 	}
 
 	return copiedSize, nil
@@ -281,9 +317,18 @@ func (folder *Folder) CopyFolderTo(folderID uint, dstFolder *Folder) (size uint6
 			return size, err
 		}
 
+		// 复制的文件引用同一个 Blob
+		if err := RetainBlobBySource(oldFile.PolicyID, oldFile.SourceName); err != nil {
+			return size, err
+		}
+
 		size += oldFile.Size
 	}
 
+	if err := ChangeFolderSize(dstFolder.OwnerID, dstFolder.ID, "+", size); err != nil {
+		return size, err
+	}
+
 	return size, nil
 
 }
@@ -298,6 +343,25 @@ func (folder *Folder) MoveFolderTo(dirs []uint, dstFolder *Folder) error {
 		return errors.New("cannot move a folder into itself")
 	}
 
+	// 已移动目录的总大小
+	var movedSize uint64
+	if HasFolderQuota(folder.OwnerID) {
+		var movedDirs []uint
+		if err := DB.Model(Folder{}).Where(
+			"id in (?) and owner_id = ? and parent_id = ?",
+			dirs,
+			folder.OwnerID,
+			folder.ID,
+		).Pluck("id", &movedDirs).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if movedSize, err = TreeSize(movedDirs, folder.OwnerID); err != nil {
+			return err
+		}
+	}
+
 	var updates = map[string]interface{}{
 		"parent_id": dstFolder.ID,
 	}
@@ -313,9 +377,21 @@ func (folder *Folder) MoveFolderTo(dirs []uint, dstFolder *Folder) error {
 		folder.OwnerID,
 		folder.ID,
 	).Update(updates).Error
+	if err != nil {
+		return err
+	}
 
-	return err
+	return folder.moveFolderSize(dstFolder, movedSize)
+
+}
+
+// moveFolderSize 对象从此目录移动至 dstFolder 后，更新两侧目录的大小
+func (folder *Folder) moveFolderSize(dstFolder *Folder, size uint64) error {
+	if err := ChangeFolderSize(folder.OwnerID, folder.ID, "-", size); err != nil {
+		return err
+	}
 
+	return ChangeFolderSize(dstFolder.OwnerID, dstFolder.ID, "+", size)
 }
 
 // Rename 重命名目录
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// FolderGrant 目录共享授权，将目录及其子对象授权给指定用户或用户组访问
+type FolderGrant struct {
+	gorm.Model
+	FolderID uint `gorm:"index:grant_folder_id"`
+	OwnerID  uint `gorm:"index:grant_owner_id"`
+	// UserID 被授权的用户，为 0 时授权给用户组
+	UserID uint `gorm:"index:grant_user_id"`
+	// GroupID 被授权的用户组，为 0 时授权给用户
+	GroupID uint `gorm:"index:grant_group_id"`
+	// Writable 是否允许写入
+	Writable bool
+
+	// 数据库忽略字段
+	Folder Folder `gorm:"PRELOAD:false,association_autoupdate:false"`
+}
+
+// Create 创建目录共享授权，已存在相同授权时更新读写权限
+func (grant *FolderGrant) Create() error {
+	var existed FolderGrant
+	if err := DB.Where("folder_id = ? and user_id = ? and group_id = ?", grant.FolderID, grant.UserID, grant.GroupID).
+		First(&existed).Error; err == nil {
+		grant.Model = existed.Model
+		return DB.Model(grant).UpdateColumn("writable", grant.Writable).Error
+	}
+
+	return DB.Create(grant).Error
+}
+
+// Delete 删除目录共享授权
+func (grant *FolderGrant) Delete() error {
+	return DB.Unscoped().Delete(grant).Error
+}
+
+// SourceFolder 获取授权的目录
+func (grant *FolderGrant) SourceFolder() *Folder {
+	if grant.Folder.ID == 0 {
+		folders, _ := GetFoldersByIDs([]uint{grant.FolderID}, grant.OwnerID)
+		if len(folders) > 0 {
+			grant.Folder = folders[0]
+		}
+	}
+	return &grant.Folder
+}
+
+// Match 返回授权是否适用于给定用户
+func (grant *FolderGrant) Match(user *User) bool {
+	if grant.OwnerID == user.ID {
+		return false
+	}
+
+	return (grant.UserID > 0 && grant.UserID == user.ID) || (grant.GroupID > 0 && grant.GroupID == user.GroupID)
+}
+
+// GetGrantByID 根据ID和所有者查找目录共享授权
+func GetGrantByID(id, ownerID uint) (*FolderGrant, error) {
+	var grant FolderGrant
+	result := DB.Where("id = ? and owner_id = ?", id, ownerID).First(&grant)
+	return &grant, result.Error
+}
+
+// GetGrantsByOwner 列出用户创建的目录共享授权
+func GetGrantsByOwner(ownerID uint) ([]FolderGrant, error) {
+	var grants []FolderGrant
+	result := DB.Where("owner_id = ?", ownerID).Order("id asc").Find(&grants)
+	return grants, result.Error
+}
+
+// GetGrantsForUser 列出授权给用户或其用户组的目录共享授权，同一目录只保留一条，可写的授权优先
+func GetGrantsForUser(user *User) ([]FolderGrant, error) {
+	var grants []FolderGrant
+	result := DB.Select("folder_grants.*").
+		Joins("inner join folders on folders.id = folder_grants.folder_id and folders.deleted_at is null").
+		Where("folder_grants.owner_id != ? and (folder_grants.user_id = ? or folder_grants.group_id = ?)",
+			user.ID, user.ID, user.GroupID).
+		Order("folder_grants.id asc").
+		Find(&grants)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	res := make([]FolderGrant, 0, len(grants))
+	index := make(map[uint]int)
+	for _, grant := range grants {
+		if i, ok := index[grant.FolderID]; ok {
+			res[i].Writable = res[i].Writable || grant.Writable
+			continue
+		}
+
+		index[grant.FolderID] = len(res)
+		res = append(res, grant)
+	}
+
+	return res, nil
+}
+
+// GetGrantForFolder 查找目录 folderID 或其上级目录授权给用户的目录共享授权，可写的授权优先
+func GetGrantForFolder(user *User, folderID uint) (*FolderGrant, bool) {
+	ancestors, err := GetFolderAncestors(folderID)
+	if err != nil || len(ancestors) == 0 {
+		return nil, false
+	}
+
+	ids := make([]uint, 0, len(ancestors))
+	for _, folder := range ancestors {
+		ids = append(ids, folder.ID)
+	}
+
+	var grants []FolderGrant
+	DB.Where("folder_id in (?)", ids).Find(&grants)
+
+	var res *FolderGrant
+	for i := range grants {
+		if grants[i].Match(user) && (res == nil || (!res.Writable && grants[i].Writable)) {
+			res = &grants[i]
+		}
+	}
+
+	return res, res != nil
+}
+
+// DeleteGrantsByFolderIDs 删除目录对应的目录共享授权
+func DeleteGrantsByFolderIDs(folderIDs []uint) error {
+	return DB.Unscoped().Where("folder_id in (?)", folderIDs).Delete(&FolderGrant{}).Error
+}
@@ -36,6 +36,10 @@ type GroupOption struct {
 	Aria2BatchSize   int                    `json:"aria2_batch,omitempty"`
 	AdvanceDelete    bool                   `json:"advance_delete,omitempty"`
 	WebDAVProxy      bool                   `json:"webdav_proxy,omitempty"`
+	S3Gateway        bool                   `json:"s3_gateway,omitempty"`        // 是否允许通过 S3 接口访问
+	TrashRetention   int                    `json:"trash_retention,omitempty"`   // 回收站保留天数，0 为不使用回收站
+	MaxVersions      int                    `json:"max_versions,omitempty"`      // 保留的历史版本数，0 为不保留
+	VersionRetention int                    `json:"version_retention,omitempty"` // 历史版本保留天数，0 为不限制
 }
 
 // GetGroupByID 用ID获取用户组
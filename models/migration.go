@@ -44,7 +44,10 @@ func migration() {
 	}
 
 	DB.AutoMigrate(&User{}, &Setting{}, &Group{}, &Policy{}, &Folder{}, &File{}, &Share{},
-		&Task{}, &Download{}, &Tag{}, &Webdav{}, &Node{}, &SourceLink{})
+		&Task{}, &Download{}, &Tag{}, &Webdav{}, &Node{}, &SourceLink{}, &Blob{}, &Trash{}, &FileVersion{}, &FolderGrant{}, &Team{}, &TeamMember{}, &ContentTerm{}, &Change{}, &Webhook{}, &WebhookDelivery{}, &AuditLog{}, &S3Key{}, &FileRequest{}, &ShareAccess{}, &ShareRecipient{})
+
+	// 搜索排序使用的索引
+	addSearchIndexes()
 
 	// 创建初始存储策略
 	addDefaultPolicy()
@@ -68,6 +71,14 @@ func migration() {
 
 }
 
+func addSearchIndexes() {
+	DB.Model(&File{}).AddIndex("file_user_name", "user_id", "name")
+	DB.Model(&File{}).AddIndex("file_user_size", "user_id", "size")
+	DB.Model(&File{}).AddIndex("file_user_created", "user_id", "created_at")
+	DB.Model(&File{}).AddIndex("file_user_updated", "user_id", "updated_at")
+	DB.Model(&File{}).AddIndex("file_user_extension", "user_id", "extension")
+}
+
 func addDefaultPolicy() {
 	_, err := GetPolicyByID(uint(1))
 	// 未找到初始存储策略时，则创建
@@ -115,6 +126,9 @@ func addDefaultGroups() {
 				Aria2BatchSize:   50,
 				RedirectedSource: true,
 				AdvanceDelete:    true,
+				TrashRetention:   30,
+				MaxVersions:      10,
+				VersionRetention: 30,
 			},
 		}
 		if err := DB.Create(&defaultAdminGroup).Error; err != nil {
@@ -137,6 +151,9 @@ func addDefaultGroups() {
 				SourceBatchSize:  10,
 				Aria2BatchSize:   1,
 				RedirectedSource: true,
+				TrashRetention:   30,
+				MaxVersions:      10,
+				VersionRetention: 30,
 			},
 		}
 		if err := DB.Create(&defaultAdminGroup).Error; err != nil {
@@ -52,6 +52,20 @@ type PolicyOption struct {
 	Region string `json:"region,omitempty"`
 	// ServerSideEndpoint 服务端请求使用的 Endpoint，为空时使用 Policy.Server 字段
 	ServerSideEndpoint string `json:"server_side_endpoint,omitempty"`
+	// S3ForcePathStyle 是否使用路径风格访问 S3 存储桶
+	S3ForcePathStyle bool `json:"s3_path_style,omitempty"`
+	// SFTPPrivateKey SFTP 登录使用的私钥，为空时使用密码登录
+	SFTPPrivateKey string `json:"sftp_private_key,omitempty"`
+	// SFTPHostKey SFTP 服务端公钥，连接时校验，必须设置
+	SFTPHostKey string `json:"sftp_host_key,omitempty"`
+	// SFTPMaxIdleConn SFTP 连接池最大空闲连接数
+	SFTPMaxIdleConn int `json:"sftp_max_idle_conn,omitempty"`
+	// Encryption 静态加密使用的算法，为空时不加密
+	Encryption string `json:"encryption,omitempty"`
+	// EncryptedSince 开启静态加密的时间戳，此前创建的文件可以未加密
+	EncryptedSince int64 `json:"encrypted_since,omitempty"`
+	// Dedup 是否按内容哈希去重存储
+	Dedup bool `json:"dedup,omitempty"`
 	// 分片上传的分片大小
 	ChunkSize uint64 `json:"chunk_size,omitempty"`
 	// 分片上传时是否需要预留空间
@@ -172,12 +186,33 @@ func (policy *Policy) GenerateFileName(uid uint, origin string) string {
 
 // IsDirectlyPreview 返回此策略下文件是否可以直接预览（不需要重定向）
 func (policy *Policy) IsDirectlyPreview() bool {
-	return policy.Type == "local"
+	return policy.Type == "local" || policy.Type == "sftp" || policy.Type == "webdav" || policy.IsEncrypted()
 }
 
 // IsTransitUpload 返回此策略上传给定size文件时是否需要服务端中转
 func (policy *Policy) IsTransitUpload(size uint64) bool {
-	return policy.Type == "local"
+	return policy.Type == "local" || policy.Type == "sftp" || policy.Type == "webdav" || policy.IsEncrypted()
+}
+
+// IsEncrypted 返回此策略是否开启了静态加密，开启后文件只能经由主机中转
+func (policy *Policy) IsEncrypted() bool {
+	return policy.OptionsSerialized.Encryption != ""
+}
+
+// IsDedupEnabled 返回此策略是否开启了去重存储，仅本机及从机策略支持
+func (policy *Policy) IsDedupEnabled() bool {
+	return policy.OptionsSerialized.Dedup && (policy.Type == "local" || policy.Type == "remote") && !policy.IsEncrypted()
+}
+
+// BlobPath 返回内容哈希为 hash 的 Blob 在存储端的路径，
+// 位于存储路径规则中第一个变量之前的固定目录下
+func (policy *Policy) BlobPath(hash string) string {
+	prefix := policy.DirNameRule
+	if i := strings.Index(prefix, "{"); i >= 0 {
+		prefix = prefix[:i]
+	}
+
+	return path.Join(path.Dir(prefix+"_"), ".blobs", hash[:2], hash[2:4], hash)
 }
 
 // IsThumbGenerateNeeded 返回此策略是否需要在上传后生成缩略图
@@ -187,10 +222,18 @@ func (policy *Policy) IsThumbGenerateNeeded() bool {
 
 // IsUploadPlaceholderWithSize 返回此策略创建上传会话时是否需要预留空间
 func (policy *Policy) IsUploadPlaceholderWithSize() bool {
+	if policy.IsEncrypted() {
+		return false
+	}
+
 	if policy.Type == "remote" {
 		return true
 	}
 
+	if policy.Type == "s3" {
+		return policy.OptionsSerialized.PlaceholderWithSize
+	}
+
 	return false
 }
 
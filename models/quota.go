@@ -0,0 +1,127 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// HasFolderQuota 返回用户是否有开启配额的目录
+func HasFolderQuota(uid uint) bool {
+	return hasFolderQuota(DB, uid)
+}
+
+func hasFolderQuota(tx *gorm.DB, uid uint) bool {
+	var count int
+	tx.Model(&Folder{}).Where("owner_id = ? and quota > 0", uid).Count(&count)
+	return count > 0
+}
+
+// GetQuotaFolders 列出用户所有开启配额的目录
+func GetQuotaFolders(uid uint) ([]Folder, error) {
+	var folders []Folder
+	result := DB.Where("owner_id = ? and quota > 0", uid).Find(&folders)
+	return folders, result.Error
+}
+
+// GetFolderAncestors 返回目录自身及其所有上级目录，由近及远排列
+func GetFolderAncestors(folderID uint) ([]Folder, error) {
+	return getFolderAncestors(DB, folderID)
+}
+
+func getFolderAncestors(tx *gorm.DB, folderID uint) ([]Folder, error) {
+	folders := make([]Folder, 0)
+	id := &folderID
+	// 最大递归65535次
+	for i := 0; i < 65535 && id != nil; i++ {
+		var folder Folder
+		if err := tx.Where("id = ?", *id).First(&folder).Error; err != nil {
+			return folders, err
+		}
+
+		folders = append(folders, folder)
+		id = folder.ParentID
+	}
+
+	return folders, nil
+}
+
+// ChangeFolderSize 更新目录 folderID 及其上级目录中开启配额的目录的大小
+func ChangeFolderSize(uid, folderID uint, operator string, size uint64) error {
+	return changeFolderSize(DB, uid, folderID, operator, size)
+}
+
+func changeFolderSize(tx *gorm.DB, uid, folderID uint, operator string, size uint64) error {
+	if size == 0 || !hasFolderQuota(tx, uid) {
+		return nil
+	}
+
+	ancestors, err := getFolderAncestors(tx, folderID)
+	if err != nil {
+		// 目录已被删除或移入回收站
+		if gorm.IsRecordNotFoundError(err) {
+			err = nil
+		}
+		if len(ancestors) == 0 {
+			return err
+		}
+	}
+
+	ids := make([]uint, 0, len(ancestors))
+	for _, folder := range ancestors {
+		if folder.Quota > 0 {
+			ids = append(ids, folder.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	expr := gorm.Expr("size + ?", size)
+	if operator == "-" {
+		expr = gorm.Expr("CASE WHEN size > ? THEN size - ? ELSE 0 END", size, size)
+	}
+
+	return tx.Model(&Folder{}).Where("id in (?)", ids).UpdateColumn("size", expr).Error
+}
+
+// TreeSize 计算目录 dirs 及其所有子目录中文件的总大小
+func TreeSize(dirs []uint, uid uint) (uint64, error) {
+	if len(dirs) == 0 {
+		return 0, nil
+	}
+
+	folders, err := GetRecursiveChildFolder(dirs, uid, true)
+	if err != nil {
+		return 0, err
+	}
+
+	files, err := GetChildFilesOfFolders(&folders)
+	if err != nil {
+		return 0, err
+	}
+
+	var size uint64
+	for _, file := range files {
+		size += file.Size
+	}
+
+	return size, nil
+}
+
+// SetQuota 设置目录配额，并重新计算目录大小
+func (folder *Folder) SetQuota(quota uint64) error {
+	var size uint64
+	if quota > 0 {
+		var err error
+		if size, err = TreeSize([]uint{folder.ID}, folder.OwnerID); err != nil {
+			return err
+		}
+	}
+
+	folder.Quota = quota
+	folder.Size = size
+	return DB.Model(folder).UpdateColumns(map[string]interface{}{
+		"quota": quota,
+		"size":  size,
+	}).Error
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// S3Key S3 接口的访问密钥
+type S3Key struct {
+	gorm.Model
+	Name      string // 密钥名称
+	AccessKey string `gorm:"unique_index:s3_access_key"`
+	SecretKey string `json:"-"`
+	UserID    uint   `gorm:"index:s3_key_user_id"`
+	Bucket    string // 对外提供的存储桶名称
+	Root      string `gorm:"type:text"` // 存储桶对应的根目录
+	Readonly  bool   `gorm:"type:bool"` // 是否只读
+}
+
+// Create 创建密钥
+func (key *S3Key) Create() (uint, error) {
+	if err := DB.Create(key).Error; err != nil {
+		return 0, err
+	}
+	return key.ID, nil
+}
+
+// GetS3KeyByAccessKey 根据 AccessKey 查找密钥
+func GetS3KeyByAccessKey(accessKey string) (*S3Key, error) {
+	key := &S3Key{}
+	res := DB.Where("access_key = ?", accessKey).First(key)
+	return key, res.Error
+}
+
+// ListS3Keys 列出用户的所有密钥
+func ListS3Keys(uid uint) []S3Key {
+	var keys []S3Key
+	DB.Where("user_id = ?", uid).Order("created_at desc").Find(&keys)
+	return keys
+}
+
+// DeleteS3KeyByID 根据密钥ID和UID删除密钥
+func DeleteS3KeyByID(id, uid uint) {
+	DB.Where("user_id = ? and id = ?", uid, id).Delete(&S3Key{})
+}
+
+// UpdateS3KeyByID 根据密钥ID和UID更新密钥
+func UpdateS3KeyByID(id, uid uint, updates map[string]interface{}) {
+	DB.Model(&S3Key{}).Where("user_id = ? and id = ?", uid, id).Updates(updates)
+}
@@ -6,4 +6,13 @@ func Init() {
 	invoker.Register("ResetAdminPassword", ResetAdminPassword(0))
 	invoker.Register("CalibrateUserStorage", UserStorageCalibration(0))
 	invoker.Register("UpgradeTo3.4.0", UpgradeTo340(0))
+	invoker.Register("UpgradeTo3.8.4", UpgradeTo384(0))
+	invoker.Register("UpgradeTo3.8.5", UpgradeTo385(0))
+	invoker.Register("UpgradeTo3.8.6", UpgradeTo386(0))
+	invoker.Register("UpgradeTo3.8.7", UpgradeTo387(0))
+	invoker.Register("UpgradeTo3.8.8", UpgradeTo388(0))
+	invoker.Register("UpgradeTo3.8.9", UpgradeTo389(0))
+	invoker.Register("UpgradeTo3.8.10", UpgradeTo3810(0))
+	invoker.Register("UpgradeTo3.8.11", UpgradeTo3811(0))
+	invoker.Register("UpgradeTo3.8.12", UpgradeTo3812(0))
 }
@@ -2,9 +2,17 @@ package scripts
 
 import (
 	"context"
+	"fmt"
 	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/conf"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
 	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"github.com/hashicorp/go-version"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type UpgradeTo340 int
@@ -41,3 +49,260 @@ func (script UpgradeTo340) Run(ctx context.Context) {
 		util.Log().Info("Aria2 配置信息已成功迁移至 3.4.0+ 版本的模式")
 	}
 }
+
+type UpgradeTo384 int
+
+// Run upgrade from older version to 3.8.4
+func (script UpgradeTo384) Run(ctx context.Context) {
+	// 将明文存储的分享密码转换为哈希
+	var shares []model.Share
+	model.DB.Where("password <> ?", "").Find(&shares)
+
+	upgraded := 0
+	for i := range shares {
+		if model.IsSharePasswordHashed(shares[i].Password) {
+			continue
+		}
+
+		if err := shares[i].SetPassword(shares[i].Password); err != nil {
+			util.Log().Warning("Failed to hash password of share %d: %s", shares[i].ID, err)
+			continue
+		}
+
+		if err := model.DB.Model(&shares[i]).UpdateColumn("password", shares[i].Password).Error; err != nil {
+			util.Log().Warning("Failed to upgrade password of share %d: %s", shares[i].ID, err)
+			continue
+		}
+		upgraded++
+	}
+
+	if upgraded > 0 {
+		util.Log().Info("Upgraded passwords of %d shares to hashed storage.", upgraded)
+	}
+}
+
+type UpgradeTo385 int
+
+// Run upgrade from older version to 3.8.5
+func (script UpgradeTo385) Run(ctx context.Context) {
+	// 重命名用户根目录下与虚拟根目录同名的对象，避免被虚拟根目录遮挡
+	names := []string{filesystem.SharedRootName, filesystem.TeamRootName}
+	roots := model.DB.Model(&model.Folder{}).Select("id").
+		Where("parent_id is NULL and deleted_at is NULL").SubQuery()
+
+	var folders []model.Folder
+	model.DB.Where("name in (?) and parent_id in ?", names, roots).Find(&folders)
+	for i := range folders {
+		name := freeRootName(*folders[i].ParentID, folders[i].Name)
+		if err := folders[i].Rename(name); err != nil {
+			util.Log().Warning("Failed to rename folder %d to %q: %s", folders[i].ID, name, err)
+		}
+	}
+
+	var files []model.File
+	model.DB.Where("name in (?) and folder_id in ?", names, roots).Find(&files)
+	for i := range files {
+		name := freeRootName(files[i].FolderID, files[i].Name)
+		if err := model.DB.Model(&files[i]).UpdateColumn("name", name).Error; err != nil {
+			util.Log().Warning("Failed to rename file %d to %q: %s", files[i].ID, name, err)
+		}
+	}
+
+	if len(folders)+len(files) > 0 {
+		util.Log().Info("Renamed %d objects conflicting with virtual root folders.", len(folders)+len(files))
+	}
+}
+
+type UpgradeTo386 int
+
+// Run upgrade from older version to 3.8.6
+func (script UpgradeTo386) Run(ctx context.Context) {
+	// 将明文存储的文件收集链接密码转换为哈希
+	var requests []model.FileRequest
+	model.DB.Where("password <> ?", "").Find(&requests)
+
+	upgraded := 0
+	for i := range requests {
+		if model.IsSharePasswordHashed(requests[i].Password) {
+			continue
+		}
+
+		if err := requests[i].SetPassword(requests[i].Password); err != nil {
+			util.Log().Warning("Failed to hash password of file request %d: %s", requests[i].ID, err)
+			continue
+		}
+
+		if err := model.DB.Model(&requests[i]).UpdateColumn("password", requests[i].Password).Error; err != nil {
+			util.Log().Warning("Failed to upgrade password of file request %d: %s", requests[i].ID, err)
+			continue
+		}
+		upgraded++
+	}
+
+	if upgraded > 0 {
+		util.Log().Info("Upgraded passwords of %d file requests to hashed storage.", upgraded)
+	}
+}
+
+type UpgradeTo387 int
+
+// Run upgrade from older version to 3.8.7
+func (script UpgradeTo387) Run(ctx context.Context) {
+	// 已开启加密的存储策略补充开启时间，此前创建的文件仍可读取未加密的内容
+	var policies []model.Policy
+	model.DB.Find(&policies)
+	for i := range policies {
+		if !policies[i].IsEncrypted() || policies[i].OptionsSerialized.EncryptedSince > 0 {
+			continue
+		}
+
+		policies[i].OptionsSerialized.EncryptedSince = time.Now().Unix()
+		if err := model.DB.Save(&policies[i]).Error; err != nil {
+			util.Log().Warning("Failed to update storage policy %d: %s", policies[i].ID, err)
+			continue
+		}
+		policies[i].ClearCache()
+	}
+}
+
+type UpgradeTo388 int
+
+// Run upgrade from older version to 3.8.8
+func (script UpgradeTo388) Run(ctx context.Context) {
+	// 补充已有文件的扩展名，用于按类型排序
+	var lastID uint
+	for {
+		var files []model.File
+		model.DB.Unscoped().Select("id, name").Where("id > ? and extension is null and name like ?", lastID, "%.%").
+			Order("id").Limit(1000).Find(&files)
+		if len(files) == 0 {
+			break
+		}
+
+		for _, file := range files {
+			model.DB.Unscoped().Model(&model.File{}).Where("id = ?", file.ID).
+				UpdateColumn("extension", model.FileExtension(file.Name))
+		}
+		lastID = files[len(files)-1].ID
+	}
+
+	model.DB.Unscoped().Model(&model.File{}).Where("extension is null").UpdateColumn("extension", "")
+}
+
+type UpgradeTo389 int
+
+// Run upgrade from older version to 3.8.9
+func (script UpgradeTo389) Run(ctx context.Context) {
+	// 升级前的用户组没有回收站设置，开启默认的 30 天回收站；只执行一次，保留管理员之后的设置
+	if reachedVersion("3.8.9") {
+		return
+	}
+
+	var groups []model.Group
+	model.DB.Where("id <> ?", 3).Find(&groups)
+	for i := range groups {
+		if groups[i].OptionsSerialized.TrashRetention != 0 {
+			continue
+		}
+
+		groups[i].OptionsSerialized.TrashRetention = 30
+		if err := model.DB.Save(&groups[i]).Error; err != nil {
+			util.Log().Warning("Failed to update user group %d: %s", groups[i].ID, err)
+		}
+	}
+}
+
+type UpgradeTo3810 int
+
+// Run upgrade from older version to 3.8.10
+func (script UpgradeTo3810) Run(ctx context.Context) {
+	// 升级前的用户组没有历史版本设置，使用默认的保留策略；只执行一次，保留管理员之后的设置
+	if reachedVersion("3.8.10") {
+		return
+	}
+
+	var groups []model.Group
+	model.DB.Where("id <> ?", 3).Find(&groups)
+	for i := range groups {
+		if groups[i].OptionsSerialized.MaxVersions != 0 || groups[i].OptionsSerialized.VersionRetention != 0 {
+			continue
+		}
+
+		groups[i].OptionsSerialized.MaxVersions = 10
+		groups[i].OptionsSerialized.VersionRetention = 30
+		if err := model.DB.Save(&groups[i]).Error; err != nil {
+			util.Log().Warning("Failed to update user group %d: %s", groups[i].ID, err)
+		}
+	}
+}
+
+type UpgradeTo3811 int
+
+// Run upgrade from older version to 3.8.11
+func (script UpgradeTo3811) Run(ctx context.Context) {
+	// 未设定的自定义短链接改为 NULL，并由唯一索引保证短链接不重复
+	model.DB.Model(&model.Share{}).Where("slug = ?", "").UpdateColumn("slug", nil)
+
+	if model.DB.Dialect().HasIndex("shares", "share_slug") {
+		if err := model.DB.Model(&model.Share{}).RemoveIndex("share_slug").Error; err != nil {
+			util.Log().Warning("Failed to remove index of share slug: %s", err)
+		}
+	}
+
+	if !model.DB.Dialect().HasIndex("shares", "idx_share_slug") {
+		if err := model.DB.Model(&model.Share{}).AddUniqueIndex("idx_share_slug", "slug").Error; err != nil {
+			util.Log().Warning("Failed to add unique index of share slug: %s", err)
+		}
+	}
+}
+
+type UpgradeTo3812 int
+
+// Run upgrade from older version to 3.8.12
+func (script UpgradeTo3812) Run(ctx context.Context) {
+	// 打包下载改为流式输出后不再清理旧版本留下的临时归档，升级时一次性删除
+	if reachedVersion("3.8.12") {
+		return
+	}
+
+	root := filepath.Join(util.RelativePath(model.GetSettingByName("temp_path")), "archive")
+	if err := os.RemoveAll(root); err != nil {
+		util.Log().Warning("Failed to delete temp batch download folder %q: %s", root, err)
+	}
+}
+
+// reachedVersion 返回数据库在本次迁移前是否已升级至 target 或更高的版本
+func reachedVersion(target string) bool {
+	targetVersion, err := version.NewVersion(target)
+	if err != nil {
+		return false
+	}
+
+	var settings []model.Setting
+	model.DB.Where("type = ?", "version").Find(&settings)
+	for _, setting := range settings {
+		raw := strings.TrimPrefix(setting.Name, "db_version_")
+		if raw == conf.RequiredDBVersion {
+			continue
+		}
+
+		if v, err := version.NewVersion(raw); err == nil && v.GreaterThanOrEqual(targetVersion) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// freeRootName 返回目录 parentID 下以 name 为前缀且未被使用的名称
+func freeRootName(parentID uint, name string) string {
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)", name, i)
+		var folders, files int
+		model.DB.Model(&model.Folder{}).Where("parent_id = ? and name = ?", parentID, candidate).Count(&folders)
+		model.DB.Model(&model.File{}).Where("folder_id = ? and name = ?", parentID, candidate).Count(&files)
+		if folders+files == 0 {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SearchOptions 文件和目录的搜索条件
+type SearchOptions struct {
+	UserID uint
+	// Name 名称匹配模式，可使用 * 和 ? 通配符，不含通配符时匹配名称中的任意位置
+	Name string
+	// Extensions 文件扩展名，不含 .
+	Extensions []string
+	MinSize    uint64
+	// MaxSize 最大文件大小，0 为不限制
+	MaxSize       uint64
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	// Folders 限定父目录，为空时不限制
+	Folders  []uint
+	PolicyID uint
+	// MetadataKeys 文件需包含的元数据键
+	MetadataKeys []string
+	// OrderBy 排序字段，可选 name、size、extension、created_at、updated_at；
+	// 按扩展名排序时扩展名相同的文件再按名称排序
+	OrderBy string
+	Desc    bool
+	Limit   int
+	Cursor  *SearchCursor
+}
+
+// SearchCursor 搜索结果的分页游标，指向上一页的最后一个对象
+type SearchCursor struct {
+	// Folder 上一页是否停在目录部分
+	Folder bool   `json:"d"`
+	Value  string `json:"v"`
+	// Name 按扩展名排序时上一个文件的名称
+	Name string `json:"n,omitempty"`
+	// ID 为 0 时从文件部分的开头继续
+	ID uint `json:"i"`
+}
+
+// fileOnly 返回是否设置了只适用于文件的条件，此时不搜索目录
+func (opts *SearchOptions) fileOnly() bool {
+	return len(opts.Extensions) > 0 || opts.MinSize > 0 || opts.MaxSize > 0 ||
+		opts.PolicyID > 0 || len(opts.MetadataKeys) > 0
+}
+
+// sortColumn 返回排序字段，目录按大小或扩展名排序时使用名称
+func (opts *SearchOptions) sortColumn(folder bool) string {
+	switch opts.OrderBy {
+	case "size", "extension":
+		if !folder {
+			return opts.OrderBy
+		}
+	case "created_at", "updated_at":
+		return opts.OrderBy
+	}
+
+	return "name"
+}
+
+// searchBatchSize 单条查询中绑定的父目录或候选对象ID的最大数量
+const searchBatchSize = 1000
+
+// query 构建文件或目录的查询，限定父目录为 parents，ids 不为空时只在其中查找
+func (opts *SearchOptions) query(folder bool, cursor *SearchCursor, parents, ids []uint) (*gorm.DB, error) {
+	tx := DB
+	owner, parent := "user_id", "folder_id"
+	if folder {
+		owner, parent = "owner_id", "parent_id"
+		tx = tx.Where("parent_id is not null")
+	} else {
+		tx = tx.Where("upload_session_id is null")
+	}
+
+	tx = tx.Where(owner+" = ?", opts.UserID)
+	if len(parents) > 0 {
+		tx = tx.Where(parent+" in (?)", parents)
+	}
+	if len(ids) > 0 {
+		tx = tx.Where("id in (?)", ids)
+	}
+
+	if opts.Name != "" {
+		tx = tx.Where("name like ? escape '!'", namePattern(opts.Name))
+	}
+
+	if opts.CreatedAfter != nil {
+		tx = tx.Where("created_at >= ?", *opts.CreatedAfter)
+	}
+	if opts.CreatedBefore != nil {
+		tx = tx.Where("created_at <= ?", *opts.CreatedBefore)
+	}
+	if opts.UpdatedAfter != nil {
+		tx = tx.Where("updated_at >= ?", *opts.UpdatedAfter)
+	}
+	if opts.UpdatedBefore != nil {
+		tx = tx.Where("updated_at <= ?", *opts.UpdatedBefore)
+	}
+
+	if !folder {
+		if len(opts.Extensions) > 0 {
+			conditions := make([]string, 0, len(opts.Extensions))
+			args := make([]interface{}, 0, len(opts.Extensions))
+			for _, ext := range opts.Extensions {
+				conditions = append(conditions, "name like ? escape '!'")
+				args = append(args, "%."+escapeLike(strings.TrimPrefix(ext, ".")))
+			}
+			tx = tx.Where(strings.Join(conditions, " or "), args...)
+		}
+
+		if opts.MinSize > 0 {
+			tx = tx.Where("size >= ?", opts.MinSize)
+		}
+		if opts.MaxSize > 0 {
+			tx = tx.Where("size <= ?", opts.MaxSize)
+		}
+		if opts.PolicyID > 0 {
+			tx = tx.Where("policy_id = ?", opts.PolicyID)
+		}
+		for _, key := range opts.MetadataKeys {
+			tx = tx.Where("metadata like ? escape '!'", "%\""+escapeLike(key)+"\":%")
+		}
+	}
+
+	// 按排序字段和ID分页，避免使用 offset
+	column := opts.sortColumn(folder)
+	order, cmp := "asc", ">"
+	if opts.Desc {
+		order, cmp = "desc", "<"
+	}
+
+	if cursor != nil && cursor.ID > 0 {
+		value, err := parseCursorValue(column, cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if column == "extension" {
+			tx = tx.Where(fmt.Sprintf("extension %s ? or (extension = ? and name %s ?) or (extension = ? and name = ? and id %s ?)", cmp, cmp, cmp),
+				value, value, cursor.Name, value, cursor.Name, cursor.ID)
+		} else {
+			tx = tx.Where(fmt.Sprintf("%s %s ? or (%s = ? and id %s ?)", column, cmp, column, cmp), value, value, cursor.ID)
+		}
+	}
+
+	tx = tx.Order(column + " " + order)
+	if column == "extension" {
+		tx = tx.Order("name " + order)
+	}
+
+	return tx.Order("id " + order), nil
+}
+
+// find 按排序取出游标后的前 limit 个文件或目录。限定的父目录过多时分批查询，
+// 各批次的前 limit 个对象作为候选，再由数据库对候选排序，避免超出绑定参数数量的限制
+func (opts *SearchOptions) find(folder bool, cursor *SearchCursor, limit int, dst interface{}) error {
+	batch := searchBatchSize
+	if batch < 2*limit {
+		batch = 2 * limit
+	}
+
+	if len(opts.Folders) <= batch {
+		tx, err := opts.query(folder, cursor, opts.Folders, nil)
+		if err != nil {
+			return err
+		}
+
+		return tx.Limit(limit).Find(dst).Error
+	}
+
+	table := interface{}(&File{})
+	if folder {
+		table = &Folder{}
+	}
+
+	// pluck 在每个批次中取出前 limit 个对象的ID
+	pluck := func(ids []uint, scope func(start, end int) (*gorm.DB, error)) ([]uint, error) {
+		res := make([]uint, 0)
+		for start := 0; start < len(ids); start += batch {
+			end := start + batch
+			if end > len(ids) {
+				end = len(ids)
+			}
+
+			tx, err := scope(start, end)
+			if err != nil {
+				return nil, err
+			}
+
+			var batchIDs []uint
+			if err := tx.Model(table).Limit(limit).Pluck("id", &batchIDs).Error; err != nil {
+				return nil, err
+			}
+			res = append(res, batchIDs...)
+		}
+
+		return res, nil
+	}
+
+	candidates, err := pluck(opts.Folders, func(start, end int) (*gorm.DB, error) {
+		return opts.query(folder, cursor, opts.Folders[start:end], nil)
+	})
+	for err == nil && len(candidates) > batch {
+		current := candidates
+		candidates, err = pluck(current, func(start, end int) (*gorm.DB, error) {
+			return opts.query(folder, cursor, nil, current[start:end])
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	tx, err := opts.query(folder, cursor, nil, candidates)
+	if err != nil {
+		return err
+	}
+
+	return tx.Limit(limit).Find(dst).Error
+}
+
+// Search 搜索文件和目录，目录排在文件之前；返回的游标为空时表示没有更多结果
+func Search(opts *SearchOptions) ([]Folder, []File, *SearchCursor, error) {
+	var (
+		folders []Folder
+		files   []File
+		limit   = opts.Limit
+	)
+
+	fileCursor := opts.Cursor
+	if (opts.Cursor == nil || opts.Cursor.Folder) && !opts.fileOnly() {
+		fileCursor = nil
+		if err := opts.find(true, opts.Cursor, limit+1, &folders); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if len(folders) > limit {
+			folders = folders[:limit]
+			last := folders[limit-1]
+			return folders, nil, &SearchCursor{
+				Folder: true,
+				Value:  cursorValue(last.Name, 0, last.CreatedAt, last.UpdatedAt, opts.sortColumn(true)),
+				ID:     last.ID,
+			}, nil
+		}
+
+		limit -= len(folders)
+	}
+
+	if err := opts.find(false, fileCursor, limit+1, &files); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(files) <= limit {
+		return folders, files, nil, nil
+	}
+
+	// 目录已填满本页，下一页从文件部分开始
+	files = files[:limit]
+	if limit == 0 {
+		return folders, files, &SearchCursor{}, nil
+	}
+
+	last := files[limit-1]
+	next := &SearchCursor{
+		Value: cursorValue(last.Name, last.Size, last.CreatedAt, last.UpdatedAt, opts.sortColumn(false)),
+		ID:    last.ID,
+	}
+	if opts.sortColumn(false) == "extension" {
+		next.Name = last.Name
+	}
+
+	return folders, files, next, nil
+}
+
+// cursorValue 将排序字段的值转换为游标中的字符串
+func cursorValue(name string, size uint64, created, updated time.Time, column string) string {
+	switch column {
+	case "size":
+		return strconv.FormatUint(size, 10)
+	case "created_at":
+		return created.Format(time.RFC3339Nano)
+	case "updated_at":
+		return updated.Format(time.RFC3339Nano)
+	case "extension":
+		return FileExtension(name)
+	}
+
+	return name
+}
+
+// parseCursorValue 解析游标中排序字段的值
+func parseCursorValue(column, value string) (interface{}, error) {
+	switch column {
+	case "size":
+		return strconv.ParseUint(value, 10, 64)
+	case "created_at", "updated_at":
+		return time.Parse(time.RFC3339Nano, value)
+	}
+
+	return value, nil
+}
+
+// namePattern 将名称匹配模式转换为 like 表达式
+func namePattern(pattern string) string {
+	if !strings.ContainsAny(pattern, "*?") {
+		return "%" + escapeLike(pattern) + "%"
+	}
+
+	escaped := escapeLike(pattern)
+	return strings.NewReplacer("*", "%", "?", "_").Replace(escaped)
+}
+
+// escapeLike 转义 like 表达式中的特殊字符，转义符为 !
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -1,8 +1,14 @@
 package model
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -11,6 +17,7 @@ import (
 	"gitee.com/jiangjiali/cloudreve/pkg/util"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Share 分享模型
@@ -23,9 +30,13 @@ type Share struct {
 	Views           int        // 浏览数
 	Downloads       int        // 下载数
 	RemainDownloads int        // 剩余下载配额，负值标识无限制
+	RemainViews     int        `gorm:"default:-1"` // 剩余访问配额，负值标识无限制
 	Expires         *time.Time // 过期时间，空值表示无过期时间
 	PreviewEnabled  bool       // 是否允许直接预览
 	SourceName      string     `gorm:"index:source"` // 用于搜索的字段
+	Restricted      bool       // 是否仅限指定的接收者访问
+	Slug            *string    `gorm:"unique_index:idx_share_slug"` // 自定义短链接，空值表示未设定
+	LinkToken       string     // 轮换链接后附加在 HashID 后的随机串，空值表示未轮换
 
 	// 数据库忽略字段
 	User   User   `gorm:"PRELOAD:false,association_autoupdate:false"`
@@ -33,23 +44,69 @@ type Share struct {
 	Folder Folder `gorm:"PRELOAD:false,association_autoupdate:false"`
 }
 
-// Create 创建分享
+// Create 创建分享，自定义短链接已被使用时返回 ErrShareSlugExist
 func (share *Share) Create() (uint, error) {
 	if err := DB.Create(share).Error; err != nil {
+		if share.slugTaken() {
+			return 0, ErrShareSlugExist
+		}
+
 		util.Log().Warning("Failed to insert share record: %s", err)
 		return 0, err
 	}
 	return share.ID, nil
 }
 
-// GetShareByHashID 根据HashID查找分享
+var (
+	// ErrShareSlugInvalid 自定义短链接格式无效或为保留字
+	ErrShareSlugInvalid = errors.New("invalid share slug")
+	// ErrShareSlugExist 自定义短链接已被使用
+	ErrShareSlugExist = errors.New("share slug already in use")
+
+	shareSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}[a-z0-9]$`)
+
+	// reservedShareSlugs 不可用作自定义短链接的保留字
+	reservedShareSlugs = []string{
+		"admin", "api", "app", "assets", "custom", "dav", "download", "f", "file",
+		"home", "login", "logout", "manifest", "new", "preview", "public", "register",
+		"reset", "s", "search", "service-worker", "setting", "settings", "share",
+		"shares", "signup", "static", "webdav", "www",
+	}
+)
+
+// GetShareByHashID 根据链接中的标识查找分享，标识可为 HashID、轮换后的 HashID 或自定义短链接
 func GetShareByHashID(hashID string) *Share {
-	id, err := hashid.DecodeHashID(hashID, hashid.ShareID)
-	if err != nil {
+	// 自定义短链接优先，避免此后新建分享的 HashID 与之相同时链接被占用
+	if shareSlugPattern.MatchString(hashID) {
+		if share := GetShareBySlug(hashID); share != nil {
+			return share
+		}
+	}
+
+	key, token := hashID, ""
+	if i := strings.LastIndex(hashID, "-"); i >= 0 {
+		key, token = hashID[:i], hashID[i+1:]
+	}
+
+	if id, err := hashid.DecodeHashID(key, hashid.ShareID); err == nil {
+		var share Share
+		result := DB.First(&share, id)
+		if result.Error == nil && subtle.ConstantTimeCompare([]byte(token), []byte(share.LinkToken)) == 1 {
+			return &share
+		}
+	}
+
+	return nil
+}
+
+// GetShareBySlug 根据自定义短链接查找分享
+func GetShareBySlug(slug string) *Share {
+	if slug == "" {
 		return nil
 	}
+
 	var share Share
-	result := DB.First(&share, id)
+	result := DB.Where("slug = ?", slug).First(&share)
 	if result.Error != nil {
 		return nil
 	}
@@ -57,6 +114,106 @@ func GetShareByHashID(hashID string) *Share {
 	return &share
 }
 
+// CustomSlug 返回自定义短链接，未设定时返回空值
+func (share *Share) CustomSlug() string {
+	if share.Slug == nil {
+		return ""
+	}
+
+	return *share.Slug
+}
+
+// Key 返回分享链接中的标识，优先使用自定义短链接
+func (share *Share) Key() string {
+	if slug := share.CustomSlug(); slug != "" {
+		return slug
+	}
+
+	key := hashid.HashID(share.ID, hashid.ShareID)
+	if share.LinkToken != "" {
+		key += "-" + share.LinkToken
+	}
+	return key
+}
+
+// SetSlug 校验并设定自定义短链接，空值为取消短链接；
+// 短链接是否已被使用由唯一索引在写入时检查
+func (share *Share) SetSlug(slug string) error {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if slug == "" {
+		share.Slug = nil
+		return nil
+	}
+
+	if !shareSlugPattern.MatchString(slug) || IsReservedShareSlug(slug) {
+		return ErrShareSlugInvalid
+	}
+
+	// 避免与其他分享的 HashID 链接冲突
+	if _, err := hashid.DecodeHashID(slug, hashid.ShareID); err == nil {
+		return ErrShareSlugInvalid
+	}
+
+	share.Slug = &slug
+	return nil
+}
+
+// UpdateSlug 校验并保存自定义短链接，已被其他分享使用时返回 ErrShareSlugExist
+func (share *Share) UpdateSlug(slug string) error {
+	if err := share.SetSlug(slug); err != nil {
+		return err
+	}
+
+	if err := share.Update(map[string]interface{}{"slug": share.Slug}); err != nil {
+		if share.slugTaken() {
+			return ErrShareSlugExist
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// slugTaken 返回此分享的自定义短链接是否已被其他分享使用
+func (share *Share) slugTaken() bool {
+	if share.Slug == nil {
+		return false
+	}
+
+	other := GetShareBySlug(*share.Slug)
+	return other != nil && other.ID != share.ID
+}
+
+// IsReservedShareSlug 返回给定短链接是否为内置或站点设定的保留字
+func IsReservedShareSlug(slug string) bool {
+	if util.ContainsString(reservedShareSlugs, slug) {
+		return true
+	}
+
+	for _, reserved := range strings.Split(GetSettingByName("share_slug_reserved"), ",") {
+		if strings.ToLower(strings.TrimSpace(reserved)) == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// RotateLink 轮换分享链接，原有的 HashID 链接及自定义短链接随即失效
+func (share *Share) RotateLink() error {
+	token := make([]byte, 6)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+
+	share.LinkToken = hex.EncodeToString(token)
+	share.Slug = nil
+	return share.Update(map[string]interface{}{
+		"link_token": share.LinkToken,
+		"slug":       share.Slug,
+	})
+}
+
 // IsAvailable 返回此分享是否可用（是否过期）
 func (share *Share) IsAvailable() bool {
 	if share.RemainDownloads == 0 {
@@ -88,6 +245,175 @@ func (share *Share) IsAvailable() bool {
 	return true
 }
 
+// SetPassword 根据给定明文设定哈希后的分享密码，空值为取消密码
+func (share *Share) SetPassword(password string) error {
+	if password == "" {
+		share.Password = ""
+		return nil
+	}
+
+	hashed, err := HashSharePassword(password)
+	if err != nil {
+		return err
+	}
+
+	share.Password = hashed
+	return nil
+}
+
+// CheckPassword 根据明文校验分享密码，校验通过时将旧格式的密码升级为 bcrypt 哈希
+func (share *Share) CheckPassword(password string) bool {
+	ok, upgrade := CheckSharePassword(share.Password, password)
+	if ok && upgrade && share.SetPassword(password) == nil {
+		if err := DB.Model(share).UpdateColumn("password", share.Password).Error; err != nil {
+			util.Log().Warning("Failed to upgrade password of share %d: %s", share.ID, err)
+		}
+	}
+
+	return ok
+}
+
+// HashSharePassword 计算分享及文件收集密码的 bcrypt 哈希，
+// 明文先经 SHA256 摘要以避开 bcrypt 的 72 字节长度限制
+func HashSharePassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword(sharePasswordDigest(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hashed), nil
+}
+
+// CheckSharePassword 校验明文与存储的密码是否一致，存储的密码为空时总是通过；
+// 兼容旧版本的明文及 Salt:SHA256 格式，此时 upgrade 为 true
+func CheckSharePassword(stored, password string) (ok, upgrade bool) {
+	switch {
+	case stored == "":
+		return true, false
+	case isBcryptHash(stored):
+		return bcrypt.CompareHashAndPassword([]byte(stored), sharePasswordDigest(password)) == nil, false
+	case isSaltedSHA256(stored):
+		hash := sha256.Sum256([]byte(password + stored[:strings.Index(stored, ":")]))
+		expected := stored[:strings.Index(stored, ":")+1] + hex.EncodeToString(hash[:])
+		return subtle.ConstantTimeCompare([]byte(expected), []byte(stored)) == 1, true
+	default:
+		return subtle.ConstantTimeCompare([]byte(password), []byte(stored)) == 1, true
+	}
+}
+
+// IsSharePasswordHashed 返回存储的分享密码是否已经过哈希
+func IsSharePasswordHashed(stored string) bool {
+	return isBcryptHash(stored) || isSaltedSHA256(stored)
+}
+
+func sharePasswordDigest(password string) []byte {
+	digest := sha256.Sum256([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(digest[:]))
+}
+
+func isBcryptHash(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// isSaltedSHA256 返回存储的密码是否为旧版本的 Salt:SHA256 格式
+func isSaltedSHA256(stored string) bool {
+	passwordStore := strings.Split(stored, ":")
+	if len(passwordStore) != 2 || len(passwordStore[0]) != 16 || len(passwordStore[1]) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(passwordStore[1])
+	return err == nil
+}
+
+// UnlockFailures 返回给定 IP 及此分享在锁定期内的密码尝试失败次数
+func (share *Share) UnlockFailures(ip string) (byIP, byShare int) {
+	return unlockFailures("share", share.ID, ip)
+}
+
+// UnlockLocked 返回给定 IP 是否因失败次数过多而被禁止尝试此分享的密码
+func (share *Share) UnlockLocked(ip string) bool {
+	return unlockLocked("share", share.ID, ip)
+}
+
+// UnlockFailed 记录一次密码尝试失败，锁定期自最后一次失败起算
+func (share *Share) UnlockFailed(ip string) {
+	unlockFailed("share", share.ID, ip)
+}
+
+// UnlockSucceeded 密码正确后清除给定 IP 的失败次数
+func (share *Share) UnlockSucceeded(ip string) {
+	unlockSucceeded("share", ip)
+}
+
+// unlockFailures 返回给定 IP 及 scope 下的对象 id 在锁定期内的密码尝试失败次数
+func unlockFailures(scope string, id uint, ip string) (byIP, byObject int) {
+	if count, ok := cache.Get(unlockIPKey(scope, ip)); ok {
+		byIP, _ = count.(int)
+	}
+	if count, ok := cache.Get(unlockObjectKey(scope, id)); ok {
+		byObject, _ = count.(int)
+	}
+	return byIP, byObject
+}
+
+// unlockLocked 返回给定 IP 是否因失败次数过多而被禁止尝试对象的密码
+func unlockLocked(scope string, id uint, ip string) bool {
+	byIP, byObject := unlockFailures(scope, id, ip)
+	return byIP >= GetIntSetting("share_unlock_ip_attempts", 10) ||
+		byObject >= GetIntSetting("share_unlock_share_attempts", 50)
+}
+
+// unlockFailed 原子地记录一次密码尝试失败
+func unlockFailed(scope string, id uint, ip string) {
+	ttl := GetIntSetting("share_unlock_lockout", 900)
+	for _, key := range []string{unlockIPKey(scope, ip), unlockObjectKey(scope, id)} {
+		if _, err := cache.Incr(key, ttl); err != nil {
+			util.Log().Warning("Failed to record password failure: %s", err)
+		}
+	}
+}
+
+func unlockSucceeded(scope, ip string) {
+	cache.Deletes([]string{unlockIPKey(scope, ip)}, "")
+}
+
+func unlockIPKey(scope, ip string) string {
+	return fmt.Sprintf("%s_unlock_fail_ip_%s", scope, ip)
+}
+
+func unlockObjectKey(scope string, id uint) string {
+	return fmt.Sprintf("%s_unlock_fail_id_%d", scope, id)
+}
+
+// HasViewQuota 返回给定用户是否仍可访问此分享，访问配额耗尽后仅创建者及此前已访问过的会话可继续访问
+func (share *Share) HasViewQuota(user *User, c *gin.Context) bool {
+	return share.RemainViews != 0 || share.UserID == user.ID ||
+		util.GetSession(c, fmt.Sprintf("share_viewed_%d", share.ID)) != nil
+}
+
+// ViewCounted 返回限制访问次数的分享是否已为当前会话扣减过访问配额，
+// 未经 ViewedBy 计数的会话不能获取分享内容
+func (share *Share) ViewCounted(user *User, c *gin.Context) bool {
+	return share.RemainViews < 0 || share.UserID == user.ID ||
+		util.GetSession(c, fmt.Sprintf("share_viewed_%d", share.ID)) != nil
+}
+
+// CanBeAccessedBy 返回受限分享是否可被给定用户访问，通过邮件验证码验证的访客亦可访问
+func (share *Share) CanBeAccessedBy(user *User, c *gin.Context) bool {
+	if !share.Restricted || share.UserID == user.ID {
+		return true
+	}
+
+	if share.IsRecipientUser(user) {
+		return true
+	}
+
+	email, ok := util.GetSession(c, fmt.Sprintf("share_recipient_%d", share.ID)).(string)
+	return ok && share.IsRecipientEmail(email)
+}
+
 // Creator 获取分享的创建者
 func (share *Share) Creator() *User {
 	if share.User.ID == 0 {
@@ -169,6 +495,18 @@ func (share *Share) Viewed() {
 	DB.Model(share).UpdateColumn("views", gorm.Expr("views + ?", 1))
 }
 
+// ViewedBy 增加访问次数，非创建者在同一会话内首次访问时扣减访问配额
+func (share *Share) ViewedBy(user *User, c *gin.Context) {
+	sessionKey := fmt.Sprintf("share_viewed_%d", share.ID)
+	if share.RemainViews > 0 && share.UserID != user.ID && util.GetSession(c, sessionKey) == nil {
+		share.RemainViews--
+		util.SetSession(c, map[string]interface{}{sessionKey: true})
+		DB.Model(share).Where("remain_views > 0").
+			UpdateColumn("remain_views", gorm.Expr("remain_views - ?", 1))
+	}
+	share.Viewed()
+}
+
 // Downloaded 增加下载次数
 func (share *Share) Downloaded() {
 	share.Downloads++
@@ -186,9 +524,15 @@ func (share *Share) Update(props map[string]interface{}) error {
 	return DB.Model(share).Updates(props).Error
 }
 
-// Delete 删除分享
+// Delete 删除分享及其接收者、访问记录
 func (share *Share) Delete() error {
-	return DB.Model(share).Delete(share).Error
+	if err := DB.Model(share).Delete(share).Error; err != nil {
+		return err
+	}
+	if err := DB.Where("share_id = ?", share.ID).Delete(&ShareRecipient{}).Error; err != nil {
+		return err
+	}
+	return DB.Where("share_id = ?", share.ID).Delete(&ShareAccess{}).Error
 }
 
 // DeleteShareBySourceIDs 根据原始资源类型和ID删除文件
@@ -205,7 +549,7 @@ func ListShares(uid uint, page, pageSize int, order string, publicOnly bool) ([]
 	dbChain := DB
 	dbChain = dbChain.Where("user_id = ?", uid)
 	if publicOnly {
-		dbChain = dbChain.Where("password = ?", "")
+		dbChain = dbChain.Where("password = ? and restricted = ?", "", false)
 	}
 
 	// 计算总数用于分页
@@ -235,7 +579,7 @@ func SearchShares(page, pageSize int, order, keywords string) ([]Share, int) {
 	}
 
 	dbChain := DB
-	dbChain = dbChain.Where("password = ? and remain_downloads <> 0 and (expires is NULL or expires > ?) and source_name like ?", "", time.Now(), "%"+strings.Join(availableList, "%")+"%")
+	dbChain = dbChain.Where("password = ? and restricted = ? and remain_downloads <> 0 and remain_views <> 0 and (expires is NULL or expires > ?) and source_name like ?", "", false, time.Now(), "%"+strings.Join(availableList, "%")+"%")
 
 	// 计算总数用于分页
 	dbChain.Model(&Share{}).Count(&total)
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+)
+
+// 分享访问行为
+const (
+	ShareActionView     = "view"
+	ShareActionPreview  = "preview"
+	ShareActionDownload = "download"
+	ShareActionArchive  = "archive"
+)
+
+// ShareAccess 分享访问记录
+type ShareAccess struct {
+	ID        uint      `gorm:"primary_key"`
+	CreatedAt time.Time `gorm:"index:share_access_created_at"`
+	ShareID   uint      `gorm:"index:share_access_share_id"`
+	// UserID 访问者，未登录时为 0
+	UserID    uint
+	IP        string
+	UserAgent string `gorm:"type:text"`
+	Referer   string `gorm:"type:text"`
+	Action    string
+	// Path 目录分享下被访问的文件相对于分享根目录的路径，文件分享下为文件名
+	Path string `gorm:"type:text"`
+}
+
+// ShareAccessCount 按访问行为统计的访问数
+type ShareAccessCount struct {
+	Action   string
+	Count    int
+	Visitors int
+}
+
+// ShareFileCount 按文件统计的访问数
+type ShareFileCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+// Create 写入分享访问记录
+func (access *ShareAccess) Create() error {
+	return DB.Create(access).Error
+}
+
+// ListShareAccesses 分页列出分享的访问记录
+func ListShareAccesses(shareID uint, page, pageSize int) ([]ShareAccess, int) {
+	var (
+		accesses []ShareAccess
+		total    int
+	)
+
+	dbChain := DB.Model(&ShareAccess{}).Where("share_id = ?", shareID)
+	dbChain.Count(&total)
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&accesses)
+	return accesses, total
+}
+
+// CountShareAccesses 统计分享在给定时间段内各访问行为的次数及独立访客数
+func CountShareAccesses(shareID uint, start, end time.Time) map[string]ShareAccessCount {
+	var counts []ShareAccessCount
+	DB.Model(&ShareAccess{}).
+		Select("action, count(*) as count, count(distinct ip) as visitors").
+		Where("share_id = ? and created_at BETWEEN ? AND ?", shareID, start, end).
+		Group("action").
+		Scan(&counts)
+
+	res := make(map[string]ShareAccessCount, len(counts))
+	for _, count := range counts {
+		res[count.Action] = count
+	}
+	return res
+}
+
+// CountShareVisitors 统计分享在给定时间段内的独立访客数
+func CountShareVisitors(shareID uint, start, end time.Time) int {
+	var total int
+	DB.Model(&ShareAccess{}).
+		Where("share_id = ? and created_at BETWEEN ? AND ?", shareID, start, end).
+		Select("count(distinct ip)").
+		Count(&total)
+	return total
+}
+
+// TopShareFiles 列出给定时间后分享中被预览、下载次数最多的文件
+func TopShareFiles(shareID uint, since time.Time, limit int) []ShareFileCount {
+	var files []ShareFileCount
+	DB.Model(&ShareAccess{}).
+		Select("path, count(*) as count").
+		Where("share_id = ? and created_at > ? and path <> ? and action in (?)",
+			shareID, since, "", []string{ShareActionPreview, ShareActionDownload}).
+		Group("path").
+		Order("count desc").
+		Limit(limit).
+		Scan(&files)
+	return files
+}
+
+// DeleteShareAccessesBefore 删除 before 之前的分享访问记录，返回删除的数量
+func DeleteShareAccessesBefore(before time.Time) (int64, error) {
+	res := DB.Where("created_at < ?", before).Delete(&ShareAccess{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ShareRecipient 受限分享的接收者，注册用户以 UserID 标识，其他接收者通过邮件验证码访问
+type ShareRecipient struct {
+	gorm.Model
+	ShareID uint   `gorm:"index:share_recipient_share_id"`
+	UserID  uint   `gorm:"index:share_recipient_user_id"`
+	Email   string `gorm:"index:share_recipient_email"`
+}
+
+// AddRecipients 为分享添加接收者，emails 为接收者邮箱，已注册的邮箱将关联到对应用户；
+// users 为直接指定的注册用户
+func (share *Share) AddRecipients(emails []string, users []User) error {
+	recipients := make([]ShareRecipient, 0, len(emails)+len(users))
+	added := make(map[string]bool, len(emails)+len(users))
+	for _, user := range users {
+		if added[strings.ToLower(user.Email)] {
+			continue
+		}
+		added[strings.ToLower(user.Email)] = true
+
+		recipients = append(recipients, ShareRecipient{
+			ShareID: share.ID,
+			UserID:  user.ID,
+			Email:   strings.ToLower(user.Email),
+		})
+	}
+
+	for _, email := range emails {
+		if added[strings.ToLower(email)] {
+			continue
+		}
+		added[strings.ToLower(email)] = true
+
+		recipient := ShareRecipient{
+			ShareID: share.ID,
+			Email:   strings.ToLower(email),
+		}
+		if user, err := GetActiveUserByEmail(email); err == nil {
+			recipient.UserID = user.ID
+		} else if user, err := GetActiveUserByEmail(recipient.Email); err == nil {
+			recipient.UserID = user.ID
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	tx := DB.Begin()
+	for i := range recipients {
+		if err := tx.Create(&recipients[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// Recipients 列出分享的接收者
+func (share *Share) Recipients() ([]ShareRecipient, error) {
+	var recipients []ShareRecipient
+	err := DB.Where("share_id = ?", share.ID).Order("id").Find(&recipients).Error
+	return recipients, err
+}
+
+// IsRecipientUser 返回给定用户是否为此分享的接收者，创建分享后才注册的用户按邮箱匹配
+func (share *Share) IsRecipientUser(user *User) bool {
+	if user.IsAnonymous() {
+		return false
+	}
+
+	var total int
+	DB.Model(&ShareRecipient{}).
+		Where("share_id = ? and (user_id = ? or email = ?)", share.ID, user.ID, strings.ToLower(user.Email)).
+		Count(&total)
+	return total > 0
+}
+
+// IsRecipientEmail 返回给定邮箱是否为此分享的接收者
+func (share *Share) IsRecipientEmail(email string) bool {
+	var total int
+	DB.Model(&ShareRecipient{}).
+		Where("share_id = ? and email = ?", share.ID, strings.ToLower(email)).
+		Count(&total)
+	return total > 0
+}
+
+// DeleteRecipient 撤销分享的指定接收者
+func (share *Share) DeleteRecipient(id uint) error {
+	result := DB.Where("share_id = ? and id = ?", share.ID, id).Delete(&ShareRecipient{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// ListReceivedShares 列出指定给用户的有效分享，创建分享后才注册的用户按邮箱匹配
+func ListReceivedShares(user *User) []Share {
+	var shares []Share
+	DB.Where("id in (?)",
+		DB.Model(&ShareRecipient{}).Select("share_id").
+			Where("(user_id = ? or email = ?) and deleted_at is NULL", user.ID, strings.ToLower(user.Email)).SubQuery(),
+	).
+		Where("restricted = ? and remain_downloads <> 0 and remain_views <> 0 and (expires is NULL or expires > ?)", true, time.Now()).
+		Order("created_at desc").
+		Find(&shares)
+	return shares
+}
@@ -0,0 +1,194 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// TeamViewer 团队成员，只读
+	TeamViewer = iota + 1
+	// TeamEditor 团队成员，可读写
+	TeamEditor
+	// TeamManager 团队管理员，可读写并管理成员
+	TeamManager
+)
+
+// Team 团队空间，目录和文件归属于团队的内部账户，与成员账户无关
+type Team struct {
+	gorm.Model
+	Name string `gorm:"size:50"`
+	// UserID 团队的内部账户
+	UserID uint
+	// GroupID 拥有团队空间的用户组，为 0 时只有指定的成员可以访问
+	GroupID uint
+	// DefaultRole 用户组成员的默认角色
+	DefaultRole int
+	// MaxStorage 团队空间容量
+	MaxStorage uint64
+	// PolicyID 团队空间使用的存储策略
+	PolicyID uint
+}
+
+// TeamMember 团队成员
+type TeamMember struct {
+	gorm.Model
+	TeamID uint `gorm:"index:member_team_id"`
+	UserID uint `gorm:"index:member_user_id"`
+	Role   int
+}
+
+// Create 创建团队空间及其内部账户
+func (team *Team) Create() error {
+	groupID := team.GroupID
+	if groupID == 0 {
+		groupID = uint(GetIntSetting("default_group", 2))
+	}
+
+	tx := DB.Begin()
+	if err := tx.Create(team).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 内部账户没有密码，无法登录
+	account := NewUser()
+	account.Email = fmt.Sprintf("team_%d@team.internal", team.ID)
+	account.Nick = team.Name
+	account.Status = Active
+	account.GroupID = groupID
+	account.OptionsSerialized.ProfileOff = true
+	account.OptionsSerialized.TeamID = team.ID
+	if err := tx.Create(&account).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	team.UserID = account.ID
+	if err := tx.Model(team).UpdateColumn("user_id", account.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Update 更新团队空间设置
+func (team *Team) Update(val map[string]interface{}) error {
+	if name, ok := val["name"]; ok {
+		DB.Model(&User{}).Where("id = ?", team.UserID).UpdateColumn("nick", name)
+	}
+
+	return DB.Model(team).Updates(val).Error
+}
+
+// Delete 删除团队空间记录及成员，不包含内部账户
+func (team *Team) Delete() error {
+	tx := DB.Begin()
+	if err := tx.Unscoped().Where("team_id = ?", team.ID).Delete(&TeamMember{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Delete(team).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Account 获取团队的内部账户
+func (team *Team) Account() (User, error) {
+	return GetActiveUserByID(team.UserID)
+}
+
+// RoleOf 返回用户在团队中的角色，0 表示不是团队成员
+func (team *Team) RoleOf(user *User) int {
+	if user.ID == 0 {
+		return 0
+	}
+
+	var member TeamMember
+	if err := DB.Where("team_id = ? and user_id = ?", team.ID, user.ID).First(&member).Error; err == nil {
+		return member.Role
+	}
+
+	if team.GroupID > 0 && team.GroupID == user.GroupID {
+		return team.DefaultRole
+	}
+
+	return 0
+}
+
+// SetMember 添加团队成员或修改成员角色
+func (team *Team) SetMember(uid uint, role int) error {
+	var member TeamMember
+	if err := DB.Where("team_id = ? and user_id = ?", team.ID, uid).First(&member).Error; err == nil {
+		return DB.Model(&member).UpdateColumn("role", role).Error
+	}
+
+	return DB.Create(&TeamMember{TeamID: team.ID, UserID: uid, Role: role}).Error
+}
+
+// RemoveMember 移除团队成员
+func (team *Team) RemoveMember(uid uint) error {
+	return DB.Unscoped().Where("team_id = ? and user_id = ?", team.ID, uid).Delete(&TeamMember{}).Error
+}
+
+// Members 列出团队的成员
+func (team *Team) Members() ([]TeamMember, error) {
+	var members []TeamMember
+	result := DB.Where("team_id = ?", team.ID).Order("id asc").Find(&members)
+	return members, result.Error
+}
+
+// GetTeamByID 根据ID查找团队空间
+func GetTeamByID(id interface{}) (*Team, error) {
+	var team Team
+	result := DB.First(&team, id)
+	return &team, result.Error
+}
+
+// GetTeamByUserID 根据内部账户查找团队空间
+func GetTeamByUserID(uid uint) (*Team, error) {
+	var team Team
+	result := DB.Where("user_id = ? and user_id > 0", uid).First(&team)
+	return &team, result.Error
+}
+
+// GetTeams 列出所有团队空间
+func GetTeams() ([]Team, error) {
+	var teams []Team
+	result := DB.Order("id asc").Find(&teams)
+	return teams, result.Error
+}
+
+// GetTeamsForUser 列出用户可以访问的团队空间
+func GetTeamsForUser(user *User) ([]Team, error) {
+	var teams []Team
+	result := DB.Where("id in (?) or (group_id > 0 and group_id = ? and default_role > 0)",
+		DB.Model(&TeamMember{}).Select("team_id").Where("user_id = ? and role > 0", user.ID).SubQuery(),
+		user.GroupID,
+	).Order("id asc").Find(&teams)
+	return teams, result.Error
+}
+
+// DeleteTeamMembersByUser 删除用户的团队成员身份
+func DeleteTeamMembersByUser(uid uint) error {
+	return DB.Unscoped().Where("user_id = ?", uid).Delete(&TeamMember{}).Error
+}
+
+// applyTeam 团队的内部账户使用团队的容量和存储策略
+func (user *User) applyTeam() {
+	team, err := GetTeamByID(user.OptionsSerialized.TeamID)
+	if err != nil {
+		return
+	}
+
+	user.Group.MaxStorage = team.MaxStorage
+	if team.PolicyID > 0 {
+		user.Group.PolicyList = []uint{team.PolicyID}
+	}
+}
@@ -0,0 +1,265 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// trashNamePrefix 回收站中顶层对象的保留名称前缀，正常的对象名称中不能包含 "/"
+const trashNamePrefix = ".trash/"
+
+// Trash 回收站中的文件或目录。对象及其所有子对象以软删除的方式保留，
+// 顶层对象改为保留名称，以免占用原位置
+type Trash struct {
+	gorm.Model
+	UserID   uint `gorm:"index:trash_user_id"`
+	ObjectID uint
+	IsFolder bool
+	// Name 原始名称
+	Name string
+	// Path 原始所在目录的路径
+	Path string `gorm:"type:text"`
+	// ParentID 原始所在目录的 ID
+	ParentID uint
+	// Size 包含的文件总大小
+	Size uint64
+}
+
+// TrashFile 将文件移入回收站，path 为文件所在目录的路径
+func TrashFile(file *File, path string) (*Trash, error) {
+	trash := &Trash{
+		UserID:   file.UserID,
+		ObjectID: file.ID,
+		Name:     file.Name,
+		Path:     path,
+		ParentID: file.FolderID,
+		Size:     file.Size,
+	}
+
+	return trash, trash.create(nil, []uint{file.ID})
+}
+
+// TrashFolder 将目录移入回收站，path 为目录所在目录的路径，
+// folders 为包括目录自身在内的所有子目录，files 为其中的所有文件
+func TrashFolder(folder *Folder, path string, folders []Folder, files []File) (*Trash, error) {
+	trash := &Trash{
+		UserID:   folder.OwnerID,
+		ObjectID: folder.ID,
+		IsFolder: true,
+		Name:     folder.Name,
+		Path:     path,
+		ParentID: *folder.ParentID,
+	}
+
+	folderIDs := make([]uint, 0, len(folders))
+	for _, value := range folders {
+		folderIDs = append(folderIDs, value.ID)
+	}
+
+	fileIDs := make([]uint, 0, len(files))
+	for _, value := range files {
+		fileIDs = append(fileIDs, value.ID)
+		trash.Size += value.Size
+	}
+
+	return trash, trash.create(folderIDs, fileIDs)
+}
+
+// create 创建回收站记录，并软删除给定的目录和文件
+func (trash *Trash) create(folderIDs, fileIDs []uint) error {
+	tx := DB.Begin()
+	if err := tx.Create(trash).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(trash.object()).Where("id = ?", trash.ObjectID).
+		UpdateColumn("name", trash.reservedName()).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(folderIDs) > 0 {
+		if err := tx.Where("id in (?)", folderIDs).Delete(&Folder{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(fileIDs) > 0 {
+		if err := tx.Where("id in (?)", fileIDs).Delete(&File{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := changeFolderSize(tx, trash.UserID, trash.ParentID, "-", trash.Size); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// object 返回顶层对象对应的模型
+func (trash *Trash) object() interface{} {
+	if trash.IsFolder {
+		return &Folder{}
+	}
+
+	return &File{}
+}
+
+// reservedName 顶层对象在回收站中的保留名称
+func (trash *Trash) reservedName() string {
+	return fmt.Sprintf("%s%d", trashNamePrefix, trash.ID)
+}
+
+// Objects 列出回收站记录包含的所有目录和文件，
+// 其中单独移入回收站的子对象属于其他记录，不会被列出
+func (trash *Trash) Objects() ([]Folder, []File, error) {
+	folders := make([]Folder, 0)
+	files := make([]File, 0)
+
+	if !trash.IsFolder {
+		var file File
+		if err := DB.Unscoped().Where("id = ? and user_id = ?", trash.ObjectID, trash.UserID).
+			First(&file).Error; err != nil {
+			return nil, nil, err
+		}
+
+		return folders, append(files, file), nil
+	}
+
+	var top Folder
+	if err := DB.Unscoped().Where("id = ? and owner_id = ?", trash.ObjectID, trash.UserID).
+		First(&top).Error; err != nil {
+		return nil, nil, err
+	}
+
+	folders = append(folders, top)
+	folderIDs := []uint{top.ID}
+	parents := folderIDs
+	for len(parents) > 0 {
+		var children []Folder
+		if err := DB.Unscoped().
+			Where("parent_id in (?) and owner_id = ? and name not like ?", parents, trash.UserID, trashNamePrefix+"%").
+			Find(&children).Error; err != nil {
+			return nil, nil, err
+		}
+
+		parents = make([]uint, 0, len(children))
+		for _, child := range children {
+			parents = append(parents, child.ID)
+		}
+
+		folders = append(folders, children...)
+		folderIDs = append(folderIDs, parents...)
+	}
+
+	if err := DB.Unscoped().
+		Where("folder_id in (?) and user_id = ? and name not like ?", folderIDs, trash.UserID, trashNamePrefix+"%").
+		Find(&files).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return folders, files, nil
+}
+
+// Restore 将回收站记录包含的对象还原至目录 parentID 下，并命名为 name
+func (trash *Trash) Restore(name string, parentID uint, folders []Folder, files []File) error {
+	parentColumn := "folder_id"
+	if trash.IsFolder {
+		parentColumn = "parent_id"
+	}
+
+	updates := map[string]interface{}{
+		"name":       name,
+		parentColumn: parentID,
+	}
+	if !trash.IsFolder {
+		updates["extension"] = FileExtension(name)
+	}
+
+	tx := DB.Begin()
+	if err := tx.Unscoped().Model(trash.object()).Where("id = ?", trash.ObjectID).
+		UpdateColumns(updates).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	folderIDs := make([]uint, 0, len(folders))
+	for _, value := range folders {
+		folderIDs = append(folderIDs, value.ID)
+	}
+
+	fileIDs := make([]uint, 0, len(files))
+	var size uint64
+	for _, value := range files {
+		fileIDs = append(fileIDs, value.ID)
+		size += value.Size
+	}
+
+	if len(folderIDs) > 0 {
+		if err := tx.Unscoped().Model(&Folder{}).Where("id in (?)", folderIDs).
+			UpdateColumn("deleted_at", gorm.Expr("NULL")).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(fileIDs) > 0 {
+		if err := tx.Unscoped().Model(&File{}).Where("id in (?)", fileIDs).
+			UpdateColumn("deleted_at", gorm.Expr("NULL")).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := changeFolderSize(tx, trash.UserID, parentID, "+", size); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Delete(trash).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Delete 删除回收站记录
+func (trash *Trash) Delete() error {
+	return DB.Unscoped().Delete(trash).Error
+}
+
+// ExpireAt 根据保留天数计算过期时间
+func (trash *Trash) ExpireAt(retention int) time.Time {
+	return trash.CreatedAt.AddDate(0, 0, retention)
+}
+
+// GetTrashByUser 列出用户回收站中的对象
+func GetTrashByUser(uid uint) ([]Trash, error) {
+	var res []Trash
+	result := DB.Where("user_id = ?", uid).Order("id desc").Find(&res)
+	return res, result.Error
+}
+
+// GetTrashByIDs 根据ID和用户查找回收站记录
+func GetTrashByIDs(ids []uint, uid uint) ([]Trash, error) {
+	var res []Trash
+	result := DB.Where("id in (?) and user_id = ?", ids, uid).Find(&res)
+	return res, result.Error
+}
+
+// GetExpiredTrash 列出用户组 groupID 中早于 before 移入回收站的记录
+func GetExpiredTrash(groupID uint, before time.Time) ([]Trash, error) {
+	var res []Trash
+	result := DB.Select("trashes.*").Joins("inner join users on users.id = trashes.user_id").
+		Where("users.group_id = ? and trashes.created_at < ?", groupID, before).
+		Find(&res)
+	return res, result.Error
+}
@@ -55,6 +55,8 @@ func init() {
 type UserOption struct {
 	ProfileOff     bool   `json:"profile_off,omitempty"`
 	PreferredTheme string `json:"preferred_theme,omitempty"`
+	// TeamID 团队空间内部账户对应的团队
+	TeamID uint `json:"team_id,omitempty"`
 }
 
 // Root 获取用户的根目录
@@ -193,6 +195,11 @@ func (user *User) AfterFind() (err error) {
 		err = json.Unmarshal([]byte(user.Options), &user.OptionsSerialized)
 	}
 
+	// 团队空间内部账户
+	if user.OptionsSerialized.TeamID > 0 {
+		user.applyTeam()
+	}
+
 	// 预加载存储策略
 	user.Policy, _ = GetPolicyByID(user.GetPolicyID(0))
 	return err
@@ -0,0 +1,164 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// FileVersion 文件被覆盖前的历史版本，保留原有的物理文件
+type FileVersion struct {
+	gorm.Model
+	FileID     uint `gorm:"index:version_file_id"`
+	UserID     uint `gorm:"index:version_user_id"`
+	PolicyID   uint
+	SourceName string `gorm:"type:text"`
+	Size       uint64
+}
+
+// NewFileVersion 以文件当前的内容创建历史版本模型
+func NewFileVersion(file *File) *FileVersion {
+	return &FileVersion{
+		FileID:     file.ID,
+		UserID:     file.UserID,
+		PolicyID:   file.PolicyID,
+		SourceName: file.SourceName,
+		Size:       file.Size,
+	}
+}
+
+// Create 创建历史版本，并增加用户已用容量
+func (version *FileVersion) Create() error {
+	tx := DB.Begin()
+	if err := tx.Create(version).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	user := User{}
+	user.ID = version.UserID
+	if err := user.ChangeStorage(tx, "+", version.Size); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Restore 将文件内容还原为此版本，文件当前的内容成为最新的历史版本
+func (version *FileVersion) Restore(file *File) error {
+	if err := file.resetThumb(); err != nil {
+		return err
+	}
+
+	source, size := file.SourceName, file.Size
+	tx := DB.Begin()
+	if err := tx.Model(file).Set("gorm:association_autoupdate", false).Updates(map[string]interface{}{
+		"source_name": version.SourceName,
+		"size":        version.Size,
+		"metadata":    file.Metadata,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 文件与版本的大小交换，用户已用容量不变
+	if err := tx.Model(version).UpdateColumns(map[string]interface{}{
+		"source_name": source,
+		"size":        size,
+		"created_at":  time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 目录大小只统计文件当前的内容
+	operator, delta := "+", version.Size-size
+	if size > version.Size {
+		operator, delta = "-", size-version.Size
+	}
+
+	if err := changeFolderSize(tx, file.UserID, file.FolderID, operator, delta); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// IsSourceShared 返回此版本的物理文件是否仍被其他文件或版本引用
+func (version *FileVersion) IsSourceShared() bool {
+	var count int
+	DB.Unscoped().Model(&File{}).
+		Where("source_name = ? and policy_id = ?", version.SourceName, version.PolicyID).
+		Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	DB.Model(&FileVersion{}).
+		Where("source_name = ? and policy_id = ? and id != ?", version.SourceName, version.PolicyID, version.ID).
+		Count(&count)
+	return count > 0
+}
+
+// IsVersionSource 返回给定物理路径是否被历史版本引用
+func IsVersionSource(policyID uint, source string) bool {
+	var count int
+	DB.Model(&FileVersion{}).Where("source_name = ? and policy_id = ?", source, policyID).Count(&count)
+	return count > 0
+}
+
+// GetVersionsByFile 列出文件的历史版本，新版本在前
+func GetVersionsByFile(fileID uint) ([]FileVersion, error) {
+	var versions []FileVersion
+	result := DB.Where("file_id = ?", fileID).Order("created_at desc, id desc").Find(&versions)
+	return versions, result.Error
+}
+
+// GetVersionsByFileIDs 列出多个文件的历史版本
+func GetVersionsByFileIDs(fileIDs []uint) ([]FileVersion, error) {
+	var versions []FileVersion
+	if len(fileIDs) == 0 {
+		return versions, nil
+	}
+
+	result := DB.Where("file_id in (?)", fileIDs).Find(&versions)
+	return versions, result.Error
+}
+
+// GetVersionsByIDs 根据ID和所属文件查找历史版本
+func GetVersionsByIDs(ids []uint, fileID uint) ([]FileVersion, error) {
+	var versions []FileVersion
+	result := DB.Where("id in (?) and file_id = ?", ids, fileID).Find(&versions)
+	return versions, result.Error
+}
+
+// GetExpiredVersions 列出用户组 groupID 中早于 before 创建的历史版本
+func GetExpiredVersions(groupID uint, before time.Time) ([]FileVersion, error) {
+	var versions []FileVersion
+	result := DB.Select("file_versions.*").Joins("inner join users on users.id = file_versions.user_id").
+		Where("users.group_id = ? and file_versions.created_at < ?", groupID, before).
+		Find(&versions)
+	return versions, result.Error
+}
+
+// DeleteVersions 删除历史版本记录，并扣除用户已用容量
+func DeleteVersions(versions []FileVersion) error {
+	tx := DB.Begin()
+	for i := range versions {
+		if err := tx.Unscoped().Delete(&versions[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		user := User{}
+		user.ID = versions[i].UserID
+		if err := user.ChangeStorage(tx, "-", versions[i].Size); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
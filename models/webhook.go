@@ -0,0 +1,134 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Webhook 事件通知地址，UserID 为 0 时由管理员创建，接收所有用户的事件
+type Webhook struct {
+	gorm.Model
+	UserID uint   `gorm:"index:webhook_user_id"`
+	Name   string // 名称
+	URL    string `gorm:"type:text"` // 接收地址
+	Secret string `json:"-"`         // 签名密钥
+	Events string `gorm:"type:text"` // 订阅的事件，以逗号分隔
+	Enable bool   `gorm:"type:bool"` // 是否启用
+}
+
+// WebhookDelivery 事件投递记录
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID  uint   `gorm:"index:delivery_webhook_id"`
+	Event      string // 事件类型
+	Payload    string `gorm:"type:text"` // 请求正文
+	Attempts   int    // 已尝试次数
+	StatusCode int    // 最后一次请求的响应状态码
+	Response   string `gorm:"type:text"` // 最后一次请求的响应正文
+	Error      string `gorm:"type:text"` // 最后一次请求的错误信息
+	Success    bool   `gorm:"type:bool"`
+}
+
+// Create 创建 Webhook
+func (hook *Webhook) Create() (uint, error) {
+	if err := DB.Create(hook).Error; err != nil {
+		return 0, err
+	}
+	return hook.ID, nil
+}
+
+// Subscribed 返回是否订阅了事件
+func (hook *Webhook) Subscribed(event string) bool {
+	for _, subscribed := range strings.Split(hook.Events, ",") {
+		if subscribed == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetWebhookByID 根据ID和UID查找 Webhook
+func GetWebhookByID(id, uid uint) (*Webhook, error) {
+	hook := &Webhook{}
+	res := DB.Where("id = ? and user_id = ?", id, uid).First(hook)
+	return hook, res.Error
+}
+
+// GetActiveWebhooks 列出用户启用的 Webhook，包括管理员创建的 Webhook
+func GetActiveWebhooks(uid uint) ([]Webhook, error) {
+	var hooks []Webhook
+	res := DB.Where("user_id in (?) and enable = ?", []uint{0, uid}, true).Find(&hooks)
+	return hooks, res.Error
+}
+
+// ListWebhooks 列出用户的所有 Webhook
+func ListWebhooks(uid uint) []Webhook {
+	var hooks []Webhook
+	DB.Where("user_id = ?", uid).Order("created_at desc").Find(&hooks)
+	return hooks
+}
+
+// CountWebhooks 统计用户的 Webhook 数量
+func CountWebhooks(uid uint) int {
+	total := 0
+	DB.Model(&Webhook{}).Where("user_id = ?", uid).Count(&total)
+	return total
+}
+
+// UpdateWebhookByID 根据ID和UID更新 Webhook
+func UpdateWebhookByID(id, uid uint, updates map[string]interface{}) error {
+	return DB.Model(&Webhook{}).Where("id = ? and user_id = ?", id, uid).Updates(updates).Error
+}
+
+// DeleteWebhookByID 根据ID和UID删除 Webhook 及其投递记录
+func DeleteWebhookByID(id, uid uint) error {
+	res := DB.Where("id = ? and user_id = ?", id, uid).Delete(&Webhook{})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.Error
+	}
+
+	return DB.Where("webhook_id = ?", id).Delete(&WebhookDelivery{}).Error
+}
+
+// DeleteWebhooksByUser 删除用户的所有 Webhook 及其投递记录
+func DeleteWebhooksByUser(uid uint) error {
+	var ids []uint
+	if err := DB.Model(&Webhook{}).Where("user_id = ?", uid).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := DB.Where("webhook_id in (?)", ids).Delete(&WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+
+	return DB.Where("id in (?)", ids).Delete(&Webhook{}).Error
+}
+
+// Create 创建投递记录
+func (delivery *WebhookDelivery) Create() error {
+	return DB.Create(delivery).Error
+}
+
+// Save 保存投递结果
+func (delivery *WebhookDelivery) Save() error {
+	return DB.Save(delivery).Error
+}
+
+// ListWebhookDeliveries 列出 Webhook 最近的投递记录
+func ListWebhookDeliveries(hookID uint, limit int) []WebhookDelivery {
+	var deliveries []WebhookDelivery
+	DB.Where("webhook_id = ?", hookID).Order("id desc").Limit(limit).Find(&deliveries)
+	return deliveries
+}
+
+// DeleteWebhookDeliveriesBefore 删除 before 之前的投递记录
+func DeleteWebhookDeliveriesBefore(before time.Time) error {
+	return DB.Unscoped().Where("created_at < ?", before).Delete(&WebhookDelivery{}).Error
+}
@@ -17,6 +17,7 @@ import (
 	"gitee.com/jiangjiali/cloudreve/pkg/mq"
 	"gitee.com/jiangjiali/cloudreve/pkg/task"
 	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"gitee.com/jiangjiali/cloudreve/pkg/webhook"
 )
 
 // Monitor 离线下载状态监控
@@ -192,7 +193,8 @@ func (monitor *Monitor) ValidateFile() error {
 
 	// 创建上下文环境
 	file := &fsctx.FileStream{
-		Size: monitor.Task.TotalSize,
+		Size:        monitor.Task.TotalSize,
+		VirtualPath: monitor.Task.Dst,
 	}
 
 	// 验证用户容量
@@ -252,6 +254,10 @@ func (monitor *Monitor) Complete(pool task.Pool) bool {
 
 		// 转存完成，回收下载目录
 		if transferTask.Type == task.TransferTaskType && transferTask.Status >= task.Error {
+			if transferTask.Status == task.Complete {
+				webhook.Trigger(monitor.Task.UserID, webhook.DownloadFinished, webhook.NewDownload(monitor.Task))
+			}
+
 			job, err := task.NewRecycleTask(monitor.Task)
 			if err != nil {
 				monitor.setErrorStatus(err)
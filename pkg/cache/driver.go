@@ -59,6 +59,9 @@ type Driver interface {
 	// 删除值
 	Delete(keys []string, prefix string) error
 
+	// 原子地将整数值加一并重设过期时间，返回增加后的值
+	Incr(key string, ttl int) (int, error)
+
 	// Save in-memory cache to disk
 	Persist(path string) error
 
@@ -76,6 +79,11 @@ func Get(key string) (interface{}, bool) {
 	return Store.Get(key)
 }
 
+// Incr 原子地将整数缓存值加一，值不存在时从 0 开始
+func Incr(key string, ttl int) (int, error) {
+	return Store.Incr(key, ttl)
+}
+
 // Deletes 删除值
 func Deletes(keys []string, prefix string) error {
 	return Store.Delete(keys, prefix)
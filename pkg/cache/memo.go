@@ -13,6 +13,9 @@ import (
 // MemoStore 内存存储驱动
 type MemoStore struct {
 	Store *sync.Map
+
+	// 保护 Incr 的读取和写入
+	incrMu sync.Mutex
 }
 
 // item 存储的对象
@@ -116,6 +119,18 @@ func (store *MemoStore) Delete(keys []string, prefix string) error {
 	return nil
 }
 
+// Incr 将整数值加一
+func (store *MemoStore) Incr(key string, ttl int) (int, error) {
+	store.incrMu.Lock()
+	defer store.incrMu.Unlock()
+
+	value, _ := store.Get(key)
+	count, _ := value.(int)
+	count++
+	store.Store.Store(key, newItem(count, ttl))
+	return count, nil
+}
+
 // Persist write memory store into cache
 func (store *MemoStore) Persist(path string) error {
 	persisted := make(map[string]itemWithTTL)
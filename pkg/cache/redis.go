@@ -117,6 +117,10 @@ func (store *RedisStore) Get(key string) (interface{}, bool) {
 
 	finalValue, err := deserializer(v)
 	if err != nil {
+		// Incr 写入的整数值未经序列化
+		if count, err := strconv.Atoi(string(v)); err == nil {
+			return count, true
+		}
 		return nil, false
 	}
 
@@ -124,6 +128,28 @@ func (store *RedisStore) Get(key string) (interface{}, bool) {
 
 }
 
+// Incr 将整数值加一
+func (store *RedisStore) Incr(key string, ttl int) (int, error) {
+	rc := store.pool.Get()
+	defer rc.Close()
+	if rc.Err() != nil {
+		return 0, rc.Err()
+	}
+
+	rc.Send("MULTI")
+	rc.Send("INCR", key)
+	if ttl > 0 {
+		rc.Send("EXPIRE", key, ttl)
+	}
+
+	values, err := redis.Values(rc.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+
+	return redis.Int(values[0], nil)
+}
+
 // Gets 批量取值
 func (store *RedisStore) Gets(keys []string, prefix string) (map[string]interface{}, []string) {
 	rc := store.pool.Get()
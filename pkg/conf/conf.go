@@ -58,6 +58,12 @@ type redis struct {
 	DB       string
 }
 
+// encryption 存储策略静态加密配置
+type encryption struct {
+	// MasterKey Base64 编码的 32 字节主密钥，用于包装每个文件的数据密钥
+	MasterKey string
+}
+
 // 跨域配置
 type cors struct {
 	AllowOrigins     []string
@@ -116,6 +122,7 @@ func Init(path string) {
 		"Redis":      RedisConfig,
 		"CORS":       CORSConfig,
 		"Slave":      SlaveConfig,
+		"Encryption": EncryptionConfig,
 	}
 	for sectionName, sectionStruct := range sections {
 		err = mapSection(sectionName, sectionStruct)
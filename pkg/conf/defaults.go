@@ -42,6 +42,9 @@ var SlaveConfig = &slave{
 	SignatureTTL:    60,
 }
 
+// EncryptionConfig 静态加密配置
+var EncryptionConfig = &encryption{}
+
 var SSLConfig = &ssl{
 	Listen:   ":443",
 	CertPath: "",
@@ -4,7 +4,7 @@ package conf
 var BackendVersion = "3.8.3"
 
 // RequiredDBVersion 与当前版本匹配的数据库版本
-var RequiredDBVersion = "3.8.1"
+var RequiredDBVersion = "3.8.12"
 
 // RequiredStaticVersion 与当前版本匹配的静态资源版本
 var RequiredStaticVersion = "3.8.3"
@@ -3,19 +3,18 @@ package crontab
 import (
 	"context"
 	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/cache"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/s3gateway"
 	"gitee.com/jiangjiali/cloudreve/pkg/util"
 )
 
 func garbageCollect() {
-	// 清理打包下载产生的临时文件
-	collectArchiveFile()
+	// 清理过期的 S3 分片上传会话留下的分片
+	collectS3MultipartParts()
 
 	// 清理过期的内置内存缓存
 	if store, ok := cache.Store.(*cache.MemoStore); ok {
@@ -25,30 +24,22 @@ func garbageCollect() {
 	util.Log().Info("Crontab job \"cron_garbage_collect\" complete.")
 }
 
-func collectArchiveFile() {
-	// 读取有效期、目录设置
-	tempPath := util.RelativePath(model.GetSettingByName("temp_path"))
-	expires := model.GetIntSetting("download_timeout", 30)
-
-	// 列出文件
-	root := filepath.Join(tempPath, "archive")
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err == nil && !info.IsDir() &&
-			strings.HasPrefix(filepath.Base(path), "archive_") &&
-			time.Now().Sub(info.ModTime()).Seconds() > float64(expires) {
-			util.Log().Debug("Delete expired batch download temp file %q.", path)
-			// 删除符合条件的文件
-			if err := os.Remove(path); err != nil {
-				util.Log().Debug("Failed to delete temp file %q: %s", path, err)
-			}
-		}
-		return nil
-	})
-
+func collectS3MultipartParts() {
+	entries, err := os.ReadDir(s3gateway.PartsDir(""))
 	if err != nil {
-		util.Log().Debug("Crontab job cannot list temp batch download folder: %s", err)
+		return
 	}
 
+	for _, e := range entries {
+		if _, ok := cache.Get(s3gateway.MultipartCachePrefix + e.Name()); ok {
+			continue
+		}
+
+		util.Log().Debug("Delete expired S3 multipart upload parts %q.", e.Name())
+		if err := os.RemoveAll(s3gateway.PartsDir(e.Name())); err != nil {
+			util.Log().Debug("Failed to delete S3 multipart upload parts %q: %s", e.Name(), err)
+		}
+	}
 }
 
 func collectCache(store *cache.MemoStore) {
@@ -97,3 +88,158 @@ func uploadSessionCollect() {
 
 	util.Log().Info("Crontab job \"cron_recycle_upload_session\" complete.")
 }
+
+func trashCollect() {
+	var groups []model.Group
+	if err := model.DB.Find(&groups).Error; err != nil {
+		util.Log().Warning("Failed to list user groups: %s", err)
+		return
+	}
+
+	// 将过期的回收站对象按照用户分组
+	userToTrash := make(map[uint][]uint)
+	for _, group := range groups {
+		// 已关闭回收站的用户组中遗留的对象立即清理
+		retention := group.OptionsSerialized.TrashRetention
+		if retention < 0 {
+			retention = 0
+		}
+
+		expired, err := model.GetExpiredTrash(group.ID, time.Now().AddDate(0, 0, -retention))
+		if err != nil {
+			util.Log().Warning("Failed to list expired trash: %s", err)
+			continue
+		}
+
+		for _, item := range expired {
+			userToTrash[item.UserID] = append(userToTrash[item.UserID], item.ID)
+		}
+	}
+
+	// 彻底删除过期的对象
+	for uid, ids := range userToTrash {
+		user, err := model.GetUserByID(uid)
+		if err != nil {
+			util.Log().Warning("Owner of the trash cannot be found: %s", err)
+			continue
+		}
+
+		fs, err := filesystem.NewFileSystem(&user)
+		if err != nil {
+			util.Log().Warning("Failed to initialize filesystem: %s", err)
+			continue
+		}
+
+		if err = fs.DeleteTrash(context.Background(), ids); err != nil {
+			util.Log().Warning("Failed to purge trash: %s", err)
+		}
+
+		fs.Recycle()
+	}
+
+	util.Log().Info("Crontab job \"cron_purge_trash\" complete.")
+}
+
+func versionCollect() {
+	var groups []model.Group
+	if err := model.DB.Find(&groups).Error; err != nil {
+		util.Log().Warning("Failed to list user groups: %s", err)
+		return
+	}
+
+	// 将过期的历史版本按照用户和文件分组
+	userToVersions := make(map[uint]map[uint][]uint)
+	for _, group := range groups {
+		// 已关闭历史版本的用户组中遗留的版本立即清理
+		before := time.Now()
+		if group.OptionsSerialized.MaxVersions > 0 {
+			if group.OptionsSerialized.VersionRetention <= 0 {
+				continue
+			}
+
+			before = before.AddDate(0, 0, -group.OptionsSerialized.VersionRetention)
+		}
+
+		expired, err := model.GetExpiredVersions(group.ID, before)
+		if err != nil {
+			util.Log().Warning("Failed to list expired versions: %s", err)
+			continue
+		}
+
+		for _, version := range expired {
+			if _, ok := userToVersions[version.UserID]; !ok {
+				userToVersions[version.UserID] = make(map[uint][]uint)
+			}
+
+			userToVersions[version.UserID][version.FileID] = append(userToVersions[version.UserID][version.FileID], version.ID)
+		}
+	}
+
+	// 删除过期的版本
+	for uid, fileToVersions := range userToVersions {
+		user, err := model.GetUserByID(uid)
+		if err != nil {
+			util.Log().Warning("Owner of the file versions cannot be found: %s", err)
+			continue
+		}
+
+		fs, err := filesystem.NewFileSystem(&user)
+		if err != nil {
+			util.Log().Warning("Failed to initialize filesystem: %s", err)
+			continue
+		}
+
+		for fileID, ids := range fileToVersions {
+			if err = fs.DeleteVersions(context.Background(), fileID, ids); err != nil {
+				util.Log().Warning("Failed to delete expired versions: %s", err)
+			}
+		}
+
+		fs.Recycle()
+	}
+
+	util.Log().Info("Crontab job \"cron_purge_versions\" complete.")
+}
+
+func changeCollect() {
+	retention := model.GetIntSetting("change_journal_retention", 30)
+	deleted, err := model.DeleteChangesBefore(time.Now().AddDate(0, 0, -retention))
+	if err != nil {
+		util.Log().Warning("Failed to compact change journal: %s", err)
+		return
+	}
+
+	util.Log().Info("Crontab job \"cron_compact_changes\" complete, %d change(s) removed.", deleted)
+}
+
+func webhookDeliveryCollect() {
+	retention := model.GetIntSetting("webhook_delivery_retention", 30)
+	if err := model.DeleteWebhookDeliveriesBefore(time.Now().AddDate(0, 0, -retention)); err != nil {
+		util.Log().Warning("Failed to purge webhook deliveries: %s", err)
+		return
+	}
+
+	util.Log().Info("Crontab job \"cron_purge_webhook_deliveries\" complete.")
+}
+
+func auditLogCollect() {
+	retention := model.GetIntSetting("audit_log_retention", 180)
+	deleted, err := model.DeleteAuditLogsBefore(time.Now().AddDate(0, 0, -retention))
+	if err != nil {
+		util.Log().Warning("Failed to purge audit logs: %s", err)
+		return
+	}
+
+	util.Log().Info("Crontab job \"cron_purge_audit_logs\" complete, %d audit log(s) removed.", deleted)
+}
+
+func shareAccessCollect() {
+	retention := model.GetIntSetting("share_access_retention", 90)
+	deleted, err := model.DeleteShareAccessesBefore(time.Now().AddDate(0, 0, -retention))
+	if err != nil {
+		util.Log().Warning("Failed to purge share access logs: %s", err)
+		return
+	}
+
+	util.Log().Info("Crontab job \"cron_purge_share_accesses\" complete, %d access log(s) removed.", deleted)
+}
@@ -24,6 +24,12 @@ func Init() {
 	options := model.GetSettingByNames(
 		"cron_garbage_collect",
 		"cron_recycle_upload_session",
+		"cron_purge_trash",
+		"cron_purge_versions",
+		"cron_compact_changes",
+		"cron_purge_webhook_deliveries",
+		"cron_purge_audit_logs",
+		"cron_purge_share_accesses",
 	)
 	Cron := cron.New()
 	for k, v := range options {
@@ -33,6 +39,18 @@ func Init() {
 			handler = garbageCollect
 		case "cron_recycle_upload_session":
 			handler = uploadSessionCollect
+		case "cron_purge_trash":
+			handler = trashCollect
+		case "cron_purge_versions":
+			handler = versionCollect
+		case "cron_compact_changes":
+			handler = changeCollect
+		case "cron_purge_webhook_deliveries":
+			handler = webhookDeliveryCollect
+		case "cron_purge_audit_logs":
+			handler = auditLogCollect
+		case "cron_purge_share_accesses":
+			handler = shareAccessCollect
 		default:
 			util.Log().Warning("Unknown crontab job type %q, skipping...", k)
 			continue
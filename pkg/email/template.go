@@ -21,6 +21,22 @@ func NewActivationEmail(userName, activateURL string) (string, string) {
 		util.Replace(replace, options["mail_activation_template"])
 }
 
+// NewShareCodeEmail 新建受限分享访问验证码邮件
+func NewShareCodeEmail(nick, shareName, code string, expire int) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_share_code_template")
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{nick}":         nick,
+		"{shareName}":    shareName,
+		"{code}":         code,
+		"{expire}":       fmt.Sprintf("%d", expire/60),
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】分享访问验证码", options["siteName"]),
+		util.Replace(replace, options["mail_share_code_template"])
+}
+
 // NewResetEmail 新建重设密码邮件
 func NewResetEmail(userName, resetURL string) (string, string) {
 	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_reset_pwd_template")
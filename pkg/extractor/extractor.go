@@ -0,0 +1,82 @@
+package extractor
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTextLength 提取文本的最大长度，超出部分会被截断
+const MaxTextLength = 1 << 20
+
+var (
+	// ErrUnsupported 不支持的文件类型
+	ErrUnsupported = errors.New("unsupported file type")
+
+	// textExtensions 按纯文本处理的扩展名，包括常见的源代码
+	textExtensions = map[string]bool{
+		"txt": true, "md": true, "markdown": true, "log": true, "csv": true, "tsv": true,
+		"json": true, "xml": true, "yaml": true, "yml": true, "toml": true, "ini": true, "conf": true,
+		"html": true, "htm": true, "css": true, "scss": true, "less": true,
+		"go": true, "py": true, "js": true, "jsx": true, "ts": true, "tsx": true, "vue": true,
+		"java": true, "kt": true, "scala": true, "c": true, "h": true, "cpp": true, "hpp": true,
+		"cc": true, "cs": true, "rb": true, "php": true, "rs": true, "swift": true, "dart": true,
+		"lua": true, "pl": true, "r": true, "sh": true, "bat": true, "ps1": true, "sql": true,
+	}
+
+	// officeExtensions Office Open XML 文档
+	officeExtensions = map[string]bool{
+		"docx": true, "xlsx": true, "pptx": true,
+	}
+)
+
+// ext 返回小写的扩展名，不含 .
+func ext(name string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+// Supported 返回是否支持提取文件 name 的文本
+func Supported(name string) bool {
+	e := ext(name)
+	return textExtensions[e] || officeExtensions[e] || e == "pdf"
+}
+
+// Extract 根据文件名提取文件内容中的文本
+func Extract(name string, data []byte) (string, error) {
+	var (
+		text string
+		err  error
+	)
+
+	switch e := ext(name); {
+	case textExtensions[e]:
+		text = string(data)
+	case officeExtensions[e]:
+		text, err = extractOffice(e, data)
+	case e == "pdf":
+		text, err = extractPDF(data)
+	default:
+		return "", ErrUnsupported
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return truncate(strings.ToValidUTF8(text, " ")), nil
+}
+
+// truncate 将文本截断至 MaxTextLength，不切断 UTF-8 字符
+func truncate(text string) string {
+	if len(text) <= MaxTextLength {
+		return text
+	}
+
+	end := MaxTextLength
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	return text[:end]
+}
@@ -0,0 +1,100 @@
+package extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxPartSize 单个部件解压后最多读取的字节数，防止压缩炸弹
+const maxPartSize = 64 << 20
+
+// officeParts 各类文档中包含文本的部件
+var officeParts = map[string][]string{
+	"docx": {"word/document.xml", "word/header*.xml", "word/footer*.xml", "word/footnotes.xml"},
+	"xlsx": {"xl/sharedStrings.xml"},
+	"pptx": {"ppt/slides/slide*.xml", "ppt/notesSlides/notesSlide*.xml"},
+}
+
+// extractOffice 提取 Office Open XML 文档中的文本
+func extractOffice(e string, data []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+
+	files := make([]*zip.File, 0)
+	for _, f := range reader.File {
+		for _, pattern := range officeParts[e] {
+			if ok, _ := path.Match(pattern, f.Name); ok {
+				files = append(files, f)
+				break
+			}
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	var buf strings.Builder
+	for _, f := range files {
+		if buf.Len() > MaxTextLength {
+			break
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+
+		err = extractXMLText(&buf, &io.LimitedReader{R: rc, N: maxPartSize})
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// extractXMLText 提取 <t> 元素中的文本，段落、单元格结束时换行；
+// 读取达到部件大小上限或文本达到 MaxTextLength 时停止
+func extractXMLText(buf *strings.Builder, r *io.LimitedReader) error {
+	decoder := xml.NewDecoder(r)
+	inText := false
+	for buf.Len() <= MaxTextLength {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// 截断的部件保留已提取的文本
+			if r.N <= 0 {
+				return nil
+			}
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			inText = t.Name.Local == "t"
+		case xml.EndElement:
+			inText = false
+			switch t.Name.Local {
+			case "p", "si", "tr", "tab":
+				buf.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				buf.Write(t)
+			}
+		}
+	}
+
+	return nil
+}
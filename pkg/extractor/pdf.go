@@ -0,0 +1,243 @@
+package extractor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxStreamSize 解压单个 PDF 数据流的最大大小
+const maxStreamSize = 16 << 20
+
+// extractPDF 提取 PDF 文档中使用简单字体的文本，CID 字体等复杂编码的文本不做处理
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", errors.New("not a PDF document")
+	}
+
+	var buf strings.Builder
+	pos := 0
+	for buf.Len() <= MaxTextLength {
+		i := bytes.Index(data[pos:], []byte("stream"))
+		if i < 0 {
+			break
+		}
+
+		keyword := pos + i
+		start := keyword + len("stream")
+
+		// 跳过 endstream
+		if bytes.HasSuffix(data[pos:keyword], []byte("end")) {
+			pos = start
+			continue
+		}
+
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+
+		// 数据流的字典位于最近的 obj 与 stream 之间
+		dict := data[pos:keyword]
+		if objStart := bytes.LastIndex(dict, []byte("obj")); objStart >= 0 {
+			dict = dict[objStart:]
+		}
+
+		stream := data[start : start+end]
+		pos = start + end + len("endstream")
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			stream = inflate(stream)
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+
+		if bytes.Contains(stream, []byte("BT")) {
+			parseContentStream(&buf, stream)
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// inflate 解压 FlateDecode 数据流，损坏的数据流返回已解压的部分
+func inflate(data []byte) []byte {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+
+	res, _ := io.ReadAll(io.LimitReader(reader, maxStreamSize))
+	return res
+}
+
+// parseContentStream 解析页面内容流中文本绘制操作的字符串
+func parseContentStream(buf *strings.Builder, s []byte) {
+	pending := make([]string, 0)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '(':
+			str, n := readLiteral(s[i:])
+			pending = append(pending, str)
+			i += n
+		case c == '<' && i+1 < len(s) && s[i+1] == '<', c == '>' && i+1 < len(s) && s[i+1] == '>':
+			i += 2
+		case c == '<':
+			str, n := readHexString(s[i:])
+			pending = append(pending, str)
+			i += n
+		case c == '%':
+			for i < len(s) && s[i] != '\n' && s[i] != '\r' {
+				i++
+			}
+		case isPDFDelimiter(c):
+			i++
+		default:
+			start := i
+			for i < len(s) && !isPDFDelimiter(s[i]) {
+				i++
+			}
+
+			switch op := string(s[start:i]); op {
+			case "Tj", "TJ", "'", "\"":
+				if op != "Tj" && op != "TJ" {
+					buf.WriteByte('\n')
+				}
+				buf.WriteString(strings.Join(pending, ""))
+				pending = pending[:0]
+			case "Td", "TD", "Tm":
+				buf.WriteByte(' ')
+			case "T*", "ET":
+				buf.WriteByte('\n')
+			default:
+				// 数字和名称是操作数，其他操作符丢弃之前的字符串
+				if (op[0] < '0' || op[0] > '9') && op[0] != '-' && op[0] != '.' && op[0] != '/' {
+					pending = pending[:0]
+				}
+			}
+		}
+	}
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '%':
+		return true
+	}
+
+	return false
+}
+
+// readLiteral 读取 (...) 字符串，返回解码后的字符串及读取的字节数
+func readLiteral(s []byte) (string, int) {
+	var (
+		res   []byte
+		depth = 0
+		i     = 0
+	)
+
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(res), i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(s) {
+				break
+			}
+
+			switch e := s[i]; e {
+			case 'n':
+				res = append(res, '\n')
+			case 'r':
+				res = append(res, '\r')
+			case 't':
+				res = append(res, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// 续行
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					for j := 0; j < 3 && i < len(s) && s[i] >= '0' && s[i] <= '7'; j++ {
+						v = v*8 + int(s[i]-'0')
+						i++
+					}
+					i--
+					res = append(res, byte(v))
+				} else {
+					res = append(res, e)
+				}
+			}
+			continue
+		}
+
+		res = append(res, c)
+	}
+
+	return decodePDFString(res), i
+}
+
+// readHexString 读取 <...> 字符串，返回解码后的字符串及读取的字节数
+func readHexString(s []byte) (string, int) {
+	end := bytes.IndexByte(s, '>')
+	if end < 0 {
+		return "", len(s)
+	}
+
+	digits := make([]byte, 0, end)
+	for _, c := range s[1:end] {
+		if !isPDFDelimiter(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	res, err := hex.DecodeString(string(digits))
+	if err != nil {
+		return "", end + 1
+	}
+
+	return decodePDFString(res), end + 1
+}
+
+// decodePDFString 解码 PDF 字符串，带 BOM 的按 UTF-16BE 解码，其余按 Latin-1 解码
+func decodePDFString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
@@ -26,33 +26,9 @@ import (
 
 // Compress 创建给定目录和文件的压缩文件
 func (fs *FileSystem) Compress(ctx context.Context, writer io.Writer, folderIDs, fileIDs []uint, isArchive bool) error {
-	// 查找待压缩目录
-	folders, err := model.GetFoldersByIDs(folderIDs, fs.User.ID)
-	if err != nil && len(folderIDs) != 0 {
-		return ErrDBListObjects
-	}
-
-	// 查找待压缩文件
-	files, err := model.GetFilesByIDs(fileIDs, fs.User.ID)
-	if err != nil && len(fileIDs) != 0 {
-		return ErrDBListObjects
-	}
-
-	// 如果上下文限制了父目录，则进行检查
-	if parent, ok := ctx.Value(fsctx.LimitParentCtx).(*model.Folder); ok {
-		// 检查目录
-		for _, folder := range folders {
-			if *folder.ParentID != parent.ID {
-				return ErrObjectNotExist
-			}
-		}
-
-		// 检查文件
-		for _, file := range files {
-			if file.FolderID != parent.ID {
-				return ErrObjectNotExist
-			}
-		}
+	folders, files, err := fs.archiveTargets(ctx, folderIDs, fileIDs)
+	if err != nil {
+		return err
 	}
 
 	// 尝试获取请求上下文，以便于后续检查用户取消任务
@@ -62,14 +38,6 @@ func (fs *FileSystem) Compress(ctx context.Context, writer io.Writer, folderIDs,
 		reqContext = ginCtx.Request.Context()
 	}
 
-	// 将顶级待处理对象的路径设为根路径
-	for i := 0; i < len(folders); i++ {
-		folders[i].Position = ""
-	}
-	for i := 0; i < len(files); i++ {
-		files[i].Position = ""
-	}
-
 	// 创建压缩文件Writer
 	zipWriter := zip.NewWriter(writer)
 	defer zipWriter.Close()
@@ -100,6 +68,48 @@ func (fs *FileSystem) Compress(ctx context.Context, writer io.Writer, folderIDs,
 	return nil
 }
 
+// archiveTargets 查找待压缩的顶级目录和文件，并将其路径设为根路径
+func (fs *FileSystem) archiveTargets(ctx context.Context, folderIDs, fileIDs []uint) ([]model.Folder, []model.File, error) {
+	// 查找待压缩目录
+	folders, err := model.GetFoldersByIDs(folderIDs, fs.User.ID)
+	if err != nil && len(folderIDs) != 0 {
+		return nil, nil, ErrDBListObjects
+	}
+
+	// 查找待压缩文件
+	files, err := model.GetFilesByIDs(fileIDs, fs.User.ID)
+	if err != nil && len(fileIDs) != 0 {
+		return nil, nil, ErrDBListObjects
+	}
+
+	// 如果上下文限制了父目录，则进行检查
+	if parent, ok := ctx.Value(fsctx.LimitParentCtx).(*model.Folder); ok {
+		// 检查目录
+		for _, folder := range folders {
+			if *folder.ParentID != parent.ID {
+				return nil, nil, ErrObjectNotExist
+			}
+		}
+
+		// 检查文件
+		for _, file := range files {
+			if file.FolderID != parent.ID {
+				return nil, nil, ErrObjectNotExist
+			}
+		}
+	}
+
+	// 将顶级待处理对象的路径设为根路径
+	for i := 0; i < len(folders); i++ {
+		folders[i].Position = ""
+	}
+	for i := 0; i < len(files); i++ {
+		files[i].Position = ""
+	}
+
+	return folders, files, nil
+}
+
 func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *model.Folder, zipWriter *zip.Writer, isArchive bool) {
 	// 如果对象是文件
 	if file != nil {
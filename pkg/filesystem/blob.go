@@ -0,0 +1,340 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+// uploadProofLength 秒传持有证明需要读取的最大字节数
+const uploadProofLength = 1 << 20
+
+/* ================
+	 去重存储
+   ================
+*/
+
+// renamer 支持移动物理文件的存储策略适配器
+type renamer interface {
+	Rename(ctx context.Context, src, dst string) error
+}
+
+// HookCommitBlob 上传完成后将文件提交至去重存储，已存在内容相同的 Blob 时
+// 引用已有的 Blob 并删除刚上传的物理文件。失败时文件保持独立存储
+func HookCommitBlob(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	if fs.Policy == nil || !fs.Policy.IsDedupEnabled() {
+		return nil
+	}
+
+	file, ok := fileHeader.Info().Model.(*model.File)
+	if !ok || file == nil || file.UploadSessionID != nil {
+		return nil
+	}
+
+	if err := fs.commitBlob(ctx, file); err != nil {
+		util.Log().Warning("Failed to commit file %q to dedup storage: %s", file.SourceName, err)
+	}
+
+	return nil
+}
+
+// HookReleaseBlob 文件内容更新至新的物理路径后，释放原路径对应的 Blob 引用
+func HookReleaseBlob(source string) Hook {
+	return func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+		remain, ok, err := model.ReleaseBlobBySource(fs.Policy.ID, source)
+		if err != nil {
+			util.Log().Warning("Failed to release blob %q: %s", source, err)
+			return nil
+		}
+
+		if ok && remain == 0 {
+			if _, err := fs.Handler.Delete(ctx, []string{source}); err != nil {
+				util.Log().Warning("Failed to delete blob %q: %s", source, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// HookLinkBlob 秒传完成后将文件指向已引用的 Blob
+func HookLinkBlob(blob *model.Blob) Hook {
+	return func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+		file := fileHeader.Info().Model.(*model.File)
+		if err := file.UpdateSourceName(blob.SourceName); err != nil {
+			return err
+		}
+
+		file.SourceName = blob.SourceName
+		return nil
+	}
+}
+
+// InstantUpload 校验持有证明后，将上传会话的占位文件指向内容相同的 Blob，
+// 无需传输文件内容即可完成上传。每个挑战只能尝试一次
+func (fs *FileSystem) InstantUpload(ctx context.Context, session *serializer.UploadSession, file *model.File, proof string) error {
+	raw, ok := cache.Get(UploadChallengeCachePrefix + session.Key)
+	if !ok {
+		return ErrUploadProofMismatch
+	}
+
+	_ = cache.Deletes([]string{session.Key}, UploadChallengeCachePrefix)
+	challenge := raw.(serializer.UploadChallenge)
+
+	blob, err := model.GetBlobByHash(fs.Policy.ID, challenge.Hash, session.Size)
+	if err != nil {
+		return ErrUploadProofMismatch
+	}
+
+	expected, err := fs.blobProof(ctx, blob, &challenge)
+	if err != nil {
+		return ErrIO.WithError(err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(proof))) != 1 || !blob.Retain() {
+		return ErrUploadProofMismatch
+	}
+
+	fileData := fsctx.FileStream{
+		Size:         session.Size,
+		Name:         session.Name,
+		VirtualPath:  session.VirtualPath,
+		SavePath:     session.SavePath,
+		Mode:         fsctx.Nop,
+		Model:        file,
+		LastModified: session.LastModified,
+	}
+
+	// 占位符未扣除容量需要校验和扣除
+	if !fs.Policy.IsUploadPlaceholderWithSize() {
+		fs.Use("AfterUpload", HookValidateCapacity)
+		fs.Use("AfterUpload", HookChunkUploaded)
+	}
+
+	fs.Use("AfterUpload", HookPopPlaceholderToFile(""))
+	fs.Use("AfterUpload", HookLinkBlob(blob))
+	fs.Use("AfterUpload", HookDeleteUploadSession(session.Key))
+	fs.Use("AfterValidateFailed", HookReleaseBlob(blob.SourceName))
+	if err := fs.Upload(ctx, &fileData); err != nil {
+		return err
+	}
+
+	// 取消存储端的上传会话
+	if err := fs.Handler.CancelToken(ctx, session); err != nil {
+		util.Log().Warning("Failed to cancel upload session for %q: %s", session.Name, err)
+	}
+
+	return nil
+}
+
+// newUploadChallenge 为声明的内容哈希生成随机范围的持有证明挑战
+func newUploadChallenge(hash string, size uint64) (*serializer.UploadChallenge, error) {
+	length := uint64(uploadProofLength)
+	if size < length {
+		length = size
+	}
+
+	offset := uint64(0)
+	if size > length {
+		n, err := rand.Int(rand.Reader, new(big.Int).SetUint64(size-length+1))
+		if err != nil {
+			return nil, err
+		}
+
+		offset = n.Uint64()
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return &serializer.UploadChallenge{
+		Nonce:  hex.EncodeToString(nonce),
+		Offset: offset,
+		Length: length,
+		Hash:   strings.ToLower(hash),
+	}, nil
+}
+
+// blobProof 读取 Blob 中挑战指定的范围，计算期望的持有证明
+func (fs *FileSystem) blobProof(ctx context.Context, blob *model.Blob, challenge *serializer.UploadChallenge) (string, error) {
+	rs, err := fs.Handler.Get(context.WithValue(ctx, fsctx.FileModelCtx, nil), blob.SourceName)
+	if err != nil {
+		return "", err
+	}
+	defer rs.Close()
+
+	if _, err := rs.Seek(int64(challenge.Offset), io.SeekStart); err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(challenge.Nonce))
+	if _, err := io.CopyN(hash, rs, int64(challenge.Length)); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// commitBlob 将文件提交至去重存储
+func (fs *FileSystem) commitBlob(ctx context.Context, file *model.File) error {
+	hash := ""
+	if holder, ok := ctx.Value(fsctx.ContentHashCtx).(*string); ok {
+		hash = *holder
+	}
+
+	if hash == "" {
+		var err error
+		if hash, err = fs.hashSource(ctx, file); err != nil {
+			return err
+		}
+	}
+
+	uploaded := file.SourceName
+	if blob, err := model.GetBlobByHash(fs.Policy.ID, hash, file.Size); err == nil && blob.Retain() {
+		return fs.linkBlob(ctx, file, blob, uploaded)
+	}
+
+	blob := &model.Blob{
+		PolicyID:   fs.Policy.ID,
+		Hash:       hash,
+		Size:       file.Size,
+		SourceName: uploaded,
+	}
+
+	if handler, ok := fs.Handler.(renamer); ok {
+		dst := fs.Policy.BlobPath(hash)
+		if err := handler.Rename(ctx, uploaded, dst); err != nil {
+			return err
+		}
+
+		blob.SourceName = dst
+	}
+
+	if err := blob.Create(); err != nil {
+		// 相同内容的文件同时上传，改为引用先创建的 Blob
+		existed, findErr := model.GetBlobByHash(fs.Policy.ID, hash, file.Size)
+		if findErr != nil || !existed.Retain() {
+			return err
+		}
+
+		return fs.linkBlob(ctx, file, existed, blob.SourceName)
+	}
+
+	if blob.SourceName == uploaded {
+		return nil
+	}
+
+	if err := file.UpdateSourceName(blob.SourceName); err != nil {
+		return err
+	}
+
+	file.SourceName = blob.SourceName
+	return nil
+}
+
+// linkBlob 将文件指向已引用的 Blob，并删除 uploaded 处多余的物理文件
+func (fs *FileSystem) linkBlob(ctx context.Context, file *model.File, blob *model.Blob, uploaded string) error {
+	if err := file.UpdateSourceName(blob.SourceName); err != nil {
+		_, _, _ = model.ReleaseBlobBySource(blob.PolicyID, blob.SourceName)
+		return err
+	}
+
+	file.SourceName = blob.SourceName
+	if uploaded != blob.SourceName {
+		if _, err := fs.Handler.Delete(ctx, []string{uploaded}); err != nil {
+			util.Log().Warning("Failed to delete duplicated file %q: %s", uploaded, err)
+		}
+	}
+
+	return nil
+}
+
+// hashSource 存储适配器未提供内容哈希时，读取物理文件计算
+func (fs *FileSystem) hashSource(ctx context.Context, file *model.File) (string, error) {
+	rs, err := fs.Handler.Get(context.WithValue(ctx, fsctx.FileModelCtx, *file), file.SourceName)
+	if err != nil {
+		return "", err
+	}
+	defer rs.Close()
+
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, rs); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// blobFiles 返回 files 中属于去重存储 Blob 的文件
+func blobFiles(files []model.File) []model.File {
+	sources := make(map[uint][]string)
+	for _, file := range files {
+		sources[file.PolicyID] = append(sources[file.PolicyID], file.SourceName)
+	}
+
+	blobs := make(map[uint]map[string]bool, len(sources))
+	for policyID, names := range sources {
+		blobs[policyID] = model.GetBlobSources(policyID, names)
+	}
+
+	res := make([]model.File, 0)
+	for _, file := range files {
+		if blobs[file.PolicyID][file.SourceName] {
+			res = append(res, file)
+		}
+	}
+
+	return res
+}
+
+// releaseBlobs 释放待删除文件对应的 Blob 引用，
+// 返回仍需删除物理文件的部分，仍被其他文件引用的 Blob 不会被删除
+func releaseBlobs(policyID uint, files []*model.File) []*model.File {
+	sources := make([]string, len(files))
+	for i, file := range files {
+		sources[i] = file.SourceName
+	}
+
+	blobs := model.GetBlobSources(policyID, sources)
+	if len(blobs) == 0 {
+		return files
+	}
+
+	res := make([]*model.File, 0, len(files))
+	for _, file := range files {
+		if !blobs[file.SourceName] {
+			res = append(res, file)
+			continue
+		}
+
+		remain, ok, err := model.ReleaseBlobBySource(policyID, file.SourceName)
+		if err != nil {
+			util.Log().Warning("Failed to release blob %q: %s", file.SourceName, err)
+			continue
+		}
+
+		if !ok || remain == 0 {
+			res = append(res, file)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,211 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"path"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"gitee.com/jiangjiali/cloudreve/pkg/webhook"
+)
+
+/* ================
+	 变更日志
+   ================
+*/
+
+// changeCursor 变更流的游标
+type changeCursor struct {
+	// ID 已返回的最后一个变更
+	ID uint `json:"i"`
+	// Time 游标生成的时间，早于变更日志保留期限的游标已失效
+	Time int64 `json:"t"`
+}
+
+// ListChanges 列出游标之后 root 目录下的变更，返回新的游标及是否还有更多变更。
+// 游标为空时只返回当前最新的游标
+func (fs *FileSystem) ListChanges(ctx context.Context, root, cursor string, limit int) ([]model.Change, string, bool, error) {
+	now := time.Now()
+	if cursor == "" {
+		latest, err := model.GetLatestChangeID(fs.User.ID)
+		if err != nil {
+			return nil, "", false, ErrDBListObjects.WithError(err)
+		}
+
+		return []model.Change{}, encodeChangeCursor(latest, now), false, nil
+	}
+
+	decoded, err := decodeChangeCursor(cursor)
+	if err != nil {
+		return nil, "", false, ErrInvalidChangeCursor.WithError(err)
+	}
+
+	// 游标之后的变更可能已被压缩，客户端需要重新同步
+	retention := model.GetIntSetting("change_journal_retention", 30)
+	if time.Unix(decoded.Time, 0).Before(now.AddDate(0, 0, -retention)) {
+		return nil, "", false, ErrChangeCursorExpired
+	}
+
+	changes, err := model.GetChanges(fs.User.ID, decoded.ID, path.Clean("/"+root), limit+1)
+	if err != nil {
+		return nil, "", false, ErrDBListObjects.WithError(err)
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+
+	last := decoded.ID
+	if len(changes) > 0 {
+		last = changes[len(changes)-1].ID
+	}
+
+	return changes, encodeChangeCursor(last, now), hasMore, nil
+}
+
+// recordChanges 写入变更日志并发送对应的 Webhook 事件，写入失败不影响文件操作
+func (fs *FileSystem) recordChanges(changes ...model.Change) {
+	for i := range changes {
+		changes[i].UserID = fs.User.ID
+	}
+
+	if err := model.RecordChanges(changes); err != nil {
+		util.Log().Warning("Failed to record changes: %s", err)
+	}
+
+	for i := range changes {
+		if event, ok := changeEvents[changes[i].Type]; ok {
+			webhook.Trigger(fs.User.ID, event, webhook.NewObject(&changes[i]))
+		}
+	}
+}
+
+// changeEvents 变更类型对应的 Webhook 事件，文件上传的事件单独发送
+var changeEvents = map[string]string{
+	model.ChangeUpdate: webhook.FileUpdated,
+	model.ChangeDelete: webhook.FileDeleted,
+	model.ChangeMove:   webhook.FileMoved,
+}
+
+// recordUpload 记录上传完成的文件
+func (fs *FileSystem) recordUpload(file *model.File) {
+	change := fs.fileChange(make(map[uint]string), model.ChangeCreate, file)
+	fs.recordChanges(change)
+	webhook.Trigger(fs.User.ID, webhook.FileUploaded, webhook.NewObject(&change))
+}
+
+// fileChange 构建文件的变更记录，paths 用于缓存已解析的目录路径
+func (fs *FileSystem) fileChange(paths map[uint]string, changeType string, file *model.File) model.Change {
+	return model.Change{
+		Type:     changeType,
+		ObjectID: file.ID,
+		Name:     file.Name,
+		Path:     fs.folderPath(paths, file.FolderID),
+		Size:     file.Size,
+	}
+}
+
+// folderChange 构建目录的变更记录，paths 用于缓存已解析的目录路径
+func (fs *FileSystem) folderChange(paths map[uint]string, changeType string, folder *model.Folder) model.Change {
+	change := model.Change{
+		Type:     changeType,
+		IsFolder: true,
+		ObjectID: folder.ID,
+		Name:     folder.Name,
+	}
+
+	if folder.ParentID != nil {
+		change.Path = fs.folderPath(paths, *folder.ParentID)
+	}
+
+	return change
+}
+
+// deleteChanges 列出删除目标中的顶层对象的变更记录，需在删除前调用
+func (fs *FileSystem) deleteChanges() []model.Change {
+	paths := make(map[uint]string)
+	folders := make(map[uint]bool, len(fs.DirTarget))
+	for _, folder := range fs.DirTarget {
+		folders[folder.ID] = true
+	}
+
+	changes := make([]model.Change, 0)
+	for i := range fs.DirTarget {
+		if parent := fs.DirTarget[i].ParentID; parent != nil && !folders[*parent] {
+			changes = append(changes, fs.folderChange(paths, model.ChangeDelete, &fs.DirTarget[i]))
+		}
+	}
+
+	for i := range fs.FileTarget {
+		if !folders[fs.FileTarget[i].FolderID] && fs.FileTarget[i].UploadSessionID == nil {
+			changes = append(changes, fs.fileChange(paths, model.ChangeDelete, &fs.FileTarget[i]))
+		}
+	}
+
+	return changes
+}
+
+// recordDeleted 记录 changes 中已删除对象的变更
+func (fs *FileSystem) recordDeleted(changes []model.Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	var dirs, files []uint
+	for _, change := range changes {
+		if change.IsFolder {
+			dirs = append(dirs, change.ObjectID)
+		} else {
+			files = append(files, change.ObjectID)
+		}
+	}
+
+	// 部分对象可能删除失败
+	remained := make(map[bool]map[uint]bool)
+	remained[true] = make(map[uint]bool)
+	remained[false] = make(map[uint]bool)
+	if len(dirs) > 0 {
+		folders, _ := model.GetFoldersByIDs(dirs, fs.User.ID)
+		for _, folder := range folders {
+			remained[true][folder.ID] = true
+		}
+	}
+	if len(files) > 0 {
+		fileModels, _ := model.GetFilesByIDs(files, fs.User.ID)
+		for _, file := range fileModels {
+			remained[false][file.ID] = true
+		}
+	}
+
+	deleted := make([]model.Change, 0, len(changes))
+	for _, change := range changes {
+		if !remained[change.IsFolder][change.ObjectID] {
+			deleted = append(deleted, change)
+		}
+	}
+
+	fs.recordChanges(deleted...)
+}
+
+func encodeChangeCursor(id uint, t time.Time) string {
+	res, _ := json.Marshal(changeCursor{ID: id, Time: t.Unix()})
+	return base64.RawURLEncoding.EncodeToString(res)
+}
+
+func decodeChangeCursor(cursor string) (*changeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var res changeCursor
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,176 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/extractor"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+/* ================
+	 文件内容索引
+   ================
+*/
+
+var (
+	// contentIndexPending 待建立内容索引的文件ID，重复加入的文件只索引一次
+	contentIndexPending = make(map[uint]struct{})
+	contentIndexMu      sync.Mutex
+	// contentIndexSignal 通知后台任务有新的待索引文件
+	contentIndexSignal = make(chan struct{}, 1)
+	contentIndexOnce   sync.Once
+)
+
+// ScheduleContentIndex 将文件加入内容索引队列，由后台任务提取文本并建立索引，
+// 未开启内容索引时忽略
+func ScheduleContentIndex(file *model.File) {
+	if file == nil || file.ID == 0 || !model.IsTrueVal(model.GetSettingByName("content_index_enabled")) {
+		return
+	}
+
+	contentIndexOnce.Do(func() {
+		go contentIndexWorker()
+	})
+
+	contentIndexMu.Lock()
+	contentIndexPending[file.ID] = struct{}{}
+	contentIndexMu.Unlock()
+
+	select {
+	case contentIndexSignal <- struct{}{}:
+	default:
+	}
+}
+
+// contentIndexWorker 依次处理内容索引队列
+func contentIndexWorker() {
+	for range contentIndexSignal {
+		for {
+			id, ok := nextContentIndex()
+			if !ok {
+				break
+			}
+
+			if err := safeIndexFileContent(id); err != nil {
+				util.Log().Debug("Failed to index content of file %d: %s", id, err)
+			}
+		}
+	}
+}
+
+// nextContentIndex 取出一个待索引的文件ID
+func nextContentIndex() (uint, bool) {
+	contentIndexMu.Lock()
+	defer contentIndexMu.Unlock()
+
+	for id := range contentIndexPending {
+		delete(contentIndexPending, id)
+		return id, true
+	}
+
+	return 0, false
+}
+
+// safeIndexFileContent 建立文件内容索引，提取文本时的 panic 不影响后续任务
+func safeIndexFileContent(id uint) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			util.Log().Warning("Panic while indexing content of file %d: %v", id, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return indexFileContent(id)
+}
+
+// indexFileContent 提取文件文本并更新内容索引，不支持的文件会移除已有索引
+func indexFileContent(id uint) error {
+	files, err := model.GetFilesByIDs([]uint{id}, 0)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	file := &files[0]
+	if file.UploadSessionID != nil {
+		return nil
+	}
+
+	maxSize := uint64(model.GetIntSetting("content_index_max_size", 10<<20))
+	if !extractor.Supported(file.Name) || file.Size > maxSize {
+		return model.DeleteFileContents([]uint{file.ID})
+	}
+
+	owner, err := model.GetUserByID(file.UserID)
+	if err != nil {
+		return err
+	}
+
+	fs, err := NewFileSystem(&owner)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	rs, err := fs.GetContent(context.Background(), file.ID)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rs, int64(maxSize)))
+	if err != nil {
+		return err
+	}
+
+	text, err := extractor.Extract(file.Name, data)
+	if err != nil {
+		return model.DeleteFileContents([]uint{file.ID})
+	}
+
+	return model.IndexFileContent(file, text)
+}
+
+// SearchContent 搜索文件内容，返回结果及下一页的游标，游标为空表示没有更多结果。
+// 限定了根目录时只在根目录下搜索
+func (fs *FileSystem) SearchContent(ctx context.Context, query string, limit int, cursor string) ([]serializer.Object, string, error) {
+	opts := &model.SearchOptions{UserID: fs.User.ID, Limit: limit}
+	if err := setSearchCursor(opts, cursor); err != nil {
+		return nil, "", err
+	}
+
+	if fs.Root != nil {
+		allFolders, err := model.GetRecursiveChildFolder([]uint{fs.Root.ID}, fs.User.ID, true)
+		if err != nil {
+			return nil, "", ErrDBListObjects.WithError(err)
+		}
+
+		opts.Folders = make([]uint, 0, len(allFolders))
+		for _, folder := range allFolders {
+			opts.Folders = append(opts.Folders, folder.ID)
+		}
+	}
+
+	files, next, err := model.SearchFileContent(opts, query)
+	if err != nil {
+		return nil, "", ErrDBListObjects.WithError(err)
+	}
+
+	fs.SetTargetFile(&files)
+	paths := make(map[uint]string)
+	objects := make([]serializer.Object, 0, len(files))
+	for i := range files {
+		parent := fs.folderPath(paths, files[i].FolderID)
+		objects = append(objects, fs.listObjects(ctx, parent, files[i:i+1], nil, nil)...)
+	}
+
+	if next == nil {
+		return objects, "", nil
+	}
+
+	return objects, encodeSearchCursor(next), nil
+}
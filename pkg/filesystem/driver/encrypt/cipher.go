@@ -0,0 +1,285 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/conf"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// AlgorithmAESGCM AES-256-GCM
+	AlgorithmAESGCM = "aes-256-gcm"
+	// AlgorithmChaCha20 ChaCha20-Poly1305
+	AlgorithmChaCha20 = "chacha20-poly1305"
+
+	// ChunkSize 加密分块的明文大小，中转上传的分片大小须为其整数倍
+	ChunkSize = 64 << 10
+
+	keySize   = 32
+	nonceSize = 12
+	tagSize   = 16
+	// version 文件头版本，版本 2 起最后一个分块的 nonce 带有结束标记，
+	// 从分块边界截断的文件无法通过校验
+	version    = 2
+	magic      = "CREF"
+	headerSize = 12 + nonceSize + keySize + tagSize
+)
+
+var (
+	// ErrMasterKeyInvalid 主密钥未设置或格式错误
+	ErrMasterKeyInvalid = errors.New("encryption master key is not set or invalid, it must be 32 bytes encoded in base64")
+	// ErrNotEncrypted 文件不是由加密适配器写入的
+	ErrNotEncrypted = errors.New("file is not encrypted")
+)
+
+var algorithms = map[string]byte{
+	AlgorithmAESGCM:   1,
+	AlgorithmChaCha20: 2,
+}
+
+// header 加密文件头，结构为：
+//
+//	magic(4) | version(1) | algorithm(1) | reserved(2) | chunk size(4) | key nonce(12) | wrapped key(48)
+//
+// 前 12 字节同时作为数据密钥包装及每个分块加密的附加数据
+type header struct {
+	raw       []byte
+	version   byte
+	chunkSize int64
+	aead      cipher.AEAD
+}
+
+// masterKey 解析配置文件中的主密钥
+func masterKey() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(conf.EncryptionConfig.MasterKey)
+	if err != nil || len(key) != keySize {
+		return nil, ErrMasterKeyInvalid
+	}
+
+	return key, nil
+}
+
+// newAEAD 根据算法编号创建 AEAD
+func newAEAD(algorithm byte, key []byte) (cipher.AEAD, error) {
+	switch algorithm {
+	case 1:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		return cipher.NewGCM(block)
+	case 2:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, fmt.Errorf("unknown encryption algorithm %d", algorithm)
+	}
+}
+
+// wrapper 使用主密钥包装数据密钥的 AEAD
+func wrapper() (cipher.AEAD, error) {
+	key, err := masterKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return newAEAD(algorithms[AlgorithmAESGCM], key)
+}
+
+// newHeader 生成新的随机数据密钥，并以主密钥包装后写入文件头
+func newHeader(algorithm string) (*header, error) {
+	id, ok := algorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption algorithm %q", algorithm)
+	}
+
+	kek, err := wrapper()
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 12, headerSize)
+	copy(raw, magic)
+	raw[4] = version
+	raw[5] = id
+	binary.BigEndian.PutUint32(raw[8:12], ChunkSize)
+
+	keyNonce := make([]byte, nonceSize)
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, keyNonce); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	raw = append(raw, keyNonce...)
+	raw = kek.Seal(raw, keyNonce, dataKey, raw[:12])
+
+	aead, err := newAEAD(id, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &header{raw: raw, version: version, chunkSize: ChunkSize, aead: aead}, nil
+}
+
+// parseHeader 解析文件头并解包数据密钥
+func parseHeader(raw []byte) (*header, error) {
+	if len(raw) < headerSize || !bytes.Equal(raw[:4], []byte(magic)) || raw[4] < 1 || raw[4] > version {
+		return nil, ErrNotEncrypted
+	}
+
+	kek, err := wrapper()
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := kek.Open(nil, raw[12:12+nonceSize], raw[12+nonceSize:headerSize], raw[:12])
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	aead, err := newAEAD(raw[5], dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkSize := int64(binary.BigEndian.Uint32(raw[8:12]))
+	if chunkSize <= 0 {
+		return nil, errors.New("invalid encryption chunk size")
+	}
+
+	return &header{raw: raw[:headerSize], version: raw[4], chunkSize: chunkSize, aead: aead}, nil
+}
+
+// readHeader 从数据流开头读取文件头
+func readHeader(r io.Reader) (*header, error) {
+	raw := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotEncrypted
+		}
+
+		return nil, err
+	}
+
+	return parseHeader(raw)
+}
+
+// nonce 第 index 个分块使用的 nonce，last 为是否是文件的最后一个分块
+func (h *header) nonce(index int64, last bool) []byte {
+	nonce := make([]byte, nonceSize)
+	if last && h.version >= 2 {
+		nonce[0] = 1
+	}
+	binary.BigEndian.PutUint64(nonce[4:], uint64(index))
+	return nonce
+}
+
+// seal 加密第 index 个分块
+func (h *header) seal(dst, plain []byte, index int64, last bool) []byte {
+	return h.aead.Seal(dst, h.nonce(index, last), plain, h.raw[:12])
+}
+
+// open 解密并校验第 index 个分块
+func (h *header) open(dst, sealed []byte, index int64, last bool) ([]byte, error) {
+	plain, err := h.aead.Open(dst, h.nonce(index, last), sealed, h.raw[:12])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt chunk #%d: %w", index, err)
+	}
+
+	return plain, nil
+}
+
+// chunks 返回 size 字节明文加密后的分块数，last 为是否包含文件结尾；
+// 版本 2 起空文件也会写入一个空的结束分块
+func (h *header) chunks(size int64, last bool) int64 {
+	chunks := size / h.chunkSize
+	if size%h.chunkSize != 0 {
+		chunks++
+	}
+
+	if chunks == 0 && last && h.version >= 2 {
+		chunks = 1
+	}
+
+	return chunks
+}
+
+// sealedChunkSize 加密后每个完整分块的大小
+func (h *header) sealedChunkSize() int64 {
+	return h.chunkSize + tagSize
+}
+
+// sealedSize 返回 size 字节明文加密后的大小，不含文件头
+func (h *header) sealedSize(size int64, last bool) int64 {
+	return size + h.chunks(size, last)*tagSize
+}
+
+// EncryptedSize 返回 size 字节的文件加密后在存储端的实际大小
+func EncryptedSize(size uint64) uint64 {
+	h := &header{version: version, chunkSize: ChunkSize}
+	return uint64(headerSize + h.sealedSize(int64(size), true))
+}
+
+// plainSize 根据存储端的实际大小计算明文大小
+func (h *header) plainSize(size int64) (int64, error) {
+	body := size - headerSize
+	if body < 0 || (body == 0 && h.version >= 2) {
+		return 0, errors.New("encrypted file is truncated")
+	}
+
+	full, rest := body/h.sealedChunkSize(), body%h.sealedChunkSize()
+	// 版本 2 的空文件只有一个空的结束分块
+	if rest > 0 && rest <= tagSize && !(h.version >= 2 && body == tagSize) {
+		return 0, errors.New("encrypted file is truncated")
+	}
+
+	plain := full * h.chunkSize
+	if rest > 0 {
+		plain += rest - tagSize
+	}
+
+	return plain, nil
+}
+
+// lastChunk 返回明文大小为 size 的文件最后一个分块的序号
+func (h *header) lastChunk(size int64) int64 {
+	if size == 0 {
+		return 0
+	}
+
+	return (size - 1) / h.chunkSize
+}
+
+// offset 返回明文中第 index 个分块在存储端的起始位置
+func (h *header) offset(index int64) int64 {
+	return headerSize + index*h.sealedChunkSize()
+}
+
+// CheckOptions 检查存储策略的加密设置是否可用
+func CheckOptions(algorithm string, chunkSize uint64) error {
+	if _, ok := algorithms[algorithm]; !ok {
+		return fmt.Errorf("unknown encryption algorithm %q", algorithm)
+	}
+
+	if _, err := masterKey(); err != nil {
+		return err
+	}
+
+	if chunkSize%ChunkSize != 0 {
+		return fmt.Errorf("chunk size must be a multiple of %d bytes on encrypted policy", ChunkSize)
+	}
+
+	return nil
+}
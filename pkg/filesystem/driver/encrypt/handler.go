@@ -0,0 +1,433 @@
+package encrypt
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/conf"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/local"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/response"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+// ErrChunkSealed 分片对应的加密分块已写入。同一数据密钥和 nonce 不能加密不同的内容，
+// 已写入的分块不能被重传覆盖，须重新开始上传
+var ErrChunkSealed = errors.New("chunk has already been written and cannot be overwritten on encrypted policy")
+
+// truncater 支持截断物理文件的存储策略适配器
+type truncater interface {
+	Truncate(ctx context.Context, src string, size uint64) error
+}
+
+// Driver 静态加密适配器，包装其他存储策略适配器。文件使用随机的数据密钥
+// 分块加密后写入存储端，数据密钥由配置文件中的主密钥包装后保存在文件头中
+type Driver struct {
+	Policy  *model.Policy
+	Handler driver.Handler
+}
+
+// NewDriver 根据存储策略的加密设置包装 handler
+func NewDriver(handler driver.Handler, policy *model.Policy) (*Driver, error) {
+	if _, ok := algorithms[policy.OptionsSerialized.Encryption]; !ok {
+		return nil, fmt.Errorf("unknown encryption algorithm %q", policy.OptionsSerialized.Encryption)
+	}
+
+	if _, err := masterKey(); err != nil {
+		return nil, err
+	}
+
+	return &Driver{
+		Policy:  policy,
+		Handler: handler,
+	}, nil
+}
+
+// appendable 返回被包装的适配器能否追加写入分片
+func (handler *Driver) appendable() bool {
+	switch handler.Policy.Type {
+	case "local", "sftp", "webdav":
+		return true
+	}
+
+	return false
+}
+
+// stagingPath 不支持追加写入时，已上传分片在本机暂存的路径
+func (handler *Driver) stagingPath(savePath string) string {
+	return filepath.Join(
+		util.RelativePath(model.GetSettingByName("temp_path")),
+		"encrypt",
+		fmt.Sprintf("%d_%x", handler.Policy.ID, sha256.Sum256([]byte(savePath))),
+	)
+}
+
+// innerContext 返回调用被包装适配器时使用的上下文，
+// 其中的文件模型大小替换为加密后的大小
+func innerContext(ctx context.Context, path string) context.Context {
+	file, ok := ctx.Value(fsctx.FileModelCtx).(model.File)
+	if !ok {
+		return ctx
+	}
+
+	if file.SourceName != path {
+		return context.WithValue(ctx, fsctx.FileModelCtx, nil)
+	}
+
+	file.Size = EncryptedSize(file.Size)
+	return context.WithValue(ctx, fsctx.FileModelCtx, file)
+}
+
+// List 列取被包装适配器中的文件
+func (handler *Driver) List(ctx context.Context, base string, recursive bool) ([]response.Object, error) {
+	return handler.Handler.List(ctx, base, recursive)
+}
+
+// plainAllowed 返回 path 是否是开启加密前创建的文件，只有这类文件允许未加密
+func (handler *Driver) plainAllowed(ctx context.Context, path string) bool {
+	file, ok := ctx.Value(fsctx.FileModelCtx).(model.File)
+	if !ok || (file.SourceName != path && file.ThumbFile() != path) {
+		return false
+	}
+
+	since := handler.Policy.OptionsSerialized.EncryptedSince
+	return since > 0 && file.CreatedAt.Unix() < since
+}
+
+// Get 获取解密后的文件内容，返回的文件流支持 Seek 到任意位置。
+// 开启加密前写入的未加密文件原样返回
+func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser, error) {
+	innerCtx := innerContext(ctx, path)
+	open := func() (response.RSCloser, error) {
+		inner, err := handler.Handler.Get(innerCtx, path)
+		if err != nil {
+			return nil, err
+		}
+
+		// 部分数据流在首次 Seek 前会忽略第一次读取
+		if _, err := inner.Seek(0, io.SeekStart); err != nil {
+			inner.Close()
+			return nil, err
+		}
+
+		return inner, nil
+	}
+
+	inner, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := inner.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = inner.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		inner.Close()
+		return nil, err
+	}
+
+	h, err := readHeader(inner)
+	if err != nil {
+		inner.Close()
+		// 开启加密前写入的文件
+		if errors.Is(err, ErrNotEncrypted) && handler.plainAllowed(ctx, path) {
+			return handler.Handler.Get(ctx, path)
+		}
+
+		return nil, err
+	}
+
+	plainSize, err := h.plainSize(size)
+	if err != nil {
+		inner.Close()
+		return nil, err
+	}
+
+	stream := &openStream{
+		inner:       inner,
+		header:      h,
+		reopen:      open,
+		size:        plainSize,
+		innerOffset: headerSize,
+		index:       -1,
+		sealed:      make([]byte, h.sealedChunkSize()),
+	}
+
+	// 空文件不会读取任何分块，需提前校验结束分块
+	if plainSize == 0 && h.version >= 2 {
+		if err := stream.load(0); err != nil {
+			inner.Close()
+			return nil, err
+		}
+	}
+
+	return stream, nil
+}
+
+// Put 加密文件流后交由被包装的适配器保存。追加写入的分片须从加密分块边界开始
+func (handler *Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
+	fileInfo := file.Info()
+	if fileInfo.Mode&fsctx.Append != fsctx.Append {
+		h, err := newHeader(handler.Policy.OptionsSerialized.Encryption)
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		return handler.Handler.Put(ctx, newSealStream(file, h, true, 0, true))
+	}
+
+	if fileInfo.AppendStart%ChunkSize != 0 {
+		file.Close()
+		return fmt.Errorf("chunk must start at a multiple of %d bytes on encrypted policy", ChunkSize)
+	}
+
+	if !handler.appendable() {
+		return handler.stage(ctx, file)
+	}
+
+	total, ok := ctx.Value(fsctx.FileSizeCtx).(uint64)
+	if !ok {
+		file.Close()
+		return errors.New("total file size is unknown")
+	}
+	last := fileInfo.AppendStart+fileInfo.Size >= total
+
+	// 首个分片生成新的数据密钥，后续分片沿用文件头中的数据密钥
+	if fileInfo.AppendStart == 0 {
+		h, err := newHeader(handler.Policy.OptionsSerialized.Encryption)
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		return handler.Handler.Put(ctx, newSealStream(file, h, true, 0, last))
+	}
+
+	h, size, err := handler.readHeader(ctx, fileInfo.SavePath)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to read encryption header: %w", err)
+	}
+
+	index := int64(fileInfo.AppendStart) / h.chunkSize
+	if size > h.offset(index) {
+		file.Close()
+		return ErrChunkSealed
+	}
+
+	stream := newSealStream(file, h, false, index, last)
+	stream.info.AppendStart = uint64(h.offset(index))
+	return handler.Handler.Put(ctx, stream)
+}
+
+// readHeader 读取存储端文件的文件头，同时返回文件的当前大小
+func (handler *Driver) readHeader(ctx context.Context, path string) (*header, int64, error) {
+	inner, err := handler.Handler.Get(context.WithValue(ctx, fsctx.FileModelCtx, nil), path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer inner.Close()
+
+	size, err := inner.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err := inner.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	h, err := readHeader(inner)
+	return h, size, err
+}
+
+// stage 被包装的适配器不支持追加写入时，分片加密后在本机暂存，
+// 最后一个分片写入后再整体上传至存储端
+func (handler *Driver) stage(ctx context.Context, file fsctx.FileHeader) error {
+	defer file.Close()
+	fileInfo := file.Info()
+	total, ok := ctx.Value(fsctx.FileSizeCtx).(uint64)
+	if !ok {
+		return errors.New("total file size is unknown")
+	}
+
+	staging := handler.stagingPath(fileInfo.SavePath)
+	if err := os.MkdirAll(filepath.Dir(staging), 0700); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(staging, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var (
+		h          *header
+		index      int64
+		withHeader = fileInfo.AppendStart == 0
+		last       = fileInfo.AppendStart+fileInfo.Size >= total
+	)
+
+	if withHeader {
+		if h, err = newHeader(handler.Policy.OptionsSerialized.Encryption); err != nil {
+			return err
+		}
+
+		if err := out.Truncate(0); err != nil {
+			return err
+		}
+	} else {
+		if h, err = readHeader(out); err != nil {
+			return err
+		}
+
+		index = int64(fileInfo.AppendStart) / h.chunkSize
+		stat, err := out.Stat()
+		if err != nil {
+			return err
+		}
+
+		if stat.Size() < h.offset(index) {
+			return errors.New("size of unfinished uploaded chunks is not as expected")
+		} else if stat.Size() > h.offset(index) {
+			return ErrChunkSealed
+		}
+
+		if _, err := out.Seek(h.offset(index), io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(out, newSealStream(file, h, withHeader, index, last)); err != nil {
+		return err
+	}
+
+	if !last {
+		return nil
+	}
+
+	// 所有分片均已暂存，上传至存储端
+	size, err := out.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	err = handler.Handler.Put(ctx, &fsctx.FileStream{
+		Mode:         fsctx.Overwrite,
+		File:         io.NopCloser(out),
+		Seeker:       out,
+		Size:         uint64(size),
+		Name:         fileInfo.FileName,
+		VirtualPath:  fileInfo.VirtualPath,
+		MimeType:     fileInfo.MimeType,
+		SavePath:     fileInfo.SavePath,
+		LastModified: fileInfo.LastModified,
+	})
+	if err != nil {
+		return err
+	}
+
+	out.Close()
+	return os.Remove(staging)
+}
+
+// Truncate 将文件截断至明文大小 size 对应的位置，size 须为加密分块边界
+func (handler *Driver) Truncate(ctx context.Context, src string, size uint64) error {
+	if size%ChunkSize != 0 {
+		return fmt.Errorf("cannot truncate encrypted file to %d bytes", size)
+	}
+
+	physical := uint64(0)
+	if size > 0 {
+		physical = EncryptedSize(size)
+	}
+
+	if !handler.appendable() {
+		staging := handler.stagingPath(src)
+		if util.Exists(staging) {
+			return os.Truncate(staging, int64(physical))
+		}
+
+		return nil
+	}
+
+	if inner, ok := handler.Handler.(truncater); ok {
+		return inner.Truncate(ctx, src, physical)
+	}
+
+	return nil
+}
+
+// Delete 删除一个或多个文件，同时清理本机暂存的分片
+func (handler *Driver) Delete(ctx context.Context, files []string) ([]string, error) {
+	if !handler.appendable() {
+		for _, value := range files {
+			_ = os.Remove(handler.stagingPath(value))
+		}
+	}
+
+	return handler.Handler.Delete(ctx, files)
+}
+
+// Thumb 获取文件缩略图，缩略图由主机生成并加密保存在文件旁
+func (handler *Driver) Thumb(ctx context.Context, file *model.File) (*response.ContentResponse, error) {
+	// 存储端无法读取加密后的文件，只使用主机生成的缩略图
+	if conf.SystemConfig.Mode == "master" && file.MetadataSerialized[model.ThumbStatusMetadataKey] != model.ThumbStatusExist {
+		return nil, driver.ErrorThumbNotExist
+	}
+
+	ctx = context.WithValue(ctx, fsctx.FileModelCtx, *file)
+	thumbFile, err := handler.Get(ctx, file.ThumbFile())
+	if err != nil {
+		return nil, fmt.Errorf("thumb not exist: %w (%w)", err, driver.ErrorThumbNotExist)
+	}
+
+	return &response.ContentResponse{
+		Redirect: false,
+		Content:  thumbFile,
+	}, nil
+}
+
+// Source 获取外链URL，文件须经由主机解密后中转
+func (handler *Driver) Source(ctx context.Context, path string, ttl int64, isDownload bool, speed int) (string, error) {
+	policy := *handler.Policy
+	// 存储端自身的 CDN 地址无法提供解密后的文件
+	if policy.Type == "remote" || policy.Type == "s3" {
+		policy.BaseURL = ""
+	}
+
+	return local.Driver{Policy: &policy}.Source(ctx, path, ttl, isDownload, speed)
+}
+
+// Token 获取上传会话，文件经由主机加密后中转上传
+func (handler *Driver) Token(ctx context.Context, ttl int64, uploadSession *serializer.UploadSession, file fsctx.FileHeader) (*serializer.UploadCredential, error) {
+	chunkSize := handler.Policy.OptionsSerialized.ChunkSize
+	if chunkSize%ChunkSize != 0 {
+		return nil, fmt.Errorf("chunk size must be a multiple of %d bytes on encrypted policy", ChunkSize)
+	}
+
+	return &serializer.UploadCredential{
+		SessionID: uploadSession.Key,
+		ChunkSize: chunkSize,
+	}, nil
+}
+
+// CancelToken 取消上传凭证
+func (handler *Driver) CancelToken(ctx context.Context, uploadSession *serializer.UploadSession) error {
+	return nil
+}
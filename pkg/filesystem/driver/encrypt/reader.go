@@ -0,0 +1,286 @@
+package encrypt
+
+import (
+	"errors"
+	"io"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/response"
+)
+
+// sealStream 将明文上传流按分块加密后的数据流，
+// 原始流支持 Seek 时，可以 Seek 到加密流中的任意位置
+type sealStream struct {
+	src    fsctx.FileHeader
+	info   *fsctx.UploadTaskInfo
+	header *header
+	// withHeader 是否在数据流开头输出文件头
+	withHeader bool
+	// start 第一个分块的序号
+	start int64
+	// end 最后一个分块的序号，last 为其是否是文件的最后一个分块
+	end  int64
+	last bool
+
+	index  int64
+	plain  []byte
+	sealed []byte
+	// pending 当前分块尚未输出的部分
+	pending []byte
+	// offset 当前在加密流中的位置
+	offset int64
+}
+
+// newSealStream 创建加密流，start 为原始流开头对应的分块序号，
+// last 为原始流是否包含文件结尾
+func newSealStream(src fsctx.FileHeader, h *header, withHeader bool, start int64, last bool) *sealStream {
+	info := *src.Info()
+	stream := &sealStream{
+		src:        src,
+		header:     h,
+		withHeader: withHeader,
+		start:      start,
+		end:        start + h.chunks(int64(info.Size), last) - 1,
+		last:       last,
+		index:      start,
+		plain:      make([]byte, h.chunkSize),
+	}
+
+	info.Size = uint64(h.sealedSize(int64(info.Size), last))
+	if withHeader {
+		info.Size += headerSize
+		stream.pending = h.raw
+	}
+
+	stream.info = &info
+	return stream
+}
+
+// Read 实现 io.Reader
+func (s *sealStream) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 {
+		if err := s.next(0); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	s.offset += int64(n)
+	return n, nil
+}
+
+// next 读取并加密下一个分块，并跳过其开头的 skip 字节
+func (s *sealStream) next(skip int64) error {
+	n, err := io.ReadFull(s.src, s.plain)
+	// 空文件仍需输出一个空的结束分块
+	if err == io.EOF && s.index > s.end {
+		return io.EOF
+	}
+
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	s.sealed = s.header.seal(s.sealed[:0], s.plain[:n], s.index, s.last && s.index == s.end)
+	s.index++
+	if skip > int64(len(s.sealed)) {
+		return io.ErrUnexpectedEOF
+	}
+
+	s.pending = s.sealed[skip:]
+	return nil
+}
+
+// Seek 实现 io.Seeker，加密结果是确定的，因此可以重新加密目标位置所在的分块
+func (s *sealStream) Seek(offset int64, whence int) (int64, error) {
+	if !s.src.Seekable() {
+		return 0, errors.New("no seeker")
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	default:
+		return 0, errors.New("unsupported whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	body := offset
+	if s.withHeader {
+		if offset < headerSize {
+			if _, err := s.src.Seek(0, io.SeekStart); err != nil {
+				return 0, err
+			}
+
+			s.index = s.start
+			s.pending = s.header.raw[offset:]
+			s.offset = offset
+			return offset, nil
+		}
+
+		body -= headerSize
+	}
+
+	chunk, skip := body/s.header.sealedChunkSize(), body%s.header.sealedChunkSize()
+	if _, err := s.src.Seek(chunk*s.header.chunkSize, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	s.index = s.start + chunk
+	s.pending = nil
+	if skip > 0 {
+		if err := s.next(skip); err != nil {
+			return 0, err
+		}
+	}
+
+	s.offset = offset
+	return offset, nil
+}
+
+// Close 实现 io.Closer
+func (s *sealStream) Close() error {
+	return s.src.Close()
+}
+
+// Info 返回加密后的上传信息
+func (s *sealStream) Info() *fsctx.UploadTaskInfo {
+	return s.info
+}
+
+// SetSize 实现 fsctx.FileHeader
+func (s *sealStream) SetSize(size uint64) {
+	s.info.Size = size
+}
+
+// SetModel 实现 fsctx.FileHeader
+func (s *sealStream) SetModel(fileModel interface{}) {
+	s.info.Model = fileModel
+	s.src.SetModel(fileModel)
+}
+
+// Seekable 实现 fsctx.FileHeader
+func (s *sealStream) Seekable() bool {
+	return s.src.Seekable()
+}
+
+// openStream 解密存储端数据流，支持 Seek 到任意明文位置
+type openStream struct {
+	inner  response.RSCloser
+	header *header
+	// reopen 存储端数据流不支持向前 Seek 时，用于重新打开
+	reopen func() (response.RSCloser, error)
+
+	size   int64
+	offset int64
+	// innerOffset 存储端数据流的当前位置
+	innerOffset int64
+
+	index  int64
+	sealed []byte
+	plain  []byte
+}
+
+// Read 实现 io.Reader
+func (s *openStream) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+
+	index := s.offset / s.header.chunkSize
+	if index != s.index {
+		if err := s.load(index); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, s.plain[s.offset-index*s.header.chunkSize:])
+	s.offset += int64(n)
+	return n, nil
+}
+
+// load 读取并解密第 index 个分块
+func (s *openStream) load(index int64) error {
+	if err := s.seekInner(s.header.offset(index)); err != nil {
+		return err
+	}
+
+	length := s.size - index*s.header.chunkSize
+	if length > s.header.chunkSize {
+		length = s.header.chunkSize
+	}
+
+	sealed := s.sealed[:length+tagSize]
+	n, err := io.ReadFull(s.inner, sealed)
+	s.innerOffset += int64(n)
+	if err != nil {
+		s.index = -1
+		return err
+	}
+
+	s.plain, err = s.header.open(s.plain[:0], sealed, index, index == s.header.lastChunk(s.size))
+	if err != nil {
+		s.index = -1
+		return err
+	}
+
+	s.index = index
+	return nil
+}
+
+// seekInner 将存储端数据流移动到 offset，不支持 Seek 时跳过数据或重新打开
+func (s *openStream) seekInner(offset int64) error {
+	if offset == s.innerOffset {
+		return nil
+	}
+
+	if _, err := s.inner.Seek(offset, io.SeekStart); err == nil {
+		s.innerOffset = offset
+		return nil
+	}
+
+	if offset < s.innerOffset {
+		inner, err := s.reopen()
+		if err != nil {
+			return err
+		}
+
+		s.inner.Close()
+		s.inner = inner
+		s.innerOffset = 0
+	}
+
+	n, err := io.CopyN(io.Discard, s.inner, offset-s.innerOffset)
+	s.innerOffset += n
+	return err
+}
+
+// Seek 实现 io.Seeker，实际读取在下一次 Read 时进行
+func (s *openStream) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	s.offset = offset
+	return offset, nil
+}
+
+// Close 实现 io.Closer
+func (s *openStream) Close() error {
+	return s.inner.Close()
+}
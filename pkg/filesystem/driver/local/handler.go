@@ -2,6 +2,8 @@ package local
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -154,11 +156,55 @@ func (handler Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
 		}
 	}
 
-	// 写入文件内容
-	_, err = io.Copy(out, file)
+	// 写入文件内容，开启去重存储时同时计算内容哈希
+	holder, _ := ctx.Value(fsctx.ContentHashCtx).(*string)
+	if holder == nil || handler.Policy == nil || !handler.Policy.IsDedupEnabled() {
+		_, err = io.Copy(out, file)
+		return err
+	}
+
+	if fileInfo.Mode&fsctx.Append != fsctx.Append {
+		hash := sha256.New()
+		if _, err = io.Copy(io.MultiWriter(out, hash), file); err != nil {
+			return err
+		}
+
+		*holder = hex.EncodeToString(hash.Sum(nil))
+		return nil
+	}
+
+	if _, err = io.Copy(out, file); err != nil {
+		return err
+	}
+
+	// 最后一个分片写入后计算完整文件的哈希
+	if total, ok := ctx.Value(fsctx.FileSizeCtx).(uint64); ok && fileInfo.AppendStart+fileInfo.Size >= total {
+		*holder, err = util.HashFile(dst)
+	}
+
 	return err
 }
 
+// Rename 移动存储端的物理文件，目标目录不存在时自动创建
+func (handler Driver) Rename(ctx context.Context, src, dst string) error {
+	dst = util.RelativePath(filepath.FromSlash(dst))
+	if err := os.MkdirAll(filepath.Dir(dst), Perm); err != nil {
+		return err
+	}
+
+	return os.Rename(util.RelativePath(filepath.FromSlash(src)), dst)
+}
+
+// Size 返回物理文件的大小
+func (handler Driver) Size(ctx context.Context, src string) (uint64, error) {
+	stat, err := os.Stat(util.RelativePath(filepath.FromSlash(src)))
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Size()), nil
+}
+
 func (handler Driver) Truncate(ctx context.Context, src string, size uint64) error {
 	util.Log().Warning("Truncate file %q to [%d].", src, size)
 	out, err := os.OpenFile(src, os.O_WRONLY, Perm)
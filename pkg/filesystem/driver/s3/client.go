@@ -0,0 +1,405 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/request"
+)
+
+// deleteBatchSize 单次批量删除请求中对象数量上限
+const deleteBatchSize = 1000
+
+// RespError S3 接口返回的错误
+type RespError struct {
+	XMLName    xml.Name `xml:"Error"`
+	Code       string   `xml:"Code"`
+	Message    string   `xml:"Message"`
+	Resource   string   `xml:"Resource"`
+	RequestID  string   `xml:"RequestId"`
+	StatusCode int      `xml:"-"`
+}
+
+// Error 实现 error 接口
+func (e *RespError) Error() string {
+	return fmt.Sprintf("s3 error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// ObjectMeta 对象元信息
+type ObjectMeta struct {
+	Size         uint64
+	ETag         string
+	ContentType  string
+	LastModified time.Time
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+// CompletedPart 已上传的分片
+type CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []CompletedPart `xml:"Part"`
+}
+
+type deleteObjectsRequest struct {
+	XMLName xml.Name       `xml:"Delete"`
+	Quiet   bool           `xml:"Quiet"`
+	Objects []deleteObject `xml:"Object"`
+}
+
+type deleteObject struct {
+	Key string `xml:"Key"`
+}
+
+type deleteObjectsResult struct {
+	Errors []struct {
+		Key     string `xml:"Key"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	} `xml:"Error"`
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         uint64    `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// objectURL 返回对象的请求地址，endpoint 为空时使用策略中的服务端 Endpoint
+func (handler *Driver) objectURL(endpoint, key string, query url.Values) (*url.URL, error) {
+	if endpoint == "" {
+		endpoint = handler.Policy.Server
+		if handler.Policy.OptionsSerialized.ServerSideEndpoint != "" {
+			endpoint = handler.Policy.OptionsSerialized.ServerSideEndpoint
+		}
+	}
+
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse s3 endpoint: %w", err)
+	}
+
+	key = strings.TrimPrefix(key, "/")
+	target := *base
+	if handler.Policy.OptionsSerialized.S3ForcePathStyle {
+		target.Path = strings.TrimSuffix(base.Path, "/") + "/" + handler.Policy.BucketName
+		if key != "" {
+			target.Path += "/" + key
+		}
+	} else {
+		target.Host = handler.Policy.BucketName + "." + base.Host
+		target.Path = strings.TrimSuffix(base.Path, "/") + "/" + key
+	}
+
+	if query != nil {
+		target.RawQuery = CanonicalQuery(query)
+	}
+
+	return &target, nil
+}
+
+// request 签名并发送请求
+func (handler *Driver) request(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	target, err := handler.objectURL("", key, query)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	payloadHash := EmptyPayload
+	if body != nil {
+		payloadHash = UnsignedPayload
+	}
+	handler.signer.Sign(req, payloadHash, time.Now())
+
+	opts := []request.Option{
+		request.WithContext(ctx),
+		request.WithHeader(req.Header),
+		request.WithContentLength(size),
+		request.WithTPSLimit(
+			fmt.Sprintf("policy_%d", handler.Policy.ID),
+			handler.Policy.OptionsSerialized.TPSLimit,
+			handler.Policy.OptionsSerialized.TPSLimitBurst,
+		),
+	}
+	if body != nil {
+		opts = append(opts, request.WithTimeout(time.Duration(0)))
+	}
+
+	resp := handler.Client.Request(method, target.String(), body, opts...)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	if resp.Response.StatusCode >= 300 {
+		return nil, decodeError(resp.Response)
+	}
+
+	return resp.Response, nil
+}
+
+// decodeError 从响应中解析错误信息
+func decodeError(resp *http.Response) error {
+	defer resp.Body.Close()
+	respErr := &RespError{StatusCode: resp.StatusCode}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if len(body) > 0 {
+		_ = xml.Unmarshal(body, respErr)
+	}
+
+	if respErr.Code == "" {
+		respErr.Code = http.StatusText(resp.StatusCode)
+	}
+
+	return respErr
+}
+
+// decodeXML 解析响应正文
+func decodeXML(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// IsNotFound 返回错误是否为对象不存在
+func IsNotFound(err error) bool {
+	var respErr *RespError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// Meta 获取对象元信息
+func (handler *Driver) Meta(ctx context.Context, key string) (*ObjectMeta, error) {
+	resp, err := handler.request(ctx, "HEAD", key, nil, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	var size uint64
+	if resp.ContentLength > 0 {
+		size = uint64(resp.ContentLength)
+	}
+
+	lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &ObjectMeta{
+		Size:         size,
+		ETag:         resp.Header.Get("ETag"),
+		ContentType:  resp.Header.Get("Content-Type"),
+		LastModified: lastModified,
+	}, nil
+}
+
+// putObject 使用单次请求上传对象
+func (handler *Driver) putObject(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	resp, err := handler.request(ctx, "PUT", key, nil, header, body, size)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+	return nil
+}
+
+// createMultipartUpload 创建分片上传，返回 UploadID
+func (handler *Driver) createMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	resp, err := handler.request(ctx, "POST", key, url.Values{"uploads": {""}}, header, nil, 0)
+	if err != nil {
+		return "", err
+	}
+
+	var res initiateMultipartUploadResult
+	if err := decodeXML(resp, &res); err != nil {
+		return "", fmt.Errorf("failed to decode multipart upload response: %w", err)
+	}
+
+	return res.UploadID, nil
+}
+
+// uploadPart 上传一个分片，返回分片的 ETag
+func (handler *Driver) uploadPart(ctx context.Context, key, uploadID string, partNumber int, body io.Reader, size int64) (string, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(partNumber)},
+		"uploadId":   {uploadID},
+	}
+
+	resp, err := handler.request(ctx, "PUT", key, query, nil, body, size)
+	if err != nil {
+		return "", err
+	}
+
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// completeMultipartUpload 完成分片上传
+func (handler *Driver) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error {
+	body, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	resp, err := handler.request(ctx, "POST", key, url.Values{"uploadId": {uploadID}}, nil, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+
+	// 完成分片上传时即使返回 200 也可能包含错误
+	defer resp.Body.Close()
+	res, _ := ioutil.ReadAll(resp.Body)
+	respErr := &RespError{StatusCode: resp.StatusCode}
+	if xml.Unmarshal(res, respErr) == nil && respErr.Code != "" {
+		return respErr
+	}
+
+	return nil
+}
+
+// abortMultipartUpload 取消分片上传
+func (handler *Driver) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	resp, err := handler.request(ctx, "DELETE", key, url.Values{"uploadId": {uploadID}}, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+	return nil
+}
+
+// getObject 获取对象内容，offset 为起始字节
+func (handler *Driver) getObject(ctx context.Context, key string, offset int64) (*http.Response, error) {
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	return handler.request(ctx, "GET", key, nil, header, nil, 0)
+}
+
+// deleteObjects 批量删除对象，返回删除失败的对象
+func (handler *Driver) deleteObjects(ctx context.Context, keys []string) ([]string, error) {
+	failed := make([]string, 0)
+	var lastErr error
+
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		batch := keys[start:end]
+		reqBody := deleteObjectsRequest{Quiet: true, Objects: make([]deleteObject, 0, len(batch))}
+		for _, key := range batch {
+			reqBody.Objects = append(reqBody.Objects, deleteObject{Key: strings.TrimPrefix(key, "/")})
+		}
+
+		body, err := xml.Marshal(reqBody)
+		if err != nil {
+			return keys, err
+		}
+
+		sum := md5.Sum(body)
+		header := http.Header{}
+		header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+		header.Set("Content-Type", "application/xml")
+
+		resp, err := handler.request(ctx, "POST", "", url.Values{"delete": {""}}, header, bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			failed = append(failed, batch...)
+			lastErr = err
+			continue
+		}
+
+		var res deleteObjectsResult
+		if err := decodeXML(resp, &res); err != nil {
+			failed = append(failed, batch...)
+			lastErr = err
+			continue
+		}
+
+		for _, e := range res.Errors {
+			failed = append(failed, e.Key)
+			lastErr = fmt.Errorf("failed to delete %q: %s %s", e.Key, e.Code, e.Message)
+		}
+	}
+
+	return failed, lastErr
+}
+
+// listObjects 列取指定前缀下的对象，delimiter 为空时递归列取
+func (handler *Driver) listObjects(ctx context.Context, prefix, delimiter string) (*listBucketResult, error) {
+	res := &listBucketResult{}
+	query := url.Values{
+		"list-type": {"2"},
+		"prefix":    {prefix},
+		"max-keys":  {"1000"},
+	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+
+	for {
+		resp, err := handler.request(ctx, "GET", "", query, nil, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		var page listBucketResult
+		if err := decodeXML(resp, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode list response: %w", err)
+		}
+
+		res.Contents = append(res.Contents, page.Contents...)
+		res.CommonPrefixes = append(res.CommonPrefixes, page.CommonPrefixes...)
+
+		// 如果本次未列取完，则继续使用 ContinuationToken 获取结果
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,303 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/chunk"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/chunk/backoff"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/response"
+	"gitee.com/jiangjiali/cloudreve/pkg/request"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+const (
+	chunkRetrySleep = time.Duration(5) * time.Second
+	defaultRegion   = "us-east-1"
+)
+
+// Driver 适配 S3 兼容对象存储的存储策略适配器
+type Driver struct {
+	Policy *model.Policy
+	Client request.Client
+
+	signer *Signer
+}
+
+// NewDriver 根据存储策略创建新的 S3 适配器
+func NewDriver(policy *model.Policy) (*Driver, error) {
+	if policy.Server == "" {
+		return nil, errors.New("s3 endpoint is not set")
+	}
+
+	region := policy.OptionsSerialized.Region
+	if region == "" {
+		region = defaultRegion
+	}
+
+	return &Driver{
+		Policy: policy,
+		Client: request.NewClient(),
+		signer: &Signer{
+			AccessKey: policy.AccessKey,
+			SecretKey: policy.SecretKey,
+			Region:    region,
+		},
+	}, nil
+}
+
+// List 列出给定路径下的文件
+func (handler *Driver) List(ctx context.Context, base string, recursive bool) ([]response.Object, error) {
+	// 初始化列目录参数
+	base = strings.TrimPrefix(base, "/")
+	if base != "" && !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	delimiter := "/"
+	if recursive {
+		delimiter = ""
+	}
+
+	listRes, err := handler.listObjects(ctx, base, delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]response.Object, 0, len(listRes.Contents)+len(listRes.CommonPrefixes))
+
+	// 处理目录
+	for _, object := range listRes.CommonPrefixes {
+		rel := strings.TrimSuffix(strings.TrimPrefix(object.Prefix, base), "/")
+		if rel == "" {
+			continue
+		}
+
+		res = append(res, response.Object{
+			Name:         path.Base(rel),
+			RelativePath: rel,
+			Size:         0,
+			IsDir:        true,
+			LastModify:   time.Now(),
+		})
+	}
+
+	// 处理文件
+	for _, object := range listRes.Contents {
+		rel := strings.TrimPrefix(object.Key, base)
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			continue
+		}
+
+		res = append(res, response.Object{
+			Name:         path.Base(object.Key),
+			Source:       object.Key,
+			RelativePath: rel,
+			Size:         object.Size,
+			IsDir:        false,
+			LastModify:   object.LastModified,
+		})
+	}
+
+	return res, nil
+}
+
+// Get 获取文件，返回的文件流支持 Seek，读取时按需发起 Range 请求
+func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser, error) {
+	var size int64
+	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok && file.SourceName == path {
+		size = int64(file.Size)
+	} else {
+		meta, err := handler.Meta(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+
+		size = int64(meta.Size)
+	}
+
+	return response.NewRangeReader(size, func(offset int64) (io.ReadCloser, error) {
+		resp, err := handler.getObject(ctx, path, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		return resp.Body, nil
+	}), nil
+}
+
+// Put 将文件流保存到指定目录，超过分片大小时使用分片上传
+func (handler *Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
+	defer file.Close()
+	fileInfo := file.Info()
+
+	// 是否允许覆盖
+	overwrite := fileInfo.Mode&fsctx.Overwrite == fsctx.Overwrite
+	if !overwrite {
+		if _, err := handler.Meta(ctx, fileInfo.SavePath); err == nil {
+			return errors.New("file with the same name existed or unavailable")
+		}
+	}
+
+	chunkSize := handler.Policy.OptionsSerialized.ChunkSize
+	if chunkSize == 0 || fileInfo.Size <= chunkSize {
+		return handler.putObject(ctx, fileInfo.SavePath, file, int64(fileInfo.Size), fileInfo.DetectMimeType())
+	}
+
+	uploadID, err := handler.createMultipartUpload(ctx, fileInfo.SavePath, fileInfo.DetectMimeType())
+	if err != nil {
+		return fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	chunks := chunk.NewChunkGroup(file, chunkSize, &backoff.ConstantBackoff{
+		Max:   model.GetIntSetting("chunk_retries", 5),
+		Sleep: chunkRetrySleep,
+	}, model.IsTrueVal(model.GetSettingByName("use_temp_chunk_buffer")))
+
+	parts := make([]CompletedPart, 0, chunks.Num())
+	uploadFunc := func(current *chunk.ChunkGroup, content io.Reader) error {
+		etag, err := handler.uploadPart(ctx, fileInfo.SavePath, uploadID, current.Index()+1, content, current.Length())
+		if err != nil {
+			return err
+		}
+
+		parts = append(parts, CompletedPart{PartNumber: current.Index() + 1, ETag: etag})
+		return nil
+	}
+
+	for chunks.Next() {
+		if err := chunks.Process(uploadFunc); err != nil {
+			if err := handler.abortMultipartUpload(context.Background(), fileInfo.SavePath, uploadID); err != nil {
+				util.Log().Warning("Failed to abort multipart upload %q: %s", uploadID, err)
+			}
+
+			return fmt.Errorf("failed to upload chunk #%d: %w", chunks.Index(), err)
+		}
+	}
+
+	return handler.completeMultipartUpload(ctx, fileInfo.SavePath, uploadID, parts)
+}
+
+// Delete 删除一个或多个文件，
+// 返回未删除的文件，及遇到的最后一个错误
+func (handler *Driver) Delete(ctx context.Context, files []string) ([]string, error) {
+	if len(files) == 0 {
+		return []string{}, nil
+	}
+
+	return handler.deleteObjects(ctx, files)
+}
+
+// Thumb 获取文件缩略图
+func (handler *Driver) Thumb(ctx context.Context, file *model.File) (*response.ContentResponse, error) {
+	return nil, driver.ErrorThumbNotSupported
+}
+
+// Source 获取外链URL
+func (handler *Driver) Source(ctx context.Context, path string, ttl int64, isDownload bool, speed int) (string, error) {
+	// 尝试从上下文获取文件名
+	fileName := ""
+	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
+		fileName = file.Name
+	}
+
+	// 公有空间不支持覆盖响应头
+	query := url.Values{}
+	if handler.Policy.IsPrivate && isDownload && fileName != "" {
+		query.Set("response-content-disposition", "attachment; filename=\""+url.PathEscape(fileName)+"\"")
+	}
+
+	target, err := handler.objectURL(handler.Policy.Server, path, query)
+	if err != nil {
+		return "", err
+	}
+
+	// 私有空间需要签名
+	finalURL := target
+	if handler.Policy.IsPrivate {
+		finalURL, err = url.Parse(handler.signer.Presign("GET", target, ttl, time.Now()))
+		if err != nil {
+			return "", serializer.NewError(serializer.CodeEncryptError, "Failed to sign url", err)
+		}
+	}
+
+	// 将最终生成的签名URL域名换成用户自定义的加速域名（如果有）
+	if handler.Policy.BaseURL != "" {
+		cdnURL, err := url.Parse(handler.Policy.BaseURL)
+		if err != nil {
+			return "", err
+		}
+
+		finalURL.Host = cdnURL.Host
+		finalURL.Scheme = cdnURL.Scheme
+	}
+
+	return finalURL.String(), nil
+}
+
+// Token 创建分片上传，并为每个分片签名客户端直传地址
+func (handler *Driver) Token(ctx context.Context, ttl int64, uploadSession *serializer.UploadSession, file fsctx.FileHeader) (*serializer.UploadCredential, error) {
+	// 检查文件是否存在
+	fileInfo := file.Info()
+	if _, err := handler.Meta(ctx, fileInfo.SavePath); err == nil {
+		return nil, errors.New("placeholder file already exist")
+	}
+
+	// 创建回调URL
+	siteURL := model.GetSiteURL()
+	apiBaseURI, _ := url.Parse(path.Join("/api/v3/callback/s3", uploadSession.Key, uploadSession.CallbackSecret))
+	apiURL := siteURL.ResolveReference(apiBaseURI)
+	uploadSession.Callback = apiURL.String()
+
+	// 创建分片上传
+	uploadID, err := handler.createMultipartUpload(ctx, fileInfo.SavePath, fileInfo.DetectMimeType())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+	uploadSession.UploadID = uploadID
+
+	// 为每个分片签名上传 URL
+	chunks := chunk.NewChunkGroup(file, handler.Policy.OptionsSerialized.ChunkSize, &backoff.ConstantBackoff{}, false)
+	urls := make([]string, chunks.Num())
+	for chunks.Next() {
+		target, err := handler.objectURL(handler.Policy.Server, fileInfo.SavePath, url.Values{
+			"partNumber": {fmt.Sprintf("%d", chunks.Index()+1)},
+			"uploadId":   {uploadID},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		urls[chunks.Index()] = handler.signer.Presign("PUT", target, ttl, time.Now())
+	}
+
+	// 签名完成分片上传的请求URL
+	target, err := handler.objectURL(handler.Policy.Server, fileInfo.SavePath, url.Values{"uploadId": {uploadID}})
+	if err != nil {
+		return nil, err
+	}
+
+	return &serializer.UploadCredential{
+		SessionID:   uploadSession.Key,
+		ChunkSize:   handler.Policy.OptionsSerialized.ChunkSize,
+		UploadID:    uploadID,
+		UploadURLs:  urls,
+		CompleteURL: handler.signer.Presign("POST", target, ttl, time.Now()),
+		Callback:    uploadSession.Callback,
+	}, nil
+}
+
+// CancelToken 取消上传凭证
+func (handler *Driver) CancelToken(ctx context.Context, uploadSession *serializer.UploadSession) error {
+	return handler.abortMultipartUpload(ctx, uploadSession.SavePath, uploadSession.UploadID)
+}
@@ -0,0 +1,363 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	cache.Init()
+	model.Init()
+	os.Exit(m.Run())
+}
+
+// fakeS3 在内存中模拟路径风格的 S3 服务端，并校验请求签名
+type fakeS3 struct {
+	signer   *Signer
+	mu       sync.Mutex
+	objects  map[string][]byte
+	uploads  map[string]map[int][]byte
+	aborted  []string
+	nextID   int
+	failPart int
+	pageSize int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *Driver) {
+	fake := &fakeS3{
+		signer:   &Signer{AccessKey: "ak", SecretKey: "sk", Region: defaultRegion},
+		objects:  make(map[string][]byte),
+		uploads:  make(map[string]map[int][]byte),
+		pageSize: 1000,
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	policy := &model.Policy{
+		Type:       "s3",
+		Server:     server.URL,
+		BucketName: "bucket",
+		AccessKey:  fake.signer.AccessKey,
+		SecretKey:  fake.signer.SecretKey,
+	}
+	policy.OptionsSerialized.S3ForcePathStyle = true
+
+	handler, err := NewDriver(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fake, handler
+}
+
+// verify 校验请求头中的签名
+func (f *fakeS3) verify(r *http.Request) bool {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), Algorithm+" ")
+	fields := make(map[string]string)
+	for _, field := range strings.Split(auth, ", ") {
+		if k, v, ok := strings.Cut(field, "="); ok {
+			fields[k] = v
+		}
+	}
+
+	now, err := ParseTime(r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	headers := strings.Split(fields["SignedHeaders"], ";")
+	return fields["Credential"] == f.signer.AccessKey+"/"+f.signer.Scope(now) &&
+		fields["Signature"] == f.signer.SignatureOf(r, r.URL.Query(), headers, r.Header.Get("X-Amz-Content-Sha256"), now)
+}
+
+func (f *fakeS3) fail(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.verify(r) {
+		f.fail(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "bucket" {
+		f.fail(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case key == "" && r.Method == "GET":
+		f.list(w, query)
+	case key == "" && r.Method == "POST" && query.Has("delete"):
+		var req deleteObjectsRequest
+		_ = xml.Unmarshal(body, &req)
+		for _, object := range req.Objects {
+			delete(f.objects, object.Key)
+		}
+		fmt.Fprint(w, "<DeleteResult></DeleteResult>")
+	case r.Method == "POST" && query.Has("uploads"):
+		f.nextID++
+		uploadID := fmt.Sprintf("upload-%d", f.nextID)
+		f.uploads[uploadID] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", uploadID)
+	case r.Method == "PUT" && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		if partNumber == f.failPart {
+			f.fail(w, http.StatusInternalServerError, "InternalError")
+			return
+		}
+		parts[partNumber] = body
+		w.Header().Set("ETag", fmt.Sprintf("\"etag-%d\"", partNumber))
+	case r.Method == "POST" && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		var req completeMultipartUpload
+		_ = xml.Unmarshal(body, &req)
+		var content []byte
+		for i, part := range req.Parts {
+			if part.PartNumber != i+1 || part.ETag != fmt.Sprintf("\"etag-%d\"", i+1) {
+				f.fail(w, http.StatusOK, "InvalidPart")
+				return
+			}
+			content = append(content, parts[part.PartNumber]...)
+		}
+		f.objects[key] = content
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == "DELETE" && query.Has("uploadId"):
+		f.aborted = append(f.aborted, query.Get("uploadId"))
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "PUT":
+		f.objects[key] = body
+	case r.Method == "HEAD" || r.Method == "GET":
+		content, ok := f.objects[key]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		var offset int
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			offset, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rangeHeader, "bytes="), "-"))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(content)-1, len(content)))
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)-offset))
+		if offset > 0 {
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		if r.Method == "GET" {
+			_, _ = w.Write(content[offset:])
+		}
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// list 按 ListObjectsV2 分页列取对象
+func (f *fakeS3) list(w http.ResponseWriter, query map[string][]string) {
+	get := func(k string) string {
+		if v := query[k]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	prefix, delimiter, token := get("prefix"), get("delimiter"), get("continuation-token")
+
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		// 续页时跳过已返回的对象及公共前缀
+		if strings.HasPrefix(key, prefix) && key > token && (delimiter == "" || !strings.HasSuffix(token, delimiter) || !strings.HasPrefix(key, token)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var (
+		res  listBucketResult
+		last string
+	)
+	for _, key := range keys {
+		entry := key
+		if delimiter != "" {
+			if idx := strings.Index(strings.TrimPrefix(key, prefix), delimiter); idx >= 0 {
+				entry = prefix + strings.TrimPrefix(key, prefix)[:idx+1]
+			}
+		}
+		if entry == last {
+			continue
+		}
+
+		if len(res.Contents)+len(res.CommonPrefixes) == f.pageSize {
+			res.IsTruncated = true
+			res.NextContinuationToken = last
+			break
+		}
+
+		last = entry
+		if entry != key {
+			res.CommonPrefixes = append(res.CommonPrefixes, struct {
+				Prefix string `xml:"Prefix"`
+			}{Prefix: entry})
+			continue
+		}
+
+		res.Contents = append(res.Contents, struct {
+			Key          string    `xml:"Key"`
+			Size         uint64    `xml:"Size"`
+			LastModified time.Time `xml:"LastModified"`
+		}{Key: key, Size: uint64(len(f.objects[key])), LastModified: time.Now().UTC()})
+	}
+
+	body, _ := xml.Marshal(struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		listBucketResult
+	}{listBucketResult: res})
+	_, _ = w.Write(body)
+}
+
+func putFile(handler *Driver, savePath, content string) error {
+	return handler.Put(context.Background(), &fsctx.FileStream{
+		File:     io.NopCloser(strings.NewReader(content)),
+		Size:     uint64(len(content)),
+		SavePath: savePath,
+		Mode:     fsctx.Overwrite,
+	})
+}
+
+func TestDriver_PutAndGet(t *testing.T) {
+	a := assert.New(t)
+	fake, handler := newFakeS3(t)
+
+	a.NoError(putFile(handler, "/dir/a.txt", "hello world"))
+	a.Equal("hello world", string(fake.objects["dir/a.txt"]))
+
+	meta, err := handler.Meta(context.Background(), "dir/a.txt")
+	a.NoError(err)
+	a.EqualValues(11, meta.Size)
+
+	rs, err := handler.Get(context.Background(), "dir/a.txt")
+	a.NoError(err)
+	defer rs.Close()
+
+	_, err = rs.Seek(6, io.SeekStart)
+	a.NoError(err)
+	content, err := io.ReadAll(rs)
+	a.NoError(err)
+	a.Equal("world", string(content))
+
+	// 对象不存在
+	_, err = handler.Meta(context.Background(), "dir/not-exist.txt")
+	a.True(IsNotFound(err))
+	_, err = handler.Get(context.Background(), "dir/not-exist.txt")
+	a.True(IsNotFound(err))
+
+	// 不允许覆盖
+	err = handler.Put(context.Background(), &fsctx.FileStream{
+		File:     io.NopCloser(strings.NewReader("new")),
+		Size:     3,
+		SavePath: "dir/a.txt",
+	})
+	a.Error(err)
+	a.Equal("hello world", string(fake.objects["dir/a.txt"]))
+}
+
+func TestDriver_PutMultipart(t *testing.T) {
+	a := assert.New(t)
+	fake, handler := newFakeS3(t)
+	handler.Policy.OptionsSerialized.ChunkSize = 4
+
+	a.NoError(putFile(handler, "big.bin", "0123456789"))
+	a.Equal("0123456789", string(fake.objects["big.bin"]))
+	a.Empty(fake.uploads)
+
+	// 分片上传失败时取消上传
+	_ = cache.SetSettings(map[string]string{"chunk_retries": "0"}, "setting_")
+	fake.failPart = 2
+	a.Error(putFile(handler, "big2.bin", "0123456789"))
+	a.NotContains(fake.objects, "big2.bin")
+	a.Equal([]string{"upload-2"}, fake.aborted)
+	a.Empty(fake.uploads)
+}
+
+func TestDriver_Delete(t *testing.T) {
+	a := assert.New(t)
+	fake, handler := newFakeS3(t)
+	fake.objects["a.txt"] = []byte("a")
+	fake.objects["b.txt"] = []byte("b")
+	fake.objects["c.txt"] = []byte("c")
+
+	failed, err := handler.Delete(context.Background(), []string{"/a.txt", "b.txt"})
+	a.NoError(err)
+	a.Empty(failed)
+	a.Equal([]string{"c.txt"}, keysOf(fake.objects))
+
+	failed, err = handler.Delete(context.Background(), nil)
+	a.NoError(err)
+	a.Empty(failed)
+}
+
+func TestDriver_List(t *testing.T) {
+	a := assert.New(t)
+	fake, handler := newFakeS3(t)
+	fake.pageSize = 2
+	for _, key := range []string{"root/a.txt", "root/b.txt", "root/sub/c.txt", "root/sub/d/e.txt", "other.txt"} {
+		fake.objects[key] = []byte(key)
+	}
+
+	res, err := handler.List(context.Background(), "/root", false)
+	a.NoError(err)
+	names := make([]string, 0, len(res))
+	for _, object := range res {
+		names = append(names, fmt.Sprintf("%s:%v", object.RelativePath, object.IsDir))
+	}
+	a.ElementsMatch([]string{"a.txt:false", "b.txt:false", "sub:true"}, names)
+
+	res, err = handler.List(context.Background(), "root", true)
+	a.NoError(err)
+	names = names[:0]
+	for _, object := range res {
+		names = append(names, object.RelativePath)
+	}
+	a.ElementsMatch([]string{"a.txt", "b.txt", "sub/c.txt", "sub/d/e.txt"}, names)
+}
+
+func keysOf(objects map[string][]byte) []string {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
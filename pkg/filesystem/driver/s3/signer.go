@@ -0,0 +1,256 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Algorithm 签名算法
+	Algorithm       = "AWS4-HMAC-SHA256"
+	signService     = "s3"
+	timeFormat      = "20060102T150405Z"
+	shortTimeFormat = "20060102"
+
+	// UnsignedPayload 不对请求正文签名时使用的摘要值
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// EmptyPayload 空请求正文的 SHA256 摘要
+	EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// StreamingPayload 请求正文为逐块签名的 aws-chunked 编码时使用的摘要值
+	StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+)
+
+// Signer AWS Signature V4 签名器
+type Signer struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+}
+
+// Sign 对请求头签名，payloadHash 为请求正文摘要
+func (s *Signer) Sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(timeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders, canonicalHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		CanonicalURI(req.URL),
+		CanonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := s.Scope(now)
+	signature := s.Signature(now, StringToSign(now, scope, canonicalRequest))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		Algorithm, s.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// Presign 生成预签名的请求 URL
+func (s *Signer) Presign(method string, target *url.URL, ttl int64, now time.Time) string {
+	now = now.UTC()
+	query := target.Query()
+	query.Set("X-Amz-Algorithm", Algorithm)
+	query.Set("X-Amz-Credential", s.AccessKey+"/"+s.Scope(now))
+	query.Set("X-Amz-Date", now.Format(timeFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(ttl, 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		CanonicalURI(target),
+		CanonicalQuery(query),
+		"host:" + target.Host + "\n",
+		"host",
+		UnsignedPayload,
+	}, "\n")
+
+	signature := s.Signature(now, StringToSign(now, s.Scope(now), canonicalRequest))
+	signed := *target
+	signed.RawQuery = CanonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return signed.String()
+}
+
+// SignatureOf 按客户端声明的参与签名的请求头计算收到的请求的签名，用于校验请求
+func (s *Signer) SignatureOf(req *http.Request, query url.Values, signedHeaders []string, payloadHash string, now time.Time) string {
+	headers := make(map[string]string, len(signedHeaders))
+	for _, name := range signedHeaders {
+		switch name {
+		case "host":
+			headers[name] = req.Host
+		case "content-length":
+			headers[name] = strconv.FormatInt(req.ContentLength, 10)
+		default:
+			headers[name] = strings.Join(strings.Fields(strings.Join(req.Header.Values(name), ",")), " ")
+		}
+	}
+
+	signed, canonical := formatHeaders(headers)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		CanonicalURI(req.URL),
+		CanonicalQuery(query),
+		canonical,
+		signed,
+		payloadHash,
+	}, "\n")
+
+	return s.Signature(now, StringToSign(now, s.Scope(now), canonicalRequest))
+}
+
+// ChunkSignature 计算 aws-chunked 编码中单个分块的签名，previous 为上一分块或请求的签名
+func (s *Signer) ChunkSignature(now time.Time, previous string, chunk []byte) string {
+	hash := sha256.Sum256(chunk)
+	stringToSign := strings.Join([]string{
+		Algorithm + "-PAYLOAD",
+		now.UTC().Format(timeFormat),
+		s.Scope(now),
+		previous,
+		EmptyPayload,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	return s.Signature(now, stringToSign)
+}
+
+// ParseTime 解析 X-Amz-Date 格式的时间
+func ParseTime(value string) (time.Time, error) {
+	return time.Parse(timeFormat, value)
+}
+
+// Scope 返回签名凭证范围
+func (s *Signer) Scope(now time.Time) string {
+	return strings.Join([]string{now.UTC().Format(shortTimeFormat), s.Region, signService, "aws4_request"}, "/")
+}
+
+// Signature 使用派生密钥计算签名
+func (s *Signer) Signature(now time.Time, stringToSign string) string {
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), now.UTC().Format(shortTimeFormat))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, signService)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// StringToSign 构造待签名字符串
+func StringToSign(now time.Time, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	return strings.Join([]string{
+		Algorithm,
+		now.UTC().Format(timeFormat),
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+}
+
+// CanonicalURI 返回规范化的请求路径
+func CanonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	// S3 要求路径中的每段只编码一次
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if unescaped, err := url.PathUnescape(seg); err == nil {
+			segments[i] = uriEncode(unescaped, false)
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// CanonicalQuery 返回规范化的查询字符串
+func CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		if k == "X-Amz-Signature" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// canonicalHeaders 返回参与签名的请求头列表及其规范化形式
+func canonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		lower := strings.ToLower(k)
+		if lower == "authorization" || lower == "user-agent" {
+			continue
+		}
+		if lower == "content-type" || lower == "content-md5" || lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+
+	return formatHeaders(headers)
+}
+
+func formatHeaders(headers map[string]string) (string, string) {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var canonical strings.Builder
+	for _, k := range keys {
+		canonical.WriteString(k + ":" + headers[k] + "\n")
+	}
+
+	return strings.Join(keys, ";"), canonical.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncode 按 AWS 的规则进行 URI 编码
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,293 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/conf"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/local"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/response"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"github.com/pkg/sftp"
+)
+
+// Driver SFTP 存储策略适配器，文件经由主机中转上传与下载
+type Driver struct {
+	Policy *model.Policy
+
+	pool *clientPool
+}
+
+// NewDriver 根据存储策略创建新的 SFTP 适配器
+func NewDriver(policy *model.Policy) (*Driver, error) {
+	if _, err := serverAddr(policy.Server); err != nil {
+		return nil, err
+	}
+
+	return &Driver{
+		Policy: policy,
+		pool:   getPool(policy),
+	}, nil
+}
+
+// realPath 返回远程主机上的实际路径，Policy.BucketName 非空时作为根目录
+func (handler *Driver) realPath(p string) string {
+	if handler.Policy.BucketName == "" {
+		if p = strings.TrimPrefix(path.Clean("/"+p), "/"); p == "" {
+			return "."
+		}
+
+		return p
+	}
+
+	return path.Join(handler.Policy.BucketName, path.Clean("/"+p))
+}
+
+// withConn 从连接池取出连接执行操作
+func (handler *Driver) withConn(fn func(c *sftp.Client) error) error {
+	c, err := handler.pool.get()
+	if err != nil {
+		return err
+	}
+
+	err = fn(c.sftp)
+	handler.pool.put(c, err)
+	return err
+}
+
+// List 列取给定路径下的文件，recursive 为真时递归列取
+func (handler *Driver) List(ctx context.Context, base string, recursive bool) ([]response.Object, error) {
+	res := make([]response.Object, 0)
+	root := handler.realPath(base)
+
+	err := handler.withConn(func(c *sftp.Client) error {
+		walker := c.Walk(root)
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				util.Log().Warning("Failed to walk folder %q: %s", walker.Path(), err)
+				if walker.Path() == root {
+					return err
+				}
+				continue
+			}
+
+			// 跳过根目录
+			if walker.Path() == root {
+				continue
+			}
+
+			rel := walker.Path()
+			if root != "." {
+				rel = strings.TrimPrefix(rel, root+"/")
+			}
+
+			info := walker.Stat()
+			res = append(res, response.Object{
+				Name:         info.Name(),
+				RelativePath: rel,
+				Source:       path.Join(base, rel),
+				Size:         uint64(info.Size()),
+				IsDir:        info.IsDir(),
+				LastModify:   info.ModTime(),
+			})
+
+			// 如果非递归，则不步入目录
+			if !recursive && info.IsDir() {
+				walker.SkipDir()
+			}
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+// Get 获取文件内容，返回的文件流在关闭时归还连接
+func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser, error) {
+	c, err := handler.pool.get()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := c.sftp.Open(handler.realPath(path))
+	if err != nil {
+		handler.pool.put(c, err)
+		return nil, err
+	}
+
+	return &pooledFile{File: file, conn: c, pool: handler.pool}, nil
+}
+
+// Put 将文件流保存到指定目录
+func (handler *Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
+	defer file.Close()
+	fileInfo := file.Info()
+	dst := handler.realPath(fileInfo.SavePath)
+
+	return handler.withConn(func(c *sftp.Client) error {
+		// 如果非 Overwrite，则检查是否有重名冲突
+		if fileInfo.Mode&fsctx.Overwrite != fsctx.Overwrite {
+			if _, err := c.Stat(dst); err == nil {
+				util.Log().Warning("File with the same name existed or unavailable: %s", dst)
+				return os.ErrExist
+			}
+		}
+
+		// 如果目标目录不存在，创建
+		if err := c.MkdirAll(path.Dir(dst)); err != nil {
+			util.Log().Warning("Failed to create directory: %s", err)
+			return err
+		}
+
+		openMode := os.O_CREATE | os.O_WRONLY
+		if fileInfo.Mode&fsctx.Append != fsctx.Append {
+			openMode |= os.O_TRUNC
+		} else {
+			stat, err := c.Stat(dst)
+			size := int64(0)
+			if err == nil {
+				size = stat.Size()
+			}
+
+			if uint64(size) < fileInfo.AppendStart {
+				return errors.New("size of unfinished uploaded chunks is not as expected")
+			} else if uint64(size) > fileInfo.AppendStart {
+				if err := c.Truncate(dst, int64(fileInfo.AppendStart)); err != nil {
+					return fmt.Errorf("failed to overwrite chunk: %w", err)
+				}
+			}
+		}
+
+		out, err := c.OpenFile(dst, openMode)
+		if err != nil {
+			util.Log().Warning("Failed to open or create file: %s", err)
+			return err
+		}
+		defer out.Close()
+
+		// 部分服务端不支持追加模式，手动移动到分片起始位置
+		if fileInfo.Mode&fsctx.Append == fsctx.Append {
+			if _, err := out.Seek(int64(fileInfo.AppendStart), io.SeekStart); err != nil {
+				return err
+			}
+		}
+
+		// 写入文件内容
+		_, err = io.Copy(out, file)
+		return err
+	})
+}
+
+// Truncate 将文件截断至指定大小
+func (handler *Driver) Truncate(ctx context.Context, src string, size uint64) error {
+	util.Log().Warning("Truncate file %q to [%d].", src, size)
+	return handler.withConn(func(c *sftp.Client) error {
+		return c.Truncate(handler.realPath(src), int64(size))
+	})
+}
+
+// Delete 删除一个或多个文件，
+// 返回未删除的文件，及遇到的最后一个错误
+func (handler *Driver) Delete(ctx context.Context, files []string) ([]string, error) {
+	deleteFailed := make([]string, 0, len(files))
+	var retErr error
+
+	err := handler.withConn(func(c *sftp.Client) error {
+		thumbSuffix := model.GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")
+		for _, value := range files {
+			if err := c.Remove(handler.realPath(value)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				util.Log().Warning("Failed to delete file: %s", err)
+				retErr = err
+				deleteFailed = append(deleteFailed, value)
+			}
+
+			// 尝试删除文件的缩略图（如果有）
+			_ = c.Remove(handler.realPath(value + thumbSuffix))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return files, err
+	}
+
+	return deleteFailed, retErr
+}
+
+// Thumb 获取文件缩略图，缩略图由主机生成后保存在文件旁
+func (handler *Driver) Thumb(ctx context.Context, file *model.File) (*response.ContentResponse, error) {
+	if conf.SystemConfig.Mode == "master" && file.MetadataSerialized[model.ThumbStatusMetadataKey] == model.ThumbStatusNotExist {
+		return nil, driver.ErrorThumbNotExist
+	}
+
+	thumbFile, err := handler.Get(ctx, file.ThumbFile())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("thumb not exist: %w (%w)", err, driver.ErrorThumbNotExist)
+		}
+
+		return nil, err
+	}
+
+	return &response.ContentResponse{
+		Redirect: false,
+		Content:  thumbFile,
+	}, nil
+}
+
+// Source 获取外链URL，与本机策略相同经由主机中转
+func (handler *Driver) Source(ctx context.Context, path string, ttl int64, isDownload bool, speed int) (string, error) {
+	return local.Driver{Policy: handler.Policy}.Source(ctx, path, ttl, isDownload, speed)
+}
+
+// Token 获取上传会话，文件经由主机中转上传
+func (handler *Driver) Token(ctx context.Context, ttl int64, uploadSession *serializer.UploadSession, file fsctx.FileHeader) (*serializer.UploadCredential, error) {
+	exist := false
+	err := handler.withConn(func(c *sftp.Client) error {
+		_, err := c.Stat(handler.realPath(uploadSession.SavePath))
+		exist = err == nil
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if exist {
+		return nil, errors.New("placeholder file already exist")
+	}
+
+	return &serializer.UploadCredential{
+		SessionID: uploadSession.Key,
+		ChunkSize: handler.Policy.OptionsSerialized.ChunkSize,
+	}, nil
+}
+
+// CancelToken 取消上传凭证
+func (handler *Driver) CancelToken(ctx context.Context, uploadSession *serializer.UploadSession) error {
+	return nil
+}
+
+// pooledFile 关闭时将连接归还连接池的远程文件
+type pooledFile struct {
+	*sftp.File
+	conn *conn
+	pool *clientPool
+}
+
+// Close 关闭文件并归还连接
+func (f *pooledFile) Close() error {
+	err := f.File.Close()
+	f.pool.put(f.conn, err)
+	return err
+}
@@ -0,0 +1,226 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultPort        = "22"
+	defaultMaxIdleConn = 4
+	dialTimeout        = time.Duration(10) * time.Second
+)
+
+var (
+	pools   = make(map[string]*clientPool)
+	poolsMu sync.Mutex
+)
+
+// conn 一个 SSH 连接及其上的 SFTP 会话
+type conn struct {
+	ssh  *ssh.Client
+	sftp *sftp.Client
+}
+
+func (c *conn) close() {
+	_ = c.sftp.Close()
+	_ = c.ssh.Close()
+}
+
+// clientPool 存储策略对应的 SFTP 连接池
+type clientPool struct {
+	policy *model.Policy
+	idle   chan *conn
+}
+
+// getPool 获取存储策略对应的连接池，策略变更后会使用新的连接池
+func getPool(policy *model.Policy) *clientPool {
+	key := fmt.Sprintf("%d_%d", policy.ID, policy.UpdatedAt.UnixNano())
+
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	if pool, ok := pools[key]; ok {
+		return pool
+	}
+
+	// 清理同一策略的旧连接池
+	prefix := fmt.Sprintf("%d_", policy.ID)
+	for k, pool := range pools {
+		if strings.HasPrefix(k, prefix) {
+			pool.drain()
+			delete(pools, k)
+		}
+	}
+
+	maxIdle := policy.OptionsSerialized.SFTPMaxIdleConn
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdleConn
+	}
+
+	pool := &clientPool{
+		policy: policy,
+		idle:   make(chan *conn, maxIdle),
+	}
+	pools[key] = pool
+	return pool
+}
+
+// get 取出一个空闲连接，没有空闲连接时建立新连接
+func (p *clientPool) get() (*conn, error) {
+	select {
+	case c := <-p.idle:
+		// 检查连接是否仍然可用
+		if _, err := c.sftp.Getwd(); err == nil {
+			return c, nil
+		}
+		c.close()
+	default:
+	}
+
+	return p.dial()
+}
+
+// put 归还连接，err 不是 SFTP 状态错误时认为连接已损坏并将其关闭
+func (p *clientPool) put(c *conn, err error) {
+	var statusErr *sftp.StatusError
+	if err != nil && !errors.As(err, &statusErr) && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, os.ErrExist) {
+		c.close()
+		return
+	}
+
+	select {
+	case p.idle <- c:
+	default:
+		c.close()
+	}
+}
+
+// drain 关闭所有空闲连接
+func (p *clientPool) drain() {
+	for {
+		select {
+		case c := <-p.idle:
+			c.close()
+		default:
+			return
+		}
+	}
+}
+
+// dial 建立新的 SFTP 连接
+func (p *clientPool) dial() (*conn, error) {
+	config, err := clientConfig(p.policy)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := serverAddr(p.policy.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	sshClient, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ssh server %q: %w", addr, err)
+	}
+
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, fmt.Errorf("failed to start sftp session: %w", err)
+	}
+
+	util.Log().Debug("New SFTP connection to %q established.", addr)
+	return &conn{ssh: sshClient, sftp: sftpClient}, nil
+}
+
+// clientConfig 根据存储策略生成 SSH 客户端设置，
+// AccessKey 为用户名，SecretKey 为密码或私钥的口令
+func clientConfig(policy *model.Policy) (*ssh.ClientConfig, error) {
+	var auths []ssh.AuthMethod
+	options := policy.OptionsSerialized
+
+	if options.SFTPPrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(options.SFTPPrivateKey))
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(options.SFTPPrivateKey), []byte(policy.SecretKey))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+
+		auths = append(auths, ssh.PublicKeys(signer))
+	} else if policy.SecretKey != "" {
+		auths = append(auths, ssh.Password(policy.SecretKey))
+	}
+
+	hostKey, err := parseHostKey(options.SFTPHostKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            policy.AccessKey,
+		Auth:            auths,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         dialTimeout,
+	}, nil
+}
+
+// CheckHostKey 检查存储策略设置的 SFTP 服务端公钥，公钥必须设置
+func CheckHostKey(key string) error {
+	_, err := parseHostKey(key)
+	return err
+}
+
+// parseHostKey 解析 authorized_keys 格式的服务端公钥，
+// 可通过 ssh-keyscan 获取，行首的主机名会被忽略
+func parseHostKey(key string) (ssh.PublicKey, error) {
+	if strings.TrimSpace(key) == "" {
+		return nil, errors.New("sftp host key is not set")
+	}
+
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+	if err != nil {
+		if _, _, hostKey, _, _, err = ssh.ParseKnownHosts([]byte(key)); err != nil {
+			return nil, fmt.Errorf("failed to parse host key: %w", err)
+		}
+	}
+
+	return hostKey, nil
+}
+
+// serverAddr 将 Policy.Server 解析为 host:port，支持 sftp:// 前缀
+func serverAddr(server string) (string, error) {
+	if strings.Contains(server, "://") {
+		u, err := url.Parse(server)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse sftp server: %w", err)
+		}
+		server = u.Host
+	}
+
+	if server == "" {
+		return "", errors.New("sftp server is not set")
+	}
+
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, defaultPort)
+	}
+
+	return server, nil
+}
@@ -0,0 +1,256 @@
+package webdav
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/request"
+)
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8" ?>` +
+	`<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/><D:getlastmodified/></D:prop></D:propfind>`
+
+// RespError 远程 WebDAV 服务端返回的错误
+type RespError struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+// Error 实现 error 接口
+func (e *RespError) Error() string {
+	return fmt.Sprintf("webdav %s %q failed with status %d", e.Method, e.Path, e.StatusCode)
+}
+
+// multistatus PROPFIND 响应
+type multistatus struct {
+	Responses []propResponse `xml:"DAV: response"`
+}
+
+type propResponse struct {
+	Href     string     `xml:"DAV: href"`
+	Propstat []propstat `xml:"DAV: propstat"`
+}
+
+type propstat struct {
+	Prop   prop   `xml:"DAV: prop"`
+	Status string `xml:"DAV: status"`
+}
+
+type prop struct {
+	ContentLength string `xml:"DAV: getcontentlength"`
+	LastModified  string `xml:"DAV: getlastmodified"`
+	ResourceType  struct {
+		Collection *struct{} `xml:"DAV: collection"`
+	} `xml:"DAV: resourcetype"`
+}
+
+// fileInfo 远程文件或目录信息
+type fileInfo struct {
+	Path         string
+	Size         uint64
+	IsDir        bool
+	LastModified time.Time
+}
+
+// remoteURL 返回给定路径在远程服务端上的地址
+func (handler *Driver) remoteURL(p string, isDir bool) *url.URL {
+	target := *handler.base
+	target.Path = path.Join(handler.base.Path, path.Clean("/"+p))
+	if isDir && !strings.HasSuffix(target.Path, "/") {
+		target.Path += "/"
+	}
+
+	return &target
+}
+
+// request 发送请求，返回状态码不在 expected 中时返回错误
+func (handler *Driver) request(ctx context.Context, method, p string, isDir bool, body io.Reader, size int64, header http.Header, expected ...int) (*http.Response, error) {
+	if header == nil {
+		header = http.Header{}
+	}
+
+	if handler.Policy.AccessKey != "" || handler.Policy.SecretKey != "" {
+		credential := handler.Policy.AccessKey + ":" + handler.Policy.SecretKey
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credential)))
+	}
+
+	opts := []request.Option{
+		request.WithContext(ctx),
+		request.WithHeader(header),
+		request.WithContentLength(size),
+	}
+	if body != nil || method == "GET" {
+		opts = append(opts, request.WithTimeout(time.Duration(0)))
+	}
+
+	resp := handler.Client.Request(method, handler.remoteURL(p, isDir).String(), body, opts...)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	for _, status := range expected {
+		if resp.Response.StatusCode == status {
+			return resp.Response, nil
+		}
+	}
+
+	resp.Response.Body.Close()
+	return nil, &RespError{Method: method, Path: p, StatusCode: resp.Response.StatusCode}
+}
+
+// IsNotFound 返回错误是否为文件不存在
+func IsNotFound(err error) bool {
+	respErr, ok := err.(*RespError)
+	return ok && respErr.StatusCode == http.StatusNotFound
+}
+
+// propfind 列取给定路径的属性，depth 为 0 时只返回路径本身
+func (handler *Driver) propfind(ctx context.Context, p string, depth int) ([]fileInfo, error) {
+	header := http.Header{}
+	header.Set("Depth", strconv.Itoa(depth))
+	header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	resp, err := handler.request(
+		ctx, "PROPFIND", p, depth > 0,
+		strings.NewReader(propfindBody), int64(len(propfindBody)),
+		header, http.StatusMultiStatus,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var res multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("failed to decode propfind response: %w", err)
+	}
+
+	infos := make([]fileInfo, 0, len(res.Responses))
+	for _, r := range res.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+
+		rel := strings.TrimPrefix(href.Path, strings.TrimSuffix(handler.base.Path, "/"))
+		info := fileInfo{Path: path.Clean("/" + rel)}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+
+			info.IsDir = ps.Prop.ResourceType.Collection != nil
+			info.Size, _ = strconv.ParseUint(ps.Prop.ContentLength, 10, 64)
+			info.LastModified, _ = http.ParseTime(ps.Prop.LastModified)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// stat 获取单个文件信息
+func (handler *Driver) stat(ctx context.Context, p string) (*fileInfo, error) {
+	infos, err := handler.propfind(ctx, p, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(infos) == 0 {
+		return nil, &RespError{Method: "PROPFIND", Path: p, StatusCode: http.StatusNotFound}
+	}
+
+	return &infos[0], nil
+}
+
+// mkcolAll 逐级创建给定目录
+func (handler *Driver) mkcolAll(ctx context.Context, dir string) error {
+	dir = path.Clean("/" + dir)
+	if dir == "/" {
+		return nil
+	}
+
+	current := ""
+	for _, seg := range strings.Split(strings.TrimPrefix(dir, "/"), "/") {
+		current += "/" + seg
+		// 405 表示目录已存在
+		resp, err := handler.request(ctx, "MKCOL", current, true, nil, 0, nil,
+			http.StatusCreated, http.StatusOK, http.StatusMethodNotAllowed)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+// put 上传文件内容，start 大于 0 时通过 Content-Range 写入文件的指定位置
+func (handler *Driver) put(ctx context.Context, p string, body io.Reader, start, size int64, total string) error {
+	header := http.Header{}
+	if start > 0 {
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%s", start, start+size-1, total))
+	}
+
+	resp, err := handler.request(ctx, "PUT", p, false, body, size, header,
+		http.StatusOK, http.StatusCreated, http.StatusNoContent)
+	if err != nil {
+		// 不支持部分写入的服务端按 RFC 7231 返回 400
+		var respErr *RespError
+		if start > 0 && errors.As(err, &respErr) && (respErr.StatusCode == http.StatusBadRequest || respErr.StatusCode == http.StatusNotImplemented) {
+			return fmt.Errorf("remote webdav server does not support Content-Range in PUT: %w", err)
+		}
+
+		return err
+	}
+
+	resp.Body.Close()
+	return nil
+}
+
+// get 从 offset 处获取文件内容
+func (handler *Driver) get(ctx context.Context, p string, offset int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := handler.request(ctx, "GET", p, false, nil, 0, header, http.StatusOK, http.StatusPartialContent)
+	if err != nil {
+		return nil, err
+	}
+
+	// 服务端不支持 Range 时手动跳过
+	if offset > 0 && resp.StatusCode == http.StatusOK {
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+
+	return resp.Body, nil
+}
+
+// remove 删除文件，文件不存在时不返回错误
+func (handler *Driver) remove(ctx context.Context, p string) error {
+	resp, err := handler.request(ctx, "DELETE", p, false, nil, 0, nil,
+		http.StatusOK, http.StatusNoContent, http.StatusAccepted, http.StatusNotFound)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+	return nil
+}
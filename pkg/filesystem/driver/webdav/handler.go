@@ -0,0 +1,234 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/conf"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/chunk"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/chunk/backoff"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/local"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/response"
+	"gitee.com/jiangjiali/cloudreve/pkg/request"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+const chunkRetrySleep = time.Duration(5) * time.Second
+
+// Driver WebDAV 存储策略适配器，以另一个 WebDAV 服务端作为存储后端，
+// 文件经由主机中转上传与下载
+type Driver struct {
+	Policy *model.Policy
+	Client request.Client
+
+	base *url.URL
+}
+
+// NewDriver 根据存储策略创建新的 WebDAV 适配器，
+// Policy.Server 为远程 WebDAV 根地址，AccessKey/SecretKey 为用户名和密码
+func NewDriver(policy *model.Policy) (*Driver, error) {
+	base, err := url.Parse(policy.Server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webdav server: %w", err)
+	}
+
+	if base.Scheme == "" || base.Host == "" {
+		return nil, errors.New("webdav server is not set")
+	}
+
+	return &Driver{
+		Policy: policy,
+		Client: request.NewClient(),
+		base:   base,
+	}, nil
+}
+
+// List 列取给定路径下的文件，recursive 为真时逐级列取子目录
+func (handler *Driver) List(ctx context.Context, base string, recursive bool) ([]response.Object, error) {
+	res := make([]response.Object, 0)
+	root := path.Clean("/" + base)
+	queue := []string{root}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		infos, err := handler.propfind(ctx, current, 1)
+		if err != nil {
+			if current == root {
+				return nil, err
+			}
+
+			util.Log().Warning("Failed to walk folder %q: %s", current, err)
+			continue
+		}
+
+		for _, info := range infos {
+			// 跳过目录本身
+			if info.Path == current {
+				continue
+			}
+
+			rel := strings.TrimPrefix(strings.TrimPrefix(info.Path, root), "/")
+			res = append(res, response.Object{
+				Name:         path.Base(info.Path),
+				RelativePath: rel,
+				Source:       strings.TrimPrefix(info.Path, "/"),
+				Size:         info.Size,
+				IsDir:        info.IsDir,
+				LastModify:   info.LastModified,
+			})
+
+			if recursive && info.IsDir {
+				queue = append(queue, info.Path)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// Get 获取文件内容，返回的文件流支持 Seek，读取时按需发起 Range 请求
+func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser, error) {
+	var size int64
+	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok && file.SourceName == path {
+		size = int64(file.Size)
+	} else {
+		info, err := handler.stat(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+
+		size = int64(info.Size)
+	}
+
+	return response.NewRangeReader(size, func(offset int64) (io.ReadCloser, error) {
+		return handler.get(ctx, path, offset)
+	}), nil
+}
+
+// Put 将文件流保存到指定目录。追加分片或文件超过分片大小时，
+// 使用带有 Content-Range 的 PUT 请求分块写入，这要求远程服务端支持部分写入
+// （如 Apache mod_dav）；Cloudreve 自身的 WebDAV 服务端不支持，分片大小应设为 0
+func (handler *Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
+	defer file.Close()
+	fileInfo := file.Info()
+	isAppend := fileInfo.Mode&fsctx.Append == fsctx.Append
+
+	// 如果非 Overwrite，则检查是否有重名冲突
+	if fileInfo.Mode&fsctx.Overwrite != fsctx.Overwrite {
+		if _, err := handler.stat(ctx, fileInfo.SavePath); err == nil {
+			return errors.New("file with the same name existed or unavailable")
+		}
+	}
+
+	if err := handler.mkcolAll(ctx, path.Dir(fileInfo.SavePath)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// 中转上传的后续分片
+	if isAppend && fileInfo.AppendStart > 0 {
+		return handler.put(ctx, fileInfo.SavePath, file, int64(fileInfo.AppendStart), int64(fileInfo.Size), "*")
+	}
+
+	chunkSize := handler.Policy.OptionsSerialized.ChunkSize
+	if isAppend || chunkSize == 0 || fileInfo.Size <= chunkSize {
+		return handler.put(ctx, fileInfo.SavePath, file, 0, int64(fileInfo.Size), "")
+	}
+
+	chunks := chunk.NewChunkGroup(file, chunkSize, &backoff.ConstantBackoff{
+		Max:   model.GetIntSetting("chunk_retries", 5),
+		Sleep: chunkRetrySleep,
+	}, model.IsTrueVal(model.GetSettingByName("use_temp_chunk_buffer")))
+
+	uploadFunc := func(current *chunk.ChunkGroup, content io.Reader) error {
+		return handler.put(ctx, fileInfo.SavePath, content, current.Start(), current.Length(), fmt.Sprintf("%d", current.Total()))
+	}
+
+	for chunks.Next() {
+		if err := chunks.Process(uploadFunc); err != nil {
+			// 清除已写入的部分内容
+			if err := handler.remove(context.Background(), fileInfo.SavePath); err != nil {
+				util.Log().Warning("Failed to remove partially uploaded file %q: %s", fileInfo.SavePath, err)
+			}
+
+			return fmt.Errorf("failed to upload chunk #%d: %w", chunks.Index(), err)
+		}
+	}
+
+	return nil
+}
+
+// Delete 删除一个或多个文件，
+// 返回未删除的文件，及遇到的最后一个错误
+func (handler *Driver) Delete(ctx context.Context, files []string) ([]string, error) {
+	deleteFailed := make([]string, 0, len(files))
+	var retErr error
+
+	thumbSuffix := model.GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")
+	for _, value := range files {
+		if err := handler.remove(ctx, value); err != nil {
+			util.Log().Warning("Failed to delete file: %s", err)
+			retErr = err
+			deleteFailed = append(deleteFailed, value)
+		}
+
+		// 尝试删除文件的缩略图（如果有）
+		_ = handler.remove(ctx, value+thumbSuffix)
+	}
+
+	return deleteFailed, retErr
+}
+
+// Thumb 获取文件缩略图，缩略图由主机生成后保存在文件旁
+func (handler *Driver) Thumb(ctx context.Context, file *model.File) (*response.ContentResponse, error) {
+	if conf.SystemConfig.Mode == "master" && file.MetadataSerialized[model.ThumbStatusMetadataKey] == model.ThumbStatusNotExist {
+		return nil, driver.ErrorThumbNotExist
+	}
+
+	thumbFile, err := handler.Get(ctx, file.ThumbFile())
+	if err != nil {
+		if IsNotFound(err) {
+			err = fmt.Errorf("thumb not exist: %w (%w)", err, driver.ErrorThumbNotExist)
+		}
+
+		return nil, err
+	}
+
+	return &response.ContentResponse{
+		Redirect: false,
+		Content:  thumbFile,
+	}, nil
+}
+
+// Source 获取外链URL，与本机策略相同经由主机中转
+func (handler *Driver) Source(ctx context.Context, path string, ttl int64, isDownload bool, speed int) (string, error) {
+	return local.Driver{Policy: handler.Policy}.Source(ctx, path, ttl, isDownload, speed)
+}
+
+// Token 获取上传会话，文件经由主机中转上传
+func (handler *Driver) Token(ctx context.Context, ttl int64, uploadSession *serializer.UploadSession, file fsctx.FileHeader) (*serializer.UploadCredential, error) {
+	if _, err := handler.stat(ctx, uploadSession.SavePath); err == nil {
+		return nil, errors.New("placeholder file already exist")
+	}
+
+	return &serializer.UploadCredential{
+		SessionID: uploadSession.Key,
+		ChunkSize: handler.Policy.OptionsSerialized.ChunkSize,
+	}, nil
+}
+
+// CancelToken 取消上传凭证
+func (handler *Driver) CancelToken(ctx context.Context, uploadSession *serializer.UploadSession) error {
+	return nil
+}
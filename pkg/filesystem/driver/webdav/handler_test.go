@@ -0,0 +1,215 @@
+package webdav_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/webdav"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/response"
+	server "gitee.com/jiangjiali/cloudreve/pkg/webdav"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	cache.Init()
+	model.Init()
+	os.Exit(m.Run())
+}
+
+// newServer 启动进程内的 WebDAV 服务端，以 1 号用户的身份提供服务，
+// 文件保存在临时目录中
+func newServer(t *testing.T) *webdav.Driver {
+	root := t.TempDir()
+	dav := &server.Handler{
+		Prefix:     "/dav",
+		LockSystem: make(map[uint]server.LockSystem),
+		Mutex:      &sync.Mutex{},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		user, err := model.GetActiveUserByID(1)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		user.Policy.DirNameRule = root + "/{path}"
+
+		fs, err := filesystem.NewFileSystem(&user)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		dav.ServeHTTP(w, r, fs)
+	}))
+	t.Cleanup(ts.Close)
+
+	handler, err := webdav.NewDriver(&model.Policy{
+		Type:      "webdav",
+		Server:    ts.URL + "/dav",
+		AccessKey: "user",
+		SecretKey: "pass",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return handler
+}
+
+func putFile(handler *webdav.Driver, savePath, content string, mode fsctx.WriteMode) error {
+	return handler.Put(context.Background(), &fsctx.FileStream{
+		File:     io.NopCloser(strings.NewReader(content)),
+		Size:     uint64(len(content)),
+		SavePath: savePath,
+		Mode:     mode,
+	})
+}
+
+func readAll(a *assert.Assertions, rs response.RSCloser, err error) string {
+	if !a.NoError(err) {
+		return ""
+	}
+	defer rs.Close()
+
+	content, err := io.ReadAll(rs)
+	a.NoError(err)
+	return string(content)
+}
+
+func TestDriver_PutAndGet(t *testing.T) {
+	a := assert.New(t)
+	handler := newServer(t)
+	ctx := context.Background()
+
+	a.NoError(putFile(handler, "/webdav-put/dir/a.txt", "hello world", 0))
+	rs, err := handler.Get(ctx, "/webdav-put/dir/a.txt")
+	a.Equal("hello world", readAll(a, rs, err))
+
+	// Seek 后通过 Range 请求读取
+	rs, err = handler.Get(ctx, "/webdav-put/dir/a.txt")
+	a.NoError(err)
+	_, err = rs.Seek(6, io.SeekStart)
+	a.NoError(err)
+	a.Equal("world", readAll(a, rs, nil))
+
+	// 不允许覆盖
+	a.Error(putFile(handler, "/webdav-put/dir/a.txt", "new", 0))
+	a.NoError(putFile(handler, "/webdav-put/dir/a.txt", "new", fsctx.Overwrite))
+	rs, err = handler.Get(ctx, "/webdav-put/dir/a.txt")
+	a.Equal("new", readAll(a, rs, err))
+
+	// 文件不存在
+	_, err = handler.Get(ctx, "/webdav-put/dir/not-exist.txt")
+	a.True(webdav.IsNotFound(err))
+}
+
+func TestDriver_PutChunked(t *testing.T) {
+	a := assert.New(t)
+	handler := newServer(t)
+	handler.Policy.OptionsSerialized.ChunkSize = 4
+	_ = cache.SetSettings(map[string]string{"chunk_retries": "0"}, "setting_")
+
+	// 服务端不支持 Content-Range，分片上传失败且不留下部分内容
+	err := putFile(handler, "/webdav-chunk/big.bin", "0123456789", 0)
+	a.Error(err)
+	a.Contains(err.Error(), "does not support Content-Range")
+	_, err = handler.Get(context.Background(), "/webdav-chunk/big.bin")
+	a.True(webdav.IsNotFound(err))
+
+	// 未超过分片大小时使用单次请求
+	a.NoError(putFile(handler, "/webdav-chunk/small.bin", "0123", 0))
+	rs, err := handler.Get(context.Background(), "/webdav-chunk/small.bin")
+	a.Equal("0123", readAll(a, rs, err))
+}
+
+func TestDriver_List(t *testing.T) {
+	a := assert.New(t)
+	handler := newServer(t)
+	for _, name := range []string{"/webdav-list/a.txt", "/webdav-list/sub/b.txt", "/webdav-list/sub/deep/c.txt"} {
+		a.NoError(putFile(handler, name, name, 0))
+	}
+
+	res, err := handler.List(context.Background(), "/webdav-list", false)
+	a.NoError(err)
+	objects := make(map[string]bool)
+	for _, object := range res {
+		objects[object.RelativePath] = object.IsDir
+	}
+	a.Equal(map[string]bool{"a.txt": false, "sub": true}, objects)
+
+	res, err = handler.List(context.Background(), "webdav-list", true)
+	a.NoError(err)
+	objects = make(map[string]bool)
+	for _, object := range res {
+		objects[object.RelativePath] = object.IsDir
+		if object.RelativePath == "sub/deep/c.txt" {
+			a.EqualValues(len("/webdav-list/sub/deep/c.txt"), object.Size)
+			a.Equal("webdav-list/sub/deep/c.txt", object.Source)
+		}
+	}
+	a.Equal(map[string]bool{"a.txt": false, "sub": true, "sub/b.txt": false, "sub/deep": true, "sub/deep/c.txt": false}, objects)
+
+	_, err = handler.List(context.Background(), "/webdav-list-not-exist", false)
+	a.True(webdav.IsNotFound(err))
+}
+
+func TestDriver_DeleteAndThumb(t *testing.T) {
+	a := assert.New(t)
+	handler := newServer(t)
+	ctx := context.Background()
+	a.NoError(putFile(handler, "/webdav-delete/a.jpg", "image", 0))
+	a.NoError(putFile(handler, "/webdav-delete/a.jpg._thumb", "thumb", 0))
+	a.NoError(putFile(handler, "/webdav-delete/b.txt", "b", 0))
+
+	file := &model.File{
+		SourceName:         "/webdav-delete/a.jpg",
+		MetadataSerialized: map[string]string{model.ThumbStatusMetadataKey: model.ThumbStatusExist},
+	}
+	thumb, err := handler.Thumb(ctx, file)
+	if a.NoError(err) {
+		a.Equal("thumb", readAll(a, thumb.Content, nil))
+	}
+
+	// 删除文件时一并删除缩略图，不存在的文件不视为失败
+	failed, err := handler.Delete(ctx, []string{"/webdav-delete/a.jpg", "/webdav-delete/not-exist.txt"})
+	a.NoError(err)
+	a.Empty(failed)
+
+	_, err = handler.Thumb(ctx, file)
+	a.True(errors.Is(err, driver.ErrorThumbNotExist))
+
+	res, err := handler.List(ctx, "/webdav-delete", false)
+	a.NoError(err)
+	if a.Len(res, 1) {
+		a.Equal("b.txt", res[0].Name)
+	}
+}
+
+func TestDriver_Unauthorized(t *testing.T) {
+	a := assert.New(t)
+	handler := newServer(t)
+	handler.Policy.SecretKey = "wrong"
+
+	err := putFile(handler, "/webdav-auth/a.txt", "a", 0)
+	a.Error(err)
+}
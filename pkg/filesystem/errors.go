@@ -23,4 +23,11 @@ var (
 	ErrDBListObjects            = serializer.NewError(serializer.CodeDBError, "Failed to list object records", nil)
 	ErrDBDeleteObjects          = serializer.NewError(serializer.CodeDBError, "Failed to delete object records", nil)
 	ErrOneObjectOnly            = serializer.ParamErr("You can only copy one object at the same time", nil)
+	ErrUploadProofMismatch      = serializer.NewError(serializer.CodeUploadProofMismatch, "Proof of possession mismatch", nil)
+	ErrFolderQuotaExceeded      = serializer.NewError(serializer.CodeFolderQuotaExceeded, "Folder quota exceeded", nil)
+	ErrSharedFolderReadOnly     = serializer.NewError(serializer.CodeSharedFolderReadOnly, "Shared folder is read-only", nil)
+	ErrCrossSharedFolder        = serializer.NewError(serializer.CodeParamErr, "Objects can only be moved within the same shared folder", nil)
+	ErrInvalidSearchCursor      = serializer.NewError(serializer.CodeParamErr, "Invalid search cursor", nil)
+	ErrInvalidChangeCursor      = serializer.NewError(serializer.CodeParamErr, "Invalid change cursor", nil)
+	ErrChangeCursorExpired      = serializer.NewError(serializer.CodeChangeCursorExpired, "Change cursor expired", nil)
 )
@@ -74,6 +74,12 @@ func (fs *FileSystem) AddFile(ctx context.Context, parent *model.Folder, file fs
 	}
 
 	fs.User.Storage += newFile.Size
+
+	// 上传会话的占位文件在上传完成后再记录变更
+	if newFile.UploadSessionID == nil {
+		fs.recordUpload(&newFile)
+	}
+
 	return &newFile, nil
 }
 
@@ -98,7 +104,7 @@ func (fs *FileSystem) GetPhysicalFileContent(ctx context.Context, path string) (
 //	isText -   是否为文本文件，文本文件会忽略重定向，直接由
 //	           服务端拉取中转给用户，故会对文件大小进行限制
 func (fs *FileSystem) Preview(ctx context.Context, id uint, isText bool) (*response.ContentResponse, error) {
-	err := fs.resetFileIDIfNotExist(ctx, id)
+	err := fs.resetFileIDIfNotExist(ctx, id, true)
 	if err != nil {
 		return nil, err
 	}
@@ -150,7 +156,7 @@ func (fs *FileSystem) GetDownloadContent(ctx context.Context, id uint) (response
 
 // GetContent 获取文件内容，path为虚拟路径
 func (fs *FileSystem) GetContent(ctx context.Context, id uint) (response.RSCloser, error) {
-	err := fs.resetFileIDIfNotExist(ctx, id)
+	err := fs.resetFileIDIfNotExist(ctx, id, true)
 	if err != nil {
 		return nil, err
 	}
@@ -174,6 +180,17 @@ func (fs *FileSystem) deleteGroupedFile(ctx context.Context, files map[uint][]*m
 	thumbs := make([]string, 0)
 
 	for policyID, toBeDeletedFiles := range files {
+		// 未完成上传的占位文件归还文件收集链接的名额
+		for _, file := range toBeDeletedFiles {
+			model.ReleaseFileRequestSlot(file)
+		}
+
+		// 释放 Blob 引用，跳过仍被引用的物理文件
+		toBeDeletedFiles = releaseBlobs(policyID, toBeDeletedFiles)
+		if len(toBeDeletedFiles) == 0 {
+			continue
+		}
+
 		// 列举出需要物理删除的文件的物理路径
 		sourceNamesAll := make([]string, 0, len(toBeDeletedFiles))
 		uploadSessions := make([]*serializer.UploadSession, 0, len(toBeDeletedFiles))
@@ -242,7 +259,7 @@ func (fs *FileSystem) GroupFileByPolicy(ctx context.Context, files []model.File)
 
 // GetDownloadURL 创建文件下载链接, timeout 为数据库中存储过期时间的字段
 func (fs *FileSystem) GetDownloadURL(ctx context.Context, id uint, timeout string) (string, error) {
-	err := fs.resetFileIDIfNotExist(ctx, id)
+	err := fs.resetFileIDIfNotExist(ctx, id, true)
 	if err != nil {
 		return "", err
 	}
@@ -266,7 +283,7 @@ func (fs *FileSystem) GetDownloadURL(ctx context.Context, id uint, timeout strin
 // GetSource 获取可直接访问文件的外链地址
 func (fs *FileSystem) GetSource(ctx context.Context, fileID uint) (string, error) {
 	// 查找文件记录
-	err := fs.resetFileIDIfNotExist(ctx, fileID)
+	err := fs.resetFileIDIfNotExist(ctx, fileID, false)
 	if err != nil {
 		return "", ErrObjectNotExist.WithError(err)
 	}
@@ -323,13 +340,23 @@ func (fs *FileSystem) ResetFileIfNotExist(ctx context.Context, path string) erro
 	return fs.resetPolicyToFirstFile(ctx)
 }
 
-// ResetFileIfNotExist 重设当前目标文件为 id，如果当前目标为空
-func (fs *FileSystem) resetFileIDIfNotExist(ctx context.Context, id uint) error {
+// ResetFileIfNotExist 重设当前目标文件为 id，如果当前目标为空；
+// granted 为 true 时允许读取授权给用户的目录或团队空间中的文件
+func (fs *FileSystem) resetFileIDIfNotExist(ctx context.Context, id uint, granted bool) error {
 	// 找到文件
 	if len(fs.FileTarget) == 0 {
 		file, err := model.GetFilesByIDs([]uint{id}, fs.User.ID)
 		if err != nil || len(file) == 0 {
-			return ErrObjectNotExist
+			if !granted {
+				return ErrObjectNotExist
+			}
+
+			// 授权给用户的共享目录中的文件
+			grantedFile, ok := fs.grantedFile(id, false)
+			if !ok {
+				return ErrObjectNotExist
+			}
+			file = []model.File{*grantedFile}
 		}
 		fs.FileTarget = []model.File{file[0]}
 	}
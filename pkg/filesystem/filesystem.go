@@ -7,10 +7,14 @@ import (
 	"gitee.com/jiangjiali/cloudreve/pkg/cluster"
 	"gitee.com/jiangjiali/cloudreve/pkg/conf"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/encrypt"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/local"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/remote"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/s3"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/sftp"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/shadow/masterinslave"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/shadow/slaveinmaster"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/webdav"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
 	"github.com/gin-gonic/gin"
 	"sync"
@@ -124,17 +128,43 @@ func (fs *FileSystem) DispatchHandler() error {
 		fs.Handler = local.Driver{
 			Policy: currentPolicy,
 		}
-		return nil
 	case "remote":
 		handler, err := remote.NewDriver(currentPolicy)
 		if err != nil {
 			return err
 		}
 		fs.Handler = handler
+	case "s3":
+		handler, err := s3.NewDriver(currentPolicy)
+		if err != nil {
+			return err
+		}
+		fs.Handler = handler
+	case "sftp":
+		handler, err := sftp.NewDriver(currentPolicy)
+		if err != nil {
+			return err
+		}
+		fs.Handler = handler
+	case "webdav":
+		handler, err := webdav.NewDriver(currentPolicy)
+		if err != nil {
+			return err
+		}
+		fs.Handler = handler
 	default:
 		return ErrUnknownPolicyType
 	}
 
+	// 开启静态加密时，包装为加密适配器
+	if currentPolicy.IsEncrypted() {
+		handler, err := encrypt.NewDriver(fs.Handler, currentPolicy)
+		if err != nil {
+			return err
+		}
+		fs.Handler = handler
+	}
+
 	return nil
 }
 
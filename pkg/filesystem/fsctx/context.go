@@ -41,4 +41,12 @@ const (
 	WebDAVCtx
 	// WebDAV反代Url
 	WebDAVProxyUrlCtx
+	// ContentHashCtx 上传文件内容的 SHA-256，值为 *string，由存储适配器写入时填充
+	ContentHashCtx
+	// UploaderIDCtx 上传者的用户 ID，上传至与我共享的目录时与文件所有者不同
+	UploaderIDCtx
+	// S3ProxyUrlCtx S3 网关反代Url
+	S3ProxyUrlCtx
+	// FileRequestCtx 通过文件收集链接上传时链接的 ID
+	FileRequestCtx
 )
@@ -0,0 +1,366 @@
+package filesystem
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+)
+
+/* ============
+	 目录共享
+   ============
+*/
+
+// SharedRootName “与我共享”虚拟根目录的名称，位于用户根目录下
+const SharedRootName = "Shared with me"
+
+// SharedRootPath “与我共享”虚拟根目录的路径
+var SharedRootPath = path.Join("/", SharedRootName)
+
+// SharedFolder 虚拟根目录下的授权目录或团队空间
+type SharedFolder struct {
+	// Name 在虚拟根目录下显示的名称
+	Name string
+	// Base 所在虚拟根目录的路径
+	Base   string
+	Folder *model.Folder
+	Owner  *model.User
+
+	writable bool
+}
+
+// IsSharedPath 返回路径是否位于“与我共享”或团队空间虚拟根目录下
+func IsSharedPath(p string) bool {
+	_, ok := virtualRootOf(p)
+	return ok
+}
+
+// IsVirtualRoot 返回路径是否为虚拟根目录本身
+func IsVirtualRoot(p string) bool {
+	p = path.Clean(p)
+	return p == SharedRootPath || p == TeamRootPath
+}
+
+// virtualRootOf 返回路径所在的虚拟根目录
+func virtualRootOf(p string) (string, bool) {
+	p = path.Clean(p)
+	for _, root := range []string{SharedRootPath, TeamRootPath} {
+		if p == root || strings.HasPrefix(p, root+"/") {
+			return root, true
+		}
+	}
+
+	return "", false
+}
+
+// ListVirtualFolders 列出虚拟根目录 root 下的目录
+func (fs *FileSystem) ListVirtualFolders(root string) ([]SharedFolder, error) {
+	if root == TeamRootPath {
+		return fs.ListTeamFolders()
+	}
+
+	return fs.ListSharedFolders()
+}
+
+// ListSharedFolders 列出授权给当前用户的目录，名称重复时追加序号
+func (fs *FileSystem) ListSharedFolders() ([]SharedFolder, error) {
+	if fs.User.ID == 0 {
+		return nil, nil
+	}
+
+	grants, err := model.GetGrantsForUser(fs.User)
+	if err != nil {
+		return nil, ErrDBListObjects.WithError(err)
+	}
+
+	res := make([]SharedFolder, 0, len(grants))
+	names := make(map[string]int)
+	for i := range grants {
+		owner, err := model.GetActiveUserByID(grants[i].OwnerID)
+		if err != nil {
+			continue
+		}
+
+		folder := grants[i].SourceFolder()
+		if folder.ID == 0 || folder.TraceRoot() != nil {
+			continue
+		}
+
+		// 根目录使用所有者的昵称
+		name := folder.Name
+		if folder.ParentID == nil {
+			name = owner.Nick
+		}
+
+		res = append(res, SharedFolder{
+			Name:     uniqueName(names, name),
+			Base:     SharedRootPath,
+			Folder:   folder,
+			Owner:    &owner,
+			writable: grants[i].Writable,
+		})
+	}
+
+	return res, nil
+}
+
+// uniqueName 名称重复时追加序号
+func uniqueName(names map[string]int, name string) string {
+	names[name]++
+	if names[name] > 1 {
+		return fmt.Sprintf("%s (%d)", name, names[name])
+	}
+
+	return name
+}
+
+// ListVirtualRoot 列出虚拟根目录 root 下的目录
+func (fs *FileSystem) ListVirtualRoot(root string) ([]serializer.Object, error) {
+	folders, err := fs.ListVirtualFolders(root)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]serializer.Object, 0, len(folders))
+	for _, shared := range folders {
+		objects = append(objects, serializer.Object{
+			ID:         hashid.HashID(shared.Folder.ID, hashid.FolderID),
+			Name:       shared.Name,
+			Path:       root,
+			Type:       "dir",
+			Date:       shared.Folder.UpdatedAt,
+			CreateDate: shared.Folder.CreatedAt,
+		})
+	}
+
+	return objects, nil
+}
+
+// VirtualRootObjects 返回用户根目录下的虚拟根目录，其中没有内容的不会返回
+func (fs *FileSystem) VirtualRootObjects() []serializer.Object {
+	objects := make([]serializer.Object, 0, 2)
+	for _, root := range []string{SharedRootPath, TeamRootPath} {
+		if folders, err := fs.ListVirtualFolders(root); err == nil && len(folders) > 0 {
+			objects = append(objects, serializer.Object{
+				Name: path.Base(root),
+				Path: "/",
+				Type: "dir",
+			})
+		}
+	}
+
+	return objects
+}
+
+// ResolveSharedPath 解析虚拟根目录下的路径，返回对应的授权目录或团队空间，及其中的相对路径
+func (fs *FileSystem) ResolveSharedPath(p string) (*SharedFolder, string, error) {
+	root, ok := virtualRootOf(p)
+	if !ok {
+		return nil, "", ErrPathNotExist
+	}
+
+	rel := strings.TrimPrefix(path.Clean(p), root)
+	segments := strings.SplitN(strings.TrimPrefix(rel, "/"), "/", 2)
+	if segments[0] == "" {
+		return nil, "", ErrPathNotExist
+	}
+
+	folders, err := fs.ListVirtualFolders(root)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for i := range folders {
+		if folders[i].Name == segments[0] {
+			inner := "/"
+			if len(segments) > 1 {
+				inner = path.Join("/", segments[1])
+			}
+
+			return &folders[i], inner, nil
+		}
+	}
+
+	return nil, "", ErrPathNotExist
+}
+
+// NewFileSystem 创建授权目录所有者的文件系统，写入的文件占用所有者的容量
+func (shared *SharedFolder) NewFileSystem() (*FileSystem, error) {
+	return NewFileSystem(shared.Owner)
+}
+
+// Writable 返回授权目录是否允许写入
+func (shared *SharedFolder) Writable() bool {
+	return shared.writable
+}
+
+// Root 返回授权目录在所有者文件系统中的路径
+func (shared *SharedFolder) Root() string {
+	return path.Join(shared.Folder.Position, shared.Folder.Name)
+}
+
+// FullPath 将授权目录下的相对路径转换为所有者文件系统中的路径
+func (shared *SharedFolder) FullPath(inner string) string {
+	return path.Join(shared.Root(), inner)
+}
+
+// VirtualPath 将所有者文件系统中的路径转换为虚拟根目录下的路径
+func (shared *SharedFolder) VirtualPath(full string) string {
+	rel := strings.TrimPrefix(full, shared.Root())
+	return path.Join(shared.Base, shared.Name, rel)
+}
+
+// grantedFile 查找授权给当前用户的目录或团队空间中的文件，writable 为 true 时要求授权可写
+func (fs *FileSystem) grantedFile(id uint, writable bool) (*model.File, bool) {
+	if fs.User.ID == 0 {
+		return nil, false
+	}
+
+	files, err := model.GetFilesByIDs([]uint{id}, 0)
+	if err != nil || len(files) == 0 {
+		return nil, false
+	}
+
+	if !fs.grantedFolder(files[0].UserID, files[0].FolderID, writable) {
+		return nil, false
+	}
+
+	return &files[0], true
+}
+
+// grantedFolder 返回用户 ownerID 的目录 folderID 是否位于授权给当前用户的目录或团队空间中，
+// writable 为 true 时要求授权可写
+func (fs *FileSystem) grantedFolder(ownerID, folderID uint, writable bool) bool {
+	if grant, ok := model.GetGrantForFolder(fs.User, folderID); ok && (grant.Writable || !writable) {
+		return true
+	}
+
+	role := fs.teamRole(ownerID)
+	return role >= model.TeamEditor || (!writable && role > 0)
+}
+
+// CanWriteFile 返回当前用户是否可以修改文件，文件需属于当前用户，或位于可写的授权目录及团队空间中
+func (fs *FileSystem) CanWriteFile(file *model.File) bool {
+	return file.UserID == fs.User.ID || fs.grantedFolder(file.UserID, file.FolderID, true)
+}
+
+// GrantedFileSystem 对象不属于当前用户时，检查其是否均位于同一所有者可写的授权目录或团队空间中，
+// 并返回所有者的文件系统；授权目录及团队空间本身不能修改。对象属于当前用户时返回 nil
+func (fs *FileSystem) GrantedFileSystem(dirs, files []uint) (*FileSystem, error) {
+	if fs.User.ID == 0 || len(dirs)+len(files) == 0 {
+		return nil, nil
+	}
+
+	if len(dirs) > 0 {
+		if own, err := model.GetFoldersByIDs(dirs, fs.User.ID); err == nil && len(own) > 0 {
+			return nil, nil
+		}
+	}
+
+	if len(files) > 0 {
+		if own, err := model.GetFilesByIDs(files, fs.User.ID); err == nil && len(own) > 0 {
+			return nil, nil
+		}
+	}
+
+	// 对象的所有者及所在目录
+	owners := make([]uint, 0, len(dirs)+len(files))
+	parents := make([]uint, 0, len(dirs)+len(files))
+	for _, id := range dirs {
+		ancestors, err := model.GetFolderAncestors(id)
+		if err != nil || len(ancestors) < 2 {
+			return nil, ErrObjectNotExist
+		}
+
+		owners = append(owners, ancestors[0].OwnerID)
+		parents = append(parents, ancestors[1].ID)
+	}
+
+	if len(files) > 0 {
+		fileObjects, err := model.GetFilesByIDs(files, 0)
+		if err != nil || len(fileObjects) != len(files) {
+			return nil, ErrObjectNotExist
+		}
+
+		for _, file := range fileObjects {
+			owners = append(owners, file.UserID)
+			parents = append(parents, file.FolderID)
+		}
+	}
+
+	for i := range owners {
+		if owners[i] != owners[0] {
+			return nil, ErrObjectNotExist
+		}
+
+		if !fs.grantedFolder(owners[i], parents[i], true) {
+			if fs.grantedFolder(owners[i], parents[i], false) {
+				return nil, ErrSharedFolderReadOnly
+			}
+			return nil, ErrObjectNotExist
+		}
+	}
+
+	owner, err := model.GetActiveUserByID(owners[0])
+	if err != nil {
+		return nil, ErrObjectNotExist
+	}
+
+	return NewFileSystem(&owner)
+}
+
+// IsReservedName 返回 name 是否为目录 parent 下的保留名称，用户根目录下不能使用虚拟根目录的名称
+func IsReservedName(parent *model.Folder, name string) bool {
+	return parent.ParentID == nil && (name == SharedRootName || name == TeamRootName)
+}
+
+// isReservedChildName 返回 name 是否为当前用户目录 parentID 下的保留名称
+func (fs *FileSystem) isReservedChildName(parentID uint, name string) bool {
+	if name != SharedRootName && name != TeamRootName {
+		return false
+	}
+
+	parents, err := model.GetFoldersByIDs([]uint{parentID}, fs.User.ID)
+	return err == nil && len(parents) > 0 && IsReservedName(&parents[0], name)
+}
+
+// validateReservedNames 检查移动或复制到目录 dst 中的对象是否使用了保留名称
+func (fs *FileSystem) validateReservedNames(dst *model.Folder, dirs, files []uint) error {
+	if dst.ParentID != nil {
+		return nil
+	}
+
+	if dst.WebdavDstName != "" {
+		if IsReservedName(dst, dst.WebdavDstName) {
+			return ErrIllegalObjectName
+		}
+		return nil
+	}
+
+	names := make([]string, 0, len(dirs)+len(files))
+	if len(dirs) > 0 {
+		folders, _ := model.GetFoldersByIDs(dirs, fs.User.ID)
+		for _, folder := range folders {
+			names = append(names, folder.Name)
+		}
+	}
+
+	if len(files) > 0 {
+		fileObjects, _ := model.GetFilesByIDs(files, fs.User.ID)
+		for _, file := range fileObjects {
+			names = append(names, file.Name)
+		}
+	}
+
+	for _, name := range names {
+		if IsReservedName(dst, name) {
+			return ErrIllegalObjectName
+		}
+	}
+
+	return nil
+}
@@ -5,7 +5,6 @@ import (
 	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/cache"
 	"gitee.com/jiangjiali/cloudreve/pkg/cluster"
-	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/local"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
 	"gitee.com/jiangjiali/cloudreve/pkg/util"
@@ -89,13 +88,13 @@ func HookResetPolicy(ctx context.Context, fs *FileSystem, file fsctx.FileHeader)
 	return fs.DispatchHandler()
 }
 
-// HookValidateCapacity 验证用户容量
+// HookValidateCapacity 验证用户容量及目录配额
 func HookValidateCapacity(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
 	// 验证并扣除容量
 	if fs.User.GetRemainingCapacity() < file.Info().Size {
 		return ErrInsufficientCapacity
 	}
-	return nil
+	return fs.validateUploadQuota(ctx, file)
 }
 
 // HookValidateCapacityDiff 根据原有文件和新文件的大小验证用户容量
@@ -174,6 +173,8 @@ func GenericAfterUpdate(ctx context.Context, fs *FileSystem, newFile fsctx.FileH
 		return err
 	}
 
+	fs.recordChanges(fs.fileChange(make(map[uint]string), model.ChangeUpdate, &originFile))
+	ScheduleContentIndex(&originFile)
 	return nil
 }
 
@@ -186,6 +187,10 @@ func SlaveAfterUpload(session *serializer.UploadSession) Hook {
 
 		// 发送回调请求
 		callbackBody := serializer.UploadCallback{}
+		if hash, ok := ctx.Value(fsctx.ContentHashCtx).(*string); ok {
+			callbackBody.Hash = *hash
+		}
+
 		return cluster.RemoteCallback(session.Callback, callbackBody)
 	}
 }
@@ -200,6 +205,11 @@ func GenericAfterUpload(ctx context.Context, fs *FileSystem, fileHeader fsctx.Fi
 		return err
 	}
 
+	// 根目录下不能使用虚拟根目录的名称
+	if IsReservedName(folder, fileInfo.FileName) {
+		return ErrIllegalObjectName
+	}
+
 	// 检查文件是否存在
 	if ok, file := fs.IsChildFileExist(
 		folder,
@@ -219,6 +229,11 @@ func GenericAfterUpload(ctx context.Context, fs *FileSystem, fileHeader fsctx.Fi
 	}
 	fileHeader.SetModel(file)
 
+	// 上传会话的占位文件在上传完成后再建立索引
+	if file.UploadSessionID == nil {
+		ScheduleContentIndex(file)
+	}
+
 	return nil
 }
 
@@ -228,10 +243,15 @@ func HookClearFileHeaderSize(ctx context.Context, fs *FileSystem, fileHeader fsc
 	return nil
 }
 
+// truncater 支持截断物理文件的存储策略适配器
+type truncater interface {
+	Truncate(ctx context.Context, src string, size uint64) error
+}
+
 // HookTruncateFileTo 将物理文件截断至 size
 func HookTruncateFileTo(size uint64) Hook {
 	return func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
-		if handler, ok := fs.Handler.(local.Driver); ok {
+		if handler, ok := fs.Handler.(truncater); ok {
 			return handler.Truncate(ctx, fileHeader.Info().SavePath, size)
 		}
 
@@ -239,6 +259,32 @@ func HookTruncateFileTo(size uint64) Hook {
 	}
 }
 
+// sizer 支持查询物理文件大小的存储策略适配器
+type sizer interface {
+	Size(ctx context.Context, src string) (uint64, error)
+}
+
+// HookSyncUploadedSize 按物理文件实际写入的大小更新占位文件，写入中断时保留已写入的内容；
+// 存储策略适配器不支持查询大小时，回退到写入前的大小
+func HookSyncUploadedSize(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	fileInfo := fileHeader.Info()
+	handler, ok := fs.Handler.(sizer)
+	if !ok {
+		return HookChunkUploadFailed(ctx, fs, fileHeader)
+	}
+
+	size, err := handler.Size(ctx, fileInfo.SavePath)
+	if err != nil {
+		return err
+	}
+
+	if size > fileInfo.AppendStart+fileInfo.Size {
+		size = fileInfo.AppendStart + fileInfo.Size
+	}
+
+	return fileInfo.Model.(*model.File).UpdateSize(size)
+}
+
 // HookChunkUploadFinished 单个分片上传结束后
 func HookChunkUploaded(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
 	fileInfo := fileHeader.Info()
@@ -260,7 +306,13 @@ func HookPopPlaceholderToFile(picInfo string) Hook {
 	return func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
 		fileInfo := fileHeader.Info()
 		fileModel := fileInfo.Model.(*model.File)
-		return fileModel.PopChunkToFile(fileInfo.LastModified, picInfo)
+		if err := fileModel.PopChunkToFile(fileInfo.LastModified, picInfo); err != nil {
+			return err
+		}
+
+		fs.recordUpload(fileModel)
+		ScheduleContentIndex(fileModel)
+		return nil
 	}
 }
 
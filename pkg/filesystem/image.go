@@ -26,7 +26,7 @@ import (
 // GetThumb 获取文件的缩略图
 func (fs *FileSystem) GetThumb(ctx context.Context, id uint) (*response.ContentResponse, error) {
 	// 根据 ID 查找文件
-	err := fs.resetFileIDIfNotExist(ctx, id)
+	err := fs.resetFileIDIfNotExist(ctx, id, true)
 	if err != nil {
 		return nil, ErrObjectNotExist
 	}
@@ -132,9 +132,9 @@ func (fs *FileSystem) generateThumbnail(ctx context.Context, file *model.File) e
 	}
 	defer source.Close()
 
-	// Provide file source path for local policy files
+	// Provide file source path for local policy files, encrypted files can only be read from the decrypted stream
 	src := ""
-	if conf.SystemConfig.Mode == "slave" || file.GetPolicy().Type == "local" {
+	if conf.SystemConfig.Mode == "slave" || (file.GetPolicy().Type == "local" && !file.GetPolicy().IsEncrypted()) {
 		src = file.SourceName
 	}
 
@@ -32,10 +32,21 @@ func (fs *FileSystem) Rename(ctx context.Context, dir, file []uint, new string)
 			return ErrPathNotExist
 		}
 
+		if fs.isReservedChildName(fileObject[0].FolderID, new) {
+			return ErrIllegalObjectName
+		}
+
+		change := fs.fileChange(make(map[uint]string), model.ChangeRename, &fileObject[0])
 		err = fileObject[0].Rename(new)
 		if err != nil {
 			return ErrFileExisted
 		}
+
+		change.OldName, change.Name = change.Name, new
+		fs.recordChanges(change)
+
+		// 扩展名可能改变，重新建立内容索引
+		ScheduleContentIndex(&fileObject[0])
 		return nil
 	}
 
@@ -45,10 +56,18 @@ func (fs *FileSystem) Rename(ctx context.Context, dir, file []uint, new string)
 			return ErrPathNotExist
 		}
 
+		if folderObject[0].ParentID != nil && fs.isReservedChildName(*folderObject[0].ParentID, new) {
+			return ErrIllegalObjectName
+		}
+
+		change := fs.folderChange(make(map[uint]string), model.ChangeRename, &folderObject[0])
 		err = folderObject[0].Rename(new)
 		if err != nil {
 			return ErrFileExisted
 		}
+
+		change.OldName, change.Name = change.Name, new
+		fs.recordChanges(change)
 		return nil
 	}
 
@@ -69,11 +88,32 @@ func (fs *FileSystem) Copy(ctx context.Context, dirs, files []uint, src, dst str
 	// 记录复制的文件的总容量
 	var newUsedStorage uint64
 
+	// 校验目的目录配额，每次只复制一个目录
+	if model.HasFolderQuota(fs.User.ID) {
+		copiedDirs := dirs
+		if len(copiedDirs) > 1 {
+			copiedDirs = copiedDirs[:1]
+		}
+
+		size, err := fs.objectsSize(srcFolder, copiedDirs, files)
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+
+		if err := fs.ValidateFolderQuota(dstFolder.ID, size); err != nil {
+			return err
+		}
+	}
+
 	// 设置webdav目标名
 	if dstName, ok := ctx.Value(fsctx.WebdavDstName).(string); ok {
 		dstFolder.WebdavDstName = dstName
 	}
 
+	if err := fs.validateReservedNames(dstFolder, dirs, files); err != nil {
+		return err
+	}
+
 	// 复制目录
 	if len(dirs) > 0 {
 		subFileSizes, err := srcFolder.CopyFolderTo(dirs[0], dstFolder)
@@ -95,9 +135,45 @@ func (fs *FileSystem) Copy(ctx context.Context, dirs, files []uint, src, dst str
 	// 扣除容量
 	fs.User.IncreaseStorageWithoutCheck(newUsedStorage)
 
+	fs.recordCopied(dirs, files, dstFolder)
 	return nil
 }
 
+// recordCopied 记录复制到 dstFolder 中的顶层对象的变更
+func (fs *FileSystem) recordCopied(dirs, files []uint, dstFolder *model.Folder) {
+	paths := make(map[uint]string)
+	changes := make([]model.Change, 0, len(files)+1)
+
+	if len(dirs) > 0 {
+		if folders, err := model.GetFoldersByIDs(dirs[:1], fs.User.ID); err == nil && len(folders) > 0 {
+			name := folders[0].Name
+			if dstFolder.WebdavDstName != "" {
+				name = dstFolder.WebdavDstName
+			}
+
+			if copied, err := dstFolder.GetChild(name); err == nil {
+				changes = append(changes, fs.folderChange(paths, model.ChangeCreate, copied))
+			}
+		}
+	}
+
+	if len(files) > 0 {
+		fileModels, _ := model.GetFilesByIDs(files, fs.User.ID)
+		for _, file := range fileModels {
+			name := file.Name
+			if dstFolder.WebdavDstName != "" {
+				name = dstFolder.WebdavDstName
+			}
+
+			if ok, copied := fs.IsChildFileExist(dstFolder, name); ok {
+				changes = append(changes, fs.fileChange(paths, model.ChangeCreate, copied))
+			}
+		}
+	}
+
+	fs.recordChanges(changes...)
+}
+
 // Move 移动文件和目录, 将id列表dirs和files从src移动至dst
 func (fs *FileSystem) Move(ctx context.Context, dirs, files []uint, src, dst string) error {
 	// 获取目的目录
@@ -108,11 +184,29 @@ func (fs *FileSystem) Move(ctx context.Context, dirs, files []uint, src, dst str
 		return ErrPathNotExist
 	}
 
+	// 校验目的目录配额，源目录与目的目录共同的配额目录不受影响
+	if model.HasFolderQuota(fs.User.ID) {
+		size, err := fs.objectsSize(srcFolder, dirs, files)
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+
+		if err := fs.ValidateFolderQuota(dstFolder.ID, size, srcFolder.ID); err != nil {
+			return err
+		}
+	}
+
 	// 设置webdav目标名
 	if dstName, ok := ctx.Value(fsctx.WebdavDstName).(string); ok {
 		dstFolder.WebdavDstName = dstName
 	}
 
+	if err := fs.validateReservedNames(dstFolder, dirs, files); err != nil {
+		return err
+	}
+
+	changes := fs.moveChanges(dirs, files, srcFolder, dstFolder)
+
 	// 处理目录及子文件移动
 	err := srcFolder.MoveFolderTo(dirs, dstFolder)
 	if err != nil {
@@ -125,21 +219,54 @@ func (fs *FileSystem) Move(ctx context.Context, dirs, files []uint, src, dst str
 		return ErrFileExisted.WithError(err)
 	}
 
-	// 移动文件
-
+	fs.recordChanges(changes...)
 	return err
 }
 
+// moveChanges 构建从 srcFolder 移动到 dstFolder 的对象的变更记录，需在移动前调用
+func (fs *FileSystem) moveChanges(dirs, files []uint, srcFolder, dstFolder *model.Folder) []model.Change {
+	paths := make(map[uint]string)
+	changes := make([]model.Change, 0, len(dirs)+len(files))
+
+	if len(dirs) > 0 {
+		folders, _ := model.GetFoldersByIDs(dirs, fs.User.ID)
+		for i := range folders {
+			if folders[i].ParentID != nil && *folders[i].ParentID == srcFolder.ID {
+				changes = append(changes, fs.folderChange(paths, model.ChangeMove, &folders[i]))
+			}
+		}
+	}
+
+	if len(files) > 0 {
+		fileModels, _ := model.GetFilesByIDs(files, fs.User.ID)
+		for i := range fileModels {
+			if fileModels[i].FolderID == srcFolder.ID {
+				changes = append(changes, fs.fileChange(paths, model.ChangeMove, &fileModels[i]))
+			}
+		}
+	}
+
+	dstPath := fs.folderPath(paths, dstFolder.ID)
+	for i := range changes {
+		changes[i].OldName, changes[i].OldPath = changes[i].Name, changes[i].Path
+		changes[i].Path = dstPath
+		if dstFolder.WebdavDstName != "" {
+			changes[i].Name = dstFolder.WebdavDstName
+		}
+
+		// 同一目录下改名
+		if srcFolder.ID == dstFolder.ID {
+			changes[i].Type = model.ChangeRename
+			changes[i].OldPath = ""
+		}
+	}
+
+	return changes
+}
+
 // Delete 递归删除对象, force 为 true 时强制删除文件记录，忽略物理删除是否成功;
 // unlink 为 true 时只删除虚拟文件系统的文件记录，不删除物理文件。
 func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force, unlink bool) error {
-	// 已删除的文件ID
-	var deletedFiles = make([]*model.File, 0, len(fs.FileTarget))
-	// 删除失败的文件的父目录ID
-
-	// 所有文件的ID
-	var allFiles = make([]*model.File, 0, len(fs.FileTarget))
-
 	// 列出要删除的目录
 	if len(dirs) > 0 {
 		err := fs.ListDeleteDirs(ctx, dirs)
@@ -156,12 +283,29 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force, unl
 		}
 	}
 
+	changes := fs.deleteChanges()
+	defer fs.recordDeleted(changes)
+
+	return fs.deleteTargets(ctx, force, unlink)
+}
+
+// deleteTargets 删除已列出的目标目录和文件
+func (fs *FileSystem) deleteTargets(ctx context.Context, force, unlink bool) error {
+	// 已删除的文件ID
+	var deletedFiles = make([]*model.File, 0, len(fs.FileTarget))
+
+	// 所有文件的ID
+	var allFiles = make([]*model.File, 0, len(fs.FileTarget))
+
 	// 去除待删除文件中包含软连接的部分
 	filesToBeDelete, err := model.RemoveFilesWithSoftLinks(fs.FileTarget)
 	if err != nil {
 		return ErrDBListObjects.WithError(err)
 	}
 
+	// 去重存储的文件由 Blob 引用计数决定是否删除物理文件
+	filesToBeDelete = append(filesToBeDelete, blobFiles(fs.FileTarget)...)
+
 	// 根据存储策略将文件分组
 	policyGroup := fs.GroupFileByPolicy(ctx, filesToBeDelete)
 
@@ -169,6 +313,10 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force, unl
 	failed := make(map[uint][]string)
 	if !unlink {
 		failed = fs.deleteGroupedFile(ctx, policyGroup)
+	} else {
+		for policyID, files := range policyGroup {
+			releaseBlobs(policyID, files)
+		}
 	}
 
 	// 整理删除结果
@@ -202,6 +350,15 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force, unl
 
 	model.DeleteShareBySourceIDs(deletedFileIDs, false)
 
+	// 删除文件的历史版本
+	versions, err := model.GetVersionsByFileIDs(deletedFileIDs)
+	if err == nil {
+		err = fs.deleteVersions(ctx, versions, unlink)
+	}
+	if err != nil {
+		util.Log().Warning("Failed to delete versions of deleted files: %s", err)
+	}
+
 	// 如果文件全部删除成功，继续删除目录
 	if len(deletedFiles) == len(allFiles) {
 		var allFolderIDs = make([]uint, 0, len(fs.DirTarget))
@@ -213,8 +370,9 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force, unl
 			return ErrDBDeleteObjects.WithError(err)
 		}
 
-		// 删除目录记录对应的分享记录
+		// 删除目录记录对应的分享记录及共享授权
 		model.DeleteShareBySourceIDs(allFolderIDs, true)
+		model.DeleteGrantsByFolderIDs(allFolderIDs)
 	}
 
 	if notDeleted := len(fs.FileTarget) - len(deletedFiles); notDeleted > 0 {
@@ -431,6 +589,11 @@ func (fs *FileSystem) CreateDirectory(ctx context.Context, fullPath string) (*mo
 		return nil, ErrFileExisted
 	}
 
+	// 根目录下不能使用虚拟根目录的名称
+	if IsReservedName(parent, dir) {
+		return nil, ErrIllegalObjectName
+	}
+
 	// 创建目录
 	newFolder := model.Folder{
 		Name:     dir,
@@ -443,6 +606,7 @@ func (fs *FileSystem) CreateDirectory(ctx context.Context, fullPath string) (*mo
 		return nil, fmt.Errorf("failed to create folder: %w", err)
 	}
 
+	fs.recordChanges(fs.folderChange(make(map[uint]string), model.ChangeCreate, &newFolder))
 	return &newFolder, nil
 }
 
@@ -0,0 +1,117 @@
+package filesystem
+
+import (
+	"context"
+	"path"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+)
+
+/* ============
+	 目录配额
+   ============
+*/
+
+// ValidateFolderQuota 校验向目录 folderID 写入 size 大小的内容后，目录自身及上级目录是否超出配额，
+// exclude 目录及其上级目录不做校验，用于同一配额目录内的移动
+func (fs *FileSystem) ValidateFolderQuota(folderID uint, size uint64, exclude ...uint) error {
+	if size == 0 || !model.HasFolderQuota(fs.User.ID) {
+		return nil
+	}
+
+	excluded := make(map[uint]bool)
+	for _, id := range exclude {
+		ancestors, err := model.GetFolderAncestors(id)
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+
+		for _, folder := range ancestors {
+			excluded[folder.ID] = true
+		}
+	}
+
+	ancestors, err := model.GetFolderAncestors(folderID)
+	if err != nil {
+		return ErrPathNotExist.WithError(err)
+	}
+
+	for _, folder := range ancestors {
+		if folder.Quota > 0 && !excluded[folder.ID] && folder.Size+size > folder.Quota {
+			return ErrFolderQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
+// validateUploadQuota 校验上传的文件是否超出所在目录的配额
+func (fs *FileSystem) validateUploadQuota(ctx context.Context, file fsctx.FileHeader) error {
+	if !model.HasFolderQuota(fs.User.ID) {
+		return nil
+	}
+
+	fileInfo := file.Info()
+	size := fileInfo.Size
+
+	// 上传会话的占位文件
+	if placeholder, ok := fileInfo.Model.(*model.File); ok && placeholder != nil {
+		return fs.ValidateFolderQuota(placeholder.FolderID, size)
+	}
+
+	// 覆盖更新只校验增加的大小
+	if originFile, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
+		if size <= originFile.Size {
+			return nil
+		}
+
+		return fs.ValidateFolderQuota(originFile.FolderID, size-originFile.Size)
+	}
+
+	if folder := fs.nearestFolder(fileInfo.VirtualPath); folder != nil {
+		return fs.ValidateFolderQuota(folder.ID, size)
+	}
+
+	return nil
+}
+
+// nearestFolder 查找路径对应的目录，目录不存在时返回最近的已存在的上级目录
+func (fs *FileSystem) nearestFolder(virtualPath string) *model.Folder {
+	if virtualPath == "" {
+		return nil
+	}
+
+	for p := path.Clean(virtualPath); ; p = path.Dir(p) {
+		if exist, folder := fs.IsPathExist(p); exist {
+			return folder
+		}
+
+		if p == "/" || p == "." {
+			return nil
+		}
+	}
+}
+
+// objectsSize 计算目录 srcFolder 下的子目录 dirs 及文件 files 的总大小
+func (fs *FileSystem) objectsSize(srcFolder *model.Folder, dirs, files []uint) (uint64, error) {
+	size, err := model.TreeSize(dirs, fs.User.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(files) > 0 {
+		fileModels, err := model.GetFilesByIDs(files, fs.User.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, file := range fileModels {
+			if file.FolderID == srcFolder.ID {
+				size += file.Size
+			}
+		}
+	}
+
+	return size, nil
+}
@@ -0,0 +1,81 @@
+package response
+
+import (
+	"errors"
+	"io"
+)
+
+// RangeOpener 从 offset 处开始打开远程数据流
+type RangeOpener func(offset int64) (io.ReadCloser, error)
+
+// rangeReader 支持 Seek 的远程数据流，读取时按当前位置发起 Range 请求
+type rangeReader struct {
+	open   RangeOpener
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+// NewRangeReader 创建支持 Seek 的远程数据流，size 为数据流总大小
+func NewRangeReader(size int64, open RangeOpener) RSCloser {
+	return &rangeReader{
+		open: open,
+		size: size,
+	}
+}
+
+// Read 实现 io.Reader，首次读取或 Seek 后重新打开数据流
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		body, err := r.open(r.offset)
+		if err != nil {
+			return 0, err
+		}
+
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+// Seek 实现 io.Seeker
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if abs != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+
+	r.offset = abs
+	return abs, nil
+}
+
+// Close 实现 io.Closer
+func (r *rangeReader) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+
+	return nil
+}
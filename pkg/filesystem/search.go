@@ -0,0 +1,141 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"path"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+)
+
+// SearchObjects 按条件搜索文件和目录，返回结果及下一页的游标，游标为空表示没有更多结果。
+// 限定了根目录时只在根目录下搜索
+func (fs *FileSystem) SearchObjects(ctx context.Context, opts *model.SearchOptions, cursor string) ([]serializer.Object, string, error) {
+	opts.UserID = fs.User.ID
+	if err := setSearchCursor(opts, cursor); err != nil {
+		return nil, "", err
+	}
+
+	if fs.Root != nil {
+		folders, err := model.GetRecursiveChildFolder([]uint{fs.Root.ID}, fs.User.ID, true)
+		if err != nil {
+			return nil, "", ErrDBListObjects.WithError(err)
+		}
+
+		opts.Folders = make([]uint, 0, len(folders))
+		for _, folder := range folders {
+			opts.Folders = append(opts.Folders, folder.ID)
+		}
+	}
+
+	folders, files, next, err := model.Search(opts)
+	if err != nil {
+		return nil, "", ErrDBListObjects.WithError(err)
+	}
+
+	// 批量解析父目录路径
+	paths := make(map[uint]string)
+	objects := make([]serializer.Object, 0, len(folders)+len(files))
+	for i := range folders {
+		parent := fs.folderPath(paths, *folders[i].ParentID)
+		objects = append(objects, fs.listObjects(ctx, parent, nil, folders[i:i+1], nil)...)
+	}
+
+	fs.SetTargetFile(&files)
+	for i := range files {
+		parent := fs.folderPath(paths, files[i].FolderID)
+		objects = append(objects, fs.listObjects(ctx, parent, files[i:i+1], nil, nil)...)
+	}
+
+	if next == nil {
+		return objects, "", nil
+	}
+
+	return objects, encodeSearchCursor(next), nil
+}
+
+// folderPath 获取目录的完整路径，已解析的目录及其上级目录会记录在 paths 中
+func (fs *FileSystem) folderPath(paths map[uint]string, id uint) string {
+	if p, ok := paths[id]; ok {
+		return p
+	}
+
+	ancestors, err := model.GetFolderAncestors(id)
+	if err != nil || len(ancestors) == 0 {
+		return "/"
+	}
+
+	// 从根目录开始逐级拼接
+	current := "/"
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if ancestors[i].ParentID != nil {
+			current = path.Join(current, ancestors[i].Name)
+		}
+		paths[ancestors[i].ID] = current
+	}
+
+	return paths[id]
+}
+
+// ListPage 分页列出目录内容，目录排在文件之前，返回下一页的游标，游标为空表示没有更多内容
+func (fs *FileSystem) ListPage(ctx context.Context, dirPath string, pathProcessor func(string) string, opts *model.SearchOptions, cursor string) ([]serializer.Object, string, error) {
+	isExist, folder := fs.IsPathExist(dirPath)
+	if !isExist {
+		return nil, "", ErrPathNotExist
+	}
+	fs.SetTargetDir(&[]model.Folder{*folder})
+
+	opts.UserID = fs.User.ID
+	opts.Folders = []uint{folder.ID}
+	if err := setSearchCursor(opts, cursor); err != nil {
+		return nil, "", err
+	}
+
+	folders, files, next, err := model.Search(opts)
+	if err != nil {
+		return nil, "", ErrDBListObjects.WithError(err)
+	}
+
+	objects := fs.listObjects(ctx, path.Join(folder.Position, folder.Name), files, folders, pathProcessor)
+	if next == nil {
+		return objects, "", nil
+	}
+
+	return objects, encodeSearchCursor(next), nil
+}
+
+// setSearchCursor 解析游标并设置到搜索条件中
+func setSearchCursor(opts *model.SearchOptions, cursor string) error {
+	if cursor == "" {
+		return nil
+	}
+
+	decoded, err := decodeSearchCursor(cursor)
+	if err != nil {
+		return ErrInvalidSearchCursor.WithError(err)
+	}
+
+	opts.Cursor = decoded
+	return nil
+}
+
+func encodeSearchCursor(cursor *model.SearchCursor) string {
+	res, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(res)
+}
+
+func decodeSearchCursor(cursor string) (*model.SearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var res model.SearchCursor
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,69 @@
+package filesystem
+
+import (
+	"path"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+)
+
+/* ============
+	 团队空间
+   ============
+*/
+
+// TeamRootName 团队空间虚拟根目录的名称，位于用户根目录下
+const TeamRootName = "Team drives"
+
+// TeamRootPath 团队空间虚拟根目录的路径
+var TeamRootPath = path.Join("/", TeamRootName)
+
+// ListTeamFolders 列出当前用户可以访问的团队空间，名称重复时追加序号
+func (fs *FileSystem) ListTeamFolders() ([]SharedFolder, error) {
+	if fs.User.ID == 0 || fs.User.OptionsSerialized.TeamID > 0 {
+		return nil, nil
+	}
+
+	teams, err := model.GetTeamsForUser(fs.User)
+	if err != nil {
+		return nil, ErrDBListObjects.WithError(err)
+	}
+
+	res := make([]SharedFolder, 0, len(teams))
+	names := make(map[string]int)
+	for i := range teams {
+		role := teams[i].RoleOf(fs.User)
+		if role == 0 {
+			continue
+		}
+
+		account, err := teams[i].Account()
+		if err != nil {
+			continue
+		}
+
+		folder, err := account.Root()
+		if err != nil {
+			continue
+		}
+
+		res = append(res, SharedFolder{
+			Name:     uniqueName(names, teams[i].Name),
+			Base:     TeamRootPath,
+			Folder:   folder,
+			Owner:    &account,
+			writable: role >= model.TeamEditor,
+		})
+	}
+
+	return res, nil
+}
+
+// teamRole 返回当前用户在用户 ownerID 对应团队中的角色，ownerID 不是团队账户时返回 0
+func (fs *FileSystem) teamRole(ownerID uint) int {
+	team, err := model.GetTeamByUserID(ownerID)
+	if err != nil {
+		return 0
+	}
+
+	return team.RoleOf(fs.User)
+}
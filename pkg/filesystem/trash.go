@@ -0,0 +1,267 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+/* ============
+	 回收站
+   ============
+*/
+
+// Trash 将对象移入回收站，用户组未开启回收站时直接删除
+func (fs *FileSystem) Trash(ctx context.Context, dirs, files []uint) error {
+	if fs.User.Group.OptionsSerialized.TrashRetention <= 0 {
+		return fs.Delete(ctx, dirs, files, false, false)
+	}
+
+	var (
+		failed int
+		// 未完成上传的文件直接删除
+		placeholders = make([]uint, 0)
+	)
+
+	if len(dirs) > 0 {
+		folders, err := model.GetFoldersByIDs(dirs, fs.User.ID)
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+
+		for i := range folders {
+			if err := fs.trashFolder(&folders[i]); err != nil {
+				util.Log().Warning("Failed to move folder %q to trash: %s", folders[i].Name, err)
+				failed++
+			}
+		}
+	}
+
+	if len(files) > 0 {
+		fileModels, err := model.GetFilesByIDs(files, fs.User.ID)
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+
+		for i := range fileModels {
+			if fileModels[i].UploadSessionID != nil {
+				placeholders = append(placeholders, fileModels[i].ID)
+				continue
+			}
+
+			if err := fs.trashFile(&fileModels[i]); err != nil {
+				util.Log().Warning("Failed to move file %q to trash: %s", fileModels[i].Name, err)
+				failed++
+			}
+		}
+	}
+
+	if len(placeholders) > 0 {
+		if err := fs.Delete(ctx, []uint{}, placeholders, false, false); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return serializer.NewError(
+			serializer.CodeNotFullySuccess,
+			fmt.Sprintf("Failed to delete %d object(s).", failed),
+			nil,
+		)
+	}
+
+	return nil
+}
+
+// trashFolder 将目录及其所有子对象移入回收站
+func (fs *FileSystem) trashFolder(folder *model.Folder) error {
+	// 根目录不能删除
+	if folder.ParentID == nil {
+		return ErrRootProtected
+	}
+
+	if err := folder.TraceRoot(); err != nil {
+		return err
+	}
+
+	folders, err := model.GetRecursiveChildFolder([]uint{folder.ID}, fs.User.ID, true)
+	if err != nil {
+		return err
+	}
+
+	files, err := model.GetChildFilesOfFolders(&folders)
+	if err != nil {
+		return err
+	}
+
+	if _, err = model.TrashFolder(folder, folder.Position, folders, files); err != nil {
+		return err
+	}
+
+	fs.recordChanges(model.Change{
+		Type:     model.ChangeDelete,
+		IsFolder: true,
+		ObjectID: folder.ID,
+		Name:     folder.Name,
+		Path:     folder.Position,
+	})
+	return nil
+}
+
+// trashFile 将文件移入回收站
+func (fs *FileSystem) trashFile(file *model.File) error {
+	parents, err := model.GetFoldersByIDs([]uint{file.FolderID}, fs.User.ID)
+	if err != nil || len(parents) == 0 {
+		return ErrPathNotExist.WithError(err)
+	}
+
+	if err := parents[0].TraceRoot(); err != nil {
+		return err
+	}
+
+	parentPath := path.Join(parents[0].Position, parents[0].Name)
+	if _, err = model.TrashFile(file, parentPath); err != nil {
+		return err
+	}
+
+	fs.recordChanges(model.Change{
+		Type:     model.ChangeDelete,
+		ObjectID: file.ID,
+		Name:     file.Name,
+		Path:     parentPath,
+		Size:     file.Size,
+	})
+	return nil
+}
+
+// RestoreTrash 还原回收站中的对象。原目录已不存在时按原路径重新创建；
+// 原位置已有同名对象时，rename 为 true 则自动重命名，否则返回冲突错误
+func (fs *FileSystem) RestoreTrash(ctx context.Context, ids []uint, rename bool) error {
+	items, err := model.GetTrashByIDs(ids, fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	for i := range items {
+		if err := fs.restoreTrash(ctx, &items[i], rename); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreTrash 还原单个回收站记录
+func (fs *FileSystem) restoreTrash(ctx context.Context, item *model.Trash, rename bool) error {
+	var parent *model.Folder
+	if parents, err := model.GetFoldersByIDs([]uint{item.ParentID}, fs.User.ID); err == nil && len(parents) > 0 {
+		parent = &parents[0]
+	} else {
+		if parent, err = fs.CreateDirectory(ctx, item.Path); err != nil {
+			return err
+		}
+	}
+
+	name := item.Name
+	for i := 1; fs.isNameTaken(parent, name); i++ {
+		if !rename {
+			return ErrFileExisted.WithError(fmt.Errorf("%q already exists", path.Join(item.Path, name)))
+		}
+
+		name = conflictName(item.Name, item.IsFolder, i)
+	}
+
+	folders, files, err := item.Objects()
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	if err := item.Restore(name, parent.ID, folders, files); err != nil {
+		return serializer.NewError(serializer.CodeDBError, "Failed to restore object", err)
+	}
+
+	change := model.Change{
+		Type:     model.ChangeCreate,
+		IsFolder: item.IsFolder,
+		ObjectID: item.ObjectID,
+		Name:     name,
+		Path:     fs.folderPath(make(map[uint]string), parent.ID),
+	}
+	if !item.IsFolder {
+		change.Size = item.Size
+	}
+
+	fs.recordChanges(change)
+	return nil
+}
+
+// isNameTaken 返回目录下是否已有名为 name 的文件或目录
+func (fs *FileSystem) isNameTaken(parent *model.Folder, name string) bool {
+	if ok, _ := fs.IsChildFileExist(parent, name); ok {
+		return true
+	}
+
+	_, err := parent.GetChild(name)
+	return err == nil
+}
+
+// conflictName 为重名的对象生成第 i 个候选名称，文件保留扩展名
+func conflictName(name string, isFolder bool, i int) string {
+	ext := ""
+	if !isFolder {
+		ext = path.Ext(name)
+	}
+
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), i, ext)
+}
+
+// DeleteTrash 彻底删除回收站中的对象，同时删除物理文件并释放容量
+func (fs *FileSystem) DeleteTrash(ctx context.Context, ids []uint) error {
+	items, err := model.GetTrashByIDs(ids, fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	var lastErr error
+	for i := range items {
+		folders, files, err := items[i].Objects()
+		if err != nil {
+			lastErr = ErrDBListObjects.WithError(err)
+			continue
+		}
+
+		fs.CleanTargets()
+		fs.SetTargetDir(&folders)
+		fs.SetTargetFile(&files)
+		if err := fs.deleteTargets(ctx, false, false); err != nil {
+			lastErr = err
+			continue
+		}
+
+		if err := items[i].Delete(); err != nil {
+			lastErr = ErrDBDeleteObjects.WithError(err)
+		}
+	}
+
+	return lastErr
+}
+
+// EmptyTrash 清空回收站
+func (fs *FileSystem) EmptyTrash(ctx context.Context) error {
+	items, err := model.GetTrashByUser(fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	return fs.DeleteTrash(ctx, ids)
+}
@@ -26,6 +26,8 @@ const (
 	UploadSessionCtx         = "uploadSession"
 	UserCtx                  = "user"
 	UploadSessionCachePrefix = "callback_"
+	// UploadChallengeCachePrefix 秒传持有证明挑战的缓存前缀
+	UploadChallengeCachePrefix = "upload_challenge_"
 )
 
 // Upload 上传文件
@@ -49,6 +51,11 @@ func (fs *FileSystem) Upload(ctx context.Context, file *fsctx.FileStream) (err e
 		file.SavePath = savePath
 	}
 
+	// 存储适配器写入时计算的内容哈希
+	if _, ok := ctx.Value(fsctx.ContentHashCtx).(*string); !ok {
+		ctx = context.WithValue(ctx, fsctx.ContentHashCtx, new(string))
+	}
+
 	// 保存文件
 	if file.Mode&fsctx.Nop != fsctx.Nop {
 		// 处理客户端未完成上传时，关闭连接
@@ -159,6 +166,12 @@ func (fs *FileSystem) CreateUploadSession(ctx context.Context, file *fsctx.FileS
 		LastModified:   file.LastModified,
 		CallbackSecret: util.RandStringRunes(32),
 	}
+	if uploader, ok := ctx.Value(fsctx.UploaderIDCtx).(uint); ok {
+		uploadSession.UploaderID = uploader
+	}
+	if requestID, ok := ctx.Value(fsctx.FileRequestCtx).(uint); ok {
+		uploadSession.FileRequestID = requestID
+	}
 
 	// 获取上传凭证
 	credential, err := fs.Handler.Token(ctx, int64(callBackSessionTTL), uploadSession, file)
@@ -186,6 +199,20 @@ func (fs *FileSystem) CreateUploadSession(ctx context.Context, file *fsctx.FileS
 		return nil, err
 	}
 
+	// 客户端提供了内容哈希时生成秒传挑战，无论 Blob 是否存在均返回，避免仅凭哈希探测
+	if hash, ok := ctx.Value(fsctx.ContentHashCtx).(*string); ok && *hash != "" && fileSize > 0 && fs.Policy.IsDedupEnabled() {
+		challenge, err := newUploadChallenge(*hash, fileSize)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := cache.Set(UploadChallengeCachePrefix+callbackKey, *challenge, callBackSessionTTL); err != nil {
+			return nil, err
+		}
+
+		credential.Challenge = challenge
+	}
+
 	// 补全上传凭证其他信息
 	credential.Expires = time.Now().Add(time.Duration(callBackSessionTTL) * time.Second).Unix()
 
@@ -210,6 +237,7 @@ func (fs *FileSystem) UploadFromStream(ctx context.Context, file *fsctx.FileStre
 		fs.Use("BeforeUpload", HookValidateCapacity)
 		fs.Use("AfterUploadCanceled", HookDeleteTempFile)
 		fs.Use("AfterUpload", GenericAfterUpload)
+		fs.Use("AfterUpload", HookCommitBlob)
 		fs.Use("AfterValidateFailed", HookDeleteTempFile)
 	}
 	fs.Lock.Unlock()
@@ -0,0 +1,213 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/response"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+/* ============
+	 历史版本
+   ============
+*/
+
+// UseVersioning 用户组开启历史版本时，为覆盖更新注入钩子：新的内容写入新的物理路径，
+// 更新完成后原有的内容保存为历史版本。未开启时返回 false
+func (fs *FileSystem) UseVersioning(ctx context.Context, originFile *model.File, file *fsctx.FileStream) bool {
+	if fs.User.Group.OptionsSerialized.MaxVersions <= 0 || originFile.Size == 0 {
+		return false
+	}
+
+	// 新的内容与原有内容使用相同的存储策略
+	fs.Policy = originFile.GetPolicy()
+	savePath := fs.GenerateSavePath(ctx, file)
+
+	// 未开启自动重命名时，生成的路径会与原有内容相同
+	if !fs.Policy.AutoRename {
+		savePath = path.Join(path.Dir(savePath), util.RandStringRunes(8)+"_"+path.Base(savePath))
+	}
+
+	origin := *originFile
+	originFile.SourceName = savePath
+	file.Mode &= ^fsctx.Overwrite
+
+	// 原有内容继续占用容量，需要校验新文件的完整大小
+	fs.Use("BeforeUpload", HookValidateCapacity)
+	fs.Use("AfterUpload", HookUpdateSourceName)
+	fs.Use("AfterUpload", HookCreateVersion(&origin))
+	fs.Use("AfterUploadFailed", HookDeleteTempFile)
+	fs.Use("AfterUploadCanceled", HookDeleteTempFile)
+	fs.Use("AfterValidateFailed", HookDeleteTempFile)
+	return true
+}
+
+// HookCreateVersion 文件内容更新后，将原有的内容保存为历史版本
+func HookCreateVersion(origin *model.File) Hook {
+	return func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+		if err := model.NewFileVersion(origin).Create(); err != nil {
+			return err
+		}
+
+		// 清理过程会切换存储策略，完成后恢复
+		policy, handler := fs.Policy, fs.Handler
+		fs.pruneVersions(ctx, origin.ID)
+		fs.Policy, fs.Handler = policy, handler
+		return nil
+	}
+}
+
+// pruneVersions 按用户组设置删除文件超出数量或已过期的历史版本
+func (fs *FileSystem) pruneVersions(ctx context.Context, fileID uint) {
+	versions, err := model.GetVersionsByFile(fileID)
+	if err != nil {
+		util.Log().Warning("Failed to list versions of file %d: %s", fileID, err)
+		return
+	}
+
+	option := fs.User.Group.OptionsSerialized
+	expired := make([]model.FileVersion, 0)
+	for i, version := range versions {
+		if i >= option.MaxVersions ||
+			(option.VersionRetention > 0 && version.CreatedAt.AddDate(0, 0, option.VersionRetention).Before(time.Now())) {
+			expired = append(expired, version)
+		}
+	}
+
+	if err := fs.deleteVersions(ctx, expired, false); err != nil {
+		util.Log().Warning("Failed to delete expired versions of file %d: %s", fileID, err)
+	}
+}
+
+// GetVersionContent 获取文件历史版本的内容
+func (fs *FileSystem) GetVersionContent(ctx context.Context, fileID, versionID uint) (response.RSCloser, error) {
+	file, version, err := fs.getVersion(fileID, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	file.SourceName = version.SourceName
+	file.Size = version.Size
+	file.UpdatedAt = version.CreatedAt
+	fs.FileTarget = []model.File{*file}
+	return fs.GetDownloadContent(ctx, 0)
+}
+
+// RestoreVersion 将文件内容还原为历史版本，当前的内容保存为最新的历史版本
+func (fs *FileSystem) RestoreVersion(ctx context.Context, fileID, versionID uint) error {
+	file, version, err := fs.getVersion(fileID, versionID)
+	if err != nil {
+		return err
+	}
+
+	if err := version.Restore(file); err != nil {
+		return serializer.NewError(serializer.CodeDBError, "Failed to restore version", err)
+	}
+
+	fs.recordChanges(fs.fileChange(make(map[uint]string), model.ChangeUpdate, file))
+	ScheduleContentIndex(file)
+	return nil
+}
+
+// DeleteVersions 删除文件的历史版本
+func (fs *FileSystem) DeleteVersions(ctx context.Context, fileID uint, ids []uint) error {
+	versions, err := model.GetVersionsByIDs(ids, fileID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	// 只能删除自己的版本，回收站中文件的版本同样可以删除
+	owned := make([]model.FileVersion, 0, len(versions))
+	for _, version := range versions {
+		if version.UserID == fs.User.ID {
+			owned = append(owned, version)
+		}
+	}
+
+	return fs.deleteVersions(ctx, owned, false)
+}
+
+// getFile 查找当前用户的文件
+func (fs *FileSystem) getFile(fileID uint) (*model.File, error) {
+	files, err := model.GetFilesByIDs([]uint{fileID}, fs.User.ID)
+	if err != nil || len(files) == 0 {
+		return nil, ErrObjectNotExist
+	}
+
+	return &files[0], nil
+}
+
+// getVersion 查找当前用户的文件及其历史版本
+func (fs *FileSystem) getVersion(fileID, versionID uint) (*model.File, *model.FileVersion, error) {
+	file, err := fs.getFile(fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	versions, err := model.GetVersionsByIDs([]uint{versionID}, fileID)
+	if err != nil || len(versions) == 0 {
+		return nil, nil, ErrObjectNotExist
+	}
+
+	return file, &versions[0], nil
+}
+
+// deleteVersions 删除历史版本记录，unlink 为 false 时同时删除不再被引用的物理文件，
+// 物理文件删除失败的版本记录会被保留
+func (fs *FileSystem) deleteVersions(ctx context.Context, versions []model.FileVersion, unlink bool) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	// 去重存储的版本由 Blob 引用计数决定是否删除物理文件
+	sources := make([]model.File, 0, len(versions))
+	for _, version := range versions {
+		if !model.IsBlobSource(version.PolicyID, version.SourceName) && version.IsSourceShared() {
+			continue
+		}
+
+		sources = append(sources, model.File{
+			UserID:     version.UserID,
+			PolicyID:   version.PolicyID,
+			SourceName: version.SourceName,
+			Size:       version.Size,
+		})
+	}
+
+	policyGroup := fs.GroupFileByPolicy(ctx, sources)
+	failed := make(map[uint][]string)
+	if !unlink {
+		failed = fs.deleteGroupedFile(ctx, policyGroup)
+	} else {
+		for policyID, files := range policyGroup {
+			releaseBlobs(policyID, files)
+		}
+	}
+
+	deleted := make([]model.FileVersion, 0, len(versions))
+	for _, version := range versions {
+		if !util.ContainsString(failed[version.PolicyID], version.SourceName) {
+			deleted = append(deleted, version)
+		}
+	}
+
+	if err := model.DeleteVersions(deleted); err != nil {
+		return ErrDBDeleteObjects.WithError(err)
+	}
+
+	if notDeleted := len(versions) - len(deleted); notDeleted > 0 {
+		return serializer.NewError(
+			serializer.CodeNotFullySuccess,
+			fmt.Sprintf("Failed to delete %d version(s).", notDeleted),
+			nil,
+		)
+	}
+
+	return nil
+}
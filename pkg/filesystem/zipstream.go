@@ -0,0 +1,646 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/response"
+)
+
+/* ==================
+     流式 ZIP 归档
+   ==================
+*/
+
+const (
+	zipLocalHeaderLen     = 30
+	zipDescriptorLen      = 16
+	zipDescriptor64Len    = 24
+	zipCentralHeaderLen   = 46
+	zipEndLen             = 22
+	zipEnd64Len           = 56
+	zipEnd64LocatorLen    = 20
+	zipUint16Max          = 0xffff
+	zipUint32Max          = 0xffffffff
+	zipVersion20          = 20
+	zipVersion45          = 45
+	zipCreatorUnix        = 3 << 8
+	zipFlagDataDescriptor = 0x8
+	zipFlagUTF8           = 0x800
+	zipExtraZip64         = 0x0001
+
+	archiveCRCCachePrefix = "archive_crc_"
+
+	// archiveRangeReadLimit 响应 Range 请求时，为计算校验和可额外读取的数据量上限
+	archiveRangeReadLimit = 64 << 20
+)
+
+var (
+	// ErrArchiveSourceChanged 归档中文件的实际大小与记录不一致
+	ErrArchiveSourceChanged = errors.New("file size in archive does not match its record")
+)
+
+// 归档中的片段类型
+const (
+	segmentHeader = iota
+	segmentData
+	segmentDescriptor
+	segmentDirectory
+)
+
+type archiveEntry struct {
+	file   *model.File
+	name   string
+	offset int64
+	crc    uint32
+	hasCRC bool
+}
+
+type archiveSegment struct {
+	kind   int
+	entry  int
+	offset int64
+	size   int64
+}
+
+// ArchiveStream 以仅存储方式按需生成的 ZIP 归档，总大小可预先计算，
+// 支持随机读取，以便直接输出到响应并响应 Range 请求
+type ArchiveStream struct {
+	fs       *FileSystem
+	ctx      context.Context
+	entries  []archiveEntry
+	segments []archiveSegment
+	size     int64
+	modTime  time.Time
+	pos      int64
+
+	// 当前打开的源文件
+	reader       response.RSCloser
+	readerEntry  int
+	readerOffset int64
+	readerCRC    hash.Hash32
+
+	// 已生成的中央目录
+	directory []byte
+}
+
+// NewArchiveStream 为给定目录和文件创建流式 ZIP 归档
+func (fs *FileSystem) NewArchiveStream(ctx context.Context, folderIDs, fileIDs []uint) (*ArchiveStream, error) {
+	folders, files, err := fs.archiveTargets(ctx, folderIDs, fileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &ArchiveStream{
+		fs:          fs,
+		ctx:         ctx,
+		readerEntry: -1,
+	}
+
+	for i := 0; i < len(folders); i++ {
+		if err := stream.addFolder(&folders[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i < len(files); i++ {
+		stream.addFile(&files[i])
+	}
+
+	stream.layout()
+	return stream, nil
+}
+
+func (stream *ArchiveStream) addFolder(folder *model.Folder) error {
+	if folder.UpdatedAt.After(stream.modTime) {
+		stream.modTime = folder.UpdatedAt
+	}
+
+	subFiles, err := folder.GetChildFiles()
+	if err != nil {
+		return ErrDBListObjects
+	}
+	for i := 0; i < len(subFiles); i++ {
+		stream.addFile(&subFiles[i])
+	}
+
+	subFolders, err := folder.GetChildFolder()
+	if err != nil {
+		return ErrDBListObjects
+	}
+	for i := 0; i < len(subFolders); i++ {
+		if err := stream.addFolder(&subFolders[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (stream *ArchiveStream) addFile(file *model.File) {
+	if file.UpdatedAt.After(stream.modTime) {
+		stream.modTime = file.UpdatedAt
+	}
+
+	entry := archiveEntry{
+		file: file,
+		name: path.Join(file.Position, file.Name),
+	}
+	if crc, ok := cache.Get(archiveCRCKey(file)); ok {
+		entry.crc, entry.hasCRC = crc.(uint32)
+	}
+	stream.entries = append(stream.entries, entry)
+}
+
+// layout 计算各片段在归档中的位置
+func (stream *ArchiveStream) layout() {
+	offset := int64(0)
+	add := func(kind, entry int, size int64) {
+		stream.segments = append(stream.segments, archiveSegment{kind: kind, entry: entry, offset: offset, size: size})
+		offset += size
+	}
+
+	for i := range stream.entries {
+		entry := &stream.entries[i]
+		entry.offset = offset
+		add(segmentHeader, i, int64(zipLocalHeaderLen+len(entry.name)+entry.localExtraLen()))
+		add(segmentData, i, int64(entry.file.Size))
+		if entry.isZip64() {
+			add(segmentDescriptor, i, zipDescriptor64Len)
+		} else {
+			add(segmentDescriptor, i, zipDescriptorLen)
+		}
+	}
+
+	directorySize := int64(0)
+	for i := range stream.entries {
+		directorySize += int64(zipCentralHeaderLen + len(stream.entries[i].name) + stream.entries[i].centralExtraLen())
+	}
+	directorySize += zipEndLen
+	if stream.directoryIsZip64(offset, directorySize) {
+		directorySize += zipEnd64Len + zipEnd64LocatorLen
+	}
+	add(segmentDirectory, -1, directorySize)
+
+	stream.size = offset
+}
+
+// Size 返回归档的总大小
+func (stream *ArchiveStream) Size() int64 {
+	return stream.size
+}
+
+// ModTime 返回归档中所有对象的最后修改时间
+func (stream *ArchiveStream) ModTime() time.Time {
+	return stream.modTime
+}
+
+// ETag 返回归档内容的标识，归档中任一文件变化后随之改变
+func (stream *ArchiveStream) ETag() string {
+	h := sha1.New()
+	for _, entry := range stream.entries {
+		fmt.Fprintf(h, "%d:%d:%d:%s\n", entry.file.ID, entry.file.Size, entry.file.UpdatedAt.UnixNano(), entry.name)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:12]) + `"`
+}
+
+// AllowRange 返回是否响应给定的 Range 请求。尚未得知校验和的文件须完整读取后
+// 才能输出其数据描述符及中央目录，从头顺序输出时可顺带计算，但从中途开始的请求
+// 需要额外读取起点之前的文件；额外读取量超过 archiveRangeReadLimit 时不支持 Range，
+// 应输出完整归档。续传时已输出部分的校验和通常已被缓存，不受此限制
+func (stream *ArchiveStream) AllowRange(header string) bool {
+	specs, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return true
+	}
+
+	start := stream.size
+	for _, spec := range strings.Split(specs, ",") {
+		first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+		if !ok {
+			return true
+		}
+
+		var (
+			offset int64
+			err    error
+		)
+		if first == "" {
+			// 后缀范围，读取末尾 last 字节
+			offset, err = strconv.ParseInt(last, 10, 64)
+			offset = stream.size - offset
+		} else {
+			offset, err = strconv.ParseInt(first, 10, 64)
+		}
+		if err != nil {
+			return true
+		}
+
+		if offset < start {
+			start = offset
+		}
+	}
+
+	cost := int64(0)
+	for i := range stream.entries {
+		entry := &stream.entries[i]
+		dataOffset := entry.offset + int64(zipLocalHeaderLen+len(entry.name)+entry.localExtraLen())
+		if !entry.hasCRC && dataOffset < start {
+			cost += int64(entry.file.Size)
+		}
+	}
+
+	return cost <= archiveRangeReadLimit
+}
+
+// Seek 设定下次读取的位置
+func (stream *ArchiveStream) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += stream.pos
+	case io.SeekEnd:
+		offset += stream.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	stream.pos = offset
+	return offset, nil
+}
+
+// Read 从当前位置读取归档内容
+func (stream *ArchiveStream) Read(p []byte) (int, error) {
+	if stream.pos >= stream.size {
+		return 0, io.EOF
+	}
+
+	select {
+	case <-stream.ctx.Done():
+		return 0, ErrClientCanceled
+	default:
+	}
+
+	i := sort.Search(len(stream.segments), func(i int) bool {
+		return stream.segments[i].offset+stream.segments[i].size > stream.pos
+	})
+	segment := stream.segments[i]
+	skip := stream.pos - segment.offset
+	if int64(len(p)) > segment.size-skip {
+		p = p[:segment.size-skip]
+	}
+
+	var (
+		n   int
+		err error
+	)
+	switch segment.kind {
+	case segmentHeader:
+		n = copy(p, stream.localHeader(segment.entry)[skip:])
+	case segmentData:
+		n, err = stream.readData(segment.entry, skip, p)
+	case segmentDescriptor:
+		var descriptor []byte
+		if descriptor, err = stream.descriptor(segment.entry); err == nil {
+			n = copy(p, descriptor[skip:])
+		}
+	case segmentDirectory:
+		var directory []byte
+		if directory, err = stream.centralDirectory(); err == nil {
+			n = copy(p, directory[skip:])
+		}
+	}
+
+	stream.pos += int64(n)
+	return n, err
+}
+
+// Close 关闭打开的源文件
+func (stream *ArchiveStream) Close() error {
+	if stream.reader == nil {
+		return nil
+	}
+
+	reader := stream.reader
+	stream.reader = nil
+	stream.readerEntry = -1
+	return reader.Close()
+}
+
+// readData 从文件的 offset 处读取内容，从头顺序读完整个文件时顺带计算校验和
+func (stream *ArchiveStream) readData(i int, offset int64, p []byte) (int, error) {
+	if stream.readerEntry != i || stream.readerOffset != offset {
+		if err := stream.openEntry(i, offset); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := stream.reader.Read(p)
+	if stream.readerCRC != nil {
+		stream.readerCRC.Write(p[:n])
+	}
+	stream.readerOffset += int64(n)
+
+	entry := &stream.entries[i]
+	if err == io.EOF {
+		if stream.readerOffset < int64(entry.file.Size) {
+			return n, ErrArchiveSourceChanged
+		}
+		err = nil
+	}
+
+	if stream.readerOffset == int64(entry.file.Size) && stream.readerCRC != nil && !entry.hasCRC {
+		stream.setCRC(i, stream.readerCRC.Sum32())
+	}
+	return n, err
+}
+
+// openEntry 打开归档中的文件并定位到 offset
+func (stream *ArchiveStream) openEntry(i int, offset int64) error {
+	stream.Close()
+
+	reader, err := stream.open(i)
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		if _, err := reader.Seek(offset, io.SeekStart); err != nil {
+			reader.Close()
+			return err
+		}
+	}
+
+	stream.reader = reader
+	stream.readerEntry = i
+	stream.readerOffset = offset
+	stream.readerCRC = nil
+	if offset == 0 && !stream.entries[i].hasCRC {
+		stream.readerCRC = crc32.NewIEEE()
+	}
+	return nil
+}
+
+func (stream *ArchiveStream) open(i int) (response.RSCloser, error) {
+	file := stream.entries[i].file
+	stream.fs.Policy = file.GetPolicy()
+	if err := stream.fs.DispatchHandler(); err != nil {
+		return nil, err
+	}
+
+	return stream.fs.Handler.Get(
+		context.WithValue(stream.ctx, fsctx.FileModelCtx, *file),
+		file.SourceName,
+	)
+}
+
+// crc 返回文件的校验和，尚未得知时读取整个文件计算
+func (stream *ArchiveStream) crc(i int) (uint32, error) {
+	entry := &stream.entries[i]
+	if entry.hasCRC || entry.file.Size == 0 {
+		return entry.crc, nil
+	}
+
+	reader, err := stream.open(i)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	h := crc32.NewIEEE()
+	n, err := io.Copy(h, reader)
+	if err != nil {
+		return 0, err
+	}
+	if n != int64(entry.file.Size) {
+		return 0, ErrArchiveSourceChanged
+	}
+
+	stream.setCRC(i, h.Sum32())
+	return entry.crc, nil
+}
+
+// setCRC 记录文件的校验和，缓存以供续传时使用
+func (stream *ArchiveStream) setCRC(i int, crc uint32) {
+	entry := &stream.entries[i]
+	entry.crc = crc
+	entry.hasCRC = true
+	cache.Set(archiveCRCKey(entry.file), crc, model.GetIntSetting("archive_timeout", 600))
+}
+
+func archiveCRCKey(file *model.File) string {
+	return fmt.Sprintf("%s%d_%d", archiveCRCCachePrefix, file.ID, file.UpdatedAt.UnixNano())
+}
+
+// localHeader 生成文件的本地文件头，校验和由其后的数据描述符给出
+func (stream *ArchiveStream) localHeader(i int) []byte {
+	entry := &stream.entries[i]
+	modTime, modDate := timeToMsDosTime(entry.file.UpdatedAt)
+	size := uint32(entry.file.Size)
+	if entry.isZip64() {
+		size = zipUint32Max
+	}
+
+	b := make(zipBuffer, 0, zipLocalHeaderLen+len(entry.name)+entry.localExtraLen())
+	b = b.uint32(0x04034b50).
+		uint16(entry.version()).
+		uint16(zipFlagDataDescriptor | zipFlagUTF8).
+		uint16(0). // 仅存储
+		uint16(modTime).
+		uint16(modDate).
+		uint32(0).
+		uint32(size).
+		uint32(size).
+		uint16(uint16(len(entry.name))).
+		uint16(uint16(entry.localExtraLen()))
+	b = append(b, entry.name...)
+	if entry.isZip64() {
+		b = b.uint16(zipExtraZip64).uint16(16).uint64(entry.file.Size).uint64(entry.file.Size)
+	}
+
+	return b
+}
+
+// descriptor 生成文件的数据描述符
+func (stream *ArchiveStream) descriptor(i int) ([]byte, error) {
+	crc, err := stream.crc(i)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &stream.entries[i]
+	b := make(zipBuffer, 0, zipDescriptor64Len)
+	b = b.uint32(0x08074b50).uint32(crc)
+	if entry.isZip64() {
+		b = b.uint64(entry.file.Size).uint64(entry.file.Size)
+	} else {
+		b = b.uint32(uint32(entry.file.Size)).uint32(uint32(entry.file.Size))
+	}
+	return b, nil
+}
+
+// centralDirectory 生成中央目录及结束记录
+func (stream *ArchiveStream) centralDirectory() ([]byte, error) {
+	if stream.directory != nil {
+		return stream.directory, nil
+	}
+
+	segment := stream.segments[len(stream.segments)-1]
+	b := make(zipBuffer, 0, segment.size)
+	for i := range stream.entries {
+		crc, err := stream.crc(i)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &stream.entries[i]
+		modTime, modDate := timeToMsDosTime(entry.file.UpdatedAt)
+		size, offset := uint32(entry.file.Size), uint32(entry.offset)
+		if entry.isZip64() {
+			size = zipUint32Max
+		}
+		if entry.offset >= zipUint32Max {
+			offset = zipUint32Max
+		}
+
+		b = b.uint32(0x02014b50).
+			uint16(zipCreatorUnix | entry.version()).
+			uint16(entry.version()).
+			uint16(zipFlagDataDescriptor | zipFlagUTF8).
+			uint16(0).
+			uint16(modTime).
+			uint16(modDate).
+			uint32(crc).
+			uint32(size).
+			uint32(size).
+			uint16(uint16(len(entry.name))).
+			uint16(uint16(entry.centralExtraLen())).
+			uint16(0).
+			uint16(0).
+			uint16(0).
+			uint32(0100644 << 16).
+			uint32(offset)
+		b = append(b, entry.name...)
+		if entry.centralExtraLen() > 0 {
+			b = b.uint16(zipExtraZip64).uint16(uint16(entry.centralExtraLen() - 4))
+			if entry.isZip64() {
+				b = b.uint64(entry.file.Size).uint64(entry.file.Size)
+			}
+			if entry.offset >= zipUint32Max {
+				b = b.uint64(uint64(entry.offset))
+			}
+		}
+	}
+
+	directoryOffset := segment.offset
+	directorySize := int64(len(b))
+	records, size, offset := uint16(len(stream.entries)), uint32(directorySize), uint32(directoryOffset)
+	if stream.directoryIsZip64(directoryOffset, segment.size) {
+		b = b.uint32(0x06064b50).
+			uint64(zipEnd64Len - 12).
+			uint16(zipCreatorUnix | zipVersion45).
+			uint16(zipVersion45).
+			uint32(0).
+			uint32(0).
+			uint64(uint64(len(stream.entries))).
+			uint64(uint64(len(stream.entries))).
+			uint64(uint64(directorySize)).
+			uint64(uint64(directoryOffset))
+		b = b.uint32(0x07064b50).
+			uint32(0).
+			uint64(uint64(directoryOffset + directorySize)).
+			uint32(1)
+		records, size, offset = zipUint16Max, zipUint32Max, zipUint32Max
+	}
+
+	b = b.uint32(0x06054b50).
+		uint16(0).
+		uint16(0).
+		uint16(records).
+		uint16(records).
+		uint32(size).
+		uint32(offset).
+		uint16(0)
+
+	stream.directory = b
+	return b, nil
+}
+
+// directoryIsZip64 返回中央目录是否需要 ZIP64 结束记录
+func (stream *ArchiveStream) directoryIsZip64(offset, size int64) bool {
+	return len(stream.entries) >= zipUint16Max || offset >= zipUint32Max || size >= zipUint32Max
+}
+
+func (entry *archiveEntry) isZip64() bool {
+	return entry.file.Size >= zipUint32Max
+}
+
+func (entry *archiveEntry) version() uint16 {
+	if entry.isZip64() || entry.offset >= zipUint32Max {
+		return zipVersion45
+	}
+	return zipVersion20
+}
+
+func (entry *archiveEntry) localExtraLen() int {
+	if entry.isZip64() {
+		return 20
+	}
+	return 0
+}
+
+func (entry *archiveEntry) centralExtraLen() int {
+	size := 0
+	if entry.isZip64() {
+		size += 16
+	}
+	if entry.offset >= zipUint32Max {
+		size += 8
+	}
+	if size > 0 {
+		size += 4
+	}
+	return size
+}
+
+// zipBuffer 以小端序追加写入 ZIP 结构
+type zipBuffer []byte
+
+func (b zipBuffer) uint16(v uint16) zipBuffer {
+	return binary.LittleEndian.AppendUint16(b, v)
+}
+
+func (b zipBuffer) uint32(v uint32) zipBuffer {
+	return binary.LittleEndian.AppendUint32(b, v)
+}
+
+func (b zipBuffer) uint64(v uint64) zipBuffer {
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+// timeToMsDosTime 将时间转换为 MS-DOS 格式的时间和日期
+func timeToMsDosTime(t time.Time) (uint16, uint16) {
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, t.Location())
+	}
+	fTime := uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	fDate := uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	return fTime, fDate
+}
@@ -0,0 +1,296 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/response"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	cache.Init()
+	model.Init()
+	os.Exit(m.Run())
+}
+
+// fakeDriver 按需生成文件内容的存储驱动，不占用实际空间，可模拟超过 4 GiB 的文件
+type fakeDriver struct {
+	driver.Handler
+	files map[string]int64
+	// opened 记录每个文件被打开的次数
+	opened map[string]int
+}
+
+// fakeByte 返回文件 offset 处的内容
+func fakeByte(name string, offset int64) byte {
+	return byte(offset%251) ^ name[0]
+}
+
+func (d *fakeDriver) Get(ctx context.Context, path string) (response.RSCloser, error) {
+	size, ok := d.files[path]
+	if !ok {
+		return nil, errors.New("not exist")
+	}
+
+	d.opened[path]++
+	return &fakeReader{name: path, size: size}, nil
+}
+
+type fakeReader struct {
+	name string
+	size int64
+	pos  int64
+}
+
+func (r *fakeReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.size-r.pos {
+		p = p[:r.size-r.pos]
+	}
+	for i := range p {
+		p[i] = fakeByte(r.name, r.pos+int64(i))
+	}
+	r.pos += int64(len(p))
+	return len(p), nil
+}
+
+func (r *fakeReader) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart {
+		return 0, errors.New("unsupported whence")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+func (r *fakeReader) Close() error {
+	return nil
+}
+
+// readerAt 通过 Seek 和 Read 随机读取归档，供 archive/zip 解析
+type readerAt struct {
+	stream *ArchiveStream
+}
+
+func (r readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := r.stream.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.stream, p)
+}
+
+var (
+	// fakeFileIDs 文件名对应的文件 ID，同名文件在各归档中使用相同的 ID，以共享缓存的校验和
+	fakeFileIDs = make(map[string]uint)
+	fakeModTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func fakeFile(d *fakeDriver, name string) *model.File {
+	if _, ok := fakeFileIDs[name]; !ok {
+		fakeFileIDs[name] = uint(len(fakeFileIDs) + 1)
+	}
+
+	return &model.File{
+		Model:      gorm.Model{ID: fakeFileIDs[name], UpdatedAt: fakeModTime},
+		Name:       name,
+		Size:       uint64(d.files[name]),
+		SourceName: name,
+		Policy:     model.Policy{Model: gorm.Model{ID: 1}, Type: "mock"},
+	}
+}
+
+// clearArchiveCRC 删除文件已缓存的校验和
+func clearArchiveCRC(d *fakeDriver, names ...string) error {
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, archiveCRCKey(fakeFile(d, name)))
+	}
+	return cache.Deletes(keys, "")
+}
+
+// newTestArchive 按 names 的顺序以 d 中的文件创建归档
+func newTestArchive(d *fakeDriver, names ...string) *ArchiveStream {
+	fs := &FileSystem{Handler: d}
+	stream := &ArchiveStream{
+		fs:          fs,
+		ctx:         context.Background(),
+		readerEntry: -1,
+	}
+
+	for _, name := range names {
+		stream.addFile(fakeFile(d, name))
+	}
+
+	stream.layout()
+	return stream
+}
+
+func newFakeDriver(files map[string]int64) *fakeDriver {
+	return &fakeDriver{files: files, opened: make(map[string]int)}
+}
+
+// assertArchive 使用 archive/zip 解析归档，检查各文件的内容及校验和
+func assertArchive(a *assert.Assertions, content []byte, files map[string]int64) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if !a.NoError(err) {
+		return
+	}
+
+	a.Len(reader.File, len(files))
+	for _, f := range reader.File {
+		size, ok := files[f.Name]
+		if !a.True(ok, f.Name) {
+			continue
+		}
+		a.Equal(zip.Store, f.Method)
+		a.EqualValues(size, f.UncompressedSize64)
+
+		rc, err := f.Open()
+		if !a.NoError(err) {
+			continue
+		}
+		// 读取到末尾时 archive/zip 会核对数据描述符中的校验和
+		data, err := io.ReadAll(rc)
+		a.NoError(err, f.Name)
+		rc.Close()
+
+		expected := make([]byte, size)
+		(&fakeReader{name: f.Name, size: size}).Read(expected)
+		a.True(bytes.Equal(expected, data), f.Name)
+	}
+}
+
+func TestArchiveStream_Read(t *testing.T) {
+	a := assert.New(t)
+	files := map[string]int64{"read-a.txt": 100 << 10, "read-empty.txt": 0, "read-b.bin": 1<<20 + 3}
+	stream := newTestArchive(newFakeDriver(files), "read-a.txt", "read-empty.txt", "read-b.bin")
+
+	content, err := io.ReadAll(stream)
+	a.NoError(err)
+	a.EqualValues(stream.Size(), len(content))
+	assertArchive(a, content, files)
+}
+
+func TestArchiveStream_RangeResume(t *testing.T) {
+	a := assert.New(t)
+	files := map[string]int64{"resume-a.txt": 300 << 10, "resume-b.txt": 200 << 10}
+	expected, err := io.ReadAll(newTestArchive(newFakeDriver(files), "resume-a.txt", "resume-b.txt"))
+	a.NoError(err)
+
+	for name, cached := range map[string]bool{"cached": true, "uncached": false} {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+			d := newFakeDriver(files)
+			a.NoError(clearArchiveCRC(d, "resume-a.txt", "resume-b.txt"))
+
+			// 第一次下载在 resume-b.txt 的中间中断
+			first := newTestArchive(d, "resume-a.txt", "resume-b.txt")
+			entry := &first.entries[1]
+			mid := entry.offset + int64(zipLocalHeaderLen+len(entry.name)) + 123<<10
+			head := make([]byte, mid)
+			_, err := io.ReadFull(first, head)
+			a.NoError(err)
+			first.Close()
+
+			if !cached {
+				a.NoError(clearArchiveCRC(d, "resume-a.txt"))
+			}
+
+			// 以新的归档响应 Range 请求续传
+			second := newTestArchive(d, "resume-a.txt", "resume-b.txt")
+			a.True(second.AllowRange("bytes=" + strconv.FormatInt(mid, 10) + "-"))
+			_, err = second.Seek(mid, io.SeekStart)
+			a.NoError(err)
+			tail, err := io.ReadAll(second)
+			a.NoError(err)
+
+			content := append(head, tail...)
+			a.True(bytes.Equal(expected, content))
+			assertArchive(a, content, files)
+
+			// resume-a.txt 的校验和已缓存时续传不需要重新读取，
+			// resume-b.txt 需从中断处读取，并完整读取一次计算校验和
+			if cached {
+				a.Equal(1, d.opened["resume-a.txt"])
+			} else {
+				a.Equal(2, d.opened["resume-a.txt"])
+			}
+			a.Equal(3, d.opened["resume-b.txt"])
+		})
+	}
+}
+
+func TestArchiveStream_Zip64(t *testing.T) {
+	a := assert.New(t)
+	const bigSize = 4<<30 + 10
+	files := map[string]int64{"zip64-a.txt": 1 << 10, "zip64-big.bin": bigSize, "zip64-c.txt": 2 << 10}
+	stream := newTestArchive(newFakeDriver(files), "zip64-a.txt", "zip64-big.bin", "zip64-c.txt")
+
+	// 大文件的校验和预先缓存，避免测试中读取 4 GiB 数据
+	const bigCRC = 0x12345678
+	stream.setCRC(1, bigCRC)
+	a.Greater(stream.entries[2].offset, int64(zipUint32Max))
+
+	reader, err := zip.NewReader(readerAt{stream}, stream.Size())
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.Len(reader.File, 3) {
+		return
+	}
+	for i, name := range []string{"zip64-a.txt", "zip64-big.bin", "zip64-c.txt"} {
+		f := reader.File[i]
+		a.Equal(name, f.Name)
+		a.EqualValues(files[name], f.UncompressedSize64)
+		offset, err := f.DataOffset()
+		a.NoError(err)
+		a.Equal(stream.entries[i].offset+int64(zipLocalHeaderLen+len(name)+stream.entries[i].localExtraLen()), offset)
+	}
+
+	big := reader.File[1]
+	a.EqualValues(bigCRC, big.CRC32)
+	a.Equal(uint32(zipUint32Max), big.UncompressedSize)
+
+	// 读取大文件 4 GiB 边界附近的内容
+	offset, _ := big.DataOffset()
+	p := make([]byte, 16)
+	_, err = readerAt{stream}.ReadAt(p, offset+bigSize-int64(len(p)))
+	a.NoError(err)
+	for i := range p {
+		a.Equal(fakeByte("zip64-big.bin", bigSize-int64(len(p))+int64(i)), p[i])
+	}
+
+	// 位于 4 GiB 之后的文件可完整读出并通过校验
+	for _, f := range []*zip.File{reader.File[0], reader.File[2]} {
+		rc, err := f.Open()
+		if !a.NoError(err) {
+			continue
+		}
+		data, err := io.ReadAll(rc)
+		a.NoError(err)
+		rc.Close()
+		a.Len(data, int(files[f.Name]))
+		for i := range data {
+			if data[i] != fakeByte(f.Name, int64(i)) {
+				a.Fail("content mismatch", f.Name)
+				break
+			}
+		}
+	}
+}
@@ -16,6 +16,12 @@ const (
 	TagID           // 标签ID
 	PolicyID        // 存储策略ID
 	SourceLinkID
+	TrashID       // 回收站ID
+	VersionID     // 历史版本ID
+	GrantID       // 目录共享授权ID
+	TeamID        // 团队空间ID
+	FileRequestID // 文件收集链接ID
+	RecipientID   // 分享接收者ID
 )
 
 var (
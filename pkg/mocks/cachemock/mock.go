@@ -28,6 +28,11 @@ func (c CacheClientMock) Delete(keys []string, prefix string) error {
 	return c.Called(keys, prefix).Error(0)
 }
 
+func (c CacheClientMock) Incr(key string, ttl int) (int, error) {
+	args := c.Called(key, ttl)
+	return args.Int(0), args.Error(1)
+}
+
 func (c CacheClientMock) Persist(path string) error {
 	return c.Called(path).Error(0)
 }
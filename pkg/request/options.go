@@ -27,6 +27,7 @@ type options struct {
 	tpsLimiterToken string
 	tps             float64
 	tpsBurst        int
+	transport       http.RoundTripper
 }
 
 type optionFunc func(*options)
@@ -57,6 +58,13 @@ func WithTimeout(t time.Duration) Option {
 	})
 }
 
+// WithTransport 设置请求使用的传输层
+func WithTransport(transport http.RoundTripper) Option {
+	return optionFunc(func(o *options) {
+		o.transport = transport
+	})
+}
+
 // WithContext 设置请求上下文
 func WithContext(c context.Context) Option {
 	return optionFunc(func(o *options) {
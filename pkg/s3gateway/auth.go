@@ -0,0 +1,362 @@
+package s3gateway
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/s3"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+const (
+	// maxClockSkew 允许的客户端时钟偏差
+	maxClockSkew = 15 * time.Minute
+	// maxPresignExpires 预签名 URL 的最长有效期，单位秒
+	maxPresignExpires = 604800
+	// maxChunkSize aws-chunked 编码中单个分块的最大长度
+	maxChunkSize = 16 << 20
+	// unsignedStreamingPayload 不对分块签名、只附带校验和尾部的 aws-chunked 编码
+	unsignedStreamingPayload = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+)
+
+// credential 请求携带的签名凭证
+type credential struct {
+	AccessKey     string
+	Region        string
+	Date          time.Time
+	SignedHeaders []string
+	Signature     string
+	PayloadHash   string
+}
+
+// Authenticate 校验请求的 AWS Signature V4 签名，支持请求头签名和预签名 URL。
+// 校验通过后请求正文会替换为在读取时继续校验摘要或分块签名的 Reader
+func Authenticate(r *http.Request) (*model.S3Key, error) {
+	var (
+		cred *credential
+		err  error
+	)
+	if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+		cred, err = parsePresigned(r)
+	} else {
+		cred, err = parseAuthorization(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := model.GetS3KeyByAccessKey(cred.AccessKey)
+	if err != nil {
+		return nil, ErrInvalidAccessKeyID
+	}
+
+	signer := &s3.Signer{AccessKey: key.AccessKey, SecretKey: key.SecretKey, Region: cred.Region}
+	expected := signer.SignatureOf(r, r.URL.Query(), cred.SignedHeaders, cred.PayloadHash, cred.Date)
+	if !hmac.Equal([]byte(expected), []byte(cred.Signature)) {
+		return nil, ErrSignatureDoesNotMatch
+	}
+
+	if err := wrapBody(r, signer, cred); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// parseAuthorization 解析 Authorization 请求头，格式为
+// AWS4-HMAC-SHA256 Credential=AK/日期/区域/s3/aws4_request, SignedHeaders=..., Signature=...
+func parseAuthorization(r *http.Request) (*credential, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, ErrAccessDenied
+	}
+
+	params, ok := strings.CutPrefix(header, s3.Algorithm+" ")
+	if !ok {
+		return nil, ErrAuthorizationMalformed
+	}
+
+	fields := make(map[string]string, 3)
+	for _, pair := range strings.Split(params, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		fields[k] = v
+	}
+
+	cred, err := parseCredential(fields["Credential"], fields["SignedHeaders"], fields["Signature"], r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, ErrAuthorizationMalformed
+	}
+
+	if skew := time.Since(cred.Date); skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, ErrRequestTimeTooSkewed
+	}
+
+	// 正文摘要及请求时间须参与签名，否则可被替换
+	cred.PayloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	if cred.PayloadHash == "" || !util.ContainsString(cred.SignedHeaders, "x-amz-content-sha256") ||
+		!util.ContainsString(cred.SignedHeaders, "x-amz-date") {
+		return nil, ErrAuthorizationMalformed
+	}
+
+	return cred, nil
+}
+
+// parsePresigned 解析预签名 URL 中的签名参数
+func parsePresigned(r *http.Request) (*credential, error) {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != s3.Algorithm {
+		return nil, ErrAuthorizationQueryMalformed
+	}
+
+	cred, err := parseCredential(query.Get("X-Amz-Credential"), query.Get("X-Amz-SignedHeaders"),
+		query.Get("X-Amz-Signature"), query.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, ErrAuthorizationQueryMalformed
+	}
+
+	expires, err := strconv.ParseInt(query.Get("X-Amz-Expires"), 10, 64)
+	if err != nil || expires < 0 || expires > maxPresignExpires {
+		return nil, ErrAuthorizationQueryMalformed
+	}
+
+	if time.Until(cred.Date) > maxClockSkew {
+		return nil, ErrRequestTimeTooSkewed
+	}
+
+	if time.Now().After(cred.Date.Add(time.Duration(expires) * time.Second)) {
+		return nil, ErrExpiredRequest
+	}
+
+	cred.PayloadHash = s3.UnsignedPayload
+	return cred, nil
+}
+
+// parseCredential 解析签名凭证的各个部分
+func parseCredential(scope, signedHeaders, signature, date string) (*credential, error) {
+	parts := strings.Split(scope, "/")
+	if len(parts) != 5 || parts[0] == "" || parts[3] != "s3" || parts[4] != "aws4_request" {
+		return nil, ErrAuthorizationMalformed
+	}
+
+	t, err := s3.ParseTime(date)
+	if err != nil || t.Format("20060102") != parts[1] {
+		return nil, ErrAuthorizationMalformed
+	}
+
+	// Host 须参与签名，避免签名被用于其他站点
+	headers := strings.Split(signedHeaders, ";")
+	if signature == "" || !util.ContainsString(headers, "host") {
+		return nil, ErrAuthorizationMalformed
+	}
+
+	return &credential{
+		AccessKey:     parts[0],
+		Region:        parts[2],
+		Date:          t,
+		SignedHeaders: headers,
+		Signature:     signature,
+	}, nil
+}
+
+// wrapBody 根据正文摘要的类型替换请求正文
+func wrapBody(r *http.Request, signer *s3.Signer, cred *credential) error {
+	switch cred.PayloadHash {
+	case s3.UnsignedPayload:
+		return nil
+	case s3.StreamingPayload, unsignedStreamingPayload:
+		decoded, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil || decoded < 0 {
+			return ErrMissingContentLength
+		}
+
+		reader := &chunkedReader{
+			reader:   bufio.NewReader(r.Body),
+			closer:   r.Body,
+			previous: cred.Signature,
+			date:     cred.Date,
+		}
+		if cred.PayloadHash == s3.StreamingPayload {
+			reader.signer = signer
+		} else {
+			// 未签名的分块须附带校验和尾部，否则正文不受任何校验
+			reader.trailer = strings.ToLower(r.Header.Get("X-Amz-Trailer"))
+			if reader.checksum = newChecksum(reader.trailer); reader.checksum == nil {
+				return ErrInvalidArgument
+			}
+		}
+
+		r.Body = reader
+		r.ContentLength = decoded
+		return nil
+	}
+
+	expected, err := hex.DecodeString(cred.PayloadHash)
+	if err != nil || len(expected) != sha256.Size {
+		return ErrContentSHA256Mismatch
+	}
+
+	r.Body = &hashReader{reader: r.Body, hash: sha256.New(), expected: expected}
+	return nil
+}
+
+// newChecksum 返回尾部校验和对应的摘要算法，不支持时返回 nil
+func newChecksum(trailer string) hash.Hash {
+	switch trailer {
+	case "x-amz-checksum-crc32":
+		return crc32.NewIEEE()
+	case "x-amz-checksum-crc32c":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case "x-amz-checksum-sha1":
+		return sha1.New()
+	case "x-amz-checksum-sha256":
+		return sha256.New()
+	}
+
+	return nil
+}
+
+// hashReader 读取完毕时校验正文的 SHA256 摘要
+type hashReader struct {
+	reader   io.ReadCloser
+	hash     hash.Hash
+	expected []byte
+}
+
+func (r *hashReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && !bytes.Equal(r.hash.Sum(nil), r.expected) {
+		return n, ErrContentSHA256Mismatch
+	}
+
+	return n, err
+}
+
+func (r *hashReader) Close() error {
+	return r.reader.Close()
+}
+
+// chunkedReader 解码 aws-chunked 编码的请求正文，signer 不为空时逐块校验签名，
+// checksum 不为空时读取完毕后校验尾部中名为 trailer 的校验和
+type chunkedReader struct {
+	reader   *bufio.Reader
+	closer   io.Closer
+	signer   *s3.Signer
+	checksum hash.Hash
+	trailer  string
+	previous string
+	date     time.Time
+	chunk    []byte
+	done     bool
+	err      error
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.next()
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+func (r *chunkedReader) Close() error {
+	return r.closer.Close()
+}
+
+// next 读取下一个分块，格式为 "十六进制长度[;chunk-signature=签名]\r\n数据\r\n"
+func (r *chunkedReader) next() error {
+	line, err := r.readLine()
+	if err != nil {
+		return err
+	}
+
+	sizeHex, ext, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(sizeHex, 16, 64)
+	if err != nil || size < 0 || size > maxChunkSize {
+		return ErrInvalidArgument
+	}
+
+	chunk := make([]byte, size)
+	if _, err := io.ReadFull(r.reader, chunk); err != nil {
+		return ErrIncompleteBody
+	}
+
+	if r.signer != nil {
+		signature, ok := strings.CutPrefix(ext, "chunk-signature=")
+		expected := r.signer.ChunkSignature(r.date, r.previous, chunk)
+		if !ok || !hmac.Equal([]byte(signature), []byte(expected)) {
+			return ErrSignatureDoesNotMatch
+		}
+		r.previous = signature
+	}
+
+	if size == 0 {
+		return r.readTrailer()
+	}
+
+	if r.checksum != nil {
+		r.checksum.Write(chunk)
+	}
+
+	if line, err := r.readLine(); err != nil || line != "" {
+		return ErrIncompleteBody
+	}
+
+	r.chunk = chunk
+	return nil
+}
+
+// readTrailer 读取尾部直至空行，并校验其中的校验和
+func (r *chunkedReader) readTrailer() error {
+	var value string
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			break
+		}
+
+		if name, v, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), r.trailer) {
+			value = strings.TrimSpace(v)
+		}
+	}
+
+	if r.checksum != nil && value != base64.StdEncoding.EncodeToString(r.checksum.Sum(nil)) {
+		return ErrBadDigest
+	}
+
+	r.done = true
+	return nil
+}
+
+func (r *chunkedReader) readLine() (string, error) {
+	line, err := r.reader.ReadString('\n')
+	if err != nil {
+		return "", ErrIncompleteBody
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
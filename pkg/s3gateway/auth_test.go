@@ -0,0 +1,286 @@
+package s3gateway
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSigner = &s3.Signer{AccessKey: "TESTACCESSKEY", SecretKey: "test-secret-key", Region: "us-east-1"}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	cache.Init()
+	model.Init()
+
+	key := &model.S3Key{
+		Name:      "test",
+		AccessKey: testSigner.AccessKey,
+		SecretKey: testSigner.SecretKey,
+		UserID:    1,
+		Bucket:    "bucket",
+		Root:      "/",
+	}
+	if _, err := key.Create(); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+func payloadHash(body string) string {
+	hash := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(hash[:])
+}
+
+// signWith 只对 headers 中的请求头签名
+func signWith(r *http.Request, headers []string, payload string, now time.Time) {
+	r.Header.Set("X-Amz-Date", now.UTC().Format("20060102T150405Z"))
+	r.Header.Set("X-Amz-Content-Sha256", payload)
+	signature := testSigner.SignatureOf(r, r.URL.Query(), headers, payload, now)
+	r.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3.Algorithm, testSigner.AccessKey, testSigner.Scope(now), strings.Join(headers, ";"), signature))
+}
+
+func TestAuthenticate_Header(t *testing.T) {
+	a := assert.New(t)
+	body := "hello"
+	r := httptest.NewRequest("PUT", "http://s3.example.com/bucket/a.txt", strings.NewReader(body))
+	testSigner.Sign(r, payloadHash(body), time.Now())
+
+	key, err := Authenticate(r)
+	a.NoError(err)
+	a.Equal("bucket", key.Bucket)
+
+	read, err := io.ReadAll(r.Body)
+	a.NoError(err)
+	a.Equal(body, string(read))
+}
+
+func TestAuthenticate_PayloadMismatch(t *testing.T) {
+	a := assert.New(t)
+	r := httptest.NewRequest("PUT", "http://s3.example.com/bucket/a.txt", strings.NewReader("tampered"))
+	testSigner.Sign(r, payloadHash("hello"), time.Now())
+
+	_, err := Authenticate(r)
+	a.NoError(err)
+
+	_, err = io.ReadAll(r.Body)
+	a.Equal(ErrContentSHA256Mismatch, err)
+}
+
+func TestAuthenticate_SignatureMismatch(t *testing.T) {
+	a := assert.New(t)
+	r := httptest.NewRequest("GET", "http://s3.example.com/bucket/a.txt", nil)
+	testSigner.Sign(r, s3.EmptyPayload, time.Now())
+	r.URL.Path = "/bucket/b.txt"
+
+	_, err := Authenticate(r)
+	a.Equal(ErrSignatureDoesNotMatch, err)
+
+	r = httptest.NewRequest("GET", "http://s3.example.com/bucket/a.txt", nil)
+	other := *testSigner
+	other.SecretKey = "wrong"
+	other.Sign(r, s3.EmptyPayload, time.Now())
+	_, err = Authenticate(r)
+	a.Equal(ErrSignatureDoesNotMatch, err)
+
+	r = httptest.NewRequest("GET", "http://s3.example.com/bucket/a.txt", nil)
+	other = *testSigner
+	other.AccessKey = "UNKNOWN"
+	other.Sign(r, s3.EmptyPayload, time.Now())
+	_, err = Authenticate(r)
+	a.Equal(ErrInvalidAccessKeyID, err)
+}
+
+func TestAuthenticate_RequiredSignedHeaders(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+
+	for _, headers := range [][]string{
+		{"x-amz-content-sha256", "x-amz-date"},
+		{"host", "x-amz-date"},
+		{"host", "x-amz-content-sha256"},
+	} {
+		r := httptest.NewRequest("GET", "http://s3.example.com/bucket/a.txt", nil)
+		signWith(r, headers, s3.EmptyPayload, now)
+		_, err := Authenticate(r)
+		a.Equal(ErrAuthorizationMalformed, err, headers)
+	}
+
+	r := httptest.NewRequest("GET", "http://s3.example.com/bucket/a.txt", nil)
+	signWith(r, []string{"host", "x-amz-content-sha256", "x-amz-date"}, s3.EmptyPayload, now)
+	_, err := Authenticate(r)
+	a.NoError(err)
+}
+
+func TestAuthenticate_Skewed(t *testing.T) {
+	a := assert.New(t)
+	r := httptest.NewRequest("GET", "http://s3.example.com/bucket/a.txt", nil)
+	testSigner.Sign(r, s3.EmptyPayload, time.Now().Add(-time.Hour))
+
+	_, err := Authenticate(r)
+	a.Equal(ErrRequestTimeTooSkewed, err)
+}
+
+func TestAuthenticate_Presigned(t *testing.T) {
+	a := assert.New(t)
+	target, _ := url.Parse("http://s3.example.com/bucket/a.txt")
+
+	r := httptest.NewRequest("GET", testSigner.Presign("GET", target, 60, time.Now()), nil)
+	_, err := Authenticate(r)
+	a.NoError(err)
+
+	// 已过期
+	r = httptest.NewRequest("GET", testSigner.Presign("GET", target, 60, time.Now().Add(-time.Hour)), nil)
+	_, err = Authenticate(r)
+	a.Equal(ErrExpiredRequest, err)
+
+	// 有效期过长
+	r = httptest.NewRequest("GET", testSigner.Presign("GET", target, maxPresignExpires+1, time.Now()), nil)
+	_, err = Authenticate(r)
+	a.Equal(ErrAuthorizationQueryMalformed, err)
+
+	// 签名的 Host 与请求不符
+	r = httptest.NewRequest("GET", testSigner.Presign("GET", target, 60, time.Now()), nil)
+	r.Host = "other.example.com"
+	_, err = Authenticate(r)
+	a.Equal(ErrSignatureDoesNotMatch, err)
+
+	// Host 未参与签名
+	signed, _ := url.Parse(testSigner.Presign("GET", target, 60, time.Now()))
+	query := signed.Query()
+	query.Set("X-Amz-SignedHeaders", "x-amz-date")
+	signed.RawQuery = query.Encode()
+	r = httptest.NewRequest("GET", signed.String(), nil)
+	_, err = Authenticate(r)
+	a.Equal(ErrAuthorizationQueryMalformed, err)
+}
+
+func TestAuthenticate_Streaming(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	chunks := []string{strings.Repeat("a", 10), strings.Repeat("b", 5)}
+
+	build := func(tamper bool) *http.Request {
+		r := httptest.NewRequest("PUT", "http://s3.example.com/bucket/a.txt", nil)
+		r.Header.Set("X-Amz-Decoded-Content-Length", "15")
+		testSigner.Sign(r, s3.StreamingPayload, now)
+		_, previous, _ := strings.Cut(r.Header.Get("Authorization"), "Signature=")
+
+		var body strings.Builder
+		for _, chunk := range append(chunks, "") {
+			signature := testSigner.ChunkSignature(now, previous, []byte(chunk))
+			previous = signature
+			if tamper && chunk != "" {
+				chunk = strings.ToUpper(chunk)
+			}
+			fmt.Fprintf(&body, "%x;chunk-signature=%s\r\n%s\r\n", len(chunk), signature, chunk)
+		}
+
+		r.Body = io.NopCloser(strings.NewReader(body.String()))
+		return r
+	}
+
+	r := build(false)
+	_, err := Authenticate(r)
+	a.NoError(err)
+	a.EqualValues(15, r.ContentLength)
+	read, err := io.ReadAll(r.Body)
+	a.NoError(err)
+	a.Equal(strings.Join(chunks, ""), string(read))
+
+	r = build(true)
+	_, err = Authenticate(r)
+	a.NoError(err)
+	_, err = io.ReadAll(r.Body)
+	a.Equal(ErrSignatureDoesNotMatch, err)
+}
+
+func TestAuthenticate_UnsignedTrailer(t *testing.T) {
+	a := assert.New(t)
+	body := "hello world"
+	sum := binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE([]byte(body)))
+
+	build := func(trailer, checksum string) *http.Request {
+		r := httptest.NewRequest("PUT", "http://s3.example.com/bucket/a.txt", nil)
+		r.Header.Set("X-Amz-Decoded-Content-Length", strconv.Itoa(len(body)))
+		if trailer != "" {
+			r.Header.Set("X-Amz-Trailer", trailer)
+		}
+		testSigner.Sign(r, unsignedStreamingPayload, time.Now())
+		r.Body = io.NopCloser(strings.NewReader(fmt.Sprintf("%x\r\n%s\r\n0\r\n%s:%s\r\n\r\n", len(body), body, trailer, checksum)))
+		return r
+	}
+
+	r := build("x-amz-checksum-crc32", base64.StdEncoding.EncodeToString(sum))
+	_, err := Authenticate(r)
+	a.NoError(err)
+	read, err := io.ReadAll(r.Body)
+	a.NoError(err)
+	a.Equal(body, string(read))
+
+	// 校验和不符
+	r = build("x-amz-checksum-crc32", base64.StdEncoding.EncodeToString([]byte("bad!")))
+	_, err = Authenticate(r)
+	a.NoError(err)
+	_, err = io.ReadAll(r.Body)
+	a.Equal(ErrBadDigest, err)
+
+	// 未声明校验和尾部
+	r = build("", "")
+	_, err = Authenticate(r)
+	a.Equal(ErrInvalidArgument, err)
+}
+
+func TestStageBody(t *testing.T) {
+	a := assert.New(t)
+	fs := &filesystem.FileSystem{Policy: &model.Policy{}}
+
+	r := httptest.NewRequest("PUT", "http://s3.example.com/bucket/a.txt", strings.NewReader("hello"))
+	testSigner.Sign(r, payloadHash("hello"), time.Now())
+	_, err := Authenticate(r)
+	a.NoError(err)
+
+	body, err := stageBody(r, fs)
+	a.NoError(err)
+	staged := body.(stagedBody).Name()
+	read, err := io.ReadAll(body)
+	a.NoError(err)
+	a.Equal("hello", string(read))
+	a.NoError(body.Close())
+	a.NoFileExists(staged)
+
+	// 摘要不符时在写入文件系统前失败
+	r = httptest.NewRequest("PUT", "http://s3.example.com/bucket/a.txt", strings.NewReader("tampered"))
+	testSigner.Sign(r, payloadHash("hello"), time.Now())
+	_, err = Authenticate(r)
+	a.NoError(err)
+	r.ContentLength = 8
+	_, err = stageBody(r, fs)
+	a.Equal(ErrContentSHA256Mismatch, err)
+
+	// 超出单文件大小限制
+	fs.Policy.MaxSize = 1
+	_, err = stageBody(r, fs)
+	a.Equal(ErrEntityTooLarge, err)
+}
@@ -0,0 +1,102 @@
+package s3gateway
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+// Error S3 接口错误
+type Error struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+// Error 返回错误信息
+func (err *Error) Error() string {
+	return err.Message
+}
+
+var (
+	ErrAccessDenied                = &Error{"AccessDenied", "Access Denied.", http.StatusForbidden}
+	ErrInvalidAccessKeyID          = &Error{"InvalidAccessKeyId", "The access key ID you provided does not exist in our records.", http.StatusForbidden}
+	ErrSignatureDoesNotMatch       = &Error{"SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden}
+	ErrRequestTimeTooSkewed        = &Error{"RequestTimeTooSkewed", "The difference between the request time and the server's time is too large.", http.StatusForbidden}
+	ErrExpiredRequest              = &Error{"AccessDenied", "Request has expired.", http.StatusForbidden}
+	ErrAuthorizationMalformed      = &Error{"AuthorizationHeaderMalformed", "The authorization header is malformed.", http.StatusBadRequest}
+	ErrAuthorizationQueryMalformed = &Error{"AuthorizationQueryParametersError", "The authorization query parameters are malformed.", http.StatusBadRequest}
+	ErrContentSHA256Mismatch       = &Error{"XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed.", http.StatusBadRequest}
+	ErrBadDigest                   = &Error{"BadDigest", "The checksum you specified did not match what was computed.", http.StatusBadRequest}
+	ErrMissingContentLength        = &Error{"MissingContentLength", "You must provide the Content-Length HTTP header.", http.StatusLengthRequired}
+	ErrIncompleteBody              = &Error{"IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header.", http.StatusBadRequest}
+	ErrNoSuchBucket                = &Error{"NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound}
+	ErrNoSuchKey                   = &Error{"NoSuchKey", "The specified key does not exist.", http.StatusNotFound}
+	ErrNoSuchUpload                = &Error{"NoSuchUpload", "The specified multipart upload does not exist.", http.StatusNotFound}
+	ErrBucketAlreadyOwnedByYou     = &Error{"BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.", http.StatusConflict}
+	ErrOperationAborted            = &Error{"OperationAborted", "A conflicting conditional operation is currently in progress against this resource.", http.StatusConflict}
+	ErrInvalidArgument             = &Error{"InvalidArgument", "Invalid Argument.", http.StatusBadRequest}
+	ErrInvalidPart                 = &Error{"InvalidPart", "One or more of the specified parts could not be found.", http.StatusBadRequest}
+	ErrInvalidPartOrder            = &Error{"InvalidPartOrder", "The list of parts was not in ascending order.", http.StatusBadRequest}
+	ErrMalformedXML                = &Error{"MalformedXML", "The XML you provided was not well-formed.", http.StatusBadRequest}
+	ErrEntityTooLarge              = &Error{"EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size or remaining capacity.", http.StatusBadRequest}
+	ErrInvalidObjectName           = &Error{"InvalidObjectName", "The specified object name is not valid.", http.StatusBadRequest}
+	ErrNotImplemented              = &Error{"NotImplemented", "A header you provided implies functionality that is not implemented.", http.StatusNotImplemented}
+	ErrMethodNotAllowed            = &Error{"MethodNotAllowed", "The specified method is not allowed against this resource.", http.StatusMethodNotAllowed}
+	ErrInternalError               = &Error{"InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError}
+)
+
+// errorResponse 错误响应正文
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string
+	Message  string
+	Resource string
+}
+
+// WriteError 输出错误响应，文件系统的错误会转换为对应的 S3 错误
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		apiErr = convertError(err)
+	}
+
+	if apiErr.Status >= 500 {
+		util.Log().Warning("S3 gateway request %s %q failed: %s", r.Method, r.URL.Path, err)
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Del("ETag")
+	w.WriteHeader(apiErr.Status)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	writeXML(w, errorResponse{Code: apiErr.Code, Message: apiErr.Message, Resource: r.URL.Path})
+}
+
+// convertError 将文件系统的错误转换为 S3 错误
+func convertError(err error) *Error {
+	var appErr serializer.AppError
+	if !errors.As(err, &appErr) {
+		return ErrInternalError
+	}
+
+	switch appErr.Code {
+	case serializer.CodeParentNotExist:
+		return ErrNoSuchKey
+	case serializer.CodeFileTooLarge, serializer.CodeInsufficientCapacity, serializer.CodeFolderQuotaExceeded:
+		return ErrEntityTooLarge
+	case serializer.CodeIllegalObjectName, serializer.CodeObjectExist:
+		return ErrInvalidObjectName
+	case serializer.CodeConflictUploadOngoing:
+		return ErrOperationAborted
+	case serializer.CodeFileTypeNotAllowed, serializer.CodeSharedFolderReadOnly:
+		return ErrAccessDenied
+	}
+
+	return ErrInternalError
+}
@@ -0,0 +1,570 @@
+// Package s3gateway 以 S3 兼容接口提供对用户文件的访问，每个访问密钥对应一个存储桶
+package s3gateway
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+)
+
+// 不支持的存储桶子资源
+var bucketSubresources = []string{
+	"accelerate", "acl", "analytics", "cors", "encryption", "intelligent-tiering", "inventory",
+	"lifecycle", "logging", "metrics", "notification", "object-lock", "ownershipControls",
+	"policy", "publicAccessBlock", "replication", "requestPayment", "tagging", "uploads",
+	"versioning", "versions", "website",
+}
+
+// 不支持的对象子资源
+var objectSubresources = []string{
+	"acl", "attributes", "legal-hold", "restore", "retention", "select", "tagging", "torrent",
+}
+
+// Handler S3 接口处理器
+type Handler struct {
+	// Prefix 接口的路径前缀
+	Prefix string
+}
+
+// ServeHTTP 处理 S3 请求，路径形如 {Prefix}/{存储桶}/{对象键}，fs 的根目录应已切换为存储桶的根目录
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, key *model.S3Key) {
+	// 上传过程中文件系统仍被取消上传的协程使用，不能回收
+	if !isUpload(r) {
+		defer fs.Recycle()
+	}
+
+	bucketName, objectKey := h.split(r.URL.Path)
+
+	var err error
+	switch {
+	case bucketName == "":
+		if r.Method != http.MethodGet {
+			err = ErrMethodNotAllowed
+			break
+		}
+		err = h.listBuckets(w, fs, key)
+	case bucketName != key.Bucket:
+		err = ErrNoSuchBucket
+	case key.Readonly && r.Method != http.MethodGet && r.Method != http.MethodHead:
+		err = ErrAccessDenied
+	case objectKey == "":
+		err = h.serveBucket(w, r, fs, key)
+	default:
+		err = h.serveObject(w, r, fs, key, objectKey)
+	}
+
+	if err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// split 将请求路径拆分为存储桶名称和对象键
+func (h *Handler) split(p string) (string, string) {
+	p = strings.TrimPrefix(strings.TrimPrefix(p, h.Prefix), "/")
+	bucketName, objectKey, _ := strings.Cut(p, "/")
+	return bucketName, objectKey
+}
+
+// isUpload 请求是否会写入文件内容
+func isUpload(r *http.Request) bool {
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodPut:
+		return r.Header.Get("X-Amz-Copy-Source") == "" && !query.Has("partNumber")
+	case http.MethodPost:
+		return query.Has("uploadId")
+	}
+
+	return false
+}
+
+// hasSubresource 查询参数中是否含有给定的子资源
+func hasSubresource(query url.Values, subresources []string) bool {
+	for _, name := range subresources {
+		if query.Has(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listBuckets 列出密钥可访问的存储桶
+func (h *Handler) listBuckets(w http.ResponseWriter, fs *filesystem.FileSystem, key *model.S3Key) error {
+	return writeXML(w, listBucketsResult{
+		Xmlns:   xmlNamespace,
+		Owner:   owner{ID: strconv.FormatUint(uint64(fs.User.ID), 10), DisplayName: fs.User.Nick},
+		Buckets: []bucket{{Name: key.Bucket, CreationDate: formatTime(key.CreatedAt)}},
+	})
+}
+
+// serveBucket 处理存储桶级别的请求
+func (h *Handler) serveBucket(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, key *model.S3Key) error {
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodHead:
+		return nil
+	case http.MethodGet:
+		if query.Has("location") {
+			return writeXML(w, locationResult{Xmlns: xmlNamespace})
+		}
+		if hasSubresource(query, bucketSubresources) {
+			return ErrNotImplemented
+		}
+		return h.listObjects(w, r, fs, key)
+	case http.MethodPost:
+		if query.Has("delete") {
+			return h.deleteObjects(w, r, fs)
+		}
+	case http.MethodPut:
+		if hasSubresource(query, bucketSubresources) {
+			return ErrNotImplemented
+		}
+		return ErrBucketAlreadyOwnedByYou
+	}
+
+	return ErrMethodNotAllowed
+}
+
+// serveObject 处理对象级别的请求
+func (h *Handler) serveObject(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, key *model.S3Key, objectKey string) error {
+	reqPath, err := objectPath(objectKey)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	if hasSubresource(query, objectSubresources) {
+		return ErrNotImplemented
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if query.Has("uploadId") {
+			return h.listParts(w, r, key, objectKey, reqPath)
+		}
+		return h.getObject(w, r, fs, objectKey, reqPath)
+	case http.MethodPut:
+		if query.Has("partNumber") {
+			return h.uploadPart(w, r, fs, key, reqPath)
+		}
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			return h.copyObject(w, r, fs, key, source, reqPath)
+		}
+		return h.putObject(w, r, fs, objectKey, reqPath)
+	case http.MethodPost:
+		if query.Has("uploads") {
+			return h.createMultipartUpload(w, r, fs, key, objectKey, reqPath)
+		}
+		if query.Has("uploadId") {
+			return h.completeMultipartUpload(w, r, fs, key, objectKey, reqPath)
+		}
+	case http.MethodDelete:
+		if query.Has("uploadId") {
+			return h.abortMultipartUpload(w, r, key, reqPath)
+		}
+		if err := deleteObject(r.Context(), fs, objectKey, reqPath); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	return ErrMethodNotAllowed
+}
+
+// objectPath 将对象键转换为文件系统中的路径，以 / 结尾的对象键表示目录
+func objectPath(objectKey string) (string, error) {
+	segments := strings.Split(strings.TrimSuffix(objectKey, "/"), "/")
+	for _, seg := range segments {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", ErrInvalidObjectName
+		}
+	}
+
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// isDirKey 对象键是否表示目录
+func isDirKey(objectKey string) bool {
+	return strings.HasSuffix(objectKey, "/")
+}
+
+// etag 根据修改时间和大小生成文件的 ETag，含有 - 以免客户端将其视为内容的 MD5
+func etag(file *model.File) string {
+	return fmt.Sprintf(`"%x-%x"`, file.UpdatedAt.UnixNano(), file.Size)
+}
+
+// findFile 查找已完成上传的文件
+func findFile(fs *filesystem.FileSystem, reqPath string) (*model.File, error) {
+	exist, file := fs.IsFileExist(reqPath)
+	if !exist || file.UploadSessionID != nil {
+		return nil, ErrNoSuchKey
+	}
+
+	return file, nil
+}
+
+var proxy = &httputil.ReverseProxy{
+	Director: func(request *http.Request) {
+		if target, ok := request.Context().Value(fsctx.S3ProxyUrlCtx).(*url.URL); ok {
+			request.URL.Scheme = target.Scheme
+			request.URL.Host = target.Host
+			request.URL.Path = target.Path
+			request.URL.RawPath = target.RawPath
+			request.URL.RawQuery = target.RawQuery
+			request.Host = target.Host
+			request.Header.Del("Authorization")
+			for name := range request.Header {
+				if strings.HasPrefix(strings.ToLower(name), "x-amz-") {
+					request.Header.Del(name)
+				}
+			}
+		}
+	},
+	ErrorHandler: func(writer http.ResponseWriter, request *http.Request, err error) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	},
+}
+
+// getObject 下载对象或获取对象的元信息
+func (h *Handler) getObject(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, objectKey, reqPath string) error {
+	if isDirKey(objectKey) {
+		exist, folder := fs.IsPathExist(reqPath)
+		if !exist {
+			return ErrNoSuchKey
+		}
+
+		w.Header().Set("Content-Type", "application/x-directory")
+		w.Header().Set("Content-Length", "0")
+		w.Header().Set("Last-Modified", folder.UpdatedAt.UTC().Format(http.TimeFormat))
+		return nil
+	}
+
+	file, err := findFile(fs, reqPath)
+	if err != nil {
+		return err
+	}
+	fs.SetTargetFile(&[]model.File{*file})
+
+	ctx := r.Context()
+	rs, err := fs.Preview(ctx, 0, false)
+	if err != nil {
+		if err == filesystem.ErrObjectNotExist {
+			return ErrNoSuchKey
+		}
+		return err
+	}
+
+	w.Header().Set("ETag", etag(file))
+	if !rs.Redirect {
+		defer rs.Content.Close()
+		http.ServeContent(w, r, file.Name, file.UpdatedAt, rs.Content)
+		return nil
+	}
+
+	// S3 客户端一般不跟随重定向，始终由网关中转文件内容
+	target, err := url.Parse(rs.URL)
+	if err != nil {
+		return err
+	}
+
+	r = r.Clone(context.WithValue(ctx, fsctx.S3ProxyUrlCtx, target))
+	// 忽略反向代理在传输错误时报错
+	defer func() {
+		if err := recover(); err != nil && err != http.ErrAbortHandler {
+			panic(err)
+		}
+	}()
+	proxy.ServeHTTP(w, r)
+	return nil
+}
+
+// putObject 上传对象，以 / 结尾的空对象会创建目录
+func (h *Handler) putObject(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, objectKey, reqPath string) error {
+	if r.ContentLength < 0 {
+		return ErrMissingContentLength
+	}
+
+	if isDirKey(objectKey) {
+		if r.ContentLength > 0 {
+			return ErrInvalidObjectName
+		}
+		if exist, _ := fs.IsPathExist(reqPath); !exist {
+			if _, err := fs.CreateDirectory(r.Context(), reqPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	body, err := stageBody(r, fs)
+	if err != nil {
+		return err
+	}
+
+	file, err := h.upload(r, fs, reqPath, body, uint64(r.ContentLength), r.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", etag(file))
+	return nil
+}
+
+// stagedBody 暂存在临时文件中的请求正文，关闭时删除临时文件
+type stagedBody struct {
+	*os.File
+}
+
+func (b stagedBody) Close() error {
+	err := b.File.Close()
+	os.Remove(b.Name())
+	return err
+}
+
+// stageBody 需要校验摘要、分块签名或校验和的正文在读取完毕时才能确认完整，
+// 先暂存到临时文件，校验通过后再写入文件系统，避免未通过校验的内容写入存储
+func stageBody(r *http.Request, fs *filesystem.FileSystem) (io.ReadCloser, error) {
+	switch r.Body.(type) {
+	case *hashReader, *chunkedReader:
+	default:
+		return r.Body, nil
+	}
+
+	if !fs.ValidateFileSize(r.Context(), uint64(r.ContentLength)) {
+		return nil, ErrEntityTooLarge
+	}
+
+	if err := os.MkdirAll(PartsDir(""), 0700); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(PartsDir(""), "put_")
+	if err != nil {
+		return nil, err
+	}
+	body := stagedBody{tmp}
+
+	written, err := io.CopyN(tmp, r.Body, r.ContentLength+1)
+	if err != nil && err != io.EOF {
+		body.Close()
+		return nil, err
+	}
+	if written != r.ContentLength {
+		body.Close()
+		return nil, ErrIncompleteBody
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// upload 写入文件内容，目标文件存在时覆盖，处理流程与 WebDAV 的 PUT 相同
+func (h *Handler) upload(r *http.Request, fs *filesystem.FileSystem, reqPath string, body io.ReadCloser, size uint64, mimeType string) (*model.File, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.HTTPCtx, r.Context())
+	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+
+	fileData := fsctx.FileStream{
+		MimeType:    mimeType,
+		File:        body,
+		Size:        size,
+		Name:        path.Base(reqPath),
+		VirtualPath: path.Dir(reqPath),
+	}
+
+	// 判断文件是否已存在
+	exist, originFile := fs.IsFileExist(reqPath)
+	if exist && originFile.UploadSessionID != nil {
+		return nil, filesystem.ErrFileUploadSessionExisted
+	}
+
+	if exist {
+		// 已存在，为更新操作
+
+		// 开启历史版本时，原有内容保存为历史版本
+		versioned := fs.UseVersioning(ctx, originFile, &fileData)
+
+		// 检查此文件是否有软链接
+		fileList, err := model.RemoveFilesWithSoftLinks([]model.File{*originFile})
+		if !versioned && err == nil && len(fileList) == 0 {
+			// 如果包含软连接，应重新生成新文件副本，并更新source_name
+			originSource := originFile.SourceName
+			originFile.SourceName = fs.GenerateSavePath(ctx, &fileData)
+			fileData.Mode &= ^fsctx.Overwrite
+			fs.Use("AfterUpload", filesystem.HookUpdateSourceName)
+			if model.IsBlobSource(originFile.PolicyID, originSource) {
+				fs.Use("AfterUpload", filesystem.HookReleaseBlob(originSource))
+			}
+			fs.Use("AfterUploadCanceled", filesystem.HookUpdateSourceName)
+			fs.Use("AfterValidateFailed", filesystem.HookUpdateSourceName)
+		}
+
+		fs.Use("BeforeUpload", filesystem.HookResetPolicy)
+		fs.Use("BeforeUpload", filesystem.HookValidateFile)
+		fs.Use("BeforeUpload", filesystem.HookValidateCapacityDiff)
+		if !versioned {
+			fs.Use("AfterUploadCanceled", filesystem.HookCleanFileContent)
+			fs.Use("AfterUploadCanceled", filesystem.HookClearFileSize)
+			fs.Use("AfterValidateFailed", filesystem.HookCleanFileContent)
+			fs.Use("AfterValidateFailed", filesystem.HookClearFileSize)
+		}
+		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
+		fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
+		fs.Use("AfterUpload", filesystem.HookCommitBlob)
+		ctx = context.WithValue(ctx, fsctx.FileModelCtx, *originFile)
+		fileData.Mode |= fsctx.Overwrite
+	} else {
+		// 给文件系统分配钩子
+		fs.Use("BeforeUpload", filesystem.HookValidateFile)
+		fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+		fs.Use("AfterUploadCanceled", filesystem.HookDeleteTempFile)
+		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
+		fs.Use("AfterUpload", filesystem.GenericAfterUpload)
+		fs.Use("AfterUpload", filesystem.HookCommitBlob)
+		fs.Use("AfterValidateFailed", filesystem.HookDeleteTempFile)
+	}
+
+	// 执行上传
+	if err := fs.Upload(ctx, &fileData); err != nil {
+		return nil, err
+	}
+
+	return fileData.Model.(*model.File), nil
+}
+
+// copyObject 复制对象，source 为 URL 编码的 /存储桶/对象键，目标已存在时原有文件移入回收站
+func (h *Handler) copyObject(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, key *model.S3Key, source, dstPath string) error {
+	source, _, _ = strings.Cut(source, "?")
+	source, err := url.PathUnescape(source)
+	if err != nil {
+		return ErrInvalidArgument
+	}
+
+	srcBucket, srcKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	if srcBucket != key.Bucket {
+		return ErrNoSuchBucket
+	}
+
+	if srcKey == "" || isDirKey(srcKey) {
+		return ErrInvalidArgument
+	}
+
+	srcPath, err := objectPath(srcKey)
+	if err != nil {
+		return err
+	}
+
+	src, err := findFile(fs, srcPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	if srcPath != dstPath {
+		if exist, dst := fs.IsFileExist(dstPath); exist {
+			if err := fs.Trash(ctx, []uint{}, []uint{dst.ID}); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fs.CreateDirectory(ctx, path.Dir(dstPath)); err != nil {
+			return err
+		}
+
+		if err := fs.Copy(
+			context.WithValue(ctx, fsctx.WebdavDstName, path.Base(dstPath)),
+			[]uint{},
+			[]uint{src.ID},
+			path.Dir(srcPath),
+			path.Dir(dstPath),
+		); err != nil {
+			return err
+		}
+	}
+
+	dst, err := findFile(fs, dstPath)
+	if err != nil {
+		return err
+	}
+
+	return writeXML(w, copyObjectResult{
+		Xmlns:        xmlNamespace,
+		LastModified: formatTime(dst.UpdatedAt),
+		ETag:         etag(dst),
+	})
+}
+
+// deleteObject 删除对象，对象不存在时视为成功。以 / 结尾的对象键只删除空目录
+func deleteObject(ctx context.Context, fs *filesystem.FileSystem, objectKey, reqPath string) error {
+	if isDirKey(objectKey) {
+		exist, folder := fs.IsPathExist(reqPath)
+		if !exist || folder.ParentID == nil {
+			return nil
+		}
+
+		if children, err := folder.GetChildFolder(); err != nil || len(children) > 0 {
+			return err
+		}
+		if files, err := folder.GetChildFiles(); err != nil || len(files) > 0 {
+			return err
+		}
+
+		return fs.Trash(ctx, []uint{folder.ID}, []uint{})
+	}
+
+	if exist, file := fs.IsFileExist(reqPath); exist {
+		return fs.Trash(ctx, []uint{}, []uint{file.ID})
+	}
+
+	return nil
+}
+
+// deleteObjects 批量删除对象
+func (h *Handler) deleteObjects(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) error {
+	var req deleteRequest
+	if err := xml.NewDecoder(io.LimitReader(r.Body, 2<<20)).Decode(&req); err != nil || len(req.Objects) > 1000 {
+		return ErrMalformedXML
+	}
+
+	res := deleteResult{Xmlns: xmlNamespace}
+	for _, obj := range req.Objects {
+		reqPath, err := objectPath(obj.Key)
+		if err == nil {
+			err = deleteObject(r.Context(), fs, obj.Key, reqPath)
+		}
+
+		if err != nil {
+			apiErr := ErrInvalidObjectName
+			if err != ErrInvalidObjectName {
+				apiErr = convertError(err)
+			}
+			res.Errors = append(res.Errors, deleteError{Key: obj.Key, Code: apiErr.Code, Message: apiErr.Message})
+			continue
+		}
+
+		if !req.Quiet {
+			res.Deleted = append(res.Deleted, deletedObject{Key: obj.Key})
+		}
+	}
+
+	return writeXML(w, res)
+}
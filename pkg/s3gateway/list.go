@@ -0,0 +1,265 @@
+package s3gateway
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+)
+
+// maxListKeys 单次列出对象的最大数量
+const maxListKeys = 1000
+
+// entry 列出的对象或公共前缀
+type entry struct {
+	Key  string
+	File *model.File
+}
+
+// listObjects 列出对象，同时支持 ListObjects 和 ListObjectsV2
+func (h *Handler) listObjects(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, key *model.S3Key) error {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	encodingType := query.Get("encoding-type")
+	if encodingType != "" && encodingType != "url" {
+		return ErrInvalidArgument
+	}
+
+	maxKeys := maxListKeys
+	if raw := query.Get("max-keys"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return ErrInvalidArgument
+		}
+		if n < maxKeys {
+			maxKeys = n
+		}
+	}
+
+	v2 := query.Get("list-type") == "2"
+	marker := query.Get("marker")
+	if v2 {
+		marker = query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			decoded, err := base64.StdEncoding.DecodeString(token)
+			if err != nil {
+				return ErrInvalidArgument
+			}
+			marker = string(decoded)
+		}
+	}
+
+	entries, err := collectEntries(fs, prefix, delimiter)
+	if err != nil {
+		return err
+	}
+
+	encode := func(s string) string {
+		if encodingType == "url" {
+			return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+		}
+		return s
+	}
+
+	var (
+		contents  []object
+		prefixes  []commonPrefix
+		truncated bool
+		last      string
+	)
+	for _, e := range entries {
+		if e.Key <= marker {
+			continue
+		}
+
+		if len(contents)+len(prefixes) >= maxKeys {
+			truncated = true
+			break
+		}
+
+		last = e.Key
+		if e.File == nil {
+			prefixes = append(prefixes, commonPrefix{Prefix: encode(e.Key)})
+			continue
+		}
+
+		contents = append(contents, object{
+			Key:          encode(e.Key),
+			LastModified: formatTime(e.File.UpdatedAt),
+			ETag:         etag(e.File),
+			Size:         e.File.Size,
+			StorageClass: "STANDARD",
+		})
+	}
+
+	if v2 {
+		res := listObjectsV2Result{
+			Xmlns:             xmlNamespace,
+			Name:              key.Bucket,
+			Prefix:            encode(prefix),
+			StartAfter:        encode(query.Get("start-after")),
+			ContinuationToken: query.Get("continuation-token"),
+			KeyCount:          len(contents) + len(prefixes),
+			Delimiter:         encode(delimiter),
+			MaxKeys:           maxKeys,
+			EncodingType:      encodingType,
+			IsTruncated:       truncated,
+			Contents:          contents,
+			CommonPrefixes:    prefixes,
+		}
+		if truncated {
+			res.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(last))
+		}
+		return writeXML(w, res)
+	}
+
+	res := listObjectsResult{
+		Xmlns:          xmlNamespace,
+		Name:           key.Bucket,
+		Prefix:         encode(prefix),
+		Marker:         encode(marker),
+		Delimiter:      encode(delimiter),
+		MaxKeys:        maxKeys,
+		EncodingType:   encodingType,
+		IsTruncated:    truncated,
+		Contents:       contents,
+		CommonPrefixes: prefixes,
+	}
+	if truncated {
+		res.NextMarker = encode(last)
+	}
+	return writeXML(w, res)
+}
+
+// collectEntries 按对象键排序返回前缀下的对象，指定分隔符时将其后的部分合并为公共前缀
+func collectEntries(fs *filesystem.FileSystem, prefix, delimiter string) ([]entry, error) {
+	// 前缀中最后一个 / 之前的部分一定是目录
+	dirPrefix := prefix[:strings.LastIndex(prefix, "/")+1]
+	base := "/"
+	if dirPrefix != "" {
+		p, err := objectPath(dirPrefix)
+		if err != nil {
+			return nil, nil
+		}
+		base = p
+	}
+
+	exist, folder := fs.IsPathExist(base)
+	if !exist {
+		return nil, nil
+	}
+
+	var entries []entry
+	if delimiter == "/" {
+		// 只需列出目录的直接子对象
+		namePrefix := strings.TrimPrefix(prefix, dirPrefix)
+		folders, err := folder.GetChildFolder()
+		if err != nil {
+			return nil, filesystem.ErrDBListObjects.WithError(err)
+		}
+		files, err := folder.GetChildFiles()
+		if err != nil {
+			return nil, filesystem.ErrDBListObjects.WithError(err)
+		}
+
+		for i := range folders {
+			if strings.HasPrefix(folders[i].Name, namePrefix) {
+				entries = append(entries, entry{Key: dirPrefix + folders[i].Name + "/"})
+			}
+		}
+		for i := range files {
+			if files[i].UploadSessionID == nil && strings.HasPrefix(files[i].Name, namePrefix) {
+				entries = append(entries, entry{Key: dirPrefix + files[i].Name, File: &files[i]})
+			}
+		}
+	} else {
+		files, err := walkFiles(fs, folder, dirPrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		seen := make(map[string]bool)
+		for _, e := range files {
+			if !strings.HasPrefix(e.Key, prefix) {
+				continue
+			}
+
+			if delimiter != "" {
+				if i := strings.Index(e.Key[len(prefix):], delimiter); i >= 0 {
+					common := e.Key[:len(prefix)+i+len(delimiter)]
+					if !seen[common] {
+						seen[common] = true
+						entries = append(entries, entry{Key: common})
+					}
+					continue
+				}
+			}
+
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, nil
+}
+
+// walkFiles 递归列出目录下的所有文件，对象键以 dirPrefix 开头
+func walkFiles(fs *filesystem.FileSystem, root *model.Folder, dirPrefix string) ([]entry, error) {
+	folders, err := model.GetRecursiveChildFolder([]uint{root.ID}, fs.User.ID, true)
+	if err != nil {
+		return nil, filesystem.ErrDBListObjects.WithError(err)
+	}
+
+	files, err := model.GetChildFilesOfFolders(&folders)
+	if err != nil {
+		return nil, filesystem.ErrDBListObjects.WithError(err)
+	}
+
+	// 计算每个目录相对于根目录的对象键前缀
+	byID := make(map[uint]*model.Folder, len(folders))
+	for i := range folders {
+		byID[folders[i].ID] = &folders[i]
+	}
+
+	prefixes := map[uint]string{root.ID: dirPrefix}
+	var prefixOf func(id uint) (string, bool)
+	prefixOf = func(id uint) (string, bool) {
+		if p, ok := prefixes[id]; ok {
+			return p, true
+		}
+
+		folder, ok := byID[id]
+		if !ok || folder.ParentID == nil {
+			return "", false
+		}
+
+		parent, ok := prefixOf(*folder.ParentID)
+		if !ok {
+			return "", false
+		}
+
+		prefixes[id] = parent + folder.Name + "/"
+		return prefixes[id], true
+	}
+
+	entries := make([]entry, 0, len(files))
+	for i := range files {
+		if files[i].UploadSessionID != nil {
+			continue
+		}
+
+		if p, ok := prefixOf(files[i].FolderID); ok {
+			entries = append(entries, entry{Key: p + files[i].Name, File: &files[i]})
+		}
+	}
+
+	return entries, nil
+}
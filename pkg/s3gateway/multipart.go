@@ -0,0 +1,310 @@
+package s3gateway
+
+import (
+	"crypto/md5"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// MultipartCachePrefix 分片上传会话的缓存前缀
+	MultipartCachePrefix = "s3_multipart_"
+	// maxPartNumber 最大分片序号
+	maxPartNumber = 10000
+)
+
+// multipartUpload 分片上传会话
+type multipartUpload struct {
+	KeyID       uint
+	UserID      uint
+	Path        string
+	ContentType string
+}
+
+func init() {
+	gob.Register(multipartUpload{})
+}
+
+// PartsDir 返回分片上传会话存放分片的临时目录，id 为空时返回所有会话的上级目录
+func PartsDir(id string) string {
+	return filepath.Join(util.RelativePath(model.GetSettingByName("temp_path")), "s3", id)
+}
+
+// uploadedPart 已上传的分片，文件名为 "序号-MD5"
+type uploadedPart struct {
+	Number int
+	MD5    string
+	Info   os.FileInfo
+}
+
+// listUploadedParts 按序号列出已上传的分片
+func listUploadedParts(id string) ([]uploadedPart, error) {
+	entries, err := os.ReadDir(PartsDir(id))
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]uploadedPart, 0, len(entries))
+	for _, e := range entries {
+		number, sum, ok := strings.Cut(e.Name(), "-")
+		n, err := strconv.Atoi(number)
+		if !ok || err != nil {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+
+		parts = append(parts, uploadedPart{Number: n, MD5: sum, Info: info})
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Number < parts[j].Number
+	})
+	return parts, nil
+}
+
+// loadUpload 查找属于当前密钥和对象的分片上传会话
+func loadUpload(r *http.Request, key *model.S3Key, reqPath string) (string, *multipartUpload, error) {
+	id := r.URL.Query().Get("uploadId")
+	raw, ok := cache.Get(MultipartCachePrefix + id)
+	if id == "" || !ok {
+		return "", nil, ErrNoSuchUpload
+	}
+
+	upload := raw.(multipartUpload)
+	if upload.KeyID != key.ID || upload.Path != reqPath {
+		return "", nil, ErrNoSuchUpload
+	}
+
+	return id, &upload, nil
+}
+
+// removeUpload 删除分片上传会话及已上传的分片
+func removeUpload(id string) {
+	cache.Deletes([]string{id}, MultipartCachePrefix)
+	if err := os.RemoveAll(PartsDir(id)); err != nil {
+		util.Log().Warning("Failed to delete S3 multipart upload parts %q: %s", id, err)
+	}
+}
+
+// createMultipartUpload 创建分片上传会话
+func (h *Handler) createMultipartUpload(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, key *model.S3Key, objectKey, reqPath string) error {
+	if isDirKey(objectKey) {
+		return ErrInvalidObjectName
+	}
+
+	// 提前检查文件名和扩展名，避免上传全部分片后才失败
+	if !fs.ValidateLegalName(r.Context(), path.Base(reqPath)) {
+		return filesystem.ErrIllegalObjectName
+	}
+	if !fs.ValidateExtension(r.Context(), path.Base(reqPath)) {
+		return filesystem.ErrFileExtensionNotAllowed
+	}
+
+	id := strings.ReplaceAll(uuid.Must(uuid.NewV4()).String(), "-", "")
+	if err := os.MkdirAll(PartsDir(id), 0700); err != nil {
+		return err
+	}
+
+	upload := multipartUpload{
+		KeyID:       key.ID,
+		UserID:      fs.User.ID,
+		Path:        reqPath,
+		ContentType: r.Header.Get("Content-Type"),
+	}
+	ttl := model.GetIntSetting("upload_session_timeout", 86400)
+	if err := cache.Set(MultipartCachePrefix+id, upload, ttl); err != nil {
+		return err
+	}
+
+	return writeXML(w, initiateMultipartUploadResult{
+		Xmlns:    xmlNamespace,
+		Bucket:   key.Bucket,
+		Key:      objectKey,
+		UploadID: id,
+	})
+}
+
+// uploadPart 上传分片，分片暂存在本机的临时目录中
+func (h *Handler) uploadPart(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, key *model.S3Key, reqPath string) error {
+	number, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || number < 1 || number > maxPartNumber {
+		return ErrInvalidArgument
+	}
+
+	if r.ContentLength < 0 {
+		return ErrMissingContentLength
+	}
+
+	id, _, err := loadUpload(r, key, reqPath)
+	if err != nil {
+		return err
+	}
+
+	parts, err := listUploadedParts(id)
+	if err != nil {
+		return ErrNoSuchUpload
+	}
+
+	// 检查所有分片的总大小是否超出单文件大小限制和剩余容量
+	total := uint64(r.ContentLength)
+	for _, p := range parts {
+		if p.Number != number {
+			total += uint64(p.Info.Size())
+		}
+	}
+	if !fs.ValidateFileSize(r.Context(), total) || total > fs.User.GetRemainingCapacity() {
+		return ErrEntityTooLarge
+	}
+
+	tmp, err := os.CreateTemp(PartsDir(id), "tmp_")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), r.Body)
+	tmp.Close()
+	if err != nil {
+		return err
+	}
+	if written != r.ContentLength {
+		return ErrIncompleteBody
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	for _, p := range parts {
+		if p.Number == number {
+			os.Remove(filepath.Join(PartsDir(id), p.Info.Name()))
+		}
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(PartsDir(id), strconv.Itoa(number)+"-"+sum)); err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", `"`+sum+`"`)
+	return nil
+}
+
+// completeMultipartUpload 按请求中的顺序合并分片并写入文件系统
+func (h *Handler) completeMultipartUpload(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, key *model.S3Key, objectKey, reqPath string) error {
+	id, upload, err := loadUpload(r, key, reqPath)
+	if err != nil {
+		return err
+	}
+
+	var req completeMultipartUpload
+	if err := xml.NewDecoder(io.LimitReader(r.Body, 2<<20)).Decode(&req); err != nil || len(req.Parts) == 0 {
+		return ErrMalformedXML
+	}
+
+	parts, err := listUploadedParts(id)
+	if err != nil {
+		return ErrNoSuchUpload
+	}
+
+	uploaded := make(map[int]uploadedPart, len(parts))
+	for _, p := range parts {
+		uploaded[p.Number] = p
+	}
+
+	var (
+		size    uint64
+		readers = make([]io.Reader, 0, len(req.Parts))
+		closers = make([]io.Closer, 0, len(req.Parts))
+	)
+	defer func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+
+	for i, p := range req.Parts {
+		if i > 0 && p.PartNumber <= req.Parts[i-1].PartNumber {
+			return ErrInvalidPartOrder
+		}
+
+		part, ok := uploaded[p.PartNumber]
+		if !ok || strings.ToLower(strings.Trim(p.ETag, `"`)) != part.MD5 {
+			return ErrInvalidPart
+		}
+
+		f, err := os.Open(filepath.Join(PartsDir(id), part.Info.Name()))
+		if err != nil {
+			return ErrInvalidPart
+		}
+
+		size += uint64(part.Info.Size())
+		readers = append(readers, f)
+		closers = append(closers, f)
+	}
+
+	file, err := h.upload(r, fs, reqPath, io.NopCloser(io.MultiReader(readers...)), size, upload.ContentType)
+	if err != nil {
+		return err
+	}
+
+	removeUpload(id)
+	return writeXML(w, completeMultipartUploadResult{
+		Xmlns:  xmlNamespace,
+		Bucket: key.Bucket,
+		Key:    objectKey,
+		ETag:   etag(file),
+	})
+}
+
+// abortMultipartUpload 取消分片上传
+func (h *Handler) abortMultipartUpload(w http.ResponseWriter, r *http.Request, key *model.S3Key, reqPath string) error {
+	id, _, err := loadUpload(r, key, reqPath)
+	if err != nil {
+		return err
+	}
+
+	removeUpload(id)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// listParts 列出已上传的分片
+func (h *Handler) listParts(w http.ResponseWriter, r *http.Request, key *model.S3Key, objectKey, reqPath string) error {
+	id, _, err := loadUpload(r, key, reqPath)
+	if err != nil {
+		return err
+	}
+
+	uploaded, err := listUploadedParts(id)
+	if err != nil {
+		return ErrNoSuchUpload
+	}
+
+	res := listPartsResult{Xmlns: xmlNamespace, Bucket: key.Bucket, Key: objectKey, UploadID: id}
+	for _, p := range uploaded {
+		res.Parts = append(res.Parts, part{
+			PartNumber:   p.Number,
+			LastModified: formatTime(p.Info.ModTime()),
+			ETag:         `"` + p.MD5 + `"`,
+			Size:         p.Info.Size(),
+		})
+	}
+
+	return writeXML(w, res)
+}
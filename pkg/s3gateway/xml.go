@@ -0,0 +1,188 @@
+package s3gateway
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	xmlNamespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+	// timeFormat 响应中使用的时间格式
+	timeFormat = "2006-01-02T15:04:05.000Z"
+)
+
+// owner 对象所有者
+type owner struct {
+	ID          string
+	DisplayName string
+}
+
+// bucket 存储桶
+type bucket struct {
+	Name         string
+	CreationDate string
+}
+
+// listBucketsResult ListBuckets 响应
+type listBucketsResult struct {
+	XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Owner   owner
+	Buckets []bucket `xml:"Buckets>Bucket"`
+}
+
+// locationResult GetBucketLocation 响应
+type locationResult struct {
+	XMLName  xml.Name `xml:"LocationConstraint"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:",chardata"`
+}
+
+// object 列表中的对象
+type object struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         uint64
+	StorageClass string
+}
+
+// commonPrefix 按分隔符合并的对象前缀
+type commonPrefix struct {
+	Prefix string
+}
+
+// listObjectsResult ListObjects 响应
+type listObjectsResult struct {
+	XMLName        xml.Name `xml:"ListBucketResult"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	Name           string
+	Prefix         string
+	Marker         string `xml:"Marker,omitempty"`
+	NextMarker     string `xml:"NextMarker,omitempty"`
+	Delimiter      string `xml:"Delimiter,omitempty"`
+	MaxKeys        int
+	EncodingType   string         `xml:"EncodingType,omitempty"`
+	IsTruncated    bool           `xml:"IsTruncated"`
+	Contents       []object       `xml:"Contents"`
+	CommonPrefixes []commonPrefix `xml:"CommonPrefixes"`
+}
+
+// listObjectsV2Result ListObjectsV2 响应
+type listObjectsV2Result struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Xmlns                 string   `xml:"xmlns,attr"`
+	Name                  string
+	Prefix                string
+	StartAfter            string `xml:"StartAfter,omitempty"`
+	ContinuationToken     string `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int
+	Delimiter             string `xml:"Delimiter,omitempty"`
+	MaxKeys               int
+	EncodingType          string         `xml:"EncodingType,omitempty"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []object       `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+// copyObjectResult CopyObject 响应
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	LastModified string
+	ETag         string
+}
+
+// deleteRequest DeleteObjects 请求
+type deleteRequest struct {
+	Quiet   bool
+	Objects []struct {
+		Key string
+	} `xml:"Object"`
+}
+
+// deletedObject 已删除的对象
+type deletedObject struct {
+	Key string
+}
+
+// deleteError 删除失败的对象
+type deleteError struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+// deleteResult DeleteObjects 响应
+type deleteResult struct {
+	XMLName xml.Name        `xml:"DeleteResult"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Deleted []deletedObject `xml:"Deleted"`
+	Errors  []deleteError   `xml:"Error"`
+}
+
+// initiateMultipartUploadResult CreateMultipartUpload 响应
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string
+	Key      string
+	UploadID string `xml:"UploadId"`
+}
+
+// completedPart CompleteMultipartUpload 请求中的分片
+type completedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// completeMultipartUpload CompleteMultipartUpload 请求
+type completeMultipartUpload struct {
+	Parts []completedPart `xml:"Part"`
+}
+
+// completeMultipartUploadResult CompleteMultipartUpload 响应
+type completeMultipartUploadResult struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Bucket  string
+	Key     string
+	ETag    string
+}
+
+// part ListParts 响应中的分片
+type part struct {
+	PartNumber   int
+	LastModified string
+	ETag         string
+	Size         int64
+}
+
+// listPartsResult ListParts 响应
+type listPartsResult struct {
+	XMLName     xml.Name `xml:"ListPartsResult"`
+	Xmlns       string   `xml:"xmlns,attr"`
+	Bucket      string
+	Key         string
+	UploadID    string `xml:"UploadId"`
+	IsTruncated bool
+	Parts       []part `xml:"Part"`
+}
+
+// writeXML 输出 XML 响应正文
+func writeXML(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", "application/xml")
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// formatTime 格式化响应中的时间
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
@@ -192,6 +192,32 @@ const (
 	CodeDisabledSharePreview = 40070
 	// 签名无效
 	CodeInvalidSign = 40071
+	// 秒传持有证明校验失败
+	CodeUploadProofMismatch = 40072
+	// 超出目录配额
+	CodeFolderQuotaExceeded = 40073
+	// 共享目录为只读
+	CodeSharedFolderReadOnly = 40074
+	// 变更游标已过期，需重新同步
+	CodeChangeCursorExpired = 40075
+	// 上传偏移量与已上传的大小不一致
+	CodeUploadOffsetMismatch = 40076
+	// 上传内容的校验和不匹配
+	CodeChecksumMismatch = 40077
+	// 文件收集链接不存在或已失效
+	CodeFileRequestNotFound = 40078
+	// 文件收集链接已达到文件数上限
+	CodeFileRequestFull = 40079
+	// 分享密码尝试次数过多
+	CodeShareUnlockLocked = 40080
+	// 分享仅限指定的接收者访问
+	CodeShareRestricted = 40081
+	// 分享访问验证码错误或已过期
+	CodeShareCodeInvalid = 40082
+	// 自定义分享短链接格式无效或为保留字
+	CodeShareSlugInvalid = 40083
+	// 自定义分享短链接已被使用
+	CodeShareSlugExist = 40084
 	// CodeDBError 数据库操作失败
 	CodeDBError = 50001
 	// CodeEncryptError 加密失败
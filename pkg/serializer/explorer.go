@@ -29,6 +29,8 @@ type ObjectList struct {
 	Parent  string         `json:"parent,omitempty"`
 	Objects []Object       `json:"objects"`
 	Policy  *PolicySummary `json:"policy,omitempty"`
+	// Cursor 分页列出时下一页的游标，为空表示没有更多内容
+	Cursor string `json:"cursor,omitempty"`
 }
 
 // Object 文件或者目录
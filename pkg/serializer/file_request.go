@@ -0,0 +1,80 @@
+package serializer
+
+import (
+	"strings"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+)
+
+// FileRequest 文件收集链接序列化
+type FileRequest struct {
+	Key         string        `json:"key"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Locked      bool          `json:"locked"`
+	Expire      int64         `json:"expire"`
+	MaxSize     uint64        `json:"max_size"`
+	Extensions  []string      `json:"extensions"`
+	RequireName bool          `json:"require_name"`
+	Remain      int           `json:"remain"`
+	CreateDate  time.Time     `json:"create_date"`
+	Creator     *shareCreator `json:"creator,omitempty"`
+
+	// 以下字段只对创建者返回
+	MaxFiles int    `json:"max_files,omitempty"`
+	Uploaded int    `json:"uploaded,omitempty"`
+	Folder   string `json:"folder,omitempty"`
+}
+
+// BuildFileRequestResponse 构建文件收集链接信息响应，owner 为 true 时包含创建者可见的字段
+func BuildFileRequestResponse(request *model.FileRequest, owner bool) FileRequest {
+	resp := FileRequest{
+		Key:         hashid.HashID(request.ID, hashid.FileRequestID),
+		Name:        request.Name,
+		Description: request.Description,
+		Locked:      request.Password != "",
+		Expire:      -1,
+		MaxSize:     request.MaxSize,
+		Extensions:  []string{},
+		RequireName: request.RequireName,
+		Remain:      -1,
+		CreateDate:  request.CreatedAt,
+	}
+
+	if request.Expires != nil {
+		resp.Expire = request.Expires.Unix() - time.Now().Unix()
+	}
+	if request.Extensions != "" {
+		resp.Extensions = strings.Split(request.Extensions, ",")
+	}
+	if request.MaxFiles > 0 {
+		resp.Remain = request.MaxFiles - request.Uploaded
+	}
+
+	if !owner {
+		creator := request.Creator()
+		resp.Creator = &shareCreator{
+			Key:       hashid.HashID(creator.ID, hashid.UserID),
+			Nick:      creator.Nick,
+			GroupName: creator.Group.Name,
+		}
+		return resp
+	}
+
+	resp.MaxFiles = request.MaxFiles
+	resp.Uploaded = request.Uploaded
+	resp.Folder = request.TargetFolder().Name
+	return resp
+}
+
+// BuildFileRequestList 构建文件收集链接列表响应
+func BuildFileRequestList(requests []model.FileRequest) Response {
+	res := make([]FileRequest, 0, len(requests))
+	for i := range requests {
+		res = append(res, BuildFileRequestResponse(&requests[i], true))
+	}
+
+	return Response{Data: res}
+}
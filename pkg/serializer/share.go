@@ -11,6 +11,7 @@ import (
 type Share struct {
 	Key        string        `json:"key"`
 	Locked     bool          `json:"locked"`
+	Restricted bool          `json:"restricted"`
 	IsDir      bool          `json:"is_dir"`
 	CreateDate time.Time     `json:"create_date,omitempty"`
 	Downloads  int           `json:"downloads"`
@@ -19,6 +20,8 @@ type Share struct {
 	Preview    bool          `json:"preview"`
 	Creator    *shareCreator `json:"creator,omitempty"`
 	Source     *shareSource  `json:"source,omitempty"`
+	// CaptchaRequired 密码尝试失败次数过多，下次尝试需要验证码
+	CaptchaRequired bool `json:"captcha_required,omitempty"`
 }
 
 type shareCreator struct {
@@ -36,10 +39,13 @@ type shareSource struct {
 type myShareItem struct {
 	Key             string       `json:"key"`
 	IsDir           bool         `json:"is_dir"`
-	Password        string       `json:"password"`
+	Locked          bool         `json:"locked"`
+	Restricted      bool         `json:"restricted"`
 	CreateDate      time.Time    `json:"create_date,omitempty"`
 	Downloads       int          `json:"downloads"`
 	RemainDownloads int          `json:"remain_downloads"`
+	RemainViews     int          `json:"remain_views"`
+	Slug            string       `json:"slug,omitempty"`
 	Views           int          `json:"views"`
 	Expire          int64        `json:"expire"`
 	Preview         bool         `json:"preview"`
@@ -52,15 +58,18 @@ func BuildShareList(shares []model.Share, total int) Response {
 	now := time.Now().Unix()
 	for i := 0; i < len(shares); i++ {
 		item := myShareItem{
-			Key:             hashid.HashID(shares[i].ID, hashid.ShareID),
+			Key:             shares[i].Key(),
 			IsDir:           shares[i].IsDir,
-			Password:        shares[i].Password,
+			Locked:          shares[i].Password != "",
+			Restricted:      shares[i].Restricted,
 			CreateDate:      shares[i].CreatedAt,
 			Downloads:       shares[i].Downloads,
 			Views:           shares[i].Views,
 			Preview:         shares[i].PreviewEnabled,
 			Expire:          -1,
 			RemainDownloads: shares[i].RemainDownloads,
+			RemainViews:     shares[i].RemainViews,
+			Slug:            shares[i].CustomSlug(),
 		}
 		if shares[i].Expires != nil {
 			item.Expire = shares[i].Expires.Unix() - now
@@ -92,8 +101,9 @@ func BuildShareList(shares []model.Share, total int) Response {
 func BuildShareResponse(share *model.Share, unlocked bool) Share {
 	creator := share.Creator()
 	resp := Share{
-		Key:    hashid.HashID(share.ID, hashid.ShareID),
-		Locked: !unlocked,
+		Key:        share.Key(),
+		Locked:     !unlocked,
+		Restricted: share.Restricted,
 		Creator: &shareCreator{
 			Key:       hashid.HashID(creator.ID, hashid.UserID),
 			Nick:      creator.Nick,
@@ -30,12 +30,26 @@ type UploadCredential struct {
 	KeyTime     string   `json:"keyTime,omitempty"` // COS用有效期
 	Policy      string   `json:"policy,omitempty"`
 	CompleteURL string   `json:"completeURL,omitempty"`
+	// Challenge 秒传持有证明挑战，存储端已有相同内容的文件时可凭此跳过上传
+	Challenge *UploadChallenge `json:"challenge,omitempty"`
+}
+
+// UploadChallenge 秒传持有证明挑战，客户端须提交
+// SHA-256(Nonce + 文件中从 Offset 开始 Length 字节的内容) 的十六进制值
+type UploadChallenge struct {
+	Nonce  string `json:"nonce"`
+	Offset uint64 `json:"offset"`
+	Length uint64 `json:"length"`
+	// Hash 客户端声明的文件 SHA-256
+	Hash string `json:"-"`
 }
 
 // UploadSession 上传会话
 type UploadSession struct {
 	Key            string     // 上传会话 GUID
 	UID            uint       // 发起者
+	UploaderID     uint       // 上传者，上传至与我共享的目录时与发起者不同
+	FileRequestID  uint       // 通过文件收集链接上传时链接的 ID
 	VirtualPath    string     // 用户文件路径，不含文件名
 	Name           string     // 文件名
 	Size           uint64     // 文件大小
@@ -52,6 +66,8 @@ type UploadSession struct {
 // UploadCallback 上传回调正文
 type UploadCallback struct {
 	PicInfo string `json:"pic_info"`
+	// Hash 文件内容的 SHA-256，从机开启去重存储时提供
+	Hash string `json:"hash,omitempty"`
 }
 
 // GeneralUploadCallbackFailed 存储策略上传回调失败响应
@@ -61,4 +77,5 @@ type GeneralUploadCallbackFailed struct {
 
 func init() {
 	gob.Register(UploadSession{})
+	gob.Register(UploadChallenge{})
 }
@@ -1,8 +1,11 @@
 package task
 
 import (
+	"encoding/json"
 	"fmt"
+
 	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"gitee.com/jiangjiali/cloudreve/pkg/webhook"
 )
 
 // Worker 处理任务的对象
@@ -25,6 +28,7 @@ func (worker *GeneralWorker) Do(job Job) {
 			util.Log().Debug("Failed to execute task: %s", err)
 			job.SetError(&JobError{Msg: "Fatal error.", Error: fmt.Sprintf("%s", err)})
 			job.SetStatus(Error)
+			notifyFailed(job)
 		}
 	}()
 
@@ -35,6 +39,7 @@ func (worker *GeneralWorker) Do(job Job) {
 	if err := job.GetError(); err != nil {
 		util.Log().Debug("Failed to execute task.")
 		job.SetStatus(Error)
+		notifyFailed(job)
 		return
 	}
 
@@ -42,3 +47,17 @@ func (worker *GeneralWorker) Do(job Job) {
 	// 执行完成
 	job.SetStatus(Complete)
 }
+
+// notifyFailed 发送任务失败事件
+func notifyFailed(job Job) {
+	event := webhook.Task{Type: job.Type()}
+	if record := job.Model(); record != nil {
+		event.ID = record.ID
+	}
+	if err := job.GetError(); err != nil {
+		res, _ := json.Marshal(err)
+		event.Error = string(res)
+	}
+
+	webhook.Trigger(job.Creator(), webhook.TaskFailed, event)
+}
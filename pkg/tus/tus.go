@@ -0,0 +1,95 @@
+package tus
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"strings"
+)
+
+const (
+	// Version 支持的 tus 协议版本
+	Version = "1.0.0"
+	// Extensions 支持的协议扩展
+	Extensions = "creation,termination,checksum"
+	// ExtensionsWithoutCreation 当前存储策略不支持 tus 上传时支持的协议扩展
+	ExtensionsWithoutCreation = "termination,checksum"
+	// ChecksumAlgorithms 校验和扩展支持的算法
+	ChecksumAlgorithms = "sha1,md5,sha256"
+	// OffsetContentType PATCH 请求正文的类型
+	OffsetContentType = "application/offset+octet-stream"
+	// StatusChecksumMismatch 校验和不匹配时的响应状态码
+	StatusChecksumMismatch = 460
+)
+
+// 协议使用的请求头和响应头
+const (
+	ResumableHeader         = "Tus-Resumable"
+	VersionHeader           = "Tus-Version"
+	ExtensionHeader         = "Tus-Extension"
+	MaxSizeHeader           = "Tus-Max-Size"
+	ChecksumAlgorithmHeader = "Tus-Checksum-Algorithm"
+	OffsetHeader            = "Upload-Offset"
+	LengthHeader            = "Upload-Length"
+	DeferLengthHeader       = "Upload-Defer-Length"
+	MetadataHeader          = "Upload-Metadata"
+	ChecksumHeader          = "Upload-Checksum"
+)
+
+var (
+	ErrInvalidMetadata      = errors.New("invalid Upload-Metadata header")
+	ErrInvalidChecksum      = errors.New("invalid Upload-Checksum header")
+	ErrUnsupportedAlgorithm = errors.New("unsupported checksum algorithm")
+)
+
+// ParseMetadata 解析 Upload-Metadata 请求头，格式为以逗号分隔的 "键 Base64(值)"
+func ParseMetadata(header string) (map[string]string, error) {
+	res := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if key == "" || err != nil {
+			return nil, ErrInvalidMetadata
+		}
+
+		res[key] = string(value)
+	}
+
+	return res, nil
+}
+
+// ParseChecksum 解析 Upload-Checksum 请求头，格式为 "算法 Base64(校验和)"，
+// 返回用于计算正文校验和的 hash 及期望的校验和
+func ParseChecksum(header string) (hash.Hash, []byte, error) {
+	algorithm, encoded, ok := strings.Cut(header, " ")
+	if !ok {
+		return nil, nil, ErrInvalidChecksum
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, ErrInvalidChecksum
+	}
+
+	var h hash.Hash
+	switch algorithm {
+	case "sha1":
+		h = sha1.New()
+	case "md5":
+		h = md5.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return nil, nil, ErrUnsupportedAlgorithm
+	}
+
+	return h, expected, nil
+}
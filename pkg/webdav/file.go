@@ -137,17 +137,17 @@ func copyFiles(ctx context.Context, fs *filesystem.FileSystem, src FileInfo, dst
 	return http.StatusNoContent, nil
 }
 
-// 判断目标 文件/夹 是否已经存在，存在则先删除目标文件/夹
+// 判断目标 文件/夹 是否已经存在，存在则先将目标文件/夹移入回收站
 func _checkOverwriteFile(ctx context.Context, fs *filesystem.FileSystem, src FileInfo, dst string) error {
 	if src.IsDir() {
 		ok, folder := fs.IsPathExist(dst)
 		if ok {
-			return fs.Delete(ctx, []uint{folder.ID}, []uint{}, false, false)
+			return fs.Trash(ctx, []uint{folder.ID}, []uint{})
 		}
 	} else {
 		ok, file := fs.IsFileExist(dst)
 		if ok {
-			return fs.Delete(ctx, []uint{}, []uint{file.ID}, false, false)
+			return fs.Trash(ctx, []uint{}, []uint{file.ID})
 		}
 	}
 	return nil
@@ -180,27 +180,77 @@ func walkFS(
 		depth = 0
 	}
 
-	dirs, _ := info.(*model.Folder).GetChildFolder()
-	files, _ := info.(*model.Folder).GetChildFiles()
+	walkChildren := func(dirs []model.Folder, files []model.File) error {
+		for _, fileInfo := range files {
+			filename := path.Join(name, fileInfo.Name)
+			err := walkFS(ctx, fs, depth, filename, &fileInfo, walkFn)
+			if err != nil {
+				if !fileInfo.IsDir() || err != filepath.SkipDir {
+					return err
+				}
+			}
+		}
 
-	for _, fileInfo := range files {
-		filename := path.Join(name, fileInfo.Name)
-		err = walkFS(ctx, fs, depth, filename, &fileInfo, walkFn)
-		if err != nil {
-			if !fileInfo.IsDir() || err != filepath.SkipDir {
-				return err
+		for _, fileInfo := range dirs {
+			filename := path.Join(name, fileInfo.Name)
+			err := walkFS(ctx, fs, depth, filename, &fileInfo, walkFn)
+			if err != nil {
+				if !fileInfo.IsDir() || err != filepath.SkipDir {
+					return err
+				}
 			}
 		}
+		return nil
+	}
+
+	folder := info.(*model.Folder)
+	if folder.ID == 0 && filesystem.IsVirtualRoot(name) {
+		// “与我共享”及团队空间虚拟目录
+		return walkChildren(sharedChildren(fs, name), nil)
+	}
+
+	// 根目录下展示“与我共享”及团队空间虚拟目录
+	if name == "/" {
+		if err := walkChildren(virtualRoots(fs), nil); err != nil {
+			return err
+		}
+	}
+
+	return listChildren(folder, walkChildren)
+}
+
+// walkBatchSize 遍历目录时每批读取的子对象数量
+const walkBatchSize = 1000
+
+// listChildren 分批读取目录的子对象，每批调用一次 fn，避免一次载入大目录的全部内容
+func listChildren(folder *model.Folder, fn func(dirs []model.Folder, files []model.File) error) error {
+	opts := &model.SearchOptions{
+		UserID:  folder.OwnerID,
+		Folders: []uint{folder.ID},
+		Limit:   walkBatchSize,
 	}
+	parent := path.Join(folder.Position, folder.Name)
 
-	for _, fileInfo := range dirs {
-		filename := path.Join(name, fileInfo.Name)
-		err = walkFS(ctx, fs, depth, filename, &fileInfo, walkFn)
+	for {
+		dirs, files, next, err := model.Search(opts)
 		if err != nil {
-			if !fileInfo.IsDir() || err != filepath.SkipDir {
-				return err
-			}
+			return err
+		}
+
+		for i := range dirs {
+			dirs[i].Position = parent
+		}
+		for i := range files {
+			files[i].Position = parent
 		}
+
+		if err := fn(dirs, files); err != nil {
+			return err
+		}
+
+		if next == nil {
+			return nil
+		}
+		opts.Cursor = next
 	}
-	return nil
 }
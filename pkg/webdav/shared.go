@@ -0,0 +1,110 @@
+package webdav
+
+import (
+	"net/http"
+	"path"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+)
+
+// virtualRoots 返回“与我共享”及团队空间虚拟目录，仅在未重定根目录且其中有内容时存在
+func virtualRoots(fs *filesystem.FileSystem) []model.Folder {
+	if fs.Root != nil {
+		return nil
+	}
+
+	objects := fs.VirtualRootObjects()
+	res := make([]model.Folder, 0, len(objects))
+	for _, object := range objects {
+		res = append(res, model.Folder{Name: object.Name})
+	}
+
+	return res
+}
+
+// virtualRoot 查找路径对应的虚拟目录
+func virtualRoot(fs *filesystem.FileSystem, p string) (*model.Folder, bool) {
+	for _, root := range virtualRoots(fs) {
+		if path.Join("/", root.Name) == p {
+			return &root, true
+		}
+	}
+
+	return nil, false
+}
+
+// sharedChildren 列出虚拟目录 root 下的授权目录或团队空间
+func sharedChildren(fs *filesystem.FileSystem, root string) []model.Folder {
+	folders, _ := fs.ListVirtualFolders(root)
+	res := make([]model.Folder, 0, len(folders))
+	for _, shared := range folders {
+		folder := *shared.Folder
+		folder.Name = shared.Name
+		res = append(res, folder)
+	}
+
+	return res
+}
+
+// isReadMethod 返回请求是否不会修改授权目录
+func isReadMethod(method string) bool {
+	switch method {
+	case "OPTIONS", "GET", "HEAD", "POST", "PROPFIND", "LOCK", "UNLOCK":
+		return true
+	}
+
+	return false
+}
+
+// serveShared 处理“与我共享”及团队空间目录下的请求，以授权目录为根目录，使用所有者的文件系统；
+// 返回 false 表示请求不在虚拟目录下
+func (h *Handler) serveShared(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) bool {
+	if fs.Root != nil {
+		return false
+	}
+
+	reqPath, _, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+	if err != nil || !filesystem.IsSharedPath(reqPath) {
+		return false
+	}
+
+	// 虚拟目录本身只能读取
+	if filesystem.IsVirtualRoot(reqPath) {
+		if isReadMethod(r.Method) {
+			return false
+		}
+
+		h.writeStatus(w, r, http.StatusForbidden, nil)
+		return true
+	}
+
+	shared, inner, err := fs.ResolveSharedPath(reqPath)
+	if err != nil {
+		h.writeStatus(w, r, http.StatusNotFound, err)
+		return true
+	}
+
+	// 只读授权，以及授权目录本身不能被修改；团队空间的查看者只读
+	if !isReadMethod(r.Method) && (!shared.Writable() || (inner == "/" && r.Method != "PROPPATCH")) {
+		h.writeStatus(w, r, http.StatusForbidden, nil)
+		return true
+	}
+
+	ownerFS, err := shared.NewFileSystem()
+	if err != nil {
+		h.writeStatus(w, r, http.StatusInternalServerError, err)
+		return true
+	}
+
+	root := *shared.Folder
+	root.Position = ""
+	root.Name = "/"
+	ownerFS.Root = &root
+	fs.Recycle()
+
+	sub := *h
+	sub.Prefix = path.Join(h.Prefix, shared.Base, shared.Name)
+	sub.ServeHTTP(w, r, ownerFS)
+	return true
+}
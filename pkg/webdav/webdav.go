@@ -51,6 +51,13 @@ func (h *Handler) stripPrefix(p string, uid uint) (string, int, error) {
 
 // isPathExist 路径是否存在
 func isPathExist(ctx context.Context, fs *filesystem.FileSystem, path string) (bool, FileInfo) {
+	// “与我共享”及团队空间虚拟目录
+	if filesystem.IsVirtualRoot(path) {
+		if root, ok := virtualRoot(fs, path); ok {
+			return true, root
+		}
+	}
+
 	// 尝试目录
 	if ok, folder := fs.IsPathExist(path); ok {
 		return ok, folder
@@ -62,6 +69,11 @@ func isPathExist(ctx context.Context, fs *filesystem.FileSystem, path string) (b
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) {
+	// 与我共享的目录
+	if h.serveShared(w, r, fs) {
+		return
+	}
+
 	status, err := http.StatusBadRequest, errUnsupportedMethod
 	h.Mutex.Lock()
 	if h.LockSystem == nil {
@@ -100,6 +112,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesyst
 		}
 	}
 
+	h.writeStatus(w, r, status, err)
+}
+
+// writeStatus 写入响应状态并记录日志
+func (h *Handler) writeStatus(w http.ResponseWriter, r *http.Request, status int, err error) {
 	if status != 0 {
 		w.WriteHeader(status)
 		if status != http.StatusNoContent {
@@ -337,7 +354,7 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *files
 
 	// 尝试作为文件删除
 	if ok, file := fs.IsFileExist(reqPath); ok {
-		if err := fs.Delete(ctx, []uint{}, []uint{file.ID}, false, false); err != nil {
+		if err := fs.Trash(ctx, []uint{}, []uint{file.ID}); err != nil {
 			return http.StatusMethodNotAllowed, err
 		}
 		return http.StatusNoContent, nil
@@ -345,7 +362,7 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *files
 
 	// 尝试作为目录删除
 	if ok, folder := fs.IsPathExist(reqPath); ok {
-		if err := fs.Delete(ctx, []uint{folder.ID}, []uint{}, false, false); err != nil {
+		if err := fs.Trash(ctx, []uint{folder.ID}, []uint{}); err != nil {
 			return http.StatusMethodNotAllowed, err
 		}
 		return http.StatusNoContent, nil
@@ -360,6 +377,10 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 	if err != nil {
 		return status, err
 	}
+	// 不支持部分写入，按 RFC 7231 拒绝带有 Content-Range 的请求，避免分片覆盖整个文件
+	if r.Header.Get("Content-Range") != "" {
+		return http.StatusBadRequest, errUnsupportedContentRange
+	}
 	release, status, err := h.confirmLocks(r, reqPath, "", fs)
 	if err != nil {
 		return status, err
@@ -391,13 +412,20 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 	if exist {
 		// 已存在，为更新操作
 
+		// 开启历史版本时，原有内容保存为历史版本
+		versioned := fs.UseVersioning(ctx, originFile, &fileData)
+
 		// 检查此文件是否有软链接
 		fileList, err := model.RemoveFilesWithSoftLinks([]model.File{*originFile})
-		if err == nil && len(fileList) == 0 {
+		if !versioned && err == nil && len(fileList) == 0 {
 			// 如果包含软连接，应重新生成新文件副本，并更新source_name
+			originSource := originFile.SourceName
 			originFile.SourceName = fs.GenerateSavePath(ctx, &fileData)
 			fileData.Mode &= ^fsctx.Overwrite
 			fs.Use("AfterUpload", filesystem.HookUpdateSourceName)
+			if model.IsBlobSource(originFile.PolicyID, originSource) {
+				fs.Use("AfterUpload", filesystem.HookReleaseBlob(originSource))
+			}
 			fs.Use("AfterUploadCanceled", filesystem.HookUpdateSourceName)
 			fs.Use("AfterValidateFailed", filesystem.HookUpdateSourceName)
 		}
@@ -405,12 +433,15 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 		fs.Use("BeforeUpload", filesystem.HookResetPolicy)
 		fs.Use("BeforeUpload", filesystem.HookValidateFile)
 		fs.Use("BeforeUpload", filesystem.HookValidateCapacityDiff)
-		fs.Use("AfterUploadCanceled", filesystem.HookCleanFileContent)
-		fs.Use("AfterUploadCanceled", filesystem.HookClearFileSize)
+		if !versioned {
+			fs.Use("AfterUploadCanceled", filesystem.HookCleanFileContent)
+			fs.Use("AfterUploadCanceled", filesystem.HookClearFileSize)
+			fs.Use("AfterValidateFailed", filesystem.HookCleanFileContent)
+			fs.Use("AfterValidateFailed", filesystem.HookClearFileSize)
+		}
 		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
 		fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
-		fs.Use("AfterValidateFailed", filesystem.HookCleanFileContent)
-		fs.Use("AfterValidateFailed", filesystem.HookClearFileSize)
+		fs.Use("AfterUpload", filesystem.HookCommitBlob)
 		ctx = context.WithValue(ctx, fsctx.FileModelCtx, *originFile)
 		fileData.Mode |= fsctx.Overwrite
 	} else {
@@ -420,6 +451,7 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 		fs.Use("AfterUploadCanceled", filesystem.HookDeleteTempFile)
 		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
 		fs.Use("AfterUpload", filesystem.GenericAfterUpload)
+		fs.Use("AfterUpload", filesystem.HookCommitBlob)
 		fs.Use("AfterValidateFailed", filesystem.HookDeleteTempFile)
 	}
 
@@ -727,6 +759,7 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request, fs *fil
 	}
 
 	mw := multistatusWriter{w: w}
+	written := 0
 
 	walkFn := func(reqPath string, info FileInfo, err error) error {
 
@@ -756,7 +789,16 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request, fs *fil
 		if href != "/" && info.IsDir() {
 			href += "/"
 		}
-		return mw.write(makePropstatResponse(href, pstats))
+		if err := mw.write(makePropstatResponse(href, pstats)); err != nil {
+			return err
+		}
+
+		// 定期将已生成的响应发送给客户端
+		written++
+		if flusher, ok := w.(http.Flusher); ok && written%walkBatchSize == 0 {
+			flusher.Flush()
+		}
+		return nil
 	}
 
 	walkErr := walkFS(ctx, fs, depth, reqPath, fi, walkFn)
@@ -897,6 +939,7 @@ var (
 	errNotADirectory           = errors.New("webdav: not a directory")
 	errPrefixMismatch          = errors.New("webdav: prefix mismatch")
 	errRecursionTooDeep        = errors.New("webdav: recursion too deep")
+	errUnsupportedContentRange = errors.New("webdav: unsupported content range")
 	errUnsupportedLockInfo     = errors.New("webdav: unsupported lock info")
 	errUnsupportedMethod       = errors.New("webdav: unsupported method")
 )
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrPrivateAddress 接收地址指向本机、内网或链路本地地址
+	ErrPrivateAddress = errors.New("webhook target resolves to a non-public address")
+
+	// reservedNetworks 除标准库可识别的地址外，其他不可作为接收地址的保留网段
+	reservedNetworks = mustParseCIDRs(
+		"0.0.0.0/8",
+		"100.64.0.0/10",
+		"192.0.0.0/24",
+		"198.18.0.0/15",
+		"240.0.0.0/4",
+		"64:ff9b::/96",
+	)
+)
+
+// publicTransport 用户创建的 Webhook 使用的传输层，建立连接时解析并校验地址，
+// 并直接连接校验过的地址，避免通过 DNS 重绑定访问内网
+var publicTransport http.RoundTripper = &http.Transport{
+	DialContext:         dialPublic,
+	TLSHandshakeTimeout: 10 * time.Second,
+	MaxIdleConns:        10,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+// dialPublic 仅连接公网地址
+func dialPublic(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		if !IsPublicIP(ip.IP) {
+			return nil, fmt.Errorf("%w: %s", ErrPrivateAddress, ip.IP)
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	for _, ip := range ips {
+		conn, dialErr := dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if dialErr == nil {
+			return conn, nil
+		}
+		err = dialErr
+	}
+
+	if err == nil {
+		err = fmt.Errorf("no address found for %q", host)
+	}
+	return nil, err
+}
+
+// IsPublicIP 返回给定地址是否为公网地址
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsPublicURL 返回是否为用户可用的接收地址，不允许使用本机及内网的主机名和 IP，
+// 域名解析后的地址在发送时校验
+func IsPublicURL(target string) bool {
+	if !IsValidURL(target) {
+		return false
+	}
+
+	u, _ := url.Parse(target)
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return IsPublicIP(ip)
+	}
+
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,278 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/auth"
+	"gitee.com/jiangjiali/cloudreve/pkg/conf"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/request"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+)
+
+// 事件类型
+const (
+	FileUploaded  = "file.uploaded"
+	FileUpdated   = "file.updated"
+	FileDeleted   = "file.deleted"
+	FileMoved     = "file.moved"
+	ShareCreated  = "share.created"
+	ShareAccessed = "share.accessed"
+	// DownloadFinished 离线下载完成并已转存
+	DownloadFinished = "download.finished"
+	TaskFailed       = "task.failed"
+)
+
+// Events 所有可订阅的事件
+var Events = []string{
+	FileUploaded, FileUpdated, FileDeleted, FileMoved,
+	ShareCreated, ShareAccessed, DownloadFinished, TaskFailed,
+}
+
+const (
+	// SignatureHeader 签名所在的请求头，签名内容为请求正文
+	SignatureHeader = auth.CrHeaderPrefix + "Webhook-Signature"
+	// EventHeader 事件类型所在的请求头
+	EventHeader = auth.CrHeaderPrefix + "Webhook-Event"
+	// DeliveryHeader 投递记录ID所在的请求头
+	DeliveryHeader = auth.CrHeaderPrefix + "Webhook-Delivery"
+
+	// maxResponseLength 管理员 Webhook 投递记录中保存的响应正文最大长度
+	maxResponseLength = 4096
+)
+
+// Client 发送事件使用的请求客户端
+var Client request.Client = request.NewClient()
+
+// retryDelay 第 attempt 次投递失败后的等待时间，按指数增长
+var retryDelay = func(attempt int) time.Duration {
+	interval := model.GetIntSetting("webhook_retry_interval", 10)
+	return time.Duration(interval) * time.Second << uint(attempt-1)
+}
+
+// Payload 事件请求正文
+type Payload struct {
+	ID    string      `json:"id"`
+	Event string      `json:"event"`
+	User  string      `json:"user"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+// Object 文件事件涉及的文件或目录
+type Object struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	IsFolder bool   `json:"is_folder"`
+	Size     uint64 `json:"size"`
+	OldName  string `json:"old_name,omitempty"`
+	OldPath  string `json:"old_path,omitempty"`
+}
+
+// NewObject 根据变更记录构建事件中的对象
+func NewObject(change *model.Change) Object {
+	idType := hashid.FileID
+	if change.IsFolder {
+		idType = hashid.FolderID
+	}
+
+	return Object{
+		ID:       hashid.HashID(change.ObjectID, idType),
+		Name:     change.Name,
+		Path:     change.Path,
+		IsFolder: change.IsFolder,
+		Size:     change.Size,
+		OldName:  change.OldName,
+		OldPath:  change.OldPath,
+	}
+}
+
+// Share 分享事件涉及的分享
+type Share struct {
+	// ID 分享的标识，轮换链接或设定短链接后保持不变。未轮换过链接的分享
+	// 可以此访问，与 URL 同样应视为访问凭据
+	ID string `json:"id"`
+	// URL 当前可用的分享链接
+	URL       string `json:"url"`
+	Name      string `json:"name"`
+	IsDir     bool   `json:"is_dir"`
+	Views     int    `json:"views"`
+	Downloads int    `json:"downloads"`
+}
+
+// NewShare 构建事件中的分享
+func NewShare(share *model.Share) Share {
+	sharePath, _ := url.Parse("/s/" + share.Key())
+	return Share{
+		ID:        hashid.HashID(share.ID, hashid.ShareID),
+		URL:       model.GetSiteURL().ResolveReference(sharePath).String(),
+		Name:      share.SourceName,
+		IsDir:     share.IsDir,
+		Views:     share.Views,
+		Downloads: share.Downloads,
+	}
+}
+
+// Download 离线下载事件涉及的下载任务
+type Download struct {
+	GID    string `json:"gid"`
+	Source string `json:"source"`
+	Dst    string `json:"dst"`
+	Size   uint64 `json:"size"`
+}
+
+// NewDownload 构建事件中的下载任务
+func NewDownload(download *model.Download) Download {
+	return Download{
+		GID:    download.GID,
+		Source: download.Source,
+		Dst:    download.Dst,
+		Size:   download.TotalSize,
+	}
+}
+
+// Task 任务事件涉及的任务
+type Task struct {
+	ID    uint   `json:"id"`
+	Type  int    `json:"type"`
+	Error string `json:"error,omitempty"`
+}
+
+// Trigger 向用户及管理员订阅了事件的 Webhook 发送事件，投递在后台进行
+func Trigger(uid uint, event string, data interface{}) {
+	// 从机不处理事件
+	if conf.SystemConfig.Mode != "master" {
+		return
+	}
+
+	hooks, err := model.GetActiveWebhooks(uid)
+	if err != nil {
+		util.Log().Warning("Failed to list webhooks: %s", err)
+		return
+	}
+
+	for i := range hooks {
+		if !hooks[i].Subscribed(event) {
+			continue
+		}
+
+		delivery, err := newDelivery(&hooks[i], uid, event, data)
+		if err != nil {
+			util.Log().Warning("Failed to create webhook delivery: %s", err)
+			continue
+		}
+
+		go deliver(&hooks[i], delivery)
+	}
+}
+
+// newDelivery 创建事件的投递记录
+func newDelivery(hook *model.Webhook, uid uint, event string, data interface{}) (*model.WebhookDelivery, error) {
+	delivery := &model.WebhookDelivery{
+		WebhookID: hook.ID,
+		Event:     event,
+	}
+	if err := delivery.Create(); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(Payload{
+		ID:    strconv.FormatUint(uint64(delivery.ID), 10),
+		Event: event,
+		User:  hashid.HashID(uid, hashid.UserID),
+		Time:  delivery.CreatedAt,
+		Data:  data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = string(payload)
+	return delivery, delivery.Save()
+}
+
+// deliver 投递事件，失败时按指数退避重试
+func deliver(hook *model.Webhook, delivery *model.WebhookDelivery) {
+	maxAttempts := model.GetIntSetting("webhook_max_attempts", 5)
+	for {
+		delivery.Attempts++
+		send(hook, delivery)
+		if err := delivery.Save(); err != nil {
+			util.Log().Warning("Failed to save webhook delivery %d: %s", delivery.ID, err)
+		}
+
+		if delivery.Success || delivery.Attempts >= maxAttempts {
+			return
+		}
+
+		time.Sleep(retryDelay(delivery.Attempts))
+	}
+}
+
+// send 发送一次事件请求，并将结果记录到投递记录中
+func send(hook *model.Webhook, delivery *model.WebhookDelivery) {
+	signer := auth.HMACAuth{SecretKey: []byte(hook.Secret)}
+	expires := time.Now().Add(time.Duration(model.GetIntSetting("webhook_sign_ttl", 300)) * time.Second).Unix()
+	header := http.Header{
+		"Content-Type":  {"application/json"},
+		SignatureHeader: {signer.Sign(delivery.Payload, expires)},
+		EventHeader:     {delivery.Event},
+		DeliveryHeader:  {strconv.FormatUint(uint64(delivery.ID), 10)},
+	}
+
+	opts := []request.Option{
+		request.WithHeader(header),
+		request.WithContentLength(int64(len(delivery.Payload))),
+		request.WithTimeout(time.Duration(model.GetIntSetting("webhook_timeout", 10)) * time.Second),
+	}
+	// 用户创建的 Webhook 只能访问公网地址
+	if hook.UserID != 0 {
+		opts = append(opts, request.WithTransport(publicTransport))
+	}
+
+	resp := Client.Request("POST", hook.URL, strings.NewReader(delivery.Payload), opts...)
+
+	delivery.StatusCode, delivery.Response, delivery.Error = 0, "", ""
+	if resp.Err != nil {
+		delivery.Error = resp.Err.Error()
+		return
+	}
+	defer resp.Response.Body.Close()
+
+	// 仅管理员 Webhook 保存响应正文，用户 Webhook 只记录状态码
+	delivery.StatusCode = resp.Response.StatusCode
+	if hook.UserID == 0 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Response.Body, maxResponseLength))
+		delivery.Response = string(body)
+	}
+	delivery.Success = resp.Response.StatusCode >= 200 && resp.Response.StatusCode < 300
+	if !delivery.Success {
+		delivery.Error = fmt.Sprintf("unexpected status code %d", resp.Response.StatusCode)
+	}
+}
+
+// IsValidURL 返回是否为可用的接收地址，只支持 HTTP 和 HTTPS
+func IsValidURL(target string) bool {
+	u, err := url.Parse(target)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// IsValidEvents 返回事件列表是否均为可订阅的事件
+func IsValidEvents(events []string) bool {
+	for _, event := range events {
+		if !util.ContainsString(Events, event) {
+			return false
+		}
+	}
+
+	return true
+}
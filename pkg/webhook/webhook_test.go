@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/auth"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	cache.Init()
+	model.Init()
+	retryDelay = func(attempt int) time.Duration { return 0 }
+	os.Exit(m.Run())
+}
+
+func newTestHook(t *testing.T, uid uint, target string) *model.Webhook {
+	hook := &model.Webhook{
+		UserID: uid,
+		Name:   "test",
+		URL:    target,
+		Secret: "secret",
+		Events: FileUploaded,
+		Enable: true,
+	}
+	_, err := hook.Create()
+	assert.NoError(t, err)
+	return hook
+}
+
+func TestSend_Signature(t *testing.T) {
+	a := assert.New(t)
+	var (
+		body   string
+		header http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		body, header = string(raw), r.Header
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	hook := newTestHook(t, 0, server.URL)
+	delivery, err := newDelivery(hook, 1, FileUploaded, Object{Name: "a.txt"})
+	a.NoError(err)
+
+	send(hook, delivery)
+	a.True(delivery.Success)
+	a.Equal(200, delivery.StatusCode)
+	a.Equal("ok", delivery.Response)
+	a.Equal(delivery.Payload, body)
+	a.Equal(FileUploaded, header.Get(EventHeader))
+	a.NoError(auth.HMACAuth{SecretKey: []byte(hook.Secret)}.Check(body, header.Get(SignatureHeader)))
+	a.Error(auth.HMACAuth{SecretKey: []byte("other")}.Check(body, header.Get(SignatureHeader)))
+}
+
+func TestDeliver_Retry(t *testing.T) {
+	a := assert.New(t)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hook := newTestHook(t, 0, server.URL)
+	delivery, err := newDelivery(hook, 1, FileUploaded, nil)
+	a.NoError(err)
+
+	deliver(hook, delivery)
+	a.EqualValues(3, atomic.LoadInt32(&calls))
+
+	// 投递记录应已保存最后一次的结果
+	deliveries := model.ListWebhookDeliveries(hook.ID, 10)
+	a.Len(deliveries, 1)
+	a.Equal(3, deliveries[0].Attempts)
+	a.True(deliveries[0].Success)
+	a.Equal(http.StatusNoContent, deliveries[0].StatusCode)
+	a.Empty(deliveries[0].Error)
+}
+
+func TestDeliver_GiveUp(t *testing.T) {
+	a := assert.New(t)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	hook := newTestHook(t, 0, server.URL)
+	delivery, err := newDelivery(hook, 1, FileUploaded, nil)
+	a.NoError(err)
+
+	deliver(hook, delivery)
+	maxAttempts := model.GetIntSetting("webhook_max_attempts", 5)
+	a.EqualValues(maxAttempts, atomic.LoadInt32(&calls))
+
+	deliveries := model.ListWebhookDeliveries(hook.ID, 10)
+	a.Len(deliveries, 1)
+	a.Equal(maxAttempts, deliveries[0].Attempts)
+	a.False(deliveries[0].Success)
+	a.Equal(http.StatusBadGateway, deliveries[0].StatusCode)
+	a.Contains(deliveries[0].Error, "502")
+}
+
+func TestSend_UserHookPrivateAddress(t *testing.T) {
+	a := assert.New(t)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	hook := newTestHook(t, 1, server.URL)
+	delivery, err := newDelivery(hook, 1, FileUploaded, nil)
+	a.NoError(err)
+
+	send(hook, delivery)
+	a.False(delivery.Success)
+	a.Zero(delivery.StatusCode)
+	a.Contains(delivery.Error, ErrPrivateAddress.Error())
+	a.Zero(atomic.LoadInt32(&calls))
+}
+
+func TestSend_UserHookResponseDiscarded(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret body"))
+	}))
+	defer server.Close()
+
+	// 测试服务器只监听本机地址，此处跳过地址校验
+	transport := publicTransport
+	publicTransport = http.DefaultTransport
+	defer func() { publicTransport = transport }()
+
+	hook := newTestHook(t, 1, server.URL)
+	delivery, err := newDelivery(hook, 1, FileUploaded, nil)
+	a.NoError(err)
+
+	send(hook, delivery)
+	a.True(delivery.Success)
+	a.Equal(200, delivery.StatusCode)
+	a.Empty(delivery.Response)
+}
+
+func TestIsPublicURL(t *testing.T) {
+	a := assert.New(t)
+	a.True(IsPublicURL("https://example.com/hook"))
+	a.True(IsPublicURL("http://93.184.216.34/hook"))
+	a.False(IsPublicURL("ftp://example.com/hook"))
+	a.False(IsPublicURL("http://localhost:8080/hook"))
+	a.False(IsPublicURL("http://127.0.0.1/hook"))
+	a.False(IsPublicURL("http://10.0.0.1/hook"))
+	a.False(IsPublicURL("http://169.254.169.254/latest/meta-data"))
+	a.False(IsPublicURL("http://[::1]/hook"))
+	a.False(IsPublicURL("http://[::ffff:192.168.1.1]/hook"))
+	a.False(IsPublicURL("http://100.64.0.1/hook"))
+}
+
+func TestIsPublicIP(t *testing.T) {
+	a := assert.New(t)
+	a.True(IsPublicIP(net.ParseIP("8.8.8.8")))
+	a.True(IsPublicIP(net.ParseIP("2001:4860:4860::8888")))
+	a.False(IsPublicIP(net.ParseIP("172.16.0.1")))
+	a.False(IsPublicIP(net.ParseIP("fe80::1")))
+	a.False(IsPublicIP(net.ParseIP("fc00::1")))
+	a.False(IsPublicIP(net.ParseIP("0.0.0.0")))
+}
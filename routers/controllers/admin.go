@@ -297,6 +297,17 @@ func AdminListFile(c *gin.Context) {
 	}
 }
 
+// AdminDedupReport 获取去重存储统计
+func AdminDedupReport(c *gin.Context) {
+	var service admin.NoParamService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.DedupReport()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminGetFile 获取文件
 func AdminGetFile(c *gin.Context) {
 	var service admin.FileService
@@ -469,3 +480,113 @@ func AdminGetNode(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// AdminListTeam 列出团队空间
+func AdminListTeam(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Teams()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAddTeam 新建或修改团队空间
+func AdminAddTeam(c *gin.Context) {
+	var service admin.AddTeamService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminGetTeam 获取团队空间详情
+func AdminGetTeam(c *gin.Context) {
+	var service admin.TeamService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Get()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteTeam 删除团队空间
+func AdminDeleteTeam(c *gin.Context) {
+	var service admin.TeamService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminSetTeamMember 设置团队成员
+func AdminSetTeamMember(c *gin.Context) {
+	var service admin.TeamMemberService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.SetMember()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListWebhook 列出 Webhook
+func AdminListWebhook(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Webhooks()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListAuditLog 列出审计日志
+func AdminListAuditLog(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.AuditLogs()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAddWebhook 新建或修改全局 Webhook
+func AdminAddWebhook(c *gin.Context) {
+	var service admin.AddWebhookService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListWebhookDeliveries 列出 Webhook 的投递记录
+func AdminListWebhookDeliveries(c *gin.Context) {
+	var service admin.WebhookService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Deliveries()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteWebhook 删除 Webhook
+func AdminDeleteWebhook(c *gin.Context) {
+	var service admin.WebhookService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -15,3 +15,14 @@ func RemoteCallback(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// S3Callback S3 客户端直传完成回调
+func S3Callback(c *gin.Context) {
+	var callbackBody callback.S3Callback
+	if err := c.ShouldBindQuery(&callbackBody); err == nil {
+		res := callbackBody.PreProcess(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"gitee.com/jiangjiali/cloudreve/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// ListChanges 列出文件变更
+func ListChanges(c *gin.Context) {
+	var service explorer.ChangeListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -19,10 +19,44 @@ func CreateDirectory(c *gin.Context) {
 // ListDirectory 列出目录下内容
 func ListDirectory(c *gin.Context) {
 	var service explorer.DirectoryService
-	if err := c.ShouldBindUri(&service); err == nil {
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindQuery(&service); err == nil {
 		res := service.ListDirectory(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// ListFolderQuotas 列出开启配额的目录
+func ListFolderQuotas(c *gin.Context) {
+	var service explorer.FolderQuotaService
+	res := service.ListQuotas(c)
+	c.JSON(200, res)
+}
+
+// GetFolderQuota 获取目录配额
+func GetFolderQuota(c *gin.Context) {
+	var service explorer.FolderQuotaService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.GetQuota(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// SetFolderQuota 设置目录配额
+func SetFolderQuota(c *gin.Context) {
+	var service explorer.FolderQuotaService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.SetQuota(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
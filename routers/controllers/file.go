@@ -20,7 +20,10 @@ func DownloadArchive(c *gin.Context) {
 
 	var service explorer.ArchiveService
 	if err := c.ShouldBindUri(&service); err == nil {
-		service.DownloadArchived(ctx, c)
+		res := service.DownloadArchived(ctx, c)
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
 	} else {
 		c.JSON(200, ErrorResponse(err))
 	}
@@ -352,6 +355,26 @@ func FileUpload(c *gin.Context) {
 	//})
 }
 
+// InstantUpload 凭持有证明秒传文件
+func InstantUpload(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.UploadProofService
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Instant(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // DeleteUploadSession 删除上传会话
 func DeleteUploadSession(c *gin.Context) {
 	// 创建上下文
@@ -409,6 +432,17 @@ func SearchFile(c *gin.Context) {
 	c.JSON(200, res)
 }
 
+// QueryFile 按条件搜索文件和目录
+func QueryFile(c *gin.Context) {
+	var service explorer.ItemQueryService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Query(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // CreateFile 创建空白文件
 func CreateFile(c *gin.Context) {
 	var service explorer.SingleFileService
@@ -419,3 +453,57 @@ func CreateFile(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// ListFileVersions 列出文件的历史版本
+func ListFileVersions(c *gin.Context) {
+	var service explorer.FileIDService
+	res := service.ListVersions(c)
+	c.JSON(200, res)
+}
+
+// DownloadFileVersion 下载文件的历史版本
+func DownloadFileVersion(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.FileVersionService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Download(ctx, c)
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// RestoreFileVersion 将文件还原为历史版本
+func RestoreFileVersion(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.FileVersionService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Restore(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteFileVersions 删除文件的历史版本
+func DeleteFileVersions(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.FileVersionDeleteService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Delete(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
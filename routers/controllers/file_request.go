@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/request"
+	"gitee.com/jiangjiali/cloudreve/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// ListFileRequests 列出用户创建的文件收集链接
+func ListFileRequests(c *gin.Context) {
+	var service explorer.FileRequestService
+	res := service.List(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// CreateFileRequest 创建文件收集链接
+func CreateFileRequest(c *gin.Context) {
+	var service explorer.FileRequestCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteFileRequest 删除文件收集链接
+func DeleteFileRequest(c *gin.Context) {
+	var service explorer.FileRequestService
+	res := service.Delete(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// GetFileRequest 获取文件收集链接信息
+func GetFileRequest(c *gin.Context) {
+	var service explorer.FileRequestService
+	res := service.Info(c)
+	c.JSON(200, res)
+}
+
+// FileRequestUploadSession 通过文件收集链接创建上传会话
+func FileRequestUploadSession(c *gin.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.FileRequestUploadSessionService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// FileRequestUpload 通过文件收集链接上传文件分片
+func FileRequestUpload(c *gin.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.FileRequestUploadService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.LocalUpload(ctx, c)
+		c.JSON(200, res)
+		request.BlackHole(c.Request.Body)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
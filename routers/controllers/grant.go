@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"gitee.com/jiangjiali/cloudreve/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// ListFolderGrants 列出用户创建的目录共享授权
+func ListFolderGrants(c *gin.Context) {
+	var service explorer.FolderGrantService
+	res := service.List(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// CreateFolderGrant 将目录授权给用户或用户组
+func CreateFolderGrant(c *gin.Context) {
+	var service explorer.FolderGrantCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteFolderGrant 撤销目录共享授权
+func DeleteFolderGrant(c *gin.Context) {
+	var service explorer.FolderGrantService
+	res := service.Delete(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// ListReceivedFolders 列出授权给当前用户的目录
+func ListReceivedFolders(c *gin.Context) {
+	var service explorer.FolderGrantService
+	res := service.Received(c)
+	c.JSON(200, res)
+}
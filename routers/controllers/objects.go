@@ -82,3 +82,51 @@ func GetProperty(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// ListTrash 列出回收站中的对象
+func ListTrash(c *gin.Context) {
+	var service explorer.TrashService
+	res := service.List(c)
+	c.JSON(200, res)
+}
+
+// RestoreTrash 还原回收站中的对象
+func RestoreTrash(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.TrashItemService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Restore(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteTrash 彻底删除回收站中的对象
+func DeleteTrash(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.TrashItemService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Delete(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// EmptyTrash 清空回收站
+func EmptyTrash(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.TrashService
+	res := service.Empty(ctx, c)
+	c.JSON(200, res)
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/s3gateway"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"gitee.com/jiangjiali/cloudreve/service/setting"
+	"github.com/gin-gonic/gin"
+)
+
+var s3Handler = &s3gateway.Handler{Prefix: "/s3"}
+
+// ServeS3 处理S3接口请求
+func ServeS3(c *gin.Context) {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		util.Log().Warning("Failed to initialize filesystem for S3 gateway，%s", err)
+		s3gateway.WriteError(c.Writer, c.Request, s3gateway.ErrInternalError)
+		return
+	}
+
+	key := c.MustGet("s3key").(*model.S3Key)
+
+	// 重定根目录
+	if key.Root != "/" {
+		exist, root := fs.IsPathExist(key.Root)
+		if !exist {
+			fs.Recycle()
+			s3gateway.WriteError(c.Writer, c.Request, s3gateway.ErrNoSuchBucket)
+			return
+		}
+
+		root.Position = ""
+		root.Name = "/"
+		fs.Root = root
+	}
+
+	s3Handler.ServeHTTP(c.Writer, c.Request, fs, key)
+}
+
+// GetS3Keys 获取S3访问密钥列表
+func GetS3Keys(c *gin.Context) {
+	var service setting.S3KeyListService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Keys(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateS3Key 创建S3访问密钥
+func CreateS3Key(c *gin.Context) {
+	var service setting.S3KeyCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteS3Key 删除S3访问密钥
+func DeleteS3Key(c *gin.Context) {
+	var service setting.S3KeyService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UpdateS3Key 更改S3访问密钥只读性
+func UpdateS3Key(c *gin.Context) {
+	var service setting.S3KeyUpdateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -78,6 +78,13 @@ func DeleteShare(c *gin.Context) {
 	}
 }
 
+// RotateShareLink 轮换分享链接
+func RotateShareLink(c *gin.Context) {
+	var service share.Service
+	res := service.RotateLink(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
 // GetShareDownload 创建分享下载会话
 func GetShareDownload(c *gin.Context) {
 	var service share.Service
@@ -235,3 +242,68 @@ func GetUserShare(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// GetShareAnalytics 获取分享访问统计
+func GetShareAnalytics(c *gin.Context) {
+	var service share.ShareAnalyticsService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Analytics(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListShareAccesses 列出分享访问记录
+func ListShareAccesses(c *gin.Context) {
+	var service share.ShareAccessListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// SendShareCode 发送受限分享访问验证码
+func SendShareCode(c *gin.Context) {
+	var service share.ShareCodeService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Send(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// VerifyShareCode 校验受限分享访问验证码
+func VerifyShareCode(c *gin.Context) {
+	var service share.ShareCodeVerifyService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Verify(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListReceivedShares 列出指定给当前用户的分享
+func ListReceivedShares(c *gin.Context) {
+	var service share.Service
+	res := service.Received(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// ListShareRecipients 列出分享的接收者
+func ListShareRecipients(c *gin.Context) {
+	var service share.Service
+	res := service.Recipients(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// RevokeShareRecipient 撤销分享的指定接收者
+func RevokeShareRecipient(c *gin.Context) {
+	var service share.Service
+	res := service.RevokeRecipient(c, CurrentUser(c))
+	c.JSON(200, res)
+}
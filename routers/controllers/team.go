@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"gitee.com/jiangjiali/cloudreve/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// ListTeams 列出当前用户可以访问的团队空间
+func ListTeams(c *gin.Context) {
+	var service explorer.TeamService
+	res := service.List(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// ListTeamMembers 列出团队成员
+func ListTeamMembers(c *gin.Context) {
+	var service explorer.TeamService
+	res := service.Members(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// SetTeamMember 添加、修改或移除团队成员
+func SetTeamMember(c *gin.Context) {
+	var service explorer.TeamMemberService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Set(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
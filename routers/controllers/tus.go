@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/request"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/tus"
+	"gitee.com/jiangjiali/cloudreve/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// TusOptions 返回服务端支持的 tus 协议版本及扩展；仅未加密的本机存储策略支持 tus 上传，
+// 当前用户的存储策略不支持时不返回 creation 扩展，创建上传会返回 403
+func TusOptions(c *gin.Context) {
+	extensions := tus.Extensions
+	if user := CurrentUser(c); user != nil && !explorer.TusSupported(&user.Policy) {
+		extensions = tus.ExtensionsWithoutCreation
+	}
+
+	c.Header(tus.VersionHeader, tus.Version)
+	c.Header(tus.ExtensionHeader, extensions)
+	c.Header(tus.ChecksumAlgorithmHeader, tus.ChecksumAlgorithms)
+	if user := CurrentUser(c); user != nil && user.Policy.MaxSize > 0 {
+		c.Header(tus.MaxSizeHeader, strconv.FormatUint(user.Policy.MaxSize, 10))
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TusCreate 创建 tus 上传
+func TusCreate(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res := explorer.TusCreate(ctx, c)
+	tusResponse(c, res, http.StatusCreated)
+}
+
+// TusHead 获取 tus 上传的偏移量
+func TusHead(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.TusService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Head(ctx, c)
+		tusResponse(c, res, http.StatusOK)
+	} else {
+		tusResponse(c, ErrorResponse(err), http.StatusOK)
+	}
+}
+
+// TusPatch 上传 tus 上传的内容
+func TusPatch(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.TusService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Patch(ctx, c)
+		tusResponse(c, res, http.StatusNoContent)
+		request.BlackHole(c.Request.Body)
+	} else {
+		tusResponse(c, ErrorResponse(err), http.StatusNoContent)
+	}
+}
+
+// TusDelete 终止 tus 上传
+func TusDelete(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.TusService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(ctx, c)
+		tusResponse(c, res, http.StatusNoContent)
+	} else {
+		tusResponse(c, ErrorResponse(err), http.StatusNoContent)
+	}
+}
+
+// tusResponse 将服务的响应转换为 tus 协议的状态码，出错时正文为错误信息
+func tusResponse(c *gin.Context, res serializer.Response, success int) {
+	if res.Code == 0 {
+		c.Status(success)
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case res.Code >= 100 && res.Code < 600:
+		// 三位数错误编码复用 HTTP 状态码
+		status = res.Code
+	case res.Code == serializer.CodeUploadSessionExpired:
+		status = http.StatusNotFound
+	case res.Code == serializer.CodeUploadOffsetMismatch:
+		status = http.StatusConflict
+	case res.Code == serializer.CodeChecksumMismatch:
+		status = tus.StatusChecksumMismatch
+	case res.Code == serializer.CodeFileTooLarge:
+		status = http.StatusRequestEntityTooLarge
+	case res.Code == serializer.CodeInsufficientCapacity, res.Code == serializer.CodeFolderQuotaExceeded:
+		status = http.StatusInsufficientStorage
+	case res.Code == serializer.CodePolicyNotAllowed, res.Code == serializer.CodeSharedFolderReadOnly:
+		status = http.StatusForbidden
+	case res.Code == serializer.CodeParamErr, res.Code == serializer.CodeInvalidContentLength,
+		res.Code == serializer.CodeParentNotExist, res.Code == serializer.CodeIllegalObjectName:
+		status = http.StatusBadRequest
+	}
+
+	c.String(status, res.Msg)
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"gitee.com/jiangjiali/cloudreve/service/setting"
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebhooks 获取 Webhook 列表
+func GetWebhooks(c *gin.Context) {
+	var service setting.WebhookListService
+	res := service.Webhooks(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// CreateWebhook 创建 Webhook
+func CreateWebhook(c *gin.Context) {
+	var service setting.WebhookCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UpdateWebhook 修改 Webhook
+func UpdateWebhook(c *gin.Context) {
+	var service setting.WebhookUpdateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteWebhook 删除 Webhook
+func DeleteWebhook(c *gin.Context) {
+	var service setting.WebhookService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListWebhookDeliveries 列出 Webhook 的投递记录
+func ListWebhookDeliveries(c *gin.Context) {
+	var service setting.WebhookService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Deliveries(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -2,6 +2,7 @@ package routers
 
 import (
 	"gitee.com/jiangjiali/cloudreve/middleware"
+	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/auth"
 	"gitee.com/jiangjiali/cloudreve/pkg/cache"
 	"gitee.com/jiangjiali/cloudreve/pkg/cluster"
@@ -121,7 +122,7 @@ func InitMasterRouter() *gin.Engine {
 	/*
 		静态资源
 	*/
-	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/api/"})))
+	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/api/", "/s3/"})))
 	r.Use(middleware.FrontendFileHandler())
 	r.GET("manifest.json", controllers.Manifest)
 
@@ -171,7 +172,11 @@ func InitMasterRouter() *gin.Engine {
 		user := v3.Group("user")
 		{
 			// 用户登录
-			user.POST("session", middleware.CaptchaRequired("login_captcha"), controllers.UserLogin)
+			user.POST("session",
+				middleware.Audit(model.AuditLogin, "login"),
+				middleware.CaptchaRequired("login_captcha"),
+				controllers.UserLogin,
+			)
 			// 用户注册
 			user.POST("",
 				middleware.IsFunctionEnabled("register_enabled"),
@@ -179,7 +184,7 @@ func InitMasterRouter() *gin.Engine {
 				controllers.UserRegister,
 			)
 			// 用二步验证户登录
-			user.POST("2fa", controllers.User2FALogin)
+			user.POST("2fa", middleware.Audit(model.AuditLogin, "login.2fa"), controllers.User2FALogin)
 			// 发送密码重设邮件
 			user.POST("reset", middleware.CaptchaRequired("forget_captcha"), controllers.UserSendReset)
 			// 通过邮件里的链接重设密码
@@ -198,6 +203,7 @@ func InitMasterRouter() *gin.Engine {
 			// WebAuthn登陆
 			user.POST("authn/finish/:username",
 				middleware.IsFunctionEnabled("authn_enabled"),
+				middleware.Audit(model.AuditLogin, "login.authn"),
 				controllers.FinishLoginAuthn,
 			)
 			// 获取用户主页展示用分享
@@ -272,15 +278,27 @@ func InitMasterRouter() *gin.Engine {
 				middleware.RemoteCallbackAuth(),
 				controllers.RemoteCallback,
 			)
+			// S3 策略上传回调
+			callback.GET(
+				"s3/:sessionID/:key",
+				middleware.UseUploadSession("s3"),
+				middleware.UploadCallbackKeyAuth(),
+				controllers.S3Callback,
+			)
 		}
 
 		// 分享相关
 		share := v3.Group("share", middleware.ShareAvailable())
 		{
 			// 获取分享
-			share.GET("info/:id", controllers.GetShare)
+			share.GET("info/:id",
+				middleware.Audit(model.AuditShare, "share.access"),
+				middleware.ShareUnlockLimit(),
+				controllers.GetShare,
+			)
 			// 创建文件下载会话
 			share.PUT("download/:id",
+				middleware.Audit(model.AuditShare, "share.download"),
 				middleware.CheckShareUnlocked(),
 				middleware.BeforeShareDownload(),
 				controllers.GetShareDownload,
@@ -306,6 +324,16 @@ func InitMasterRouter() *gin.Engine {
 				middleware.BeforeShareDownload(),
 				controllers.PreviewShareText,
 			)
+			// 发送受限分享访问验证码
+			share.POST("code/:id",
+				middleware.CaptchaRequired("share_code_captcha"),
+				controllers.SendShareCode,
+			)
+			// 校验受限分享访问验证码
+			share.POST("verify/:id",
+				middleware.Audit(model.AuditShare, "share.verify"),
+				controllers.VerifyShareCode,
+			)
 			// 分享目录列文件
 			share.GET("list/:id/*path",
 				middleware.CheckShareUnlocked(),
@@ -337,6 +365,21 @@ func InitMasterRouter() *gin.Engine {
 			v3.Group("share").GET("search", controllers.SearchShare)
 		}
 
+		// 文件收集链接
+		fileRequest := v3.Group("request", middleware.FileRequestAvailable())
+		{
+			// 获取文件收集链接信息
+			fileRequest.GET("info/:id", controllers.GetFileRequest)
+			// 创建上传会话
+			fileRequest.POST("upload/:id",
+				middleware.Audit(model.AuditFile, "request.upload"),
+				middleware.CaptchaRequired("file_request_captcha"),
+				controllers.FileRequestUploadSession,
+			)
+			// 上传分片
+			fileRequest.POST("upload/:id/:sessionId/:index", controllers.FileRequestUpload)
+		}
+
 		wopi := v3.Group(
 			"wopi",
 			middleware.HashID(hashid.FileID),
@@ -358,7 +401,7 @@ func InitMasterRouter() *gin.Engine {
 		auth.Use(middleware.AuthRequired())
 		{
 			// 管理
-			admin := auth.Group("admin", middleware.IsAdmin())
+			admin := auth.Group("admin", middleware.Audit(model.AuditAdmin, ""), middleware.IsAdmin())
 			{
 				// 获取站点概况
 				admin.GET("summary", controllers.AdminSummary)
@@ -432,6 +475,38 @@ func InitMasterRouter() *gin.Engine {
 					user.PATCH("ban/:id", controllers.AdminBanUser)
 				}
 
+				team := admin.Group("team")
+				{
+					// 列出团队空间
+					team.POST("list", controllers.AdminListTeam)
+					// 获取团队空间
+					team.GET(":id", controllers.AdminGetTeam)
+					// 创建/保存团队空间
+					team.POST("", controllers.AdminAddTeam)
+					// 设置团队成员
+					team.PUT("member", controllers.AdminSetTeamMember)
+					// 删除团队空间
+					team.DELETE(":id", controllers.AdminDeleteTeam)
+				}
+
+				audit := admin.Group("audit")
+				{
+					// 列出审计日志
+					audit.POST("list", controllers.AdminListAuditLog)
+				}
+
+				webhook := admin.Group("webhook")
+				{
+					// 列出 Webhook
+					webhook.POST("list", controllers.AdminListWebhook)
+					// 创建/保存全局 Webhook
+					webhook.POST("", controllers.AdminAddWebhook)
+					// 列出投递记录
+					webhook.GET(":id/deliveries", controllers.AdminListWebhookDeliveries)
+					// 删除 Webhook
+					webhook.DELETE(":id", controllers.AdminDeleteWebhook)
+				}
+
 				file := admin.Group("file")
 				{
 					// 列出文件
@@ -440,6 +515,8 @@ func InitMasterRouter() *gin.Engine {
 					file.GET("preview/:id", middleware.Sandbox(), controllers.AdminGetFile)
 					// 删除
 					file.POST("delete", controllers.AdminDeleteFile)
+					// 去重存储统计
+					file.GET("dedup", controllers.AdminDedupReport)
 					// 列出用户或外部文件系统目录
 					file.GET("folders/:type/:id/*path",
 						controllers.AdminListFolders)
@@ -537,18 +614,34 @@ func InitMasterRouter() *gin.Engine {
 					// 文件上传
 					upload.POST(":sessionId/:index", controllers.FileUpload)
 					// 创建上传会话
-					upload.PUT("", controllers.GetUploadSession)
+					upload.PUT("", middleware.Audit(model.AuditFile, "file.upload"), controllers.GetUploadSession)
+					// 凭持有证明秒传
+					upload.PATCH(":sessionId", middleware.Audit(model.AuditFile, "file.upload"), controllers.InstantUpload)
 					// 删除给定上传会话
 					upload.DELETE(":sessionId", controllers.DeleteUploadSession)
 					// 删除全部上传会话
 					upload.DELETE("", controllers.DeleteAllUploadSession)
 				}
+				// tus 断点续传协议
+				tus := file.Group("tus", middleware.TusResumable())
+				{
+					// 获取支持的协议版本及扩展
+					tus.OPTIONS("", controllers.TusOptions)
+					// 创建上传
+					tus.POST("", middleware.Audit(model.AuditFile, "file.upload"), controllers.TusCreate)
+					// 获取上传偏移量
+					tus.HEAD(":sessionId", controllers.TusHead)
+					// 上传内容
+					tus.PATCH(":sessionId", controllers.TusPatch)
+					// 终止上传
+					tus.DELETE(":sessionId", controllers.TusDelete)
+				}
 				// 更新文件
-				file.PUT("update/:id", controllers.PutContent)
+				file.PUT("update/:id", middleware.Audit(model.AuditFile, "file.update"), controllers.PutContent)
 				// 创建空白文件
-				file.POST("create", controllers.CreateFile)
+				file.POST("create", middleware.Audit(model.AuditFile, "file.create"), controllers.CreateFile)
 				// 创建文件下载会话
-				file.PUT("download/:id", controllers.CreateDownloadSession)
+				file.PUT("download/:id", middleware.Audit(model.AuditFile, "file.download"), controllers.CreateDownloadSession)
 				// 预览文件
 				file.GET("preview/:id", middleware.Sandbox(), controllers.Preview)
 				// 获取文本文件内容
@@ -560,13 +653,23 @@ func InitMasterRouter() *gin.Engine {
 				// 取得文件外链
 				file.POST("source", controllers.GetSource)
 				// 打包要下载的文件
-				file.POST("archive", controllers.Archive)
+				file.POST("archive", middleware.Audit(model.AuditFile, "file.archive"), controllers.Archive)
 				// 创建文件压缩任务
 				file.POST("compress", controllers.Compress)
 				// 创建文件解压缩任务
 				file.POST("decompress", controllers.Decompress)
 				// 创建文件解压缩任务
 				file.GET("search/:type/:keywords", controllers.SearchFile)
+				// 按条件搜索文件和目录
+				file.POST("search", controllers.QueryFile)
+				// 列出文件的历史版本
+				file.GET("versions/:id", controllers.ListFileVersions)
+				// 下载文件的历史版本
+				file.GET("versions/:id/:version", controllers.DownloadFileVersion)
+				// 将文件还原为历史版本
+				file.POST("versions/:id/:version/restore", middleware.Audit(model.AuditFile, "file.restore_version"), controllers.RestoreFileVersion)
+				// 删除文件的历史版本
+				file.DELETE("versions/:id", middleware.Audit(model.AuditFile, "file.delete_versions"), controllers.DeleteFileVersions)
 			}
 
 			// 离线下载任务
@@ -590,43 +693,133 @@ func InitMasterRouter() *gin.Engine {
 			directory := auth.Group("directory")
 			{
 				// 创建目录
-				directory.PUT("", controllers.CreateDirectory)
+				directory.PUT("", middleware.Audit(model.AuditFile, "directory.create"), controllers.CreateDirectory)
 				// 列出目录下内容
 				directory.GET("*path", controllers.ListDirectory)
 			}
 
+			// 文件变更，供同步客户端使用
+			change := auth.Group("change")
+			{
+				// 列出游标之后的变更
+				change.GET("", controllers.ListChanges)
+			}
+
+			// 目录共享授权
+			grant := auth.Group("grant")
+			{
+				// 列出创建的授权
+				grant.GET("", controllers.ListFolderGrants)
+				// 创建授权
+				grant.POST("", controllers.CreateFolderGrant)
+				// 撤销授权
+				grant.DELETE(":id", middleware.HashID(hashid.GrantID), controllers.DeleteFolderGrant)
+				// 列出与我共享的目录
+				grant.GET("received", controllers.ListReceivedFolders)
+			}
+
+			// 文件收集链接
+			fileRequest := auth.Group("request")
+			{
+				// 列出创建的文件收集链接
+				fileRequest.GET("", controllers.ListFileRequests)
+				// 创建文件收集链接
+				fileRequest.POST("", middleware.Audit(model.AuditShare, "request.create"), controllers.CreateFileRequest)
+				// 删除文件收集链接
+				fileRequest.DELETE(":id",
+					middleware.Audit(model.AuditShare, "request.delete"),
+					middleware.HashID(hashid.FileRequestID),
+					controllers.DeleteFileRequest,
+				)
+			}
+
+			// 团队空间
+			team := auth.Group("team")
+			{
+				// 列出可以访问的团队空间
+				team.GET("", controllers.ListTeams)
+				// 列出团队成员
+				team.GET(":id/member", middleware.HashID(hashid.TeamID), controllers.ListTeamMembers)
+				// 设置团队成员
+				team.PUT(":id/member", middleware.HashID(hashid.TeamID), controllers.SetTeamMember)
+			}
+
+			// 目录配额
+			quota := auth.Group("quota")
+			{
+				// 列出开启配额的目录
+				quota.GET("", controllers.ListFolderQuotas)
+				// 设置目录配额
+				quota.PUT("", controllers.SetFolderQuota)
+				// 获取目录配额
+				quota.GET("folder/*path", controllers.GetFolderQuota)
+			}
+
 			// 对象，文件和目录的抽象
 			object := auth.Group("object")
 			{
 				// 删除对象
-				object.DELETE("", controllers.Delete)
+				object.DELETE("", middleware.Audit(model.AuditFile, "object.delete"), controllers.Delete)
 				// 移动对象
-				object.PATCH("", controllers.Move)
+				object.PATCH("", middleware.Audit(model.AuditFile, "object.move"), controllers.Move)
 				// 复制对象
-				object.POST("copy", controllers.Copy)
+				object.POST("copy", middleware.Audit(model.AuditFile, "object.copy"), controllers.Copy)
 				// 重命名对象
-				object.POST("rename", controllers.Rename)
+				object.POST("rename", middleware.Audit(model.AuditFile, "object.rename"), controllers.Rename)
 				// 获取对象属性
 				object.GET("property/:id", controllers.GetProperty)
 			}
 
+			// 回收站
+			trash := auth.Group("trash")
+			{
+				// 列出回收站中的对象
+				trash.GET("", controllers.ListTrash)
+				// 还原对象
+				trash.POST("restore", middleware.Audit(model.AuditFile, "trash.restore"), controllers.RestoreTrash)
+				// 彻底删除对象
+				trash.DELETE("", middleware.Audit(model.AuditFile, "trash.delete"), controllers.DeleteTrash)
+				// 清空回收站
+				trash.DELETE("all", middleware.Audit(model.AuditFile, "trash.empty"), controllers.EmptyTrash)
+			}
+
 			// 分享
 			share := auth.Group("share")
 			{
 				// 创建新分享
-				share.POST("", controllers.CreateShare)
+				share.POST("", middleware.Audit(model.AuditShare, "share.create"), controllers.CreateShare)
 				// 列出我的分享
 				share.GET("", controllers.ListShare)
 				// 更新分享属性
 				share.PATCH(":id",
+					middleware.Audit(model.AuditShare, "share.update"),
 					middleware.ShareAvailable(),
 					middleware.ShareOwner(),
 					controllers.UpdateShare,
 				)
 				// 删除分享
 				share.DELETE(":id",
+					middleware.Audit(model.AuditShare, "share.delete"),
 					controllers.DeleteShare,
 				)
+				// 轮换分享链接
+				share.POST("rotate/:id",
+					middleware.Audit(model.AuditShare, "share.rotate"),
+					controllers.RotateShareLink,
+				)
+				// 分享访问统计
+				share.GET("analytics/:id", controllers.GetShareAnalytics)
+				// 分享访问记录
+				share.GET("accesses/:id", controllers.ListShareAccesses)
+				// 列出指定给我的分享
+				share.GET("received", controllers.ListReceivedShares)
+				// 列出分享的接收者
+				share.GET("recipients/:id", controllers.ListShareRecipients)
+				// 撤销分享的接收者
+				share.DELETE("recipients/:id/:recipient",
+					middleware.Audit(model.AuditShare, "share.revoke"),
+					controllers.RevokeShareRecipient,
+				)
 			}
 
 			// 用户标签
@@ -646,11 +839,39 @@ func InitMasterRouter() *gin.Engine {
 				// 获取账号信息
 				webdav.GET("accounts", controllers.GetWebDAVAccounts)
 				// 新建账号
-				webdav.POST("accounts", controllers.CreateWebDAVAccounts)
+				webdav.POST("accounts", middleware.Audit(model.AuditWebDAV, "webdav.create"), controllers.CreateWebDAVAccounts)
 				// 删除账号
-				webdav.DELETE("accounts/:id", controllers.DeleteWebDAVAccounts)
+				webdav.DELETE("accounts/:id", middleware.Audit(model.AuditWebDAV, "webdav.delete"), controllers.DeleteWebDAVAccounts)
 				// 更新账号可读性和是否使用代理服务
-				webdav.PATCH("accounts", controllers.UpdateWebDAVAccounts)
+				webdav.PATCH("accounts", middleware.Audit(model.AuditWebDAV, "webdav.update"), controllers.UpdateWebDAVAccounts)
+			}
+
+			// S3 访问密钥管理相关
+			s3 := auth.Group("s3")
+			{
+				// 获取密钥列表
+				s3.GET("keys", controllers.GetS3Keys)
+				// 新建密钥
+				s3.POST("keys", middleware.Audit(model.AuditS3, "s3.create"), controllers.CreateS3Key)
+				// 删除密钥
+				s3.DELETE("keys/:id", middleware.Audit(model.AuditS3, "s3.delete"), controllers.DeleteS3Key)
+				// 更新密钥只读性
+				s3.PATCH("keys", middleware.Audit(model.AuditS3, "s3.update"), controllers.UpdateS3Key)
+			}
+
+			// Webhook
+			webhook := auth.Group("webhook")
+			{
+				// 列出 Webhook
+				webhook.GET("", controllers.GetWebhooks)
+				// 创建 Webhook
+				webhook.POST("", controllers.CreateWebhook)
+				// 修改 Webhook
+				webhook.PATCH("", controllers.UpdateWebhook)
+				// 删除 Webhook
+				webhook.DELETE(":id", controllers.DeleteWebhook)
+				// 列出投递记录
+				webhook.GET(":id/deliveries", controllers.ListWebhookDeliveries)
 			}
 
 		}
@@ -659,9 +880,22 @@ func InitMasterRouter() *gin.Engine {
 
 	// 初始化WebDAV相关路由
 	initWebDAV(r.Group("dav"))
+
+	// 初始化S3接口相关路由
+	initS3(r.Group("s3"))
 	return r
 }
 
+// initS3 初始化S3接口相关路由
+func initS3(group *gin.RouterGroup) {
+	{
+		group.Use(middleware.S3Auth())
+
+		group.Any("/*path", controllers.ServeS3)
+		group.Any("", controllers.ServeS3)
+	}
+}
+
 // initWebDAV 初始化WebDAV相关路由
 func initWebDAV(group *gin.RouterGroup) {
 	{
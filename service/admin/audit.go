@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+)
+
+// AuditLogs 列出审计日志
+func (service *AdminListService) AuditLogs() serializer.Response {
+	var res []model.AuditLog
+	total := 0
+
+	tx := model.DB.Model(&model.AuditLog{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	if len(service.Searches) > 0 {
+		search := ""
+		for k, v := range service.Searches {
+			search += k + " like '%" + v + "%' OR "
+		}
+		search = strings.TrimSuffix(search, " OR ")
+		tx = tx.Where(search)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	// 补全操作者信息
+	users := make(map[uint]model.User)
+	for _, log := range res {
+		users[log.UserID] = model.User{}
+	}
+
+	userIDs := make([]uint, 0, len(users))
+	for k := range users {
+		userIDs = append(userIDs, k)
+	}
+
+	var userList []model.User
+	model.DB.Where("id in (?)", userIDs).Find(&userList)
+
+	for _, v := range userList {
+		users[v.ID] = v
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+		"users": users,
+	}}
+}
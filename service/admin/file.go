@@ -206,3 +206,36 @@ func (service *AdminListService) Files() serializer.Response {
 		"users": users,
 	}}
 }
+
+// DedupReport 统计各存储策略去重存储节省的空间
+func (service *NoParamService) DedupReport() serializer.Response {
+	stats, err := model.GetBlobStatistics()
+	if err != nil {
+		return serializer.DBErr("Failed to query blob statistics", err)
+	}
+
+	var physical, logical uint64
+	items := make([]map[string]interface{}, 0, len(stats))
+	for _, stat := range stats {
+		policy, _ := model.GetPolicyByID(stat.PolicyID)
+		items = append(items, map[string]interface{}{
+			"policy_id":   stat.PolicyID,
+			"policy_name": policy.Name,
+			"blobs":       stat.Blobs,
+			"refs":        stat.Refs,
+			"physical":    stat.Physical,
+			"logical":     stat.Logical,
+			"saved":       stat.Logical - stat.Physical,
+		})
+
+		physical += stat.Physical
+		logical += stat.Logical
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"items":    items,
+		"physical": physical,
+		"logical":  logical,
+		"saved":    logical - physical,
+	}}
+}
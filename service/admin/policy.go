@@ -14,6 +14,8 @@ import (
 	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/auth"
 	"gitee.com/jiangjiali/cloudreve/pkg/conf"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/encrypt"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/sftp"
 	"gitee.com/jiangjiali/cloudreve/pkg/request"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
 	"gitee.com/jiangjiali/cloudreve/pkg/util"
@@ -171,6 +173,35 @@ func (service *AddPolicyService) Add() serializer.Response {
 		service.Policy.DirNameRule = strings.TrimPrefix(service.Policy.DirNameRule, "/")
 	}
 
+	if service.Policy.Type == "sftp" {
+		if err := sftp.CheckHostKey(service.Policy.OptionsSerialized.SFTPHostKey); err != nil {
+			return serializer.ParamErr(err.Error(), err)
+		}
+	}
+
+	// S3 分片上传中除最后一个分片外，每个分片须在 5 MiB 至 5 GiB 之间
+	if service.Policy.Type == "s3" {
+		chunkSize := service.Policy.OptionsSerialized.ChunkSize
+		if chunkSize < 5<<20 || chunkSize > 5<<30 {
+			return serializer.ParamErr("Chunk size of S3 policy must be between 5 MiB and 5 GiB", nil)
+		}
+	}
+
+	service.Policy.OptionsSerialized.EncryptedSince = 0
+	if service.Policy.IsEncrypted() {
+		if err := encrypt.CheckOptions(service.Policy.OptionsSerialized.Encryption, service.Policy.OptionsSerialized.ChunkSize); err != nil {
+			return serializer.ParamErr(err.Error(), err)
+		}
+
+		// 记录开启加密的时间，已开启时保留原有时间
+		service.Policy.OptionsSerialized.EncryptedSince = time.Now().Unix()
+		if service.Policy.ID > 0 {
+			if old, err := model.GetPolicyByID(service.Policy.ID); err == nil && old.IsEncrypted() && old.OptionsSerialized.EncryptedSince > 0 {
+				service.Policy.OptionsSerialized.EncryptedSince = old.OptionsSerialized.EncryptedSince
+			}
+		}
+	}
+
 	if service.Policy.ID > 0 {
 		if err := model.DB.Save(&service.Policy).Error; err != nil {
 			return serializer.DBErr("Failed to save policy", err)
@@ -4,7 +4,6 @@ import (
 	"strings"
 
 	model "gitee.com/jiangjiali/cloudreve/models"
-	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
 	"github.com/gin-gonic/gin"
 )
@@ -56,7 +55,7 @@ func (service *AdminListService) Shares() serializer.Response {
 	hashIDs := make(map[uint]string, len(res))
 	for _, file := range res {
 		users[file.UserID] = model.User{}
-		hashIDs[file.ID] = hashid.HashID(file.ID, hashid.ShareID)
+		hashIDs[file.ID] = file.Key()
 	}
 
 	userIDs := make([]uint, 0, len(users))
@@ -0,0 +1,159 @@
+package admin
+
+import (
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+)
+
+// AddTeamService 团队空间添加服务
+type AddTeamService struct {
+	Team model.Team `json:"team" binding:"required"`
+}
+
+// TeamService 团队空间ID服务
+type TeamService struct {
+	ID uint `uri:"id" json:"id" binding:"required"`
+}
+
+// TeamMemberService 团队成员设置服务，Role 为 0 时移除成员
+type TeamMemberService struct {
+	ID   uint   `json:"id" binding:"required"`
+	User string `json:"user" binding:"required,email"`
+	Role int    `json:"role" binding:"min=0,max=3"`
+}
+
+// Get 获取团队空间详情
+func (service *TeamService) Get() serializer.Response {
+	team, err := model.GetTeamByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Team not exist", err)
+	}
+
+	members, err := team.Members()
+	if err != nil {
+		return serializer.DBErr("Failed to list team members", err)
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"team":    team,
+		"members": members,
+	}}
+}
+
+// Delete 删除团队空间及其中的文件
+func (service *TeamService) Delete() serializer.Response {
+	team, err := model.GetTeamByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Team not exist", err)
+	}
+
+	// 删除内部账户时会一并删除团队空间
+	if _, err := model.GetUserByID(team.UserID); err == nil {
+		batch := &UserBatchService{ID: []uint{team.UserID}}
+		return batch.Delete()
+	}
+
+	if err := team.Delete(); err != nil {
+		return serializer.DBErr("Failed to delete team", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Add 添加或修改团队空间
+func (service *AddTeamService) Add() serializer.Response {
+	if service.Team.GroupID > 0 {
+		if _, err := model.GetGroupByID(service.Team.GroupID); err != nil {
+			return serializer.Err(serializer.CodeGroupNotFound, "", err)
+		}
+	}
+
+	if service.Team.PolicyID > 0 {
+		if _, err := model.GetPolicyByID(service.Team.PolicyID); err != nil {
+			return serializer.Err(serializer.CodePolicyNotExist, "", err)
+		}
+	}
+
+	if service.Team.ID > 0 {
+		team, err := model.GetTeamByID(service.Team.ID)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotFound, "Team not exist", err)
+		}
+
+		if err := team.Update(map[string]interface{}{
+			"name":         service.Team.Name,
+			"group_id":     service.Team.GroupID,
+			"default_role": service.Team.DefaultRole,
+			"max_storage":  service.Team.MaxStorage,
+			"policy_id":    service.Team.PolicyID,
+		}); err != nil {
+			return serializer.DBErr("Failed to save team record", err)
+		}
+	} else {
+		if err := service.Team.Create(); err != nil {
+			return serializer.DBErr("Failed to create team record", err)
+		}
+	}
+
+	return serializer.Response{Data: service.Team.ID}
+}
+
+// SetMember 添加、修改或移除团队成员
+func (service *TeamMemberService) SetMember() serializer.Response {
+	team, err := model.GetTeamByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Team not exist", err)
+	}
+
+	user, err := model.GetUserByEmail(service.User)
+	if err != nil || user.OptionsSerialized.TeamID > 0 {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if service.Role == 0 {
+		err = team.RemoveMember(user.ID)
+	} else {
+		err = team.SetMember(user.ID, service.Role)
+	}
+
+	if err != nil {
+		return serializer.DBErr("Failed to update team member", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Teams 列出团队空间
+func (service *AdminListService) Teams() serializer.Response {
+	var res []model.Team
+	total := 0
+
+	tx := model.DB.Model(&model.Team{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	// 统计每个团队空间的已用容量
+	statics := make(map[uint]uint64, len(res))
+	for i := 0; i < len(res); i++ {
+		if account, err := model.GetUserByID(res[i].UserID); err == nil {
+			statics[res[i].ID] = account.Storage
+		}
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total":   total,
+		"items":   res,
+		"statics": statics,
+	}}
+}
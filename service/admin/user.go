@@ -66,6 +66,7 @@ func (service *UserBatchService) Delete() serializer.Response {
 		if err != nil {
 			return serializer.Err(serializer.CodeInternalSetting, "User's root folder not exist", err)
 		}
+		fs.EmptyTrash(context.Background())
 		fs.Delete(context.Background(), []uint{root.ID}, []uint{}, false, false)
 
 		// 删除相关任务
@@ -78,6 +79,29 @@ func (service *UserBatchService) Delete() serializer.Response {
 		// 删除WebDAV账号
 		model.DB.Where("user_id = ?", uid).Delete(&model.Webdav{})
 
+		// 删除 S3 访问密钥
+		model.DB.Where("user_id = ?", uid).Delete(&model.S3Key{})
+
+		// 删除文件收集链接
+		model.DB.Where("user_id = ?", uid).Delete(&model.FileRequest{})
+
+		// 删除作为分享接收者的记录
+		model.DB.Where("user_id = ?", uid).Delete(&model.ShareRecipient{})
+
+		// 删除 Webhook
+		model.DeleteWebhooksByUser(uid)
+
+		// 删除目录共享授权及团队成员身份，团队空间中的文件归属于团队，不受影响
+		model.DB.Unscoped().Where("owner_id = ? or user_id = ?", uid, uid).Delete(&model.FolderGrant{})
+		model.DeleteTeamMembersByUser(uid)
+
+		// 删除团队的内部账户时一并删除团队空间
+		if user.OptionsSerialized.TeamID > 0 {
+			if team, err := model.GetTeamByID(user.OptionsSerialized.TeamID); err == nil {
+				team.Delete()
+			}
+		}
+
 		// 删除此用户
 		model.DB.Unscoped().Delete(user)
 
@@ -0,0 +1,113 @@
+package admin
+
+import (
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"gitee.com/jiangjiali/cloudreve/pkg/webhook"
+)
+
+// AddWebhookService 全局 Webhook 添加服务
+type AddWebhookService struct {
+	Webhook model.Webhook `json:"webhook" binding:"required"`
+}
+
+// WebhookService Webhook ID服务
+type WebhookService struct {
+	ID uint `uri:"id" json:"id" binding:"required"`
+}
+
+// Add 新建或修改全局 Webhook，全局 Webhook 接收所有用户的事件
+func (service *AddWebhookService) Add() serializer.Response {
+	if !webhook.IsValidURL(service.Webhook.URL) {
+		return serializer.ParamErr("Invalid webhook URL", nil)
+	}
+
+	if service.Webhook.Events == "" || !webhook.IsValidEvents(strings.Split(service.Webhook.Events, ",")) {
+		return serializer.ParamErr("Unknown webhook event", nil)
+	}
+
+	if service.Webhook.ID > 0 {
+		if _, err := model.GetWebhookByID(service.Webhook.ID, 0); err != nil {
+			return serializer.Err(serializer.CodeNotFound, "Webhook not exist", err)
+		}
+
+		if err := model.UpdateWebhookByID(service.Webhook.ID, 0, map[string]interface{}{
+			"name":   service.Webhook.Name,
+			"url":    service.Webhook.URL,
+			"events": service.Webhook.Events,
+			"enable": service.Webhook.Enable,
+		}); err != nil {
+			return serializer.DBErr("Failed to update webhook", err)
+		}
+
+		return serializer.Response{Data: service.Webhook.ID}
+	}
+
+	service.Webhook.UserID = 0
+	service.Webhook.Secret = util.RandStringRunes(32)
+	if _, err := service.Webhook.Create(); err != nil {
+		return serializer.DBErr("Failed to create webhook", err)
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"id":     service.Webhook.ID,
+		"secret": service.Webhook.Secret,
+	}}
+}
+
+// Delete 删除 Webhook
+func (service *WebhookService) Delete() serializer.Response {
+	var hook model.Webhook
+	if err := model.DB.First(&hook, service.ID).Error; err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Webhook not exist", err)
+	}
+
+	if err := model.DeleteWebhookByID(hook.ID, hook.UserID); err != nil {
+		return serializer.DBErr("Failed to delete webhook", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Deliveries 列出 Webhook 最近的投递记录
+func (service *WebhookService) Deliveries() serializer.Response {
+	var hook model.Webhook
+	if err := model.DB.First(&hook, service.ID).Error; err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Webhook not exist", err)
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"webhook":    hook,
+		"deliveries": model.ListWebhookDeliveries(hook.ID, 100),
+	}}
+}
+
+// Webhooks 列出所有用户及全局的 Webhook
+func (service *AdminListService) Webhooks() serializer.Response {
+	var res []model.Webhook
+	total := 0
+
+	tx := model.DB.Model(&model.Webhook{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total":  total,
+		"items":  res,
+		"events": webhook.Events,
+	}}
+}
@@ -2,8 +2,10 @@ package callback
 
 import (
 	"context"
+	"fmt"
 	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/driver/s3"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
 	"github.com/gin-gonic/gin"
@@ -24,6 +26,50 @@ func (service RemoteUploadCallbackService) GetBody() serializer.UploadCallback {
 	return service.Data
 }
 
+// S3Callback S3 客户端直传完成回调
+type S3Callback struct {
+}
+
+// GetBody 返回回调正文
+func (service *S3Callback) GetBody() serializer.UploadCallback {
+	return serializer.UploadCallback{}
+}
+
+// PreProcess 对 S3 客户端回调进行预处理验证
+func (service *S3Callback) PreProcess(c *gin.Context) serializer.Response {
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromCallback(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 获取回调会话
+	uploadSession := c.MustGet(filesystem.UploadSessionCtx).(*serializer.UploadSession)
+
+	// 获取文件信息
+	handler, ok := fs.Handler.(*s3.Driver)
+	if !ok {
+		return serializer.Err(serializer.CodePolicyNotAllowed, "", nil)
+	}
+
+	info, err := handler.Meta(context.Background(), uploadSession.SavePath)
+	if err != nil {
+		return serializer.Err(serializer.CodeUploadFailed, "Failed to get file info from S3", err)
+	}
+
+	// 验证实际文件信息与回调会话是否一致
+	if info.Size != uploadSession.Size {
+		return serializer.Err(
+			serializer.CodeUploadFailed,
+			fmt.Sprintf("File size not match, expected %d, got %d", uploadSession.Size, info.Size),
+			nil,
+		)
+	}
+
+	return ProcessCallback(service, c)
+}
+
 // ProcessCallback 处理上传结果回调
 func ProcessCallback(service CallbackProcessService, c *gin.Context) serializer.Response {
 	callbackBody := service.GetBody()
@@ -61,8 +107,10 @@ func ProcessCallback(service CallbackProcessService, c *gin.Context) serializer.
 	}
 
 	fs.Use("AfterUpload", filesystem.HookPopPlaceholderToFile(callbackBody.PicInfo))
+	fs.Use("AfterUpload", filesystem.HookCommitBlob)
 	fs.Use("AfterValidateFailed", filesystem.HookDeleteTempFile)
-	err = fs.Upload(context.Background(), &fileData)
+	uploadCtx := context.WithValue(context.Background(), fsctx.ContentHashCtx, &callbackBody.Hash)
+	err = fs.Upload(uploadCtx, &fileData)
 	if err != nil {
 		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
 	}
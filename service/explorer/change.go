@@ -0,0 +1,83 @@
+package explorer
+
+import (
+	"context"
+	"time"
+
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// ChangeListService 列出文件变更服务
+type ChangeListService struct {
+	// Cursor 上次返回的游标，为空时只返回当前最新的游标
+	Cursor string `form:"cursor"`
+	// Path 只列出此目录下的变更
+	Path  string `form:"path" binding:"max=65535"`
+	Limit int    `form:"limit" binding:"min=0,max=1000"`
+}
+
+// ChangeItem 文件或目录的变更
+type ChangeItem struct {
+	Type     string    `json:"type"`
+	ID       string    `json:"id"`
+	IsFolder bool      `json:"is_folder"`
+	Name     string    `json:"name"`
+	Path     string    `json:"path"`
+	OldName  string    `json:"old_name,omitempty"`
+	OldPath  string    `json:"old_path,omitempty"`
+	Size     uint64    `json:"size"`
+	Date     time.Time `json:"date"`
+}
+
+// List 列出游标之后的文件变更
+func (service *ChangeListService) List(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if service.Limit == 0 {
+		service.Limit = 500
+	}
+
+	if service.Path == "" {
+		service.Path = "/"
+	}
+
+	changes, cursor, hasMore, err := fs.ListChanges(context.Background(), service.Path, service.Cursor, service.Limit)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	res := make([]ChangeItem, 0, len(changes))
+	for _, change := range changes {
+		idType := hashid.FileID
+		if change.IsFolder {
+			idType = hashid.FolderID
+		}
+
+		res = append(res, ChangeItem{
+			Type:     change.Type,
+			ID:       hashid.HashID(change.ObjectID, idType),
+			IsFolder: change.IsFolder,
+			Name:     change.Name,
+			Path:     change.Path,
+			OldName:  change.OldName,
+			OldPath:  change.OldPath,
+			Size:     change.Size,
+			Date:     change.CreatedAt,
+		})
+	}
+
+	return serializer.Response{
+		Data: map[string]interface{}{
+			"changes":  res,
+			"cursor":   cursor,
+			"has_more": hasMore,
+		},
+	}
+}
@@ -2,7 +2,9 @@ package explorer
 
 import (
 	"context"
+	"path"
 
+	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
 	"github.com/gin-gonic/gin"
@@ -11,6 +13,37 @@ import (
 // DirectoryService 创建新目录服务
 type DirectoryService struct {
 	Path string `uri:"path" json:"path" binding:"required,min=1,max=65535"`
+
+	// 分页列出目录内容，Limit 为 0 时列出全部
+	Limit  int    `form:"limit" json:"-" binding:"min=0,max=1000"`
+	Cursor string `form:"cursor" json:"-"`
+	// OrderBy 排序字段，目录总是排在文件之前
+	OrderBy string `form:"order_by" json:"-" binding:"omitempty,eq=name|eq=size|eq=mtime|eq=type"`
+	Order   string `form:"order" json:"-" binding:"omitempty,eq=asc|eq=desc"`
+}
+
+// list 列出目录内容，返回下一页的游标
+func (service *DirectoryService) list(ctx context.Context, fs *filesystem.FileSystem, dirPath string, pathProcessor func(string) string) ([]serializer.Object, string, error) {
+	if service.Limit == 0 {
+		objects, err := fs.List(ctx, dirPath, pathProcessor)
+		return objects, "", err
+	}
+
+	opts := &model.SearchOptions{
+		Desc:  service.Order == "desc",
+		Limit: service.Limit,
+	}
+
+	switch service.OrderBy {
+	case "size":
+		opts.OrderBy = "size"
+	case "mtime":
+		opts.OrderBy = "updated_at"
+	case "type":
+		opts.OrderBy = "extension"
+	}
+
+	return fs.ListPage(ctx, dirPath, pathProcessor, opts, service.Cursor)
 }
 
 // ListDirectory 列出目录内容
@@ -26,8 +59,13 @@ func (service *DirectoryService) ListDirectory(c *gin.Context) serializer.Respon
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// 与我共享的目录及团队空间
+	if filesystem.IsSharedPath(service.Path) {
+		return service.listShared(ctx, fs)
+	}
+
 	// 获取子项目
-	objects, err := fs.List(ctx, service.Path, nil)
+	objects, cursor, err := service.list(ctx, fs, service.Path, nil)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
@@ -37,9 +75,55 @@ func (service *DirectoryService) ListDirectory(c *gin.Context) serializer.Respon
 		parentID = fs.DirTarget[0].ID
 	}
 
+	// 根目录下展示“与我共享”及团队空间目录，分页时只在第一页展示
+	if path.Clean(service.Path) == "/" && service.Cursor == "" {
+		objects = append(objects, fs.VirtualRootObjects()...)
+	}
+
+	res := serializer.BuildObjectList(parentID, objects, fs.Policy)
+	res.Cursor = cursor
+	return serializer.Response{
+		Code: 0,
+		Data: res,
+	}
+}
+
+// listShared 列出与我共享的目录或团队空间内容
+func (service *DirectoryService) listShared(ctx context.Context, fs *filesystem.FileSystem) serializer.Response {
+	if filesystem.IsVirtualRoot(service.Path) {
+		objects, err := fs.ListVirtualRoot(path.Clean(service.Path))
+		if err != nil {
+			return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		}
+
+		return serializer.Response{
+			Code: 0,
+			Data: serializer.BuildObjectList(0, objects, fs.Policy),
+		}
+	}
+
+	shared, inner, err := fs.ResolveSharedPath(service.Path)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	ownerFS, err := shared.NewFileSystem()
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer ownerFS.Recycle()
+
+	objects, cursor, err := service.list(ctx, ownerFS, shared.FullPath(inner), shared.VirtualPath)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	// 上传使用所有者的存储策略
+	res := serializer.BuildObjectList(ownerFS.DirTarget[0].ID, objects, ownerFS.Policy)
+	res.Cursor = cursor
 	return serializer.Response{
 		Code: 0,
-		Data: serializer.BuildObjectList(parentID, objects, fs.Policy),
+		Data: res,
 	}
 }
 
@@ -56,6 +140,27 @@ func (service *DirectoryService) CreateDirectory(c *gin.Context) serializer.Resp
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// 在与我共享的目录中创建
+	if filesystem.IsSharedPath(service.Path) {
+		shared, inner, err := fs.ResolveSharedPath(service.Path)
+		if err != nil {
+			return serializer.Err(serializer.CodeCreateFolderFailed, err.Error(), err)
+		}
+
+		if !shared.Writable() {
+			return serializer.Err(serializer.CodeSharedFolderReadOnly, "", nil)
+		}
+
+		ownerFS, err := shared.NewFileSystem()
+		if err != nil {
+			return serializer.Err(serializer.CodeCreateFSError, "", err)
+		}
+		defer ownerFS.Recycle()
+
+		fs = ownerFS
+		service.Path = shared.FullPath(inner)
+	}
+
 	// 创建目录
 	_, err = fs.CreateDirectory(ctx, service.Path)
 	if err != nil {
@@ -119,16 +119,32 @@ func (service *ArchiveService) DownloadArchived(ctx context.Context, c *gin.Cont
 		return serializer.Err(serializer.CodeNotFound, "Archive session not exist", nil)
 	}
 
-	// 开始打包
-	c.Header("Content-Disposition", "attachment;")
-	c.Header("Content-Type", "application/zip")
+	// 恢复打包范围限制
+	ctx = c.Request.Context()
+	if parentID, ok := cache.Get("archive_parent_" + service.ID); ok {
+		parents, err := model.GetFoldersByIDs([]uint{parentID.(uint)}, user.ID)
+		if err != nil || len(parents) == 0 {
+			return serializer.Err(serializer.CodeParentNotExist, "", err)
+		}
+		ctx = context.WithValue(ctx, fsctx.LimitParentCtx, &parents[0])
+	}
+
+	// 以仅存储方式直接输出归档，预先计算大小以支持断点续传
 	itemService := archiveSession.(ItemIDService)
 	items := itemService.Raw()
-	ctx = context.WithValue(ctx, fsctx.GinCtx, c)
-	err = fs.Compress(ctx, c.Writer, items.Dirs, items.Items, true)
+	archive, err := fs.NewArchiveStream(ctx, items.Dirs, items.Items)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, "Failed to compress file", err)
 	}
+	defer archive.Close()
+
+	c.Header("Content-Disposition", "attachment;")
+	c.Header("Content-Type", "application/zip")
+	c.Header("ETag", archive.ETag())
+	if !archive.AllowRange(c.Request.Header.Get("Range")) {
+		c.Request.Header.Del("Range")
+	}
+	http.ServeContent(c.Writer, c.Request, "archive.zip", archive.ModTime(), archive)
 
 	return serializer.Response{
 		Code: 0,
@@ -248,6 +264,10 @@ func (service *FileIDService) CreateDocPreviewSession(ctx context.Context, c *gi
 
 		action := wopi.ActionPreview
 		if editable {
+			// 只读授权目录中的文件不能编辑
+			if !fs.CanWriteFile(&fs.FileTarget[0]) {
+				return serializer.Err(serializer.CodeSharedFolderReadOnly, "", nil)
+			}
 			action = wopi.ActionEdit
 		}
 
@@ -439,13 +459,20 @@ func (service *FileIDService) PutContent(ctx context.Context, c *gin.Context) se
 	}
 	fileData.Name = originFile[0].Name
 
+	// 开启历史版本时，原有内容保存为历史版本
+	versioned := fs.UseVersioning(uploadCtx, &originFile[0], &fileData)
+
 	// 检查此文件是否有软链接
 	fileList, err := model.RemoveFilesWithSoftLinks([]model.File{originFile[0]})
-	if err == nil && len(fileList) == 0 {
+	if !versioned && err == nil && len(fileList) == 0 {
 		// 如果包含软连接，应重新生成新文件副本，并更新source_name
+		originSource := originFile[0].SourceName
 		originFile[0].SourceName = fs.GenerateSavePath(uploadCtx, &fileData)
 		fileData.Mode &= ^fsctx.Overwrite
 		fs.Use("AfterUpload", filesystem.HookUpdateSourceName)
+		if model.IsBlobSource(originFile[0].PolicyID, originSource) {
+			fs.Use("AfterUpload", filesystem.HookReleaseBlob(originSource))
+		}
 		fs.Use("AfterUploadCanceled", filesystem.HookUpdateSourceName)
 		fs.Use("AfterUploadCanceled", filesystem.HookCleanFileContent)
 		fs.Use("AfterUploadCanceled", filesystem.HookClearFileSize)
@@ -459,6 +486,7 @@ func (service *FileIDService) PutContent(ctx context.Context, c *gin.Context) se
 	fs.Use("BeforeUpload", filesystem.HookValidateFile)
 	fs.Use("BeforeUpload", filesystem.HookValidateCapacityDiff)
 	fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
+	fs.Use("AfterUpload", filesystem.HookCommitBlob)
 
 	// 执行上传
 	uploadCtx = context.WithValue(uploadCtx, fsctx.FileModelCtx, originFile[0])
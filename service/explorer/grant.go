@@ -0,0 +1,170 @@
+package explorer
+
+import (
+	"path"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// FolderGrantService 目录共享授权服务
+type FolderGrantService struct {
+}
+
+// FolderGrantCreateService 创建目录共享授权服务，User 与 Group 二选一
+type FolderGrantCreateService struct {
+	Path string `json:"path" binding:"required,min=1,max=65535"`
+	// User 被授权用户的 Email
+	User string `json:"user" binding:"omitempty,email"`
+	// Group 被授权的用户组ID
+	Group    uint `json:"group"`
+	Writable bool `json:"writable"`
+}
+
+// FolderGrant 目录共享授权
+type FolderGrant struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"`
+	User      string    `json:"user,omitempty"`
+	Group     string    `json:"group,omitempty"`
+	Writable  bool      `json:"writable"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReceivedFolder 授权给当前用户的目录
+type ReceivedFolder struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Owner    string `json:"owner"`
+	Writable bool   `json:"writable"`
+}
+
+// buildFolderGrant 构建目录共享授权信息
+func buildFolderGrant(grant *model.FolderGrant) FolderGrant {
+	res := FolderGrant{
+		ID:        hashid.HashID(grant.ID, hashid.GrantID),
+		Writable:  grant.Writable,
+		CreatedAt: grant.CreatedAt,
+	}
+
+	if folder := grant.SourceFolder(); folder.TraceRoot() == nil {
+		res.Path = path.Join(folder.Position, folder.Name)
+	}
+
+	if grant.UserID > 0 {
+		if user, err := model.GetUserByID(grant.UserID); err == nil {
+			res.User = user.Email
+		}
+	}
+
+	if grant.GroupID > 0 {
+		if group, err := model.GetGroupByID(grant.GroupID); err == nil {
+			res.Group = group.Name
+		}
+	}
+
+	return res
+}
+
+// Create 将目录授权给用户或用户组
+func (service *FolderGrantCreateService) Create(c *gin.Context) serializer.Response {
+	if (service.User == "") == (service.Group == 0) {
+		return serializer.ParamErr("Either user or group must be specified", nil)
+	}
+
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	exist, folder := fs.IsPathExist(service.Path)
+	if !exist {
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
+	grant := &model.FolderGrant{
+		FolderID: folder.ID,
+		OwnerID:  fs.User.ID,
+		Writable: service.Writable,
+	}
+
+	if service.User != "" {
+		user, err := model.GetActiveUserByEmail(service.User)
+		if err != nil || user.ID == fs.User.ID {
+			return serializer.Err(serializer.CodeUserNotFound, "", err)
+		}
+		grant.UserID = user.ID
+	} else {
+		if _, err := model.GetGroupByID(service.Group); err != nil {
+			return serializer.Err(serializer.CodeGroupNotFound, "", err)
+		}
+		grant.GroupID = service.Group
+	}
+
+	if err := grant.Create(); err != nil {
+		return serializer.DBErr("Failed to create folder grant", err)
+	}
+
+	return serializer.Response{Data: buildFolderGrant(grant)}
+}
+
+// List 列出用户创建的目录共享授权
+func (service *FolderGrantService) List(c *gin.Context, user *model.User) serializer.Response {
+	grants, err := model.GetGrantsByOwner(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list folder grants", err)
+	}
+
+	res := make([]FolderGrant, 0, len(grants))
+	for i := range grants {
+		res = append(res, buildFolderGrant(&grants[i]))
+	}
+
+	return serializer.Response{Data: res}
+}
+
+// Delete 撤销目录共享授权
+func (service *FolderGrantService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	grantID, _ := c.Get("object_id")
+	grant, err := model.GetGrantByID(grantID.(uint), user.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Folder grant not exist", err)
+	}
+
+	if err := grant.Delete(); err != nil {
+		return serializer.DBErr("Failed to delete folder grant", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Received 列出授权给当前用户的目录
+func (service *FolderGrantService) Received(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	folders, err := fs.ListSharedFolders()
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	res := make([]ReceivedFolder, 0, len(folders))
+	for _, shared := range folders {
+		res = append(res, ReceivedFolder{
+			Name:     shared.Name,
+			Path:     path.Join(shared.Base, shared.Name),
+			Owner:    shared.Owner.Nick,
+			Writable: shared.Writable(),
+		})
+	}
+
+	return serializer.Response{Data: res}
+}
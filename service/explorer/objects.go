@@ -13,6 +13,7 @@ import (
 	"gitee.com/jiangjiali/cloudreve/pkg/auth"
 	"gitee.com/jiangjiali/cloudreve/pkg/cache"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
 	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
 	"gitee.com/jiangjiali/cloudreve/pkg/task"
@@ -253,6 +254,9 @@ func (service *ItemIDService) Archive(ctx context.Context, c *gin.Context) seria
 	downloadSessionID := util.RandStringRunes(16)
 	cache.Set("archive_"+downloadSessionID, *service, ttl)
 	cache.Set("archive_user_"+downloadSessionID, *fs.User, ttl)
+	if parent, ok := ctx.Value(fsctx.LimitParentCtx).(*model.Folder); ok {
+		cache.Set("archive_parent_"+downloadSessionID, parent.ID, ttl)
+	}
 	signURL, err := auth.SignURI(
 		auth.General,
 		fmt.Sprintf("/api/v3/file/archive/%s/archive.zip", downloadSessionID),
@@ -280,9 +284,23 @@ func (service *ItemIDService) Delete(ctx context.Context, c *gin.Context) serial
 		unlink = service.UnlinkOnly
 	}
 
-	// 删除对象
+	// 删除授权目录或团队空间中的对象
 	items := service.Raw()
-	err = fs.Delete(ctx, items.Dirs, items.Items, force, unlink)
+	ownerFS, err := fs.GrantedFileSystem(items.Dirs, items.Items)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	if ownerFS != nil {
+		defer ownerFS.Recycle()
+		fs = ownerFS
+	}
+
+	// 删除对象，未指定高级删除选项时移入回收站
+	if force || unlink {
+		err = fs.Delete(ctx, items.Dirs, items.Items, force, unlink)
+	} else {
+		err = fs.Trash(ctx, items.Dirs, items.Items)
+	}
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
@@ -302,9 +320,21 @@ func (service *ItemMoveService) Move(ctx context.Context, c *gin.Context) serial
 	}
 	defer fs.Recycle()
 
+	// 在授权目录或团队空间中移动
+	src, dst := service.SrcDir, service.Dst
+	if filesystem.IsSharedPath(src) || filesystem.IsSharedPath(dst) {
+		ownerFS, srcFull, dstFull, err := service.resolveShared(fs)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		}
+		defer ownerFS.Recycle()
+
+		fs, src, dst = ownerFS, srcFull, dstFull
+	}
+
 	// 移动对象
 	items := service.Src.Raw()
-	err = fs.Move(ctx, items.Dirs, items.Items, service.SrcDir, service.Dst)
+	err = fs.Move(ctx, items.Dirs, items.Items, src, dst)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
@@ -315,6 +345,39 @@ func (service *ItemMoveService) Move(ctx context.Context, c *gin.Context) serial
 
 }
 
+// resolveShared 解析授权目录或团队空间中的源目录和目的目录，两者需位于同一个可写的授权目录中，
+// 返回所有者的文件系统及两者在其中的路径
+func (service *ItemMoveService) resolveShared(fs *filesystem.FileSystem) (*filesystem.FileSystem, string, string, error) {
+	if !filesystem.IsSharedPath(service.SrcDir) || !filesystem.IsSharedPath(service.Dst) {
+		return nil, "", "", filesystem.ErrCrossSharedFolder
+	}
+
+	src, srcInner, err := fs.ResolveSharedPath(service.SrcDir)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	dst, dstInner, err := fs.ResolveSharedPath(service.Dst)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if src.Base != dst.Base || src.Folder.ID != dst.Folder.ID {
+		return nil, "", "", filesystem.ErrCrossSharedFolder
+	}
+
+	if !src.Writable() {
+		return nil, "", "", filesystem.ErrSharedFolderReadOnly
+	}
+
+	ownerFS, err := src.NewFileSystem()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return ownerFS, src.FullPath(srcInner), dst.FullPath(dstInner), nil
+}
+
 // Copy 复制对象
 func (service *ItemMoveService) Copy(ctx context.Context, c *gin.Context) serializer.Response {
 	// 复制操作只能对一个目录或文件对象进行操作
@@ -355,6 +418,16 @@ func (service *ItemRenameService) Rename(ctx context.Context, c *gin.Context) se
 	}
 	defer fs.Recycle()
 
+	// 重命名授权目录或团队空间中的对象
+	ownerFS, err := fs.GrantedFileSystem(service.Src.Raw().Dirs, service.Src.Raw().Items)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	if ownerFS != nil {
+		defer ownerFS.Recycle()
+		fs = ownerFS
+	}
+
 	// 重命名对象
 	err = fs.Rename(ctx, service.Src.Raw().Dirs, service.Src.Raw().Items, service.NewName)
 	if err != nil {
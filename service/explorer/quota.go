@@ -0,0 +1,120 @@
+package explorer
+
+import (
+	"path"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// FolderQuotaService 目录配额服务
+type FolderQuotaService struct {
+	Path string `uri:"path" json:"path" binding:"required,min=1,max=65535"`
+	// Quota 目录配额，0 为取消配额
+	Quota uint64 `json:"quota"`
+}
+
+// FolderQuota 目录配额信息
+type FolderQuota struct {
+	ID    string `json:"id"`
+	Path  string `json:"path"`
+	Quota uint64 `json:"quota"`
+	Size  uint64 `json:"size"`
+}
+
+// buildFolderQuota 构建目录配额信息，未开启配额时实时计算目录大小
+func buildFolderQuota(folder *model.Folder) (FolderQuota, error) {
+	size := folder.Size
+	if folder.Quota == 0 {
+		var err error
+		if size, err = model.TreeSize([]uint{folder.ID}, folder.OwnerID); err != nil {
+			return FolderQuota{}, err
+		}
+	}
+
+	return FolderQuota{
+		ID:    hashid.HashID(folder.ID, hashid.FolderID),
+		Path:  path.Join(folder.Position, folder.Name),
+		Quota: folder.Quota,
+		Size:  size,
+	}, nil
+}
+
+// ListQuotas 列出用户所有开启配额的目录
+func (service *FolderQuotaService) ListQuotas(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	folders, err := model.GetQuotaFolders(fs.User.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list folder quotas", err)
+	}
+
+	res := make([]FolderQuota, 0, len(folders))
+	for i := range folders {
+		if err := folders[i].TraceRoot(); err != nil {
+			continue
+		}
+
+		quota, err := buildFolderQuota(&folders[i])
+		if err != nil {
+			return serializer.DBErr("Failed to calculate folder size", err)
+		}
+
+		res = append(res, quota)
+	}
+
+	return serializer.Response{Data: res}
+}
+
+// GetQuota 获取目录的配额及大小
+func (service *FolderQuotaService) GetQuota(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	exist, folder := fs.IsPathExist(service.Path)
+	if !exist {
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
+	quota, err := buildFolderQuota(folder)
+	if err != nil {
+		return serializer.DBErr("Failed to calculate folder size", err)
+	}
+
+	return serializer.Response{Data: quota}
+}
+
+// SetQuota 设置目录的配额
+func (service *FolderQuotaService) SetQuota(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	exist, folder := fs.IsPathExist(service.Path)
+	if !exist {
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
+	if err := folder.SetQuota(service.Quota); err != nil {
+		return serializer.DBErr("Failed to set folder quota", err)
+	}
+
+	quota, err := buildFolderQuota(folder)
+	if err != nil {
+		return serializer.DBErr("Failed to calculate folder size", err)
+	}
+
+	return serializer.Response{Data: quota}
+}
@@ -0,0 +1,234 @@
+package explorer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// FileRequestService 文件收集链接服务
+type FileRequestService struct {
+}
+
+// FileRequestCreateService 创建文件收集链接服务
+type FileRequestCreateService struct {
+	Path        string   `json:"path" binding:"required,min=1,max=65535"`
+	Name        string   `json:"name" binding:"required,max=255"`
+	Description string   `json:"description" binding:"max=65535"`
+	Password    string   `json:"password" binding:"max=255"`
+	Expire      int      `json:"expire" binding:"min=0"`
+	MaxFiles    int      `json:"max_files" binding:"min=0"`
+	MaxSize     uint64   `json:"max_size"`
+	Extensions  []string `json:"extensions"`
+	RequireName bool     `json:"require_name"`
+}
+
+// FileRequestUploadSessionService 通过文件收集链接创建上传会话服务
+type FileRequestUploadSessionService struct {
+	Password     string `json:"password"`
+	Name         string `json:"name" binding:"required"`
+	Size         uint64 `json:"size" binding:"min=0"`
+	LastModified int64  `json:"last_modified"`
+	MimeType     string `json:"mime_type"`
+	// Uploader 上传者填写的姓名
+	Uploader string `json:"uploader" binding:"max=255"`
+}
+
+// FileRequestUploadService 通过文件收集链接上传分片服务
+type FileRequestUploadService struct {
+	ID    string `uri:"sessionId" binding:"required"`
+	Index int    `uri:"index" form:"index" binding:"min=0"`
+}
+
+// Create 创建文件收集链接
+func (service *FileRequestCreateService) Create(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 是否拥有权限
+	if !fs.User.Group.ShareEnabled {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	exist, folder := fs.IsPathExist(service.Path)
+	if !exist {
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
+	extensions := make([]string, 0, len(service.Extensions))
+	for _, ext := range service.Extensions {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if ext != "" && !strings.Contains(ext, ",") {
+			extensions = append(extensions, ext)
+		}
+	}
+
+	request := &model.FileRequest{
+		UserID:      fs.User.ID,
+		FolderID:    folder.ID,
+		Name:        service.Name,
+		Description: service.Description,
+		MaxFiles:    service.MaxFiles,
+		MaxSize:     service.MaxSize,
+		Extensions:  strings.Join(extensions, ","),
+		RequireName: service.RequireName,
+		Folder:      *folder,
+	}
+
+	if err := request.SetPassword(service.Password); err != nil {
+		return serializer.Err(serializer.CodeEncryptError, "Failed to hash password", err)
+	}
+
+	if service.Expire > 0 {
+		expires := time.Now().Add(time.Duration(service.Expire) * time.Second)
+		request.Expires = &expires
+	}
+
+	if _, err := request.Create(); err != nil {
+		return serializer.DBErr("Failed to create file request", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildFileRequestResponse(request, true)}
+}
+
+// List 列出用户创建的文件收集链接
+func (service *FileRequestService) List(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.BuildFileRequestList(model.ListFileRequests(user.ID))
+}
+
+// Delete 删除文件收集链接
+func (service *FileRequestService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	requestID, _ := c.Get("object_id")
+	if err := model.DeleteFileRequestByID(requestID.(uint), user.ID); err != nil {
+		return serializer.Err(serializer.CodeFileRequestNotFound, "", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Info 获取文件收集链接的公开信息
+func (service *FileRequestService) Info(c *gin.Context) serializer.Response {
+	request := c.MustGet("fileRequest").(*model.FileRequest)
+	return serializer.Response{Data: serializer.BuildFileRequestResponse(request, false)}
+}
+
+// Create 通过文件收集链接创建上传会话，使用链接所有者的文件系统及容量
+func (service *FileRequestUploadSessionService) Create(ctx context.Context, c *gin.Context) serializer.Response {
+	request := c.MustGet("fileRequest").(*model.FileRequest)
+
+	// 校验上传密码，失败次数过多时锁定
+	if request.Password != "" {
+		if request.UnlockLocked(c.ClientIP()) {
+			return serializer.Err(serializer.CodeShareUnlockLocked, "", nil)
+		}
+
+		if !request.CheckPassword(service.Password) {
+			request.UnlockFailed(c.ClientIP())
+			return serializer.Err(serializer.CodeIncorrectPassword, "", nil)
+		}
+		request.UnlockSucceeded(c.ClientIP())
+	}
+
+	service.Uploader = strings.TrimSpace(service.Uploader)
+	if request.RequireName && service.Uploader == "" {
+		return serializer.ParamErr("Uploader name is required", nil)
+	}
+
+	if request.MaxSize > 0 && service.Size > request.MaxSize {
+		return serializer.Err(serializer.CodeFileTooLarge, "", nil)
+	}
+
+	if !request.AllowExtension(service.Name) {
+		return serializer.Err(serializer.CodeFileTypeNotAllowed, "", nil)
+	}
+
+	dst, err := request.TargetPath()
+	if err != nil {
+		return serializer.Err(serializer.CodeFileRequestNotFound, "", err)
+	}
+
+	fs, err := filesystem.NewFileSystem(request.Creator())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if !request.Reserve() {
+		return serializer.Err(serializer.CodeFileRequestFull, "", nil)
+	}
+
+	file := &fsctx.FileStream{
+		Size:        service.Size,
+		Name:        availableName(request.TargetFolder(), service.Name),
+		VirtualPath: dst,
+		File:        ioutil.NopCloser(strings.NewReader("")),
+		MimeType:    service.MimeType,
+		Metadata: map[string]string{
+			model.FileRequestMetadataKey:  hashid.HashID(request.ID, hashid.FileRequestID),
+			model.UploaderNameMetadataKey: service.Uploader,
+		},
+	}
+	if service.LastModified > 0 {
+		lastModified := time.UnixMilli(service.LastModified)
+		file.LastModified = &lastModified
+	}
+
+	ctx = context.WithValue(ctx, fsctx.FileRequestCtx, request.ID)
+	credential, err := fs.CreateUploadSession(ctx, file)
+	if err != nil {
+		request.Release()
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{Data: credential}
+}
+
+// LocalUpload 通过文件收集链接处理本机文件分片上传
+func (service *FileRequestUploadService) LocalUpload(ctx context.Context, c *gin.Context) serializer.Response {
+	request := c.MustGet("fileRequest").(*model.FileRequest)
+
+	uploadSessionRaw, ok := cache.Get(filesystem.UploadSessionCachePrefix + service.ID)
+	if !ok {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+	}
+
+	uploadSession := uploadSessionRaw.(serializer.UploadSession)
+	if uploadSession.FileRequestID != request.ID {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+	}
+
+	fs, err := filesystem.NewFileSystem(request.Creator())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	return localChunkUpload(ctx, c, fs, &uploadSession, service.Index)
+}
+
+// availableName 目录下存在同名文件时，返回添加序号后的文件名
+func availableName(folder *model.Folder, name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 1; ; i++ {
+		if _, err := folder.GetChildFile(candidate); err != nil {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+}
@@ -3,6 +3,7 @@ package explorer
 import (
 	"context"
 	"strings"
+	"time"
 
 	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
@@ -11,11 +12,22 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// typeExtensions 各文件类型对应的扩展名
+var typeExtensions = map[string][]string{
+	"image": {"bmp", "iff", "png", "gif", "jpg", "jpeg", "psd", "svg", "webp"},
+	"video": {"mp4", "flv", "avi", "wmv", "mkv", "rm", "rmvb", "mov", "ogv"},
+	"audio": {"mp3", "flac", "ape", "wav", "acc", "ogg", "midi", "mid"},
+	"doc":   {"txt", "md", "pdf", "doc", "docx", "ppt", "pptx", "xls", "xlsx", "pub"},
+}
+
 // ItemSearchService 文件搜索服务
 type ItemSearchService struct {
 	Type     string `uri:"type" binding:"required"`
 	Keywords string `uri:"keywords" binding:"required"`
 	Path     string `form:"path"`
+	// Limit 和 Cursor 用于内容搜索的分页
+	Limit  int    `form:"limit" binding:"min=0,max=200"`
+	Cursor string `form:"cursor"`
 }
 
 // Search 执行搜索
@@ -39,14 +51,15 @@ func (service *ItemSearchService) Search(c *gin.Context) serializer.Response {
 	switch service.Type {
 	case "keywords":
 		return service.SearchKeywords(c, fs, "%"+service.Keywords+"%")
-	case "image":
-		return service.SearchKeywords(c, fs, "%.bmp", "%.iff", "%.png", "%.gif", "%.jpg", "%.jpeg", "%.psd", "%.svg", "%.webp")
-	case "video":
-		return service.SearchKeywords(c, fs, "%.mp4", "%.flv", "%.avi", "%.wmv", "%.mkv", "%.rm", "%.rmvb", "%.mov", "%.ogv")
-	case "audio":
-		return service.SearchKeywords(c, fs, "%.mp3", "%.flac", "%.ape", "%.wav", "%.acc", "%.ogg", "%.midi", "%.mid")
-	case "doc":
-		return service.SearchKeywords(c, fs, "%.txt", "%.md", "%.pdf", "%.doc", "%.docx", "%.ppt", "%.pptx", "%.xls", "%.xlsx", "%.pub")
+	case "content":
+		return service.SearchContent(c, fs)
+	case "image", "video", "audio", "doc":
+		exts := typeExtensions[service.Type]
+		keywords := make([]interface{}, len(exts))
+		for i := 0; i < len(exts); i++ {
+			keywords[i] = "%." + exts[i]
+		}
+		return service.SearchKeywords(c, fs, keywords...)
 	case "tag":
 		if tid, err := hashid.DecodeHashID(service.Keywords, hashid.TagID); err == nil {
 			if tag, err := model.GetTagsByID(tid, fs.User.ID); err == nil {
@@ -86,3 +99,118 @@ func (service *ItemSearchService) SearchKeywords(c *gin.Context, fs *filesystem.
 		},
 	}
 }
+
+// SearchContent 根据关键字搜索文件内容
+func (service *ItemSearchService) SearchContent(c *gin.Context, fs *filesystem.FileSystem) serializer.Response {
+	if !model.IsTrueVal(model.GetSettingByName("content_index_enabled")) {
+		return serializer.Err(serializer.CodeFeatureNotEnabled, "Content search is not enabled", nil)
+	}
+
+	// 上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	limit := service.Limit
+	if limit == 0 {
+		limit = 50
+	}
+
+	objects, next, err := fs.SearchContent(ctx, service.Keywords, limit, service.Cursor)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: map[string]interface{}{
+			"parent":  0,
+			"objects": objects,
+			"cursor":  next,
+		},
+	}
+}
+
+// ItemQueryService 结构化搜索服务
+type ItemQueryService struct {
+	// Name 名称匹配模式，可使用 * 和 ? 通配符
+	Name string `json:"name" binding:"max=255"`
+	// Type 预设的文件类型，与 Extensions 合并
+	Type          string     `json:"type" binding:"omitempty,eq=image|eq=video|eq=audio|eq=doc"`
+	Extensions    []string   `json:"extensions" binding:"max=100"`
+	MinSize       uint64     `json:"min_size"`
+	MaxSize       uint64     `json:"max_size"`
+	CreatedAfter  *time.Time `json:"created_after"`
+	CreatedBefore *time.Time `json:"created_before"`
+	UpdatedAfter  *time.Time `json:"updated_after"`
+	UpdatedBefore *time.Time `json:"updated_before"`
+	// Path 限定搜索的目录
+	Path     string   `json:"path"`
+	Policy   string   `json:"policy"`
+	Metadata []string `json:"metadata" binding:"max=20"`
+	OrderBy  string   `json:"order_by" binding:"omitempty,eq=name|eq=size|eq=created_at|eq=updated_at"`
+	Order    string   `json:"order" binding:"omitempty,eq=asc|eq=desc"`
+	Limit    int      `json:"limit" binding:"min=0,max=200"`
+	Cursor   string   `json:"cursor"`
+}
+
+// Query 按条件搜索文件和目录
+func (service *ItemQueryService) Query(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if service.Path != "" {
+		ok, parent := fs.IsPathExist(service.Path)
+		if !ok {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
+
+		fs.Root = parent
+	}
+
+	opts := &model.SearchOptions{
+		Name:          service.Name,
+		Extensions:    append(service.Extensions, typeExtensions[service.Type]...),
+		MinSize:       service.MinSize,
+		MaxSize:       service.MaxSize,
+		CreatedAfter:  service.CreatedAfter,
+		CreatedBefore: service.CreatedBefore,
+		UpdatedAfter:  service.UpdatedAfter,
+		UpdatedBefore: service.UpdatedBefore,
+		MetadataKeys:  service.Metadata,
+		OrderBy:       service.OrderBy,
+		Desc:          service.Order == "desc",
+		Limit:         service.Limit,
+	}
+
+	if opts.Limit == 0 {
+		opts.Limit = 50
+	}
+
+	if service.Policy != "" {
+		policyID, err := hashid.DecodeHashID(service.Policy, hashid.PolicyID)
+		if err != nil {
+			return serializer.ParamErr("Invalid policy", err)
+		}
+		opts.PolicyID = policyID
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	objects, next, err := fs.SearchObjects(ctx, opts, service.Cursor)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: map[string]interface{}{
+			"parent":  0,
+			"objects": objects,
+			"cursor":  next,
+		},
+	}
+}
@@ -0,0 +1,134 @@
+package explorer
+
+import (
+	"path"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// TeamService 团队空间服务
+type TeamService struct {
+}
+
+// TeamMemberService 设置团队成员服务，Role 为 0 时移除成员
+type TeamMemberService struct {
+	// User 成员的 Email
+	User string `json:"user" binding:"required,email"`
+	Role int    `json:"role" binding:"min=0,max=3"`
+}
+
+// Team 团队空间
+type Team struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Role       int    `json:"role"`
+	MaxStorage uint64 `json:"max_storage"`
+	Storage    uint64 `json:"storage"`
+}
+
+// TeamMember 团队成员
+type TeamMember struct {
+	User string `json:"user"`
+	Nick string `json:"nick"`
+	Role int    `json:"role"`
+}
+
+// List 列出当前用户可以访问的团队空间
+func (service *TeamService) List(c *gin.Context, user *model.User) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	folders, err := fs.ListTeamFolders()
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	res := make([]Team, 0, len(folders))
+	for _, shared := range folders {
+		team, err := model.GetTeamByUserID(shared.Owner.ID)
+		if err != nil {
+			continue
+		}
+
+		res = append(res, Team{
+			ID:         hashid.HashID(team.ID, hashid.TeamID),
+			Name:       shared.Name,
+			Path:       path.Join(shared.Base, shared.Name),
+			Role:       team.RoleOf(user),
+			MaxStorage: shared.Owner.Group.MaxStorage,
+			Storage:    shared.Owner.Storage,
+		})
+	}
+
+	return serializer.Response{Data: res}
+}
+
+// Members 列出团队成员
+func (service *TeamService) Members(c *gin.Context, user *model.User) serializer.Response {
+	team, err := teamFromContext(c, user, model.TeamViewer)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Team not exist", err)
+	}
+
+	members, err := team.Members()
+	if err != nil {
+		return serializer.DBErr("Failed to list team members", err)
+	}
+
+	res := make([]TeamMember, 0, len(members))
+	for _, member := range members {
+		if u, err := model.GetUserByID(member.UserID); err == nil {
+			res = append(res, TeamMember{User: u.Email, Nick: u.Nick, Role: member.Role})
+		}
+	}
+
+	return serializer.Response{Data: res}
+}
+
+// Set 添加、修改或移除团队成员，仅团队管理员可用
+func (service *TeamMemberService) Set(c *gin.Context, user *model.User) serializer.Response {
+	team, err := teamFromContext(c, user, model.TeamManager)
+	if err != nil {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Team manager required", err)
+	}
+
+	member, err := model.GetActiveUserByEmail(service.User)
+	if err != nil || member.OptionsSerialized.TeamID > 0 {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if service.Role == 0 {
+		err = team.RemoveMember(member.ID)
+	} else {
+		err = team.SetMember(member.ID, service.Role)
+	}
+
+	if err != nil {
+		return serializer.DBErr("Failed to update team member", err)
+	}
+
+	return serializer.Response{}
+}
+
+// teamFromContext 获取路由参数中的团队空间，用户角色低于 role 时返回错误
+func teamFromContext(c *gin.Context, user *model.User, role int) (*model.Team, error) {
+	teamID, _ := c.Get("object_id")
+	team, err := model.GetTeamByID(teamID.(uint))
+	if err != nil {
+		return nil, err
+	}
+
+	if team.RoleOf(user) < role {
+		return nil, filesystem.ErrPathNotExist
+	}
+
+	return team, nil
+}
@@ -0,0 +1,127 @@
+package explorer
+
+import (
+	"context"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// TrashService 回收站服务
+type TrashService struct {
+}
+
+// TrashItemService 回收站对象操作服务，字段值为HashID
+type TrashItemService struct {
+	Items  []string `json:"items" binding:"required,min=1"`
+	Rename bool     `json:"rename"`
+}
+
+// TrashItem 回收站中的对象
+type TrashItem struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      uint64    `json:"size"`
+	IsFolder  bool      `json:"is_folder"`
+	DeletedAt time.Time `json:"deleted_at"`
+	ExpireAt  time.Time `json:"expire_at"`
+}
+
+// raw 批量解码回收站对象的HashID
+func (service *TrashItemService) raw() []uint {
+	ids := make([]uint, 0, len(service.Items))
+	for _, item := range service.Items {
+		if id, err := hashid.DecodeHashID(item, hashid.TrashID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// List 列出回收站中的对象
+func (service *TrashService) List(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	items, err := model.GetTrashByUser(fs.User.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list trash", err)
+	}
+
+	retention := fs.User.Group.OptionsSerialized.TrashRetention
+	res := make([]TrashItem, 0, len(items))
+	var total uint64
+	for _, item := range items {
+		total += item.Size
+		res = append(res, TrashItem{
+			ID:        hashid.HashID(item.ID, hashid.TrashID),
+			Name:      item.Name,
+			Path:      item.Path,
+			Size:      item.Size,
+			IsFolder:  item.IsFolder,
+			DeletedAt: item.CreatedAt,
+			ExpireAt:  item.ExpireAt(retention),
+		})
+	}
+
+	return serializer.Response{
+		Data: map[string]interface{}{
+			"items": res,
+			"total": total,
+		},
+	}
+}
+
+// Restore 还原回收站中的对象
+func (service *TrashItemService) Restore(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if err := fs.RestoreTrash(ctx, service.raw(), service.Rename); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Delete 彻底删除回收站中的对象
+func (service *TrashItemService) Delete(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if err := fs.DeleteTrash(ctx, service.raw()); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Empty 清空回收站
+func (service *TrashService) Empty(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if err := fs.EmptyTrash(ctx); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
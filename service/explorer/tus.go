@@ -0,0 +1,293 @@
+package explorer
+
+import (
+	"bytes"
+	"context"
+	"hash"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/tus"
+	"github.com/gin-gonic/gin"
+)
+
+// TusCreate 按 tus 协议的 creation 扩展创建上传会话，会话 ID 即为上传地址的最后一段。
+// 元数据中 filename 为文件名，path 为上传目录
+func TusCreate(ctx context.Context, c *gin.Context) serializer.Response {
+	if c.GetHeader(tus.DeferLengthHeader) != "" {
+		return serializer.ParamErr("Deferred upload length is not supported", nil)
+	}
+
+	size, err := strconv.ParseUint(c.GetHeader(tus.LengthHeader), 10, 64)
+	if err != nil {
+		return serializer.ParamErr("Invalid Upload-Length", err)
+	}
+
+	meta, err := tus.ParseMetadata(c.GetHeader(tus.MetadataHeader))
+	if err != nil {
+		return serializer.ParamErr(err.Error(), err)
+	}
+
+	service := &CreateUploadSessionService{
+		Path:     meta["path"],
+		Size:     size,
+		Name:     meta["filename"],
+		MimeType: meta["filetype"],
+	}
+	if service.Path == "" {
+		service.Path = "/"
+	}
+	if service.Name == "" {
+		return serializer.ParamErr("File name is required in Upload-Metadata", nil)
+	}
+	if lastModified, err := strconv.ParseInt(meta["lastModified"], 10, 64); err == nil {
+		service.LastModified = lastModified
+	}
+
+	ctx, fs, file, err := service.prepare(ctx, c)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	// 需要按任意偏移量续传并查询已写入的大小，只支持未加密的本机存储策略
+	if !TusSupported(fs.Policy) {
+		return serializer.Err(serializer.CodePolicyNotAllowed, "tus upload is only supported by unencrypted local storage policies", nil)
+	}
+
+	credential, err := fs.CreateUploadSession(ctx, file)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	c.Header("Location", path.Join(c.Request.URL.Path, credential.SessionID))
+
+	// 空文件无需后续的 PATCH 请求，创建后直接完成上传
+	if size == 0 {
+		upload := &TusService{ID: credential.SessionID}
+		fs, session, file, err := upload.resolve(c)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		}
+		defer fs.Recycle()
+
+		return upload.write(ctx, c, fs, session, file, strings.NewReader(""), 0, nil)
+	}
+
+	return serializer.Response{}
+}
+
+// TusSupported 返回存储策略是否支持 tus 上传
+func TusSupported(policy *model.Policy) bool {
+	return policy.Type == "local" && !policy.IsEncrypted()
+}
+
+// TusService tus 协议上传服务
+type TusService struct {
+	ID string `uri:"sessionId" binding:"required"`
+}
+
+// Head 返回已上传的偏移量
+func (service *TusService) Head(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, session, file, err := service.resolve(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	c.Header("Cache-Control", "no-store")
+	c.Header(tus.OffsetHeader, strconv.FormatUint(file.Size, 10))
+	c.Header(tus.LengthHeader, strconv.FormatUint(session.Size, 10))
+	return serializer.Response{}
+}
+
+// Patch 从给定偏移量开始写入文件内容，写入完成后与原生的分片上传一样更新占位文件
+func (service *TusService) Patch(ctx context.Context, c *gin.Context) serializer.Response {
+	if c.ContentType() != tus.OffsetContentType {
+		return serializer.Err(http.StatusUnsupportedMediaType, "Content-Type must be "+tus.OffsetContentType, nil)
+	}
+
+	fs, session, file, err := service.resolve(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	offset, err := strconv.ParseUint(c.GetHeader(tus.OffsetHeader), 10, 64)
+	if err != nil {
+		return serializer.ParamErr("Invalid Upload-Offset", err)
+	}
+
+	if offset != file.Size {
+		return serializer.Err(serializer.CodeUploadOffsetMismatch, "Upload-Offset does not match uploaded size", nil)
+	}
+
+	// 未指定 Content-Length 时写入至请求结束或文件末尾
+	length := c.Request.ContentLength
+	if length >= 0 && offset+uint64(length) > session.Size {
+		return serializer.Err(serializer.CodeInvalidContentLength, "Invalid Content-Length", nil)
+	}
+
+	var (
+		validate filesystem.Hook
+		body     io.Reader = c.Request.Body
+	)
+	if header := c.GetHeader(tus.ChecksumHeader); header != "" {
+		checksum, expected, err := tus.ParseChecksum(header)
+		if err != nil {
+			return serializer.ParamErr(err.Error(), err)
+		}
+		body = io.TeeReader(body, checksum)
+		validate = hookValidateChecksum(checksum, expected)
+	}
+
+	return service.write(ctx, c, fs, session, file, body, length, validate)
+}
+
+// write 从占位文件当前的大小开始写入 length 字节，length 小于 0 时写入至 body 结束；
+// validate 不为空时在写入后校验内容
+func (service *TusService) write(ctx context.Context, c *gin.Context, fs *filesystem.FileSystem, session *serializer.UploadSession,
+	file *model.File, body io.Reader, length int64, validate filesystem.Hook) serializer.Response {
+	offset := file.Size
+	size := session.Size - offset
+	if length >= 0 {
+		size = uint64(length)
+	}
+
+	fileData := fsctx.FileStream{
+		File:         io.NopCloser(io.LimitReader(body, int64(size))),
+		Size:         size,
+		Name:         session.Name,
+		VirtualPath:  session.VirtualPath,
+		SavePath:     session.SavePath,
+		Mode:         fsctx.Append | fsctx.Overwrite,
+		AppendStart:  offset,
+		Model:        file,
+		LastModified: session.LastModified,
+	}
+
+	// 给文件系统分配钩子，写入中断时保留已写入的内容，带校验和的请求则丢弃
+	if validate != nil {
+		fs.Use("AfterUploadFailed", filesystem.HookTruncateFileTo(offset))
+		fs.Use("AfterUpload", validate)
+	} else {
+		fs.Use("AfterUploadFailed", filesystem.HookSyncUploadedSize)
+	}
+	fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+	fs.Use("AfterUpload", filesystem.HookSyncUploadedSize)
+	fs.Use("AfterUpload", hookCompleteTusUpload(session))
+	fs.Use("AfterValidateFailed", filesystem.HookTruncateFileTo(offset))
+	fs.Use("AfterValidateFailed", filesystem.HookChunkUploadFailed)
+
+	// 执行上传
+	uploadCtx := context.WithValue(ctx, fsctx.GinCtx, c)
+	uploadCtx = context.WithValue(uploadCtx, fsctx.FileSizeCtx, session.Size)
+	if err := fs.Upload(uploadCtx, &fileData); err != nil {
+		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
+	}
+
+	c.Header(tus.OffsetHeader, strconv.FormatUint(file.Size, 10))
+	return serializer.Response{}
+}
+
+// hookCompleteTusUpload 写入完整文件后将占位文件提升为正式文件，并删除上传会话
+func hookCompleteTusUpload(session *serializer.UploadSession) filesystem.Hook {
+	return func(ctx context.Context, fs *filesystem.FileSystem, fileHeader fsctx.FileHeader) error {
+		if fileHeader.Info().Model.(*model.File).Size != session.Size {
+			return nil
+		}
+
+		for _, hook := range []filesystem.Hook{
+			filesystem.HookPopPlaceholderToFile(""),
+			filesystem.HookCommitBlob,
+			filesystem.HookDeleteUploadSession(session.Key),
+		} {
+			if err := hook(ctx, fs, fileHeader); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// Delete 终止上传并删除占位文件
+func (service *TusService) Delete(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, _, file, err := service.resolve(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	if err := fs.Delete(ctx, []uint{}, []uint{file.ID}, false, false); err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to delete upload session", err)
+	}
+
+	return serializer.Response{}
+}
+
+// resolve 查找上传会话及其占位文件，返回的文件系统已切换为会话的存储策略
+func (service *TusService) resolve(c *gin.Context) (*filesystem.FileSystem, *serializer.UploadSession, *model.File, error) {
+	uploadSessionRaw, ok := cache.Get(filesystem.UploadSessionCachePrefix + service.ID)
+	if !ok {
+		return nil, nil, nil, serializer.NewError(serializer.CodeUploadSessionExpired, "", nil)
+	}
+
+	uploadSession := uploadSessionRaw.(serializer.UploadSession)
+
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return nil, nil, nil, serializer.NewError(serializer.CodeCreateFSError, "", err)
+	}
+
+	if uploadSession.UID != fs.User.ID {
+		fs.Recycle()
+		if uploadSession.UploaderID == 0 || uploadSession.UploaderID != fs.User.ID {
+			return nil, nil, nil, serializer.NewError(serializer.CodeUploadSessionExpired, "", nil)
+		}
+
+		// 上传至与我共享的目录，切换为所有者的文件系统
+		owner, err := model.GetActiveUserByID(uploadSession.UID)
+		if err != nil {
+			return nil, nil, nil, serializer.NewError(serializer.CodeUploadSessionExpired, "", err)
+		}
+
+		if fs, err = filesystem.NewFileSystem(&owner); err != nil {
+			return nil, nil, nil, serializer.NewError(serializer.CodeCreateFSError, "", err)
+		}
+	}
+
+	// 查找上传会话创建的占位文件
+	file, err := model.GetFilesByUploadSession(service.ID, fs.User.ID)
+	if err != nil {
+		fs.Recycle()
+		return nil, nil, nil, serializer.NewError(serializer.CodeUploadSessionExpired, "", err)
+	}
+
+	fs.Policy = &uploadSession.Policy
+	if err := fs.DispatchHandler(); err != nil {
+		fs.Recycle()
+		return nil, nil, nil, serializer.NewError(serializer.CodePolicyNotExist, "", err)
+	}
+
+	return fs, &uploadSession, file, nil
+}
+
+// hookValidateChecksum 校验写入内容的校验和
+func hookValidateChecksum(checksum hash.Hash, expected []byte) filesystem.Hook {
+	return func(ctx context.Context, fs *filesystem.FileSystem, file fsctx.FileHeader) error {
+		if !bytes.Equal(checksum.Sum(nil), expected) {
+			return serializer.NewError(serializer.CodeChecksumMismatch, "Checksum mismatch", nil)
+		}
+
+		return nil
+	}
+}
@@ -27,14 +27,15 @@ type CreateUploadSessionService struct {
 	PolicyID     string `json:"policy_id" binding:"required"`
 	LastModified int64  `json:"last_modified"`
 	MimeType     string `json:"mime_type"`
+	// Hash 文件内容的 SHA-256，存储策略开启去重时用于秒传
+	Hash string `json:"hash" binding:"omitempty,len=64,hexadecimal"`
 }
 
 // Create 创建新的上传会话
 func (service *CreateUploadSessionService) Create(ctx context.Context, c *gin.Context) serializer.Response {
-	// 创建文件系统
-	fs, err := filesystem.NewFileSystemFromContext(c)
+	ctx, fs, file, err := service.prepare(ctx, c)
 	if err != nil {
-		return serializer.Err(serializer.CodeCreateFSError, "", err)
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
 	// 取得存储策略的ID
@@ -47,6 +48,43 @@ func (service *CreateUploadSessionService) Create(ctx context.Context, c *gin.Co
 		return serializer.Err(serializer.CodePolicyNotAllowed, "存储策略发生变化，请刷新文件列表并重新添加此任务", nil)
 	}
 
+	credential, err := fs.CreateUploadSession(ctx, file)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: credential,
+	}
+}
+
+// prepare 返回上传使用的文件系统及待上传的文件，上传至与我共享的目录时使用所有者的文件系统
+func (service *CreateUploadSessionService) prepare(ctx context.Context, c *gin.Context) (context.Context, *filesystem.FileSystem, *fsctx.FileStream, error) {
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return ctx, nil, nil, serializer.NewError(serializer.CodeCreateFSError, "", err)
+	}
+
+	// 上传至与我共享的目录时，使用所有者的文件系统
+	if filesystem.IsSharedPath(service.Path) {
+		shared, inner, err := fs.ResolveSharedPath(service.Path)
+		if err != nil {
+			return ctx, nil, nil, serializer.NewError(serializer.CodeParentNotExist, "", err)
+		}
+
+		if !shared.Writable() {
+			return ctx, nil, nil, serializer.NewError(serializer.CodeSharedFolderReadOnly, "", nil)
+		}
+
+		ctx = context.WithValue(ctx, fsctx.UploaderIDCtx, fs.User.ID)
+		if fs, err = shared.NewFileSystem(); err != nil {
+			return ctx, nil, nil, serializer.NewError(serializer.CodeCreateFSError, "", err)
+		}
+		service.Path = shared.FullPath(inner)
+	}
+
 	file := &fsctx.FileStream{
 		Size:        service.Size,
 		Name:        service.Name,
@@ -58,15 +96,12 @@ func (service *CreateUploadSessionService) Create(ctx context.Context, c *gin.Co
 		lastModified := time.UnixMilli(service.LastModified)
 		file.LastModified = &lastModified
 	}
-	credential, err := fs.CreateUploadSession(ctx, file)
-	if err != nil {
-		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	if service.Hash != "" {
+		hash := strings.ToLower(service.Hash)
+		ctx = context.WithValue(ctx, fsctx.ContentHashCtx, &hash)
 	}
 
-	return serializer.Response{
-		Code: 0,
-		Data: credential,
-	}
+	return ctx, fs, file, nil
 }
 
 // UploadService 本机及从机策略上传服务
@@ -90,11 +125,28 @@ func (service *UploadService) LocalUpload(ctx context.Context, c *gin.Context) s
 	}
 
 	if uploadSession.UID != fs.User.ID {
-		return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+		if uploadSession.UploaderID == 0 || uploadSession.UploaderID != fs.User.ID {
+			return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+		}
+
+		// 上传至与我共享的目录，切换为所有者的文件系统
+		owner, err := model.GetActiveUserByID(uploadSession.UID)
+		if err != nil {
+			return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
+		}
+
+		if fs, err = filesystem.NewFileSystem(&owner); err != nil {
+			return serializer.Err(serializer.CodeCreateFSError, "", err)
+		}
 	}
 
+	return localChunkUpload(ctx, c, fs, &uploadSession, service.Index)
+}
+
+// localChunkUpload 校验分片序号后将分片写入上传会话的占位文件
+func localChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.FileSystem, uploadSession *serializer.UploadSession, index int) serializer.Response {
 	// 查找上传会话创建的占位文件
-	file, err := model.GetFilesByUploadSession(service.ID, fs.User.ID)
+	file, err := model.GetFilesByUploadSession(uploadSession.Key, fs.User.ID)
 	if err != nil {
 		return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
 	}
@@ -110,8 +162,8 @@ func (service *UploadService) LocalUpload(ctx context.Context, c *gin.Context) s
 	}
 
 	expectedSizeStart := file.Size
-	actualSizeStart := uint64(service.Index) * uploadSession.Policy.OptionsSerialized.ChunkSize
-	if uploadSession.Policy.OptionsSerialized.ChunkSize == 0 && service.Index > 0 {
+	actualSizeStart := uint64(index) * uploadSession.Policy.OptionsSerialized.ChunkSize
+	if uploadSession.Policy.OptionsSerialized.ChunkSize == 0 && index > 0 {
 		return serializer.Err(serializer.CodeInvalidChunkIndex, "Chunk index cannot be greater than 0", nil)
 	}
 
@@ -120,10 +172,10 @@ func (service *UploadService) LocalUpload(ctx context.Context, c *gin.Context) s
 	}
 
 	if expectedSizeStart > actualSizeStart {
-		util.Log().Info("Trying to overwrite chunk[%d] Start=%d", service.Index, actualSizeStart)
+		util.Log().Info("Trying to overwrite chunk[%d] Start=%d", index, actualSizeStart)
 	}
 
-	return processChunkUpload(ctx, c, fs, &uploadSession, service.Index, file, fsctx.Append)
+	return processChunkUpload(ctx, c, fs, uploadSession, index, file, fsctx.Append)
 }
 
 // SlaveUpload 处理从机文件分片上传
@@ -140,7 +192,7 @@ func (service *UploadService) SlaveUpload(ctx context.Context, c *gin.Context) s
 		return serializer.Err(serializer.CodeCreateFSError, "", err)
 	}
 
-	fs.Handler = local.Driver{}
+	fs.Handler = local.Driver{Policy: &uploadSession.Policy}
 
 	// 解析需要的参数
 	service.Index, _ = strconv.Atoi(c.Query("chunk"))
@@ -198,6 +250,7 @@ func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.File
 		fs.Use("AfterValidateFailed", filesystem.HookChunkUploadFailed)
 		if isLastChunk {
 			fs.Use("AfterUpload", filesystem.HookPopPlaceholderToFile(""))
+			fs.Use("AfterUpload", filesystem.HookCommitBlob)
 			fs.Use("AfterUpload", filesystem.HookDeleteUploadSession(session.Key))
 		}
 	} else {
@@ -209,6 +262,7 @@ func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.File
 
 	// 执行上传
 	uploadCtx := context.WithValue(ctx, fsctx.GinCtx, c)
+	uploadCtx = context.WithValue(uploadCtx, fsctx.FileSizeCtx, session.Size)
 	err = fs.Upload(uploadCtx, &fileData)
 	if err != nil {
 		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
@@ -217,6 +271,62 @@ func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.File
 	return serializer.Response{}
 }
 
+// UploadProofService 秒传持有证明服务
+type UploadProofService struct {
+	ID    string `uri:"sessionId" binding:"required"`
+	Proof string `json:"proof" binding:"required,len=64,hexadecimal"`
+}
+
+// Instant 校验持有证明，通过后无需上传文件内容即完成上传会话
+func (service *UploadProofService) Instant(ctx context.Context, c *gin.Context) serializer.Response {
+	uploadSessionRaw, ok := cache.Get(filesystem.UploadSessionCachePrefix + service.ID)
+	if !ok {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+	}
+
+	uploadSession := uploadSessionRaw.(serializer.UploadSession)
+
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if uploadSession.UID != fs.User.ID {
+		if uploadSession.UploaderID == 0 || uploadSession.UploaderID != fs.User.ID {
+			return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+		}
+
+		// 上传至与我共享的目录，切换为所有者的文件系统
+		owner, err := model.GetActiveUserByID(uploadSession.UID)
+		if err != nil {
+			return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
+		}
+
+		if fs, err = filesystem.NewFileSystem(&owner); err != nil {
+			return serializer.Err(serializer.CodeCreateFSError, "", err)
+		}
+		defer fs.Recycle()
+	}
+
+	// 查找上传会话创建的占位文件
+	file, err := model.GetFilesByUploadSession(service.ID, fs.User.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
+	}
+
+	fs.Policy = &uploadSession.Policy
+	if err := fs.DispatchHandler(); err != nil {
+		return serializer.Err(serializer.CodePolicyNotExist, "", err)
+	}
+
+	if err := fs.InstantUpload(ctx, &uploadSession, file, service.Proof); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
 // UploadSessionService 上传会话服务
 type UploadSessionService struct {
 	ID string `uri:"sessionId" binding:"required"`
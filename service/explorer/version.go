@@ -0,0 +1,137 @@
+package explorer
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem"
+	"gitee.com/jiangjiali/cloudreve/pkg/filesystem/fsctx"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// FileVersionService 文件单个历史版本服务
+type FileVersionService struct {
+	Version string `uri:"version" binding:"required"`
+}
+
+// FileVersionDeleteService 文件历史版本删除服务，字段值为HashID
+type FileVersionDeleteService struct {
+	Versions []string `json:"versions" binding:"required,min=1"`
+}
+
+// FileVersion 文件历史版本
+type FileVersion struct {
+	ID        string    `json:"id"`
+	Size      uint64    `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListVersions 列出文件的历史版本
+func (service *FileIDService) ListVersions(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	fileID, _ := c.Get("object_id")
+	files, err := model.GetFilesByIDs([]uint{fileID.(uint)}, fs.User.ID)
+	if err != nil || len(files) == 0 {
+		return serializer.Err(serializer.CodeFileNotFound, "", err)
+	}
+
+	versions, err := model.GetVersionsByFile(files[0].ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list versions", err)
+	}
+
+	res := make([]FileVersion, 0, len(versions))
+	for _, version := range versions {
+		res = append(res, FileVersion{
+			ID:        hashid.HashID(version.ID, hashid.VersionID),
+			Size:      version.Size,
+			CreatedAt: version.CreatedAt,
+		})
+	}
+
+	return serializer.Response{Data: res}
+}
+
+// Download 下载文件的历史版本
+func (service *FileVersionService) Download(ctx context.Context, c *gin.Context) serializer.Response {
+	versionID, err := hashid.DecodeHashID(service.Version, hashid.VersionID)
+	if err != nil {
+		return serializer.ParamErr("Failed to parse version ID", err)
+	}
+
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	fileID, _ := c.Get("object_id")
+	ctx = context.WithValue(ctx, fsctx.GinCtx, c)
+	rs, err := fs.GetVersionContent(ctx, fileID.(uint), versionID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer rs.Close()
+
+	// 设置文件名
+	c.Header("Content-Disposition", "attachment; filename=\""+url.PathEscape(fs.FileTarget[0].Name)+"\"")
+
+	// 发送文件
+	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, rs)
+
+	return serializer.Response{}
+}
+
+// Restore 将文件还原为历史版本
+func (service *FileVersionService) Restore(ctx context.Context, c *gin.Context) serializer.Response {
+	versionID, err := hashid.DecodeHashID(service.Version, hashid.VersionID)
+	if err != nil {
+		return serializer.ParamErr("Failed to parse version ID", err)
+	}
+
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	fileID, _ := c.Get("object_id")
+	if err := fs.RestoreVersion(ctx, fileID.(uint), versionID); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Delete 删除文件的历史版本
+func (service *FileVersionDeleteService) Delete(ctx context.Context, c *gin.Context) serializer.Response {
+	ids := make([]uint, 0, len(service.Versions))
+	for _, version := range service.Versions {
+		if id, err := hashid.DecodeHashID(version, hashid.VersionID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	fileID, _ := c.Get("object_id")
+	if err := fs.DeleteVersions(ctx, fileID.(uint), ids); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
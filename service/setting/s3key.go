@@ -0,0 +1,95 @@
+package setting
+
+import (
+	"regexp"
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// bucketNamePattern S3 存储桶名称规则
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// S3KeyListService S3 访问密钥列表服务
+type S3KeyListService struct {
+}
+
+// S3KeyService S3 访问密钥管理服务
+type S3KeyService struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// S3KeyCreateService S3 访问密钥创建服务
+type S3KeyCreateService struct {
+	Name   string `json:"name" binding:"required,min=1,max=255"`
+	Path   string `json:"path" binding:"required,min=1,max=65535"`
+	Bucket string `json:"bucket"`
+}
+
+// S3KeyUpdateService S3 访问密钥修改只读性服务
+type S3KeyUpdateService struct {
+	ID       uint  `json:"id" binding:"required,min=1"`
+	Readonly *bool `json:"readonly" binding:"required"`
+}
+
+// Create 创建S3访问密钥，SecretKey 只在创建时返回
+func (service *S3KeyCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if !user.Group.OptionsSerialized.S3Gateway {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "S3 gateway is not enabled for your group", nil)
+	}
+
+	if service.Bucket == "" {
+		service.Bucket = "cloudreve"
+	}
+	if !bucketNamePattern.MatchString(service.Bucket) {
+		return serializer.ParamErr("Invalid bucket name", nil)
+	}
+
+	key := model.S3Key{
+		Name:      service.Name,
+		AccessKey: strings.ToUpper(util.RandStringRunes(20)),
+		SecretKey: util.RandStringRunes(40),
+		UserID:    user.ID,
+		Bucket:    service.Bucket,
+		Root:      service.Path,
+	}
+
+	if _, err := key.Create(); err != nil {
+		return serializer.DBErr("Failed to create S3 access key", err)
+	}
+
+	return serializer.Response{
+		Data: map[string]interface{}{
+			"id":         key.ID,
+			"access_key": key.AccessKey,
+			"secret_key": key.SecretKey,
+			"bucket":     key.Bucket,
+			"created_at": key.CreatedAt,
+		},
+	}
+}
+
+// Delete 删除S3访问密钥
+func (service *S3KeyService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	model.DeleteS3KeyByID(service.ID, user.ID)
+	return serializer.Response{}
+}
+
+// Update 修改S3访问密钥只读性
+func (service *S3KeyUpdateService) Update(c *gin.Context, user *model.User) serializer.Response {
+	updates := map[string]interface{}{"readonly": *service.Readonly}
+	model.UpdateS3KeyByID(service.ID, user.ID, updates)
+	return serializer.Response{Data: updates}
+}
+
+// Keys 列出S3访问密钥
+func (service *S3KeyListService) Keys(c *gin.Context, user *model.User) serializer.Response {
+	keys := model.ListS3Keys(user.ID)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"keys": keys,
+	}}
+}
@@ -0,0 +1,144 @@
+package setting
+
+import (
+	"strings"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"gitee.com/jiangjiali/cloudreve/pkg/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookListService Webhook 列表服务
+type WebhookListService struct {
+}
+
+// WebhookService Webhook 管理服务
+type WebhookService struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// WebhookCreateService Webhook 创建服务
+type WebhookCreateService struct {
+	Name   string   `json:"name" binding:"required,min=1,max=255"`
+	URL    string   `json:"url" binding:"required,min=1,max=65535"`
+	Events []string `json:"events" binding:"required,min=1"`
+}
+
+// WebhookUpdateService Webhook 修改服务
+type WebhookUpdateService struct {
+	ID     uint     `json:"id" binding:"required,min=1"`
+	Name   *string  `json:"name" binding:"omitempty,min=1,max=255"`
+	URL    *string  `json:"url" binding:"omitempty,min=1,max=65535"`
+	Events []string `json:"events"`
+	Enable *bool    `json:"enable"`
+	// ResetSecret 是否重新生成签名密钥
+	ResetSecret bool `json:"reset_secret"`
+}
+
+// Create 创建 Webhook
+func (service *WebhookCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if !webhook.IsPublicURL(service.URL) {
+		return serializer.ParamErr("Invalid webhook URL", nil)
+	}
+
+	if !webhook.IsValidEvents(service.Events) {
+		return serializer.ParamErr("Unknown webhook event", nil)
+	}
+
+	if model.CountWebhooks(user.ID) >= model.GetIntSetting("webhook_max_per_user", 10) {
+		return serializer.ParamErr("Too many webhooks", nil)
+	}
+
+	hook := model.Webhook{
+		UserID: user.ID,
+		Name:   service.Name,
+		URL:    service.URL,
+		Secret: util.RandStringRunes(32),
+		Events: strings.Join(service.Events, ","),
+		Enable: true,
+	}
+
+	if _, err := hook.Create(); err != nil {
+		return serializer.DBErr("Failed to create webhook", err)
+	}
+
+	return serializer.Response{
+		Data: map[string]interface{}{
+			"id":         hook.ID,
+			"secret":     hook.Secret,
+			"created_at": hook.CreatedAt,
+		},
+	}
+}
+
+// Update 修改 Webhook
+func (service *WebhookUpdateService) Update(c *gin.Context, user *model.User) serializer.Response {
+	if _, err := model.GetWebhookByID(service.ID, user.ID); err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Webhook not exist", err)
+	}
+
+	updates := make(map[string]interface{})
+	if service.Name != nil {
+		updates["name"] = *service.Name
+	}
+	if service.URL != nil {
+		if !webhook.IsPublicURL(*service.URL) {
+			return serializer.ParamErr("Invalid webhook URL", nil)
+		}
+		updates["url"] = *service.URL
+	}
+	if service.Events != nil {
+		if len(service.Events) == 0 || !webhook.IsValidEvents(service.Events) {
+			return serializer.ParamErr("Unknown webhook event", nil)
+		}
+		updates["events"] = strings.Join(service.Events, ",")
+	}
+	if service.Enable != nil {
+		updates["enable"] = *service.Enable
+	}
+	if service.ResetSecret {
+		updates["secret"] = util.RandStringRunes(32)
+	}
+
+	if err := model.UpdateWebhookByID(service.ID, user.ID, updates); err != nil {
+		return serializer.DBErr("Failed to update webhook", err)
+	}
+
+	return serializer.Response{Data: updates}
+}
+
+// Delete 删除 Webhook
+func (service *WebhookService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	if err := model.DeleteWebhookByID(service.ID, user.ID); err != nil {
+		return serializer.DBErr("Failed to delete webhook", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Deliveries 列出 Webhook 最近的投递记录
+func (service *WebhookService) Deliveries(c *gin.Context, user *model.User) serializer.Response {
+	if _, err := model.GetWebhookByID(service.ID, user.ID); err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Webhook not exist", err)
+	}
+
+	// 不返回早期版本保存的响应正文
+	deliveries := model.ListWebhookDeliveries(service.ID, 100)
+	for i := range deliveries {
+		deliveries[i].Response = ""
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"deliveries": deliveries,
+	}}
+}
+
+// Webhooks 列出 Webhook 及可订阅的事件
+func (service *WebhookListService) Webhooks(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.Response{Data: map[string]interface{}{
+		"webhooks": model.ListWebhooks(user.ID),
+		"events":   webhook.Events,
+	}}
+}
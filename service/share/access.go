@@ -0,0 +1,157 @@
+package share
+
+import (
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ShareAnalyticsService 分享访问统计服务
+type ShareAnalyticsService struct {
+	Days int `form:"days" binding:"omitempty,min=1,max=90"`
+}
+
+// ShareAccessListService 分享访问记录列表服务
+type ShareAccessListService struct {
+	Page     int `form:"page" binding:"required,min=1"`
+	PageSize int `form:"page_size" binding:"required,min=1,max=100"`
+}
+
+// ShareAccess 分享访问记录
+type ShareAccess struct {
+	Date      time.Time `json:"date"`
+	User      string    `json:"user,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Referer   string    `json:"referer"`
+	Action    string    `json:"action"`
+	Path      string    `json:"path"`
+}
+
+// ShareAnalytics 分享访问统计
+type ShareAnalytics struct {
+	Views     int                    `json:"views"`
+	Downloads int                    `json:"downloads"`
+	Date      []string               `json:"date"`
+	Series    map[string][]int       `json:"series"`
+	Visitors  []int                  `json:"visitors"`
+	TopFiles  []model.ShareFileCount `json:"top_files"`
+}
+
+// recordAccess 记录一次分享访问，path 为目录分享下被访问的文件路径
+func recordAccess(c *gin.Context, share *model.Share, action, path string) {
+	access := &model.ShareAccess{
+		ShareID:   share.ID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Referer:   c.Request.Referer(),
+		Action:    action,
+		Path:      path,
+	}
+	if !share.IsDir {
+		access.Path = share.SourceName
+	}
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok && !u.IsAnonymous() {
+			access.UserID = u.ID
+		}
+	}
+
+	if err := access.Create(); err != nil {
+		util.Log().Warning("Failed to write share access log: %s", err)
+	}
+}
+
+// ownedShare 返回当前用户创建的分享，分享过期后仍可查看统计
+func ownedShare(c *gin.Context, user *model.User) *model.Share {
+	share := model.GetShareByHashID(c.Param("id"))
+	if share == nil || share.UserID != user.ID {
+		return nil
+	}
+	return share
+}
+
+// Analytics 获取分享每日访问统计及热门文件
+func (service *ShareAnalyticsService) Analytics(c *gin.Context, user *model.User) serializer.Response {
+	share := ownedShare(c, user)
+	if share == nil {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	total := service.Days
+	if total == 0 {
+		total = 30
+	}
+
+	res := ShareAnalytics{
+		Views:     share.Views,
+		Downloads: share.Downloads,
+		Date:      make([]string, total),
+		Series:    make(map[string][]int),
+		Visitors:  make([]int, total),
+	}
+	for _, action := range []string{
+		model.ShareActionView,
+		model.ShareActionPreview,
+		model.ShareActionDownload,
+		model.ShareActionArchive,
+	} {
+		res.Series[action] = make([]int, total)
+	}
+
+	// 统计每日访问
+	toRound := time.Now()
+	timeBase := time.Date(toRound.Year(), toRound.Month(), toRound.Day()+1, 0, 0, 0, 0, toRound.Location())
+	for day := 0; day < total; day++ {
+		start := timeBase.Add(-time.Duration(total-day) * time.Hour * 24)
+		end := timeBase.Add(-time.Duration(total-day-1) * time.Hour * 24)
+		res.Date[day] = start.Format("2006-01-02")
+		for action, count := range model.CountShareAccesses(share.ID, start, end) {
+			if series, ok := res.Series[action]; ok {
+				series[day] = count.Count
+			}
+		}
+		res.Visitors[day] = model.CountShareVisitors(share.ID, start, end)
+	}
+
+	res.TopFiles = model.TopShareFiles(share.ID, timeBase.AddDate(0, 0, -total), 10)
+	if res.TopFiles == nil {
+		res.TopFiles = []model.ShareFileCount{}
+	}
+
+	return serializer.Response{Data: res}
+}
+
+// List 列出分享的访问记录
+func (service *ShareAccessListService) List(c *gin.Context, user *model.User) serializer.Response {
+	share := ownedShare(c, user)
+	if share == nil {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	accesses, total := model.ListShareAccesses(share.ID, service.Page, service.PageSize)
+	res := make([]ShareAccess, 0, len(accesses))
+	for _, access := range accesses {
+		item := ShareAccess{
+			Date:      access.CreatedAt,
+			IP:        access.IP,
+			UserAgent: access.UserAgent,
+			Referer:   access.Referer,
+			Action:    access.Action,
+			Path:      access.Path,
+		}
+		if access.UserID > 0 {
+			item.User = hashid.HashID(access.UserID, hashid.UserID)
+		}
+		res = append(res, item)
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
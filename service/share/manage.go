@@ -2,11 +2,13 @@ package share
 
 import (
 	"net/url"
+	"strconv"
 	"time"
 
 	model "gitee.com/jiangjiali/cloudreve/models"
 	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/webhook"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,11 +20,17 @@ type ShareCreateService struct {
 	RemainDownloads int    `json:"downloads"`
 	Expire          int    `json:"expire"`
 	Preview         bool   `json:"preview"`
+	RemainViews     int    `json:"views"`
+	Slug            string `json:"slug" binding:"max=64"`
+	// Recipients 接收者邮箱，指定后分享仅限接收者访问
+	Recipients []string `json:"recipients" binding:"omitempty,max=100,dive,email"`
+	// RecipientUsers 以用户 ID 指定的注册用户接收者
+	RecipientUsers []string `json:"recipient_users" binding:"omitempty,max=100"`
 }
 
 // ShareUpdateService 分享更新服务
 type ShareUpdateService struct {
-	Prop  string `json:"prop" binding:"required,eq=password|eq=preview_enabled"`
+	Prop  string `json:"prop" binding:"required,eq=password|eq=preview_enabled|eq=slug|eq=remain_views"`
 	Value string `json:"value" binding:"max=255"`
 }
 
@@ -47,7 +55,10 @@ func (service *ShareUpdateService) Update(c *gin.Context) serializer.Response {
 
 	switch service.Prop {
 	case "password":
-		err := share.Update(map[string]interface{}{"password": service.Value})
+		if err := share.SetPassword(service.Value); err != nil {
+			return serializer.Err(serializer.CodeEncryptError, "Failed to hash password", err)
+		}
+		err := share.Update(map[string]interface{}{"password": share.Password})
 		if err != nil {
 			return serializer.DBErr("Failed to update share record", err)
 		}
@@ -60,6 +71,28 @@ func (service *ShareUpdateService) Update(c *gin.Context) serializer.Response {
 		return serializer.Response{
 			Data: value,
 		}
+	case "slug":
+		if err := share.UpdateSlug(service.Value); err != nil {
+			return slugErr(err)
+		}
+		return serializer.Response{
+			Data: share.CustomSlug(),
+		}
+	case "remain_views":
+		// 非正值表示不限制访问次数
+		value, err := strconv.Atoi(service.Value)
+		if err != nil {
+			return serializer.ParamErr("Invalid remain views", err)
+		}
+		if value <= 0 {
+			value = -1
+		}
+		if err := share.Update(map[string]interface{}{"remain_views": value}); err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
+		return serializer.Response{
+			Data: value,
+		}
 	}
 	return serializer.Response{
 		Data: service.Value,
@@ -112,14 +145,40 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeNotFound, "", nil)
 	}
 
+	// 以用户 ID 指定的接收者
+	recipientUsers := make([]model.User, 0, len(service.RecipientUsers))
+	for _, id := range service.RecipientUsers {
+		uid, err := hashid.DecodeHashID(id, hashid.UserID)
+		if err != nil {
+			return serializer.Err(serializer.CodeUserNotFound, "", err)
+		}
+
+		recipient, err := model.GetActiveUserByID(uid)
+		if err != nil {
+			return serializer.Err(serializer.CodeUserNotFound, "", err)
+		}
+		recipientUsers = append(recipientUsers, recipient)
+	}
+
 	newShare := model.Share{
-		Password:        service.Password,
 		IsDir:           service.IsDir,
 		UserID:          user.ID,
 		SourceID:        sourceID,
 		RemainDownloads: -1,
+		RemainViews:     -1,
 		PreviewEnabled:  service.Preview,
 		SourceName:      sourceName,
+		Restricted:      len(service.Recipients)+len(recipientUsers) > 0,
+	}
+	if err := newShare.SetPassword(service.Password); err != nil {
+		return serializer.Err(serializer.CodeEncryptError, "Failed to hash password", err)
+	}
+	if err := newShare.SetSlug(service.Slug); err != nil {
+		return slugErr(err)
+	}
+
+	if service.RemainViews > 0 {
+		newShare.RemainViews = service.RemainViews
 	}
 
 	// 如果开启了自动过期
@@ -130,21 +189,55 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 	}
 
 	// 创建分享
-	id, err := newShare.Create()
-	if err != nil {
+	if _, err := newShare.Create(); err != nil {
+		if err == model.ErrShareSlugExist {
+			return slugErr(err)
+		}
 		return serializer.DBErr("Failed to create share link record", err)
 	}
 
-	// 获取分享的唯一id
-	uid := hashid.HashID(id, hashid.ShareID)
-	// 最终得到分享链接
-	siteURL := model.GetSiteURL()
-	sharePath, _ := url.Parse("/s/" + uid)
-	shareURL := siteURL.ResolveReference(sharePath)
+	if newShare.Restricted {
+		if err := newShare.AddRecipients(service.Recipients, recipientUsers); err != nil {
+			newShare.Delete()
+			return serializer.DBErr("Failed to add share recipients", err)
+		}
+	}
 
+	webhook.Trigger(user.ID, webhook.ShareCreated, webhook.NewShare(&newShare))
 	return serializer.Response{
 		Code: 0,
-		Data: shareURL.String(),
+		Data: shareURL(&newShare),
 	}
 
 }
+
+// RotateLink 轮换分享链接，原有链接失效，分享的其他属性及统计保持不变
+func (service *Service) RotateLink(c *gin.Context, user *model.User) serializer.Response {
+	share := ownedShare(c, user)
+	if share == nil {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	if err := share.RotateLink(); err != nil {
+		return serializer.DBErr("Failed to rotate share link", err)
+	}
+
+	return serializer.Response{Data: shareURL(share)}
+}
+
+// shareURL 返回分享的完整链接
+func shareURL(share *model.Share) string {
+	sharePath, _ := url.Parse("/s/" + share.Key())
+	return model.GetSiteURL().ResolveReference(sharePath).String()
+}
+
+// slugErr 将自定义短链接错误转换为响应
+func slugErr(err error) serializer.Response {
+	switch err {
+	case model.ErrShareSlugExist:
+		return serializer.Err(serializer.CodeShareSlugExist, "", err)
+	case model.ErrShareSlugInvalid:
+		return serializer.Err(serializer.CodeShareSlugInvalid, "", err)
+	}
+	return serializer.DBErr("Failed to update share record", err)
+}
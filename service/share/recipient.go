@@ -0,0 +1,182 @@
+package share
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	model "gitee.com/jiangjiali/cloudreve/models"
+	"gitee.com/jiangjiali/cloudreve/pkg/cache"
+	"gitee.com/jiangjiali/cloudreve/pkg/email"
+	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
+	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
+	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	shareCodeTTL         = 600
+	shareCodeCooldown    = 60
+	shareCodeMaxAttempts = 5
+)
+
+// ShareCodeService 发送受限分享访问验证码服务
+type ShareCodeService struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ShareCodeVerifyService 校验受限分享访问验证码服务
+type ShareCodeVerifyService struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// ShareRecipient 分享接收者
+type ShareRecipient struct {
+	ID         string    `json:"id"`
+	Email      string    `json:"email"`
+	Registered bool      `json:"registered"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func shareCodeKey(shareID uint, email string) string {
+	return fmt.Sprintf("share_code_%d_%s", shareID, strings.ToLower(email))
+}
+
+// Send 向受限分享的接收者发送访问验证码，非接收者的邮箱同样返回成功以避免被探测
+func (service *ShareCodeService) Send(c *gin.Context) serializer.Response {
+	share := c.MustGet("share").(*model.Share)
+	if !share.Restricted {
+		return serializer.ParamErr("This share is not restricted", nil)
+	}
+
+	if !share.IsRecipientEmail(service.Email) {
+		return serializer.Response{}
+	}
+
+	// 限制发送频率
+	key := shareCodeKey(share.ID, service.Email)
+	if _, ok := cache.Get(key + "_cooldown"); ok {
+		return serializer.Err(serializer.CodeEmailSent, "Please wait before requesting another code", nil)
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to generate share code", err)
+	}
+
+	code := fmt.Sprintf("%06d", n.Int64())
+	if err := cache.Set(key, code, shareCodeTTL); err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to save share code", err)
+	}
+	cache.Set(key+"_cooldown", true, shareCodeCooldown)
+	cache.Deletes([]string{key + "_fail"}, "")
+
+	title, body := email.NewShareCodeEmail(share.Creator().Nick, share.SourceName, code, shareCodeTTL)
+	if err := email.Send(service.Email, title, body); err != nil {
+		cache.Deletes([]string{key, key + "_cooldown"}, "")
+		return serializer.Err(serializer.CodeFailedSendEmail, "Failed to send email", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Verify 校验访问验证码，通过后当前会话可访问受限分享
+func (service *ShareCodeVerifyService) Verify(c *gin.Context) serializer.Response {
+	share := c.MustGet("share").(*model.Share)
+	key := shareCodeKey(share.ID, service.Email)
+
+	expected, ok := cache.Get(key)
+	if !ok {
+		return serializer.Err(serializer.CodeShareCodeInvalid, "", nil)
+	}
+
+	if expected.(string) != service.Code {
+		// 错误次数过多时作废验证码
+		failures, _ := cache.Get(key + "_fail")
+		count, _ := failures.(int)
+		if count+1 >= shareCodeMaxAttempts {
+			cache.Deletes([]string{key, key + "_fail"}, "")
+		} else {
+			cache.Set(key+"_fail", count+1, shareCodeTTL)
+		}
+		return serializer.Err(serializer.CodeShareCodeInvalid, "", nil)
+	}
+
+	// 验证码只能使用一次
+	cache.Deletes([]string{key, key + "_fail"}, "")
+	if !share.IsRecipientEmail(service.Email) {
+		return serializer.Err(serializer.CodeShareCodeInvalid, "", nil)
+	}
+
+	util.SetSession(c, map[string]interface{}{
+		fmt.Sprintf("share_recipient_%d", share.ID): strings.ToLower(service.Email),
+	})
+	return serializer.Response{}
+}
+
+// Received 列出指定给当前用户的分享
+func (service *Service) Received(c *gin.Context, user *model.User) serializer.Response {
+	shares := model.ListReceivedShares(user)
+	res := make([]serializer.Share, 0, len(shares))
+	for i := range shares {
+		if shares[i].IsAvailable() {
+			res = append(res, serializer.BuildShareResponse(&shares[i], shares[i].Password == ""))
+		}
+	}
+
+	return serializer.Response{Data: res}
+}
+
+// Recipients 列出分享的接收者
+func (service *Service) Recipients(c *gin.Context, user *model.User) serializer.Response {
+	share := ownedShare(c, user)
+	if share == nil {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	recipients, err := share.Recipients()
+	if err != nil {
+		return serializer.DBErr("Failed to list share recipients", err)
+	}
+
+	res := make([]ShareRecipient, 0, len(recipients))
+	for _, recipient := range recipients {
+		// 创建分享后才注册的接收者按邮箱判断
+		registered := recipient.UserID > 0
+		if !registered {
+			_, err := model.GetActiveUserByEmail(recipient.Email)
+			registered = err == nil
+		}
+
+		res = append(res, ShareRecipient{
+			ID:         hashid.HashID(recipient.ID, hashid.RecipientID),
+			Email:      recipient.Email,
+			Registered: registered,
+			CreatedAt:  recipient.CreatedAt,
+		})
+	}
+
+	return serializer.Response{Data: res}
+}
+
+// RevokeRecipient 撤销分享的指定接收者
+func (service *Service) RevokeRecipient(c *gin.Context, user *model.User) serializer.Response {
+	share := ownedShare(c, user)
+	if share == nil {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	recipientID, err := hashid.DecodeHashID(c.Param("recipient"), hashid.RecipientID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	if err := share.DeleteRecipient(recipientID); err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Recipient not exist", err)
+	}
+
+	return serializer.Response{}
+}
@@ -12,6 +12,7 @@ import (
 	"gitee.com/jiangjiali/cloudreve/pkg/hashid"
 	"gitee.com/jiangjiali/cloudreve/pkg/serializer"
 	"gitee.com/jiangjiali/cloudreve/pkg/util"
+	"gitee.com/jiangjiali/cloudreve/pkg/webhook"
 	"gitee.com/jiangjiali/cloudreve/service/explorer"
 	"github.com/gin-gonic/gin"
 )
@@ -119,27 +120,43 @@ func (service *ShareGetService) Get(c *gin.Context) serializer.Response {
 	shareCtx, _ := c.Get("share")
 	share := shareCtx.(*model.Share)
 
+	// 是否可访问受限分享
+	userCtx, _ := c.Get("user")
+	user := userCtx.(*model.User)
+	unlocked := share.CanBeAccessedBy(user, c)
+
 	// 是否已解锁
-	unlocked := true
-	if share.Password != "" {
+	if unlocked && share.Password != "" {
 		sessionKey := fmt.Sprintf("share_unlock_%d", share.ID)
 		unlocked = util.GetSession(c, sessionKey) != nil
 		if !unlocked && service.Password != "" {
 			// 如果未解锁，且指定了密码，则尝试解锁
-			if service.Password == share.Password {
+			if share.CheckPassword(service.Password) {
 				unlocked = true
+				share.UnlockSucceeded(c.ClientIP())
 				util.SetSession(c, map[string]interface{}{sessionKey: true})
+			} else {
+				share.UnlockFailed(c.ClientIP())
 			}
 		}
 	}
 
 	if unlocked {
-		share.Viewed()
+		share.ViewedBy(user, c)
+		recordAccess(c, share, model.ShareActionView, "")
+		webhook.Trigger(share.UserID, webhook.ShareAccessed, webhook.NewShare(share))
+	}
+
+	res := serializer.BuildShareResponse(share, unlocked)
+	if !unlocked && share.Password != "" {
+		byIP, _ := share.UnlockFailures(c.ClientIP())
+		threshold := model.GetIntSetting("share_unlock_captcha_attempts", 3)
+		res.CaptchaRequired = threshold > 0 && byIP >= threshold
 	}
 
 	return serializer.Response{
 		Code: 0,
-		Data: serializer.BuildShareResponse(share, unlocked),
+		Data: res,
 	}
 }
 
@@ -182,6 +199,8 @@ func (service *Service) CreateDownloadSession(c *gin.Context) serializer.Respons
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	recordAccess(c, share, model.ShareActionDownload, service.Path)
+
 	return serializer.Response{
 		Code: 0,
 		Data: downloadURL,
@@ -203,7 +222,12 @@ func (service *Service) PreviewContent(ctx context.Context, c *gin.Context, isTe
 	}
 	subService := explorer.FileIDService{}
 
-	return subService.PreviewContent(ctx, c, isText)
+	res := subService.PreviewContent(ctx, c, isText)
+	if res.Code == 0 || res.Code == -301 {
+		recordAccess(c, share, model.ShareActionPreview, service.Path)
+	}
+
+	return res
 }
 
 // CreateDocPreviewSession 创建Office预览会话，返回预览地址
@@ -221,7 +245,12 @@ func (service *Service) CreateDocPreviewSession(c *gin.Context) serializer.Respo
 	}
 	subService := explorer.FileIDService{}
 
-	return subService.CreateDocPreviewSession(ctx, c, false)
+	res := subService.CreateDocPreviewSession(ctx, c, false)
+	if res.Code == 0 {
+		recordAccess(c, share, model.ShareActionPreview, service.Path)
+	}
+
+	return res
 }
 
 // List 列出分享的目录下的对象
@@ -253,7 +282,7 @@ func (service *Service) List(c *gin.Context) serializer.Response {
 	fs.Root.Name = "/"
 
 	// 分享Key上下文
-	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, hashid.HashID(share.ID, hashid.ShareID))
+	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, share.Key())
 
 	// 获取子项目
 	objects, err := fs.List(ctx, service.Path, nil)
@@ -364,7 +393,13 @@ func (service *ArchiveService) Archive(c *gin.Context) serializer.Response {
 		Items: service.Items,
 	}
 
-	return subService.Archive(ctx, c)
+	res := subService.Archive(ctx, c)
+	if res.Code == 0 {
+		c.Set("user", user)
+		recordAccess(c, share, model.ShareActionArchive, service.Path)
+	}
+
+	return res
 }
 
 // SearchService 对分享的目录进行搜索
@@ -409,7 +444,7 @@ func (service *SearchService) Search(c *gin.Context) serializer.Response {
 	}
 
 	// 分享Key上下文
-	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, hashid.HashID(share.ID, hashid.ShareID))
+	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, share.Key())
 
 	return service.SearchKeywords(c, fs, "%"+service.Keywords+"%")
 }